@@ -12,7 +12,9 @@ import (
 	"os/signal"
 	"shared/config"
 	"shared/kafka/producer"
+	"shared/mailer"
 	"shared/mongodb"
+	"shared/storage"
 	"shared/utils"
 	"strings"
 	"syscall"
@@ -65,6 +67,11 @@ func main() {
 		log.Fatal("CORS: No allowed origins specified, please specify with CORS_ALLOW_ORIGINS environment variable")
 	}
 
+	// Without trusted proxies the client IP is the connecting address, so it can't be spoofed with X-Forwarded-For
+	if err := r.SetTrustedProxies(apiConfig.TRUSTED_PROXIES); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	r.Use(middlewares.CORSMiddleware(strings.Join(apiConfig.CORS_ALLOW_ORIGINS, ",")))
 
 	mongoService, cleanup, err := mongodb.NewService()
@@ -80,16 +87,33 @@ func main() {
 		log.Fatalf("Failed to seed plans: %v", err)
 	}
 
+	err = mongoService.CreateIndexes(context.TODO())
+	if err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+
 	producer, err := producer.NewMessageProducer()
 	if err != nil {
 		log.Fatalf("Failed to create Kafka producer: %v", err)
 	}
 	defer producer.Close()
 
+	objectStorage, err := storage.NewObjectStorage()
+	if err != nil {
+		log.Fatalf("Failed to create object storage: %v", err)
+	}
+
+	platformMailer, err := mailer.NewMailer()
+	if err != nil {
+		log.Fatalf("Failed to create mailer: %v", err)
+	}
+
 	// Setup routes
 	params := types.RouteParams{
 		MongoService:    mongoService,
 		MessageProducer: producer,
+		ObjectStorage:   objectStorage,
+		Mailer:          platformMailer,
 	}
 	routes.SetupRoutes(r, &params)
 
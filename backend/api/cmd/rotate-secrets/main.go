@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"shared/mongodb"
+)
+
+/*
+Re-encrypts event secrets and the secrets of users' authenticator apps after the master key is rotated. Add the new key to
+SECRETS_MASTER_KEYS and make it SECRETS_MASTER_KEY_ID, keeping the old key, deploy, then run this with the same config:
+
+	go run ./cmd/rotate-secrets
+
+Only the data keys are re-wrapped, the secrets themselves stay as they are. Secrets stored before they were encrypted get encrypted too.
+Once it finishes the old key can be removed from SECRETS_MASTER_KEYS.
+*/
+
+func main() {
+	mongoService, cleanup, err := mongodb.NewService()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cleanup()
+
+	rotated, err := mongoService.RotateEventSecretsKey(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to rotate event secrets after %d events: %v", rotated, err)
+	}
+
+	log.Printf("Re-encrypted the secrets of %d events with master key %q", rotated, mongoService.Keys.ActiveKeyID())
+
+	rotated, err = mongoService.RotateTwoFactorSecretsKey(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to rotate two-factor secrets after %d users: %v", rotated, err)
+	}
+
+	log.Printf("Re-encrypted the two-factor secrets of %d users with master key %q", rotated, mongoService.Keys.ActiveKeyID())
+}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Export writes tabular data one row at a time in the formats organizers download responses as,
+so an export streams straight to the client without holding every row in memory.
+*/
+
+// Format of an export file
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatXLSX    Format = "xlsx"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+var formatContentTypes = map[Format]string{
+	FormatCSV:     "text/csv",
+	FormatXLSX:    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatParquet: "application/vnd.apache.parquet",
+}
+
+// ErrUnknownFormat is returned when asked for a format we can't export to
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ParseFormat reads a format from its name or file extension, or from a content type as sent in an Accept header
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "jsonl", "json-lines":
+		return FormatNDJSON, nil
+	case "xls", "excel":
+		return FormatXLSX, nil
+	}
+
+	for format, contentType := range formatContentTypes {
+		if name == string(format) || name == contentType {
+			return format, nil
+		}
+	}
+
+	return "", ErrUnknownFormat
+}
+
+// ContentType is the MIME type of files in the format
+func (f Format) ContentType() string {
+	return formatContentTypes[f]
+}
+
+// Extension is the file extension of files in the format, without the dot
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// ColumnType is the kind of value a column holds, formats with types use it for the column's type
+type ColumnType int
+
+const (
+	ColumnString ColumnType = iota
+	ColumnNumber
+	ColumnBool
+	ColumnTime
+)
+
+// Column of an export
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Writer writes the rows of an export, each row holds a value for every column in order.
+// Values that don't fit their column's type are written as text, or left empty in Parquet which is strictly typed.
+// nil and "" leave the cell empty
+type Writer interface {
+	WriteRow(values []interface{}) error
+	// Close finishes the file, the output is incomplete until it is closed
+	Close() error
+}
+
+// NewWriter starts an export in the format to w
+func NewWriter(format Format, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns), nil
+	case FormatParquet:
+		return newParquetWriter(w, columns)
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+// UniqueColumnNames renames columns that share a name, eg: two questions with the same text, as "Name (2)"
+func UniqueColumnNames(columns []Column) []Column {
+	seen := make(map[string]int)
+	unique := make([]Column, len(columns))
+	for i, column := range columns {
+		seen[column.Name]++
+		if n := seen[column.Name]; n > 1 {
+			column.Name = fmt.Sprintf("%s (%d)", column.Name, n)
+		}
+		unique[i] = column
+	}
+
+	return unique
+}
+
+func isEmpty(value interface{}) bool {
+	return value == nil || value == ""
+}
+
+// formatText writes a value as the text of a cell
+func formatText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// toNumber reads a number cell, stored numbers decode to different types depending on where they came from
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil && !math.IsNaN(number) && !math.IsInf(number, 0)
+	}
+	return 0, false
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
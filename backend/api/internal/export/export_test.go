@@ -0,0 +1,156 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	testColumns = []Column{
+		{Name: "Name", Type: ColumnString},
+		{Name: "Age", Type: ColumnNumber},
+		{Name: "Accepted", Type: ColumnBool},
+		{Name: "Submitted At", Type: ColumnTime},
+	}
+	testSubmittedAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func writeTestExport(t *testing.T, format Format, rows ...[]interface{}) []byte {
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf, testColumns)
+	require.NoError(t, err)
+
+	for _, row := range rows {
+		require.NoError(t, writer.WriteRow(row))
+	}
+	require.NoError(t, writer.Close())
+
+	return buf.Bytes()
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]Format{
+		"csv":                  FormatCSV,
+		" XLSX ":               FormatXLSX,
+		"jsonl":                FormatNDJSON,
+		"application/x-ndjson": FormatNDJSON,
+		"parquet":              FormatParquet,
+	} {
+		format, err := ParseFormat(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, format)
+	}
+
+	_, err := ParseFormat("pdf")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestUniqueColumnNames(t *testing.T) {
+	columns := UniqueColumnNames([]Column{{Name: "Name"}, {Name: "Email"}, {Name: "Name"}, {Name: "Name"}})
+	assert.Equal(t, []string{"Name", "Email", "Name (2)", "Name (3)"}, []string{columns[0].Name, columns[1].Name, columns[2].Name, columns[3].Name})
+}
+
+func TestCSVWriter(t *testing.T) {
+	out := writeTestExport(t, FormatCSV,
+		[]interface{}{"Ada, Lovelace", 36.0, true, testSubmittedAt},
+		[]interface{}{"Grace", nil},
+	)
+
+	assert.Equal(t, "Name,Age,Accepted,Submitted At\n\"Ada, Lovelace\",36,true,2024-03-01T12:00:00Z\nGrace,,,\n", string(out))
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	out := writeTestExport(t, FormatNDJSON,
+		[]interface{}{"Ada", 36.0, true, testSubmittedAt},
+		[]interface{}{primitive.D{{Key: "b", Value: primitive.A{"x"}}, {Key: "a", Value: 1}}},
+	)
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"Name":"Ada","Age":36,"Accepted":true,"Submitted At":"2024-03-01T12:00:00Z"}`, lines[0])
+	assert.Equal(t, `{"Name":{"a":1,"b":["x"]},"Age":null,"Accepted":null,"Submitted At":null}`, lines[1])
+}
+
+func TestXLSXWriter(t *testing.T) {
+	out := writeTestExport(t, FormatXLSX,
+		[]interface{}{"Ada & <Co>", 36.0, true, testSubmittedAt},
+		[]interface{}{"Grace", "unknown", nil, ""},
+	)
+
+	archive, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		parts[file.Name] = string(content)
+	}
+
+	assert.Contains(t, parts, "[Content_Types].xml")
+	assert.Contains(t, parts, "xl/workbook.xml")
+	assert.Contains(t, parts, "xl/styles.xml")
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<row r="1"><c r="A1" s="2" t="inlineStr"><is><t xml:space="preserve">Name</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">Ada &amp; &lt;Co&gt;</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>36</v></c>`)
+	assert.Contains(t, sheet, `<c r="C2" t="b"><v>1</v></c>`)
+	assert.Contains(t, sheet, `<c r="D2" s="1"><v>45352.5</v></c>`)
+	// Values that aren't numbers fall back to text and empty cells are skipped
+	assert.Contains(t, sheet, `<row r="3"><c r="A3" t="inlineStr"><is><t xml:space="preserve">Grace</t></is></c><c r="B3" t="inlineStr"><is><t xml:space="preserve">unknown</t></is></c></row>`)
+	assert.True(t, strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumnName(0))
+	assert.Equal(t, "Z", xlsxColumnName(25))
+	assert.Equal(t, "AA", xlsxColumnName(26))
+	assert.Equal(t, "AZ", xlsxColumnName(51))
+	assert.Equal(t, "BA", xlsxColumnName(52))
+}
+
+func TestParquetWriter(t *testing.T) {
+	out := writeTestExport(t, FormatParquet,
+		[]interface{}{"Ada", 36.0, true, testSubmittedAt},
+		[]interface{}{"Grace", "unknown", nil, nil},
+	)
+
+	file, err := parquet.OpenFile(bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), file.NumRows())
+
+	reader := parquet.NewReader(file)
+	rows := make([]parquet.Row, 2)
+	n, err := reader.ReadRows(rows)
+	if err != io.EOF {
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, n)
+
+	value := func(row parquet.Row, name string) parquet.Value {
+		leaf, ok := file.Schema().Lookup(name)
+		require.True(t, ok)
+		return row[leaf.ColumnIndex]
+	}
+
+	assert.Equal(t, "Ada", value(rows[0], "Name").String())
+	assert.Equal(t, 36.0, value(rows[0], "Age").Double())
+	assert.True(t, value(rows[0], "Accepted").Boolean())
+	assert.Equal(t, testSubmittedAt.UnixMilli(), value(rows[0], "Submitted At").Int64())
+
+	assert.Equal(t, "Grace", value(rows[1], "Name").String())
+	assert.True(t, value(rows[1], "Age").IsNull())
+	assert.True(t, value(rows[1], "Accepted").IsNull())
+}
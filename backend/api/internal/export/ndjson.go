@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ndjsonWriter writes each row as a JSON object on its own line, keyed by column name in column order.
+// Values keep their structure, eg: matrix answers stay objects
+type ndjsonWriter struct {
+	buffer  *bufio.Writer
+	columns [][]byte // the encoded column names
+}
+
+func newNDJSONWriter(w io.Writer, columns []Column) *ndjsonWriter {
+	names := make([][]byte, len(columns))
+	for i, column := range columns {
+		names[i], _ = json.Marshal(column.Name)
+	}
+
+	return &ndjsonWriter{buffer: bufio.NewWriter(w), columns: names}
+}
+
+func (w *ndjsonWriter) WriteRow(values []interface{}) error {
+	w.buffer.WriteByte('{')
+	for i, name := range w.columns {
+		var value interface{}
+		if i < len(values) {
+			value = values[i]
+		}
+
+		encoded, err := json.Marshal(plainValue(value))
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			w.buffer.WriteByte(',')
+		}
+		w.buffer.Write(name)
+		w.buffer.WriteByte(':')
+		w.buffer.Write(encoded)
+	}
+	// Write errors are sticky, so this reports any failed write of the row, eg: the client went away
+	_, err := w.buffer.WriteString("}\n")
+	return err
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buffer.Flush()
+}
+
+// plainValue turns the documents and arrays decoded from mongo into plain maps and slices, so they encode as JSON objects
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.D:
+		m := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			m[e.Key] = plainValue(e.Value)
+		}
+		return m
+	case primitive.M:
+		return plainValue(map[string]interface{}(v))
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = plainValue(item)
+		}
+		return m
+	case primitive.A:
+		return plainValue([]interface{}(v))
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = plainValue(item)
+		}
+		return list
+	}
+	return value
+}
@@ -0,0 +1,116 @@
+package export
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	// parquetRowGroupSize is how many rows are buffered before they are written out as a row group
+	parquetRowGroupSize = 5000
+
+	// parquetWriteBatchSize is how many rows are handed to the parquet writer at once
+	parquetWriteBatchSize = 100
+)
+
+// parquetWriter writes every column as an optional leaf of the column's type
+type parquetWriter struct {
+	writer  *parquet.Writer
+	columns []Column
+	leaves  []int // the parquet column index of each of our columns, parquet orders a group's columns by name
+	rows    []parquet.Row
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (*parquetWriter, error) {
+	group := parquet.Group{}
+	for _, column := range columns {
+		group[column.Name] = parquet.Optional(parquetNode(column.Type))
+	}
+
+	schema := parquet.NewSchema("responses", group)
+	leaves := make([]int, len(columns))
+	for i, column := range columns {
+		leaf, _ := schema.Lookup(column.Name)
+		leaves[i] = leaf.ColumnIndex
+	}
+
+	writer := parquet.NewWriter(w, schema, parquet.MaxRowsPerRowGroup(parquetRowGroupSize))
+	return &parquetWriter{writer: writer, columns: columns, leaves: leaves}, nil
+}
+
+func parquetNode(columnType ColumnType) parquet.Node {
+	switch columnType {
+	case ColumnNumber:
+		return parquet.Leaf(parquet.DoubleType)
+	case ColumnBool:
+		return parquet.Leaf(parquet.BooleanType)
+	case ColumnTime:
+		return parquet.Timestamp(parquet.Millisecond)
+	}
+	return parquet.String()
+}
+
+func (w *parquetWriter) WriteRow(values []interface{}) error {
+	row := make(parquet.Row, len(w.columns))
+	for i, column := range w.columns {
+		var value interface{}
+		if i < len(values) {
+			value = values[i]
+		}
+
+		// The definition level of an optional column says whether it has a value
+		cell := parquetValue(column.Type, value)
+		definitionLevel := 1
+		if cell.IsNull() {
+			definitionLevel = 0
+		}
+		row[w.leaves[i]] = cell.Level(0, definitionLevel, w.leaves[i])
+	}
+
+	w.rows = append(w.rows, row)
+	if len(w.rows) < parquetWriteBatchSize {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *parquetWriter) flush() error {
+	_, err := w.writer.WriteRows(w.rows)
+	w.rows = w.rows[:0]
+	return err
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.writer.Close()
+}
+
+// parquetValue converts a cell to the column's type, values that don't fit are null
+func parquetValue(columnType ColumnType, value interface{}) parquet.Value {
+	if isEmpty(value) {
+		return parquet.NullValue()
+	}
+
+	switch columnType {
+	case ColumnNumber:
+		if number, ok := toNumber(value); ok {
+			return parquet.DoubleValue(number)
+		}
+		return parquet.NullValue()
+	case ColumnBool:
+		if checked, ok := value.(bool); ok {
+			return parquet.BooleanValue(checked)
+		}
+		return parquet.NullValue()
+	case ColumnTime:
+		if t, ok := toTime(value); ok {
+			return parquet.Int64Value(t.UnixMilli())
+		}
+		return parquet.NullValue()
+	}
+
+	return parquet.ByteArrayValue([]byte(formatText(value)))
+}
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Proof of work makes every anonymous submission cost the client some CPU time, which is cheap for a person but adds up for a bot.
+
+The API hands out a signed challenge, the client searches for a solution where sha256("<challenge>:<solution>")
+starts with the challenge's number of zero bits, eg: by counting up from 0.
+Challenges are stateless until they are spent, the caller records spent challenges so each one is only used once.
+
+	<scope>.<expires unix>.<difficulty>.<nonce>.<signature>
+*/
+
+const ProofOfWorkChallengeTTL = 10 * time.Minute
+
+var (
+	// ErrInvalidProofOfWork is returned when a challenge wasn't issued by us, is for another scope or the solution doesn't satisfy it
+	ErrInvalidProofOfWork = errors.New("invalid proof of work")
+
+	// ErrExpiredProofOfWork is returned when the challenge was solved too late
+	ErrExpiredProofOfWork = errors.New("proof of work challenge has expired")
+)
+
+// ProofOfWorkChallenge is what the client needs to solve a challenge
+type ProofOfWorkChallenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"` // leading zero bits the solution's hash needs
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// NewProofOfWorkChallenge issues a challenge that can only be spent on the scope, eg: a form ID
+func NewProofOfWorkChallenge(secret []byte, scope string, difficulty int, now time.Time) (ProofOfWorkChallenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return ProofOfWorkChallenge{}, err
+	}
+
+	expiresAt := now.Add(ProofOfWorkChallengeTTL).Truncate(time.Second)
+	payload := fmt.Sprintf("%s.%d.%d.%s", scope, expiresAt.Unix(), difficulty, hex.EncodeToString(nonce))
+
+	return ProofOfWorkChallenge{
+		Challenge:  payload + "." + signToken(secret, payload),
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// VerifyProofOfWork checks the solution to a challenge we issued for the scope, it returns when the challenge expires
+// so the caller can remember it as spent until then
+func VerifyProofOfWork(secret []byte, scope string, challenge string, solution string, now time.Time) (time.Time, error) {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 5 || solution == "" {
+		return time.Time{}, ErrInvalidProofOfWork
+	}
+
+	payload := strings.Join(parts[:4], ".")
+	if !hmac.Equal([]byte(parts[4]), []byte(signToken(secret, payload))) || parts[0] != scope {
+		return time.Time{}, ErrInvalidProofOfWork
+	}
+
+	expiresUnix, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidProofOfWork
+	}
+
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return time.Time{}, ErrInvalidProofOfWork
+	}
+
+	expiresAt := time.Unix(expiresUnix, 0)
+	if !now.Before(expiresAt) {
+		return time.Time{}, ErrExpiredProofOfWork
+	}
+
+	hash := sha256.Sum256([]byte(challenge + ":" + solution))
+	if leadingZeroBits(hash[:]) < difficulty {
+		return time.Time{}, ErrInvalidProofOfWork
+	}
+
+	return expiresAt, nil
+}
+
+func leadingZeroBits(hash []byte) int {
+	count := 0
+	for _, b := range hash {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return count
+}
@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// solveProofOfWork does what a client does, counting up until the hash has enough zero bits
+func solveProofOfWork(challenge ProofOfWorkChallenge) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		hash := sha256.Sum256([]byte(challenge.Challenge + ":" + solution))
+		if leadingZeroBits(hash[:]) >= challenge.Difficulty {
+			return solution
+		}
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	assert.Equal(t, 0, leadingZeroBits([]byte{0x80}))
+	assert.Equal(t, 7, leadingZeroBits([]byte{0x01, 0xff}))
+	assert.Equal(t, 12, leadingZeroBits([]byte{0x00, 0x0f}))
+	assert.Equal(t, 16, leadingZeroBits([]byte{0x00, 0x00}))
+}
+
+func TestVerifyProofOfWork(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+
+	challenge, err := NewProofOfWorkChallenge(secret, "form", 8, now)
+	require.NoError(t, err)
+	solution := solveProofOfWork(challenge)
+
+	t.Run("valid solution", func(t *testing.T) {
+		expiresAt, err := VerifyProofOfWork(secret, "form", challenge.Challenge, solution, now)
+		assert.NoError(t, err)
+		assert.Equal(t, challenge.ExpiresAt.Unix(), expiresAt.Unix())
+	})
+
+	t.Run("wrong solution", func(t *testing.T) {
+		// Find a solution that doesn't satisfy the difficulty
+		wrong := ""
+		for i := 0; wrong == ""; i++ {
+			candidate := "x" + strconv.Itoa(i)
+			hash := sha256.Sum256([]byte(challenge.Challenge + ":" + candidate))
+			if leadingZeroBits(hash[:]) < challenge.Difficulty {
+				wrong = candidate
+			}
+		}
+
+		_, err := VerifyProofOfWork(secret, "form", challenge.Challenge, wrong, now)
+		assert.ErrorIs(t, err, ErrInvalidProofOfWork)
+	})
+
+	t.Run("other scope", func(t *testing.T) {
+		_, err := VerifyProofOfWork(secret, "other-form", challenge.Challenge, solution, now)
+		assert.ErrorIs(t, err, ErrInvalidProofOfWork)
+	})
+
+	t.Run("other secret", func(t *testing.T) {
+		_, err := VerifyProofOfWork([]byte("not the secret"), "form", challenge.Challenge, solution, now)
+		assert.ErrorIs(t, err, ErrInvalidProofOfWork)
+	})
+
+	t.Run("lowered difficulty", func(t *testing.T) {
+		parts := strings.Split(challenge.Challenge, ".")
+		parts[2] = "0"
+		_, err := VerifyProofOfWork(secret, "form", strings.Join(parts, "."), "anything", now)
+		assert.ErrorIs(t, err, ErrInvalidProofOfWork)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := VerifyProofOfWork(secret, "form", challenge.Challenge, solution, now.Add(ProofOfWorkChallengeTTL+time.Second))
+		assert.ErrorIs(t, err, ErrExpiredProofOfWork)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := VerifyProofOfWork(secret, "form", "not-a-challenge", solution, now)
+		assert.ErrorIs(t, err, ErrInvalidProofOfWork)
+	})
+}
@@ -0,0 +1,64 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Signed tokens go into links we email out, they carry what they are for and until when, so nothing but the subject needs to be stored.
+The subject can't contain a ".", the caller looks it up to check the token hasn't been revoked.
+
+	<subject>.<expires unix>.<signature>
+*/
+
+var (
+	// ErrInvalidSignedToken is returned when a token wasn't signed by us with the secret
+	ErrInvalidSignedToken = errors.New("invalid token")
+
+	// ErrExpiredSignedToken is returned when a token is used after it expired
+	ErrExpiredSignedToken = errors.New("token has expired")
+)
+
+// NewSignedToken signs a token for the subject that is valid until expiresAt, which is truncated to the second
+func NewSignedToken(secret []byte, subject string, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%s.%d", subject, expiresAt.Unix())
+	return payload + "." + signToken(secret, payload)
+}
+
+// VerifySignedToken checks a token we signed with the secret hasn't expired, it returns its subject and expiry
+func VerifySignedToken(secret []byte, token string, now time.Time) (string, time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(signToken(secret, payload))) {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+
+	expiresUnix, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+
+	expiresAt := time.Unix(expiresUnix, 0)
+	if !now.Before(expiresAt) {
+		return "", time.Time{}, ErrExpiredSignedToken
+	}
+
+	return parts[0], expiresAt, nil
+}
+
+func signToken(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
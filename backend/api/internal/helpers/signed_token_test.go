@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifySignedToken(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+	token := NewSignedToken(secret, "invite", expiresAt)
+
+	t.Run("valid token", func(t *testing.T) {
+		subject, tokenExpiresAt, err := VerifySignedToken(secret, token, now)
+		require.NoError(t, err)
+		assert.Equal(t, "invite", subject)
+		assert.Equal(t, expiresAt.Unix(), tokenExpiresAt.Unix())
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		_, _, err := VerifySignedToken(secret, token, expiresAt)
+		assert.ErrorIs(t, err, ErrExpiredSignedToken)
+	})
+
+	t.Run("other secret", func(t *testing.T) {
+		_, _, err := VerifySignedToken([]byte("other"), token, now)
+		assert.ErrorIs(t, err, ErrInvalidSignedToken)
+	})
+
+	t.Run("tampered expiry", func(t *testing.T) {
+		tampered := NewSignedToken(secret, "invite", expiresAt.Add(time.Hour))
+		forged := tampered[:len(tampered)-64] + token[len(token)-64:]
+		_, _, err := VerifySignedToken(secret, forged, now)
+		assert.ErrorIs(t, err, ErrInvalidSignedToken)
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		for _, malformed := range []string{"", "invite", "invite.123", ".123.abc", "invite.soon." + token[len(token)-64:]} {
+			_, _, err := VerifySignedToken(secret, malformed, now)
+			assert.ErrorIs(t, err, ErrInvalidSignedToken, malformed)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The secret of the RFC 6238 test vectors, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestVerifyTOTP(t *testing.T) {
+	// The RFC's 8 digit codes end with the 6 digit ones
+	tests := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, code := range tests {
+		step, ok := VerifyTOTP(rfcSecret, code, time.Unix(unix, 0))
+		assert.True(t, ok, "code at %d", unix)
+		assert.Equal(t, unix/30, step)
+	}
+}
+
+func TestVerifyTOTPAcceptsAdjacentSteps(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	step, ok := VerifyTOTP(rfcSecret, "287082", now.Add(30*time.Second))
+	assert.True(t, ok, "the previous step's code")
+	assert.Equal(t, int64(1), step)
+
+	_, ok = VerifyTOTP(rfcSecret, "287082", now.Add(90*time.Second))
+	assert.False(t, ok, "a code from two steps ago")
+
+	_, ok = VerifyTOTP(rfcSecret, "000000", now)
+	assert.False(t, ok)
+
+	_, ok = VerifyTOTP(rfcSecret, "28708", now)
+	assert.False(t, ok)
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	now := time.Now()
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	_, ok := VerifyTOTP(secret, totpCode(key, now.Unix()/30), now)
+	assert.True(t, ok)
+
+	assert.True(t, strings.HasPrefix(TOTPURL("ApplicantAtlas", "ada@example.com", secret), "otpauth://totp/ApplicantAtlas:ada@example.com?"))
+}
@@ -0,0 +1,107 @@
+package imports
+
+import (
+	"api/internal/export"
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCSV(t *testing.T) {
+	data := []byte("\ufeffName , Age,\nAda,36\n,\nGrace,45,extra\n,\n")
+
+	table, err := ReadTable("applicants.CSV", data, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Name", "Age"}, table.Header)
+	assert.Equal(t, [][]string{{"Ada", "36"}, {"", ""}, {"Grace", "45"}}, table.Rows)
+	assert.Equal(t, 4, table.RowNumber(2))
+	assert.True(t, IsEmptyRow(table.Rows[1]))
+}
+
+func TestReadTableErrors(t *testing.T) {
+	_, err := ReadTable("applicants.ods", []byte("Name\nAda\n"), 10)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = ReadTable("applicants.csv", []byte("Name\nAda\nGrace\n"), 1)
+	assert.ErrorIs(t, err, ErrTooManyRows)
+
+	_, err = ReadTable("applicants.csv", []byte(",\nAda,36\n"), 10)
+	assert.ErrorIs(t, err, ErrNoHeader)
+
+	_, err = ReadTable("applicants.xlsx", []byte("not a zip"), 10)
+	assert.Error(t, err)
+}
+
+func TestReadExportedXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := export.NewWriter(export.FormatXLSX, &buf, []export.Column{
+		{Name: "Name", Type: export.ColumnString},
+		{Name: "Age", Type: export.ColumnNumber},
+		{Name: "Accepted", Type: export.ColumnBool},
+		{Name: "Born", Type: export.ColumnTime},
+		{Name: "Submitted At", Type: export.ColumnTime},
+	})
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteRow([]interface{}{"Ada <Lovelace>", 36, true, time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)}))
+	require.NoError(t, writer.WriteRow([]interface{}{"Grace", nil, false, nil, nil}))
+	require.NoError(t, writer.Close())
+
+	table, err := ReadTable("responses.xlsx", buf.Bytes(), 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Name", "Age", "Accepted", "Born", "Submitted At"}, table.Header)
+	assert.Equal(t, [][]string{
+		{"Ada <Lovelace>", "36", "TRUE", "1815-12-10", "2024-03-01T12:30:00Z"},
+		{"Grace", "", "FALSE", "", ""},
+	}, table.Rows)
+}
+
+func TestReadXLSXSharedStrings(t *testing.T) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Applicants" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId7" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/applicants.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Name</t></si><si><r><t>Ada </t></r><r><t>Lovelace</t></r></si></sst>`,
+		"xl/worksheets/applicants.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c></row>` +
+			`<row r="3"><c r="B3"><v>7</v></c><c r="A3" t="s"><v>1</v></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		part, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+
+	table, err := ReadTable("applicants.xlsx", buf.Bytes(), 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Name"}, table.Header)
+	assert.Equal(t, [][]string{{""}, {"Ada Lovelace"}}, table.Rows)
+	assert.Equal(t, 3, table.RowNumber(1))
+}
+
+func TestIsDateFormatCode(t *testing.T) {
+	assert.True(t, isDateFormatCode("yyyy-mm-dd"))
+	assert.True(t, isDateFormatCode("[$-409]h:mm AM/PM"))
+	assert.False(t, isDateFormatCode("0.00"))
+	assert.False(t, isDateFormatCode(`#,##0 "days"`))
+	assert.False(t, isDateFormatCode("[Red]0%"))
+}
+
+func TestFormatSerialDate(t *testing.T) {
+	assert.Equal(t, "2024-03-01", formatSerialDate(45352, false))
+	assert.Equal(t, "2024-03-01T12:00:00Z", formatSerialDate(45352.5, false))
+	assert.Equal(t, "2024-03-01", formatSerialDate(43890, true))
+}
@@ -0,0 +1,117 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+/*
+Imports read the spreadsheets organizers upload into a plain table of text cells.
+
+The first row is the header and every other row is kept, empty ones included, so a row can be
+reported by the number it has in the organizer's spreadsheet. Only the first sheet of a workbook is read.
+*/
+
+var (
+	ErrUnsupportedFormat = errors.New("only CSV and XLSX files can be imported")
+	ErrNoHeader          = errors.New("the first row must name the columns")
+	ErrTooManyRows       = errors.New("the file has too many rows")
+)
+
+// Table is the text of a spreadsheet's cells
+type Table struct {
+	Header []string
+	Rows   [][]string // the rows after the header, each as long as the header
+}
+
+// RowNumber returns the number of a row in the spreadsheet, counting the header as row 1
+func (t *Table) RowNumber(index int) int {
+	return index + 2
+}
+
+// IsEmptyRow reports whether every cell of a row is blank
+func IsEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadTable reads a CSV or XLSX file, picked by the extension of its name, refusing files with more than maxRows rows
+func ReadTable(fileName string, data []byte, maxRows int) (*Table, error) {
+	var rows [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		rows, err = readCSV(data, maxRows)
+	case ".xlsx":
+		rows, err = readXLSX(data, maxRows)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newTable(rows)
+}
+
+// newTable splits the header from the rows and pads every row to the header's width
+func newTable(rows [][]string) (*Table, error) {
+	if len(rows) == 0 || IsEmptyRow(rows[0]) {
+		return nil, ErrNoHeader
+	}
+
+	header := make([]string, len(rows[0]))
+	for i, name := range rows[0] {
+		header[i] = strings.TrimSpace(name)
+	}
+	// Trailing blank header cells are left over formatting rather than columns
+	for len(header) > 0 && header[len(header)-1] == "" {
+		header = header[:len(header)-1]
+	}
+
+	table := &Table{Header: header, Rows: make([][]string, 0, len(rows)-1)}
+	for _, row := range rows[1:] {
+		padded := make([]string, len(header))
+		copy(padded, row)
+		table.Rows = append(table.Rows, padded)
+	}
+
+	// Blank rows at the end aren't worth reporting
+	for len(table.Rows) > 0 && IsEmptyRow(table.Rows[len(table.Rows)-1]) {
+		table.Rows = table.Rows[:len(table.Rows)-1]
+	}
+
+	return table, nil
+}
+
+func readCSV(data []byte, maxRows int) ([][]string, error) {
+	// Spreadsheet programs often start their CSV files with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	rows := [][]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rows) > maxRows {
+			return nil, ErrTooManyRows
+		}
+		rows = append(rows, record)
+	}
+}
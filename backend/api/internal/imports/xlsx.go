@@ -0,0 +1,354 @@
+package imports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+The XLSX reader only reads what an import needs from a workbook: the text of the first sheet's cells.
+
+Shared and inline strings are resolved, booleans are read as TRUE or FALSE and numbers keep the text Excel stored,
+except for numbers formatted as dates which are turned into dates (YYYY-MM-DD) or timestamps (RFC 3339).
+*/
+
+// maxXLSXPartSize bounds how much of a part is decompressed, so a small upload can't expand into gigabytes
+const maxXLSXPartSize = 100 << 20
+
+var errInvalidXLSX = errors.New("the file is not a valid XLSX workbook")
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		RelationshipID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxStyleSheet struct {
+	NumberFormats []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellFormats []struct {
+		NumberFormatID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+// String returns the text, rich text is split into runs
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxRow struct {
+	Number int        `xml:"r,attr"`
+	Cells  []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// xlsxBook is what the cells of a sheet are read with
+type xlsxBook struct {
+	sharedStrings []string
+	dateStyles    map[int]bool
+	date1904      bool
+}
+
+func readXLSX(data []byte, maxRows int) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errInvalidXLSX
+	}
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[strings.TrimPrefix(file.Name, "/")] = file
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodeXLSXPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errInvalidXLSX
+	}
+
+	var relationships xlsxRelationships
+	if err := decodeXLSXPart(files, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+		return nil, err
+	}
+
+	sheetPath := ""
+	for _, relationship := range relationships.Relationships {
+		if relationship.ID == workbook.Sheets[0].RelationshipID {
+			sheetPath = xlsxPartPath(relationship.Target)
+		}
+	}
+
+	book := xlsxBook{date1904: workbook.Properties.Date1904}
+
+	// Workbooks without any text or styling leave these parts out
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		var sharedStrings xlsxSharedStrings
+		if err := decodeXLSXPart(files, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+			return nil, err
+		}
+		for _, item := range sharedStrings.Items {
+			book.sharedStrings = append(book.sharedStrings, item.String())
+		}
+	}
+
+	if _, ok := files["xl/styles.xml"]; ok {
+		var styles xlsxStyleSheet
+		if err := decodeXLSXPart(files, "xl/styles.xml", &styles); err != nil {
+			return nil, err
+		}
+		book.dateStyles = dateStyles(styles)
+	}
+
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, errInvalidXLSX
+	}
+
+	reader, err := sheet.Open()
+	if err != nil {
+		return nil, errInvalidXLSX
+	}
+	defer reader.Close()
+
+	return book.readRows(io.LimitReader(reader, maxXLSXPartSize), maxRows)
+}
+
+// xlsxPartPath resolves a relationship target of the workbook to the path of the part in the archive
+func xlsxPartPath(target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Join("xl", target)
+}
+
+func decodeXLSXPart(files map[string]*zip.File, name string, v interface{}) error {
+	file, ok := files[name]
+	if !ok {
+		return errInvalidXLSX
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return errInvalidXLSX
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(reader, maxXLSXPartSize)).Decode(v); err != nil {
+		return errInvalidXLSX
+	}
+	return nil
+}
+
+// readRows decodes the sheet a row at a time, filling in the rows Excel leaves out because they are empty
+func (b xlsxBook) readRows(r io.Reader, maxRows int) ([][]string, error) {
+	decoder := xml.NewDecoder(r)
+	rows := [][]string{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, errInvalidXLSX
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := decoder.DecodeElement(&row, &start); err != nil {
+			return nil, errInvalidXLSX
+		}
+
+		number := row.Number
+		if number == 0 {
+			number = len(rows) + 1
+		}
+		if number < len(rows)+1 {
+			return nil, errInvalidXLSX
+		}
+		if number > maxRows+1 {
+			return nil, ErrTooManyRows
+		}
+		for len(rows) < number-1 {
+			rows = append(rows, []string{})
+		}
+
+		cells := []string{}
+		for i, cell := range row.Cells {
+			column := i
+			if cell.Ref != "" {
+				if column, err = xlsxColumnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(cells) <= column {
+				cells = append(cells, "")
+			}
+			cells[column] = b.cellText(cell)
+		}
+		rows = append(rows, cells)
+	}
+}
+
+// cellText returns the text of a cell
+func (b xlsxBook) cellText(cell xlsxCell) string {
+	switch cell.Type {
+	case "s":
+		index, err := strconv.Atoi(cell.Value)
+		if err != nil || index < 0 || index >= len(b.sharedStrings) {
+			return ""
+		}
+		return b.sharedStrings[index]
+	case "inlineStr":
+		return cell.Inline.String()
+	case "b":
+		if cell.Value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "", "n":
+		if b.dateStyles[cell.Style] {
+			if serial, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+				return formatSerialDate(serial, b.date1904)
+			}
+		}
+	}
+
+	// Formula results and errors keep their text
+	return cell.Value
+}
+
+// xlsxColumnIndex reads the zero based column of a cell reference, eg: C7 is column 2
+func xlsxColumnIndex(ref string) (int, error) {
+	column := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A') + 1
+		letters++
+	}
+
+	// The last column of a workbook is XFD
+	if letters == 0 || letters > 3 || column > 16384 {
+		return 0, fmt.Errorf("%w: bad cell reference %q", errInvalidXLSX, ref)
+	}
+	return column - 1, nil
+}
+
+// builtinDateFormats are the IDs of the number formats Excel defines itself that show dates or times
+var builtinDateFormats = map[int]bool{
+	14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true,
+	27: true, 28: true, 29: true, 30: true, 31: true, 32: true, 33: true, 34: true, 35: true, 36: true,
+	45: true, 46: true, 47: true, 50: true, 51: true, 52: true, 53: true, 54: true, 55: true, 56: true, 57: true, 58: true,
+}
+
+// dateStyles returns the cell styles whose number format shows a date or time
+func dateStyles(styles xlsxStyleSheet) map[int]bool {
+	dateFormats := make(map[int]bool)
+	for id := range builtinDateFormats {
+		dateFormats[id] = true
+	}
+	for _, format := range styles.NumberFormats {
+		dateFormats[format.ID] = isDateFormatCode(format.Code)
+	}
+
+	result := make(map[int]bool)
+	for i, cellFormat := range styles.CellFormats {
+		if dateFormats[cellFormat.NumberFormatID] {
+			result[i] = true
+		}
+	}
+	return result
+}
+
+// isDateFormatCode reports whether a custom number format shows a date or time, ignoring quoted text, escapes and [colors]
+func isDateFormatCode(code string) bool {
+	inQuote, inBracket := false, false
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; {
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			inBracket = c != ']'
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\' || c == '_' || c == '*':
+			i++
+		case strings.ContainsRune("yYdDhHsS", rune(c)):
+			return true
+		}
+	}
+	return false
+}
+
+// formatSerialDate turns the days since the workbook's epoch Excel stores dates as into a date, or a timestamp when it has a time
+func formatSerialDate(serial float64, date1904 bool) string {
+	// 1900 dates count from 1899-12-30 to make up for Excel treating 1900 as a leap year
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	seconds := math.Round(serial * 24 * 60 * 60)
+	date := epoch.Add(time.Duration(seconds) * time.Second)
+	if math.Mod(seconds, 24*60*60) == 0 {
+		return date.Format("2006-01-02")
+	}
+	return date.Format(time.RFC3339)
+}
@@ -0,0 +1,307 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"shared/config"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+The audit log records who did what to an event. RequirePermission marks the request with its event, and handlers of routes without it
+(creating a form, accepting an invite, ...) mark it themselves with SetAuditTarget. Every successful change to a marked request is recorded,
+and so are reads that give away secrets or the responses' personal data. Handlers can record what changed with SetAuditChanges, otherwise the
+request body is recorded as the new values.
+*/
+
+const (
+	auditEventKey      = "auditEventID"
+	auditPermissionKey = "auditPermission"
+	auditTargetKey     = "auditTarget"
+	auditChangesKey    = "auditChanges"
+
+	// maxAuditBodyBytes is the largest request body recorded, larger ones like imports are only recorded as the action
+	maxAuditBodyBytes = 64 << 10
+
+	redactedValue = "[redacted]"
+)
+
+// auditedReads are the permissions whose reads are audited as well, they give away the event's secrets or its responses
+var auditedReads = map[models.Permission]bool{
+	models.PermissionManageSecrets:   true,
+	models.PermissionViewResponses:   true,
+	models.PermissionExportResponses: true,
+}
+
+// sensitiveFields are the fields whose values are never written to the audit log, matched anywhere in the field's name at any depth
+var sensitiveFields = []string{"password", "secret", "token"}
+
+// auditTargetParams are the path params that identify the target of an action, the most specific first
+var auditTargetParams = []struct {
+	param      string
+	targetType string
+}{
+	{"response_id", "response"},
+	{"form_id", "form"},
+	{"pipeline_id", "pipeline"},
+	{"template_id", "emailTemplate"},
+	{"invite_id", "invite"},
+	{"user_id", "organizer"},
+	{"event_id", "event"},
+}
+
+// AuditLog is a middleware that writes the audit log entries of the requests, it has to come before the routes' own middlewares.
+// Entries are only written once the handler has succeeded, a failure to write one is logged but doesn't fail the request
+func AuditLog(mongo mongodb.MongoService) gin.HandlerFunc {
+	retentionDays := 0
+	if apiConfig, err := config.GetAPIConfig(); err == nil {
+		retentionDays = apiConfig.AUDIT_LOG_RETENTION_DAYS
+	}
+
+	return func(c *gin.Context) {
+		body := readAuditBody(c)
+
+		c.Next()
+
+		entry, ok := auditLogEntry(c, body)
+		if !ok {
+			return
+		}
+
+		if retentionDays > 0 {
+			expiresAt := entry.CreatedAt.AddDate(0, 0, retentionDays)
+			entry.ExpiresAt = &expiresAt
+		}
+
+		// The client may have gone by now, the entry is written regardless
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := mongo.InsertAuditLogEntry(ctx, *entry); err != nil {
+			logger.Error("Failed to write audit log entry", err)
+		}
+	}
+}
+
+// SetAuditTarget marks the request to be audited on the event, for routes without RequirePermission, like those creating something in an event
+func SetAuditTarget(c *gin.Context, eventID primitive.ObjectID, targetType string, targetID primitive.ObjectID) {
+	c.Set(auditEventKey, eventID)
+	c.Set(auditTargetKey, models.AuditTarget{Type: targetType, ID: targetID})
+}
+
+// SetAuditChanges records the fields of the target that differ between before and after, instead of the request body.
+// Both are compared by their JSON fields, so fields hidden from JSON are never recorded
+func SetAuditChanges(c *gin.Context, before interface{}, after interface{}) {
+	changes := []models.AuditChange{}
+	for _, change := range models.DiffResponseData(jsonFields(before), jsonFields(after)) {
+		changes = append(changes, models.AuditChange{Field: change.Key, Old: change.Old, New: change.New})
+	}
+	c.Set(auditChangesKey, changes)
+}
+
+// auditLogEntry builds the entry of the request if it has to be audited
+func auditLogEntry(c *gin.Context, body []byte) (*models.AuditLogEntry, bool) {
+	value, ok := c.Get(auditEventKey)
+	if !ok {
+		return nil, false
+	}
+	eventID, ok := value.(primitive.ObjectID)
+	if !ok {
+		return nil, false
+	}
+
+	value, _ = c.Get(auditPermissionKey)
+	permission, _ := value.(models.Permission)
+	if !isMutatingMethod(c.Request.Method) && !auditedReads[permission] {
+		return nil, false
+	}
+
+	if c.Writer.Status() >= http.StatusBadRequest {
+		return nil, false
+	}
+
+	entry := &models.AuditLogEntry{
+		EventID:    eventID,
+		Action:     c.Request.Method + " " + c.FullPath(),
+		Path:       c.Request.URL.Path,
+		Permission: permission,
+		Target:     auditTarget(c, eventID),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Status:     c.Writer.Status(),
+		CreatedAt:  time.Now(),
+	}
+
+	// The user was already checked by the route, so it is read directly rather than with GetUserFromContext
+	value, _ = c.Get("user")
+	if user, ok := value.(*models.User); ok {
+		entry.ActorID = user.ID
+		entry.ActorEmail = user.Email
+	}
+	if apiToken, ok := utils.GetAPITokenFromContext(c); ok {
+		entry.APITokenID = &apiToken.ID
+	}
+
+	value, _ = c.Get(auditChangesKey)
+	changes, ok := value.([]models.AuditChange)
+	if !ok {
+		changes = bodyChanges(body)
+	}
+	entry.Changes = redactChanges(changes, permission == models.PermissionManageSecrets)
+
+	return entry, true
+}
+
+// auditTarget is the target the handler set, or the most specific ID in the path
+func auditTarget(c *gin.Context, eventID primitive.ObjectID) models.AuditTarget {
+	value, _ := c.Get(auditTargetKey)
+	if target, ok := value.(models.AuditTarget); ok {
+		return target
+	}
+
+	for _, target := range auditTargetParams {
+		if id, err := primitive.ObjectIDFromHex(c.Param(target.param)); err == nil {
+			return models.AuditTarget{Type: target.targetType, ID: id}
+		}
+	}
+
+	return models.AuditTarget{Type: "event", ID: eventID}
+}
+
+// readAuditBody reads the JSON body of a change so it can be recorded, the handler still reads the whole body afterwards
+func readAuditBody(c *gin.Context) []byte {
+	if !isMutatingMethod(c.Request.Method) || c.Request.Body == nil || c.ContentType() != gin.MIMEJSON {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBodyBytes+1))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil || len(body) > maxAuditBodyBytes {
+		return nil
+	}
+
+	return body
+}
+
+// bodyChanges records the fields of a JSON object body as new values
+func bodyChanges(body []byte) []models.AuditChange {
+	var fields map[string]interface{}
+	if len(body) == 0 || json.Unmarshal(body, &fields) != nil {
+		return nil
+	}
+
+	changes := []models.AuditChange{}
+	for field, value := range fields {
+		changes = append(changes, models.AuditChange{Field: field, New: value})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	return changes
+}
+
+// redactChanges replaces the values of sensitive fields, or of every field, so only the names of the fields that changed are kept.
+// Sensitive fields nested in the values, like in a pipeline's steps, are redacted too
+func redactChanges(changes []models.AuditChange, redactAll bool) []models.AuditChange {
+	for i, change := range changes {
+		if !redactAll && !isSensitiveField(change.Field) {
+			changes[i].Old = redactNested(change.Old)
+			changes[i].New = redactNested(change.New)
+			continue
+		}
+
+		if change.Old != nil {
+			changes[i].Old = redactedValue
+		}
+		if change.New != nil {
+			changes[i].New = redactedValue
+		}
+	}
+	return changes
+}
+
+// redactNested copies a JSON value with the values of its sensitive fields replaced, at any depth
+func redactNested(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for field, fieldValue := range value {
+			if isSensitiveField(field) && fieldValue != nil {
+				redacted[field] = redactedValue
+			} else {
+				redacted[field] = redactNested(fieldValue)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, item := range value {
+			redacted[i] = redactNested(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+func isSensitiveField(field string) bool {
+	field = strings.ToLower(field)
+	for _, sensitive := range sensitiveFields {
+		if strings.Contains(field, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonFields is the value's top level fields as they are in JSON, without the fields updates can't override like its ID
+func jsonFields(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if value == nil {
+		return fields
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+
+	typ := reflect.TypeOf(value)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Tag.Get("mongoPreventOverride") != "true" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		delete(fields, name)
+	}
+
+	return fields
+}
+
+func isMutatingMethod(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions
+}
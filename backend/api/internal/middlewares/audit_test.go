@@ -0,0 +1,157 @@
+package middlewares
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"shared/models"
+	"shared/mongodb"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// auditMongo keeps the audit log in memory, only inserting entries is implemented
+type auditMongo struct {
+	mongodb.MongoService
+	entries []models.AuditLogEntry
+}
+
+func (m *auditMongo) InsertAuditLogEntry(ctx context.Context, entry models.AuditLogEntry) (*mongo.InsertOneResult, error) {
+	m.entries = append(m.entries, entry)
+	return &mongo.InsertOneResult{InsertedID: primitive.NewObjectID()}, nil
+}
+
+func TestAuditLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := &auditMongo{}
+	user := &models.User{ID: primitive.NewObjectID(), Email: "organizer@example.com"}
+	eventID := primitive.NewObjectID()
+
+	// Stands in for JWTAuthMiddleware and RequirePermission
+	permitted := func(permission models.Permission) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("user", user)
+			c.Set(auditEventKey, eventID)
+			c.Set(auditPermissionKey, permission)
+		}
+	}
+
+	r := gin.New()
+	r.Use(AuditLog(m))
+	r.PUT("/forms/:form_id", permitted(models.PermissionEditForms), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	r.PATCH("/forms/:form_id", permitted(models.PermissionEditForms), func(c *gin.Context) {
+		SetAuditChanges(c,
+			models.FormStructure{ID: primitive.NewObjectID(), Name: "Before", Description: "Same"},
+			models.FormStructure{Name: "After", Description: "Same"},
+		)
+		c.Status(http.StatusOK)
+	})
+	r.POST("/forms", func(c *gin.Context) {
+		c.Set("user", user)
+		SetAuditTarget(c, eventID, "form", primitive.NewObjectID())
+		c.Status(http.StatusOK)
+	})
+	r.GET("/forms/:form_id", permitted(models.PermissionViewEvent), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/forms/:form_id/responses", permitted(models.PermissionViewResponses), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/forms/:form_id/responses/export", permitted(models.PermissionExportResponses), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.DELETE("/forms/:form_id", permitted(models.PermissionEditForms), func(c *gin.Context) { c.Status(http.StatusConflict) })
+	r.PUT("/events/:event_id/secrets", permitted(models.PermissionManageSecrets), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.PUT("/users/me", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	formID := primitive.NewObjectID()
+
+	t.Run("changes record the request body", func(t *testing.T) {
+		m.entries = nil
+		body := `{"title": "Applications", "smtpPassword": "hunter2"}`
+		resp := request(http.MethodPut, "/forms/"+formID.Hex(), body)
+		assert.Equal(t, body, resp.Body.String(), "the handler still reads the whole body")
+
+		require.Len(t, m.entries, 1)
+		entry := m.entries[0]
+		assert.Equal(t, eventID, entry.EventID)
+		assert.Equal(t, user.ID, entry.ActorID)
+		assert.Equal(t, "organizer@example.com", entry.ActorEmail)
+		assert.Equal(t, "PUT /forms/:form_id", entry.Action)
+		assert.Equal(t, models.PermissionEditForms, entry.Permission)
+		assert.Equal(t, models.AuditTarget{Type: "form", ID: formID}, entry.Target)
+		assert.NotEmpty(t, entry.IP)
+		assert.NotNil(t, entry.ExpiresAt)
+		assert.Equal(t, []models.AuditChange{
+			{Field: "smtpPassword", New: redactedValue},
+			{Field: "title", New: "Applications"},
+		}, entry.Changes)
+	})
+
+	t.Run("handlers can record a diff", func(t *testing.T) {
+		m.entries = nil
+		request(http.MethodPatch, "/forms/"+formID.Hex(), `{}`)
+
+		require.Len(t, m.entries, 1)
+		assert.Equal(t, []models.AuditChange{{Field: "name", Old: "Before", New: "After"}}, m.entries[0].Changes, "the ID can't be changed by an update")
+	})
+
+	t.Run("handlers can set the event and target", func(t *testing.T) {
+		m.entries = nil
+		request(http.MethodPost, "/forms", `{"eventID": "`+eventID.Hex()+`"}`)
+
+		require.Len(t, m.entries, 1)
+		assert.Equal(t, eventID, m.entries[0].EventID)
+		assert.Equal(t, "form", m.entries[0].Target.Type)
+	})
+
+	t.Run("only sensitive reads are recorded", func(t *testing.T) {
+		m.entries = nil
+		request(http.MethodGet, "/forms/"+formID.Hex(), "")
+		assert.Empty(t, m.entries)
+
+		request(http.MethodGet, "/forms/"+formID.Hex()+"/responses", "")
+		request(http.MethodGet, "/forms/"+formID.Hex()+"/responses/export", "")
+		require.Len(t, m.entries, 2)
+		assert.Equal(t, models.PermissionViewResponses, m.entries[0].Permission)
+		assert.Equal(t, models.PermissionExportResponses, m.entries[1].Permission)
+	})
+
+	t.Run("nested sensitive fields are redacted", func(t *testing.T) {
+		m.entries = nil
+		request(http.MethodPut, "/forms/"+formID.Hex(), `{"steps": [{"email": {"to": "ada@example.com", "smtp": {"password": "hunter2"}}}]}`)
+
+		require.Len(t, m.entries, 1)
+		assert.Equal(t, []models.AuditChange{{Field: "steps", New: []interface{}{
+			map[string]interface{}{"email": map[string]interface{}{"to": "ada@example.com", "smtp": map[string]interface{}{"password": redactedValue}}},
+		}}}, m.entries[0].Changes)
+	})
+
+	t.Run("secrets are never recorded", func(t *testing.T) {
+		m.entries = nil
+		request(http.MethodPut, "/events/"+eventID.Hex()+"/secrets", `{"smtpHost": "smtp.example.com"}`)
+
+		require.Len(t, m.entries, 1)
+		assert.Equal(t, []models.AuditChange{{Field: "smtpHost", New: redactedValue}}, m.entries[0].Changes)
+	})
+
+	t.Run("failed and unmarked requests aren't recorded", func(t *testing.T) {
+		m.entries = nil
+		request(http.MethodDelete, "/forms/"+formID.Hex(), "")
+		request(http.MethodPut, "/users/me", `{"firstName": "Ada"}`)
+		assert.Empty(t, m.entries)
+	})
+}
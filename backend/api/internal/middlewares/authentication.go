@@ -1,15 +1,21 @@
 package middlewares
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
 	"shared/utils"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// JWTAuthMiddleware is a middleware that checks for a valid JWT token, and sets the user info in the context
-func JWTAuthMiddleware() gin.HandlerFunc {
+// JWTAuthMiddleware is a middleware that checks for a valid JWT token whose session hasn't been signed out, and sets the user info in the context.
+// It accepts API tokens too, which RequirePermission then limits to their scope
+func JWTAuthMiddleware(mongo mongodb.MongoService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 
@@ -19,16 +25,55 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Verify the JWT token
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-		user, err := utils.VerifyJWT(tokenString)
+		if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+			authenticateAPIToken(c, mongo, tokenString)
+			return
+		}
+
+		// Verify the JWT token
+		user, sessionID, err := utils.VerifyJWT(tokenString)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			return
 		}
 
+		// The session is gone once its device is signed out, or the password changed
+		session, err := mongo.GetSession(c, sessionID)
+		if err != nil || session.UserID != user.ID {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+
 		// Token is valid, set user info in context and proceed
 		c.Set("user", user)
+		c.Set("sessionID", sessionID)
+		c.Set("twoFactorVerified", session.TwoFactorVerified)
 		c.Next()
 	}
 }
+
+// authenticateAPIToken checks the API token hasn't been revoked or expired and sets its user in the context
+func authenticateAPIToken(c *gin.Context, mongo mongodb.MongoService, tokenString string) {
+	hash := sha256.Sum256([]byte(tokenString))
+	token, err := mongo.GetAPITokenByHash(c, hex.EncodeToString(hash[:]))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	user, err := mongo.GetUserDetails(c, token.UserID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	if err := mongo.TouchAPIToken(c, token.ID); err != nil {
+		logger.Error("Failed to record API token use", err)
+	}
+
+	c.Set("user", user)
+	c.Set("apiToken", token)
+	c.Set("twoFactorVerified", token.TwoFactorVerified && user.HasTwoFactor())
+	c.Next()
+}
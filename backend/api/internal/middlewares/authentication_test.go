@@ -1,39 +1,71 @@
 package middlewares
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"shared/models"
+	"shared/mongodb"
 	"shared/utils"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func setupRouter() *gin.Engine {
+// sessionMongo keeps sessions in memory, only the session lookup of the service is implemented
+type sessionMongo struct {
+	mongodb.MongoService
+	sessions map[primitive.ObjectID]models.Session
+}
+
+func (m *sessionMongo) GetSession(ctx context.Context, sessionID primitive.ObjectID) (*models.Session, error) {
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &session, nil
+}
+
+func setupRouter(mongo mongodb.MongoService) *gin.Engine {
 	r := gin.Default()
-	r.Use(JWTAuthMiddleware())
+	r.Use(JWTAuthMiddleware(mongo))
 	return r
 }
 
 func TestJWTAuthMiddleware(t *testing.T) {
-	r := setupRouter()
-
-	// Mock handler to check if middleware passes control
-	r.GET("/test", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"message": "passed"})
-	})
-
 	// Create a test user
 	testUser := models.User{
+		ID:        primitive.NewObjectID(),
 		Email:     "test@example.com",
 		FirstName: "John",
 		LastName:  "Doe",
 	}
 
+	sessionID := primitive.NewObjectID()
+	m := &sessionMongo{sessions: map[primitive.ObjectID]models.Session{
+		sessionID: {ID: sessionID, UserID: testUser.ID, ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	r := setupRouter(m)
+
+	// Mock handler to check if middleware passes control
+	r.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "passed"})
+	})
+
 	// Generate a valid token
-	validToken, _ := utils.GenerateJWT(&testUser)
+	validToken, _ := utils.GenerateJWT(&testUser, sessionID)
+
+	// A token whose session was signed out
+	signedOutToken, _ := utils.GenerateJWT(&testUser, primitive.NewObjectID())
+
+	// A token for another user's session
+	otherUser := testUser
+	otherUser.ID = primitive.NewObjectID()
+	otherUserToken, _ := utils.GenerateJWT(&otherUser, sessionID)
 
 	// Test cases
 	tests := []struct {
@@ -44,6 +76,8 @@ func TestJWTAuthMiddleware(t *testing.T) {
 		{"Valid Token", "Bearer " + validToken, http.StatusOK},
 		{"Invalid Token", "Bearer invalidtoken", http.StatusUnauthorized},
 		{"No Token", "", http.StatusUnauthorized},
+		{"Signed Out Session", "Bearer " + signedOutToken, http.StatusUnauthorized},
+		{"Another User's Session", "Bearer " + otherUserToken, http.StatusUnauthorized},
 	}
 
 	for _, tc := range tests {
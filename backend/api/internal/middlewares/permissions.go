@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"net/http"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RequirePermission is a middleware that checks the user's role on an event grants the permission, it must come after JWTAuthMiddleware.
+// The event is the one in the path, or the event of the form, pipeline or email template in the path.
+// Events can require organizers to have signed in with a second factor too. API tokens are only allowed what they were given, as well.
+// The request is then marked to be written to the event's audit log by AuditLog
+func RequirePermission(mongo mongodb.MongoService, permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// The token's scope is checked below, the request is aborted if it doesn't allow it
+		apiToken, usingAPIToken := utils.GetAPITokenFromContext(c)
+		if usingAPIToken {
+			utils.AllowAPIToken(c)
+		}
+
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		eventID, ok := pathEventID(c, mongo)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		event, err := mongo.FindEvent(c, eventID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+
+		// pathEventID has already checked the form ID is valid
+		formID, _ := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if usingAPIToken && !apiToken.Allows(event.ID, formID, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This API token isn't allowed to do this"})
+			return
+		}
+
+		// HasEventPermission refuses organizers without a second factor too, they are told how to get in
+		if _, organizer := event.OrganizerRole(authenticatedUser.ID); organizer && mongodb.MissingTwoFactor(c, event) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":             "This event requires two-factor authentication, set it up on your account and sign in with it",
+				"twoFactorRequired": true,
+			})
+			return
+		}
+
+		if !mongodb.HasEventPermission(c, mongo, authenticatedUser, event.ID, event, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to do this on this event"})
+			return
+		}
+
+		c.Set(auditEventKey, event.ID)
+		c.Set(auditPermissionKey, permission)
+
+		c.Next()
+	}
+}
+
+// pathEventID finds the event the path refers to, it writes the error response on failure
+func pathEventID(c *gin.Context, mongo mongodb.MongoService) (primitive.ObjectID, bool) {
+	if formParam := c.Param("form_id"); formParam != "" {
+		formID, err := primitive.ObjectIDFromHex(formParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return primitive.NilObjectID, false
+		}
+
+		form, err := mongo.GetForm(c, formID, true)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Form not found"})
+			return primitive.NilObjectID, false
+		}
+		return form.EventID, true
+	}
+
+	if pipelineParam := c.Param("pipeline_id"); pipelineParam != "" {
+		pipelineID, err := primitive.ObjectIDFromHex(pipelineParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pipeline ID"})
+			return primitive.NilObjectID, false
+		}
+
+		pipeline, err := mongo.GetPipeline(c, pipelineID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pipeline configuration not found"})
+			return primitive.NilObjectID, false
+		}
+		return pipeline.EventID, true
+	}
+
+	if templateParam := c.Param("template_id"); templateParam != "" {
+		templateID, err := primitive.ObjectIDFromHex(templateParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email template ID"})
+			return primitive.NilObjectID, false
+		}
+
+		template, err := mongo.GetEmailTemplate(c, templateID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+			return primitive.NilObjectID, false
+		}
+		return template.EventID, true
+	}
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return primitive.NilObjectID, false
+	}
+	return eventID, true
+}
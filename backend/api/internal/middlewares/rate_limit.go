@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"shared/logger"
+	"shared/mongodb"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitMiddleware limits how many requests each client IP can make to the routes sharing the name within a window.
+// Counts are kept in mongo so every API instance shares them, if they can't be counted the request is let through
+func RateLimitMiddleware(mongo mongodb.MongoService, name string, limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		count, err := mongo.IncrementRateLimit(c, rateLimitKey(name, c.ClientIP()), window)
+		if err != nil {
+			logger.Error("Failed to count request against the rate limit", err)
+			c.Next()
+			return
+		}
+
+		if count > int64(limit) {
+			retryAfter := time.Until(time.Now().Truncate(window).Add(window))
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey identifies the client's counter for the named limit
+func rateLimitKey(name string, clientIP string) string {
+	return fmt.Sprintf("%s:%s", name, clientIP)
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"shared/mongodb"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// rateLimitMongo counts in memory, only the rate limit method of the service is implemented
+type rateLimitMongo struct {
+	mongodb.MongoService
+	counts map[string]int64
+	err    error
+}
+
+func (m *rateLimitMongo) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+func setupRateLimitedRouter(mongo mongodb.MongoService) *gin.Engine {
+	r := gin.New()
+	r.GET("/test", RateLimitMiddleware(mongo, "test", 2, time.Hour), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "passed"})
+	})
+	return r
+}
+
+func requestFrom(r *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	mongo := &rateLimitMongo{counts: map[string]int64{}}
+	r := setupRateLimitedRouter(mongo)
+
+	assert.Equal(t, http.StatusOK, requestFrom(r, "10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusOK, requestFrom(r, "10.0.0.1:1234").Code)
+
+	limited := requestFrom(r, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.NotEmpty(t, limited.Header().Get("Retry-After"))
+
+	// Other clients have their own count
+	assert.Equal(t, http.StatusOK, requestFrom(r, "10.0.0.2:1234").Code)
+	assert.Equal(t, int64(3), mongo.counts["test:10.0.0.1"])
+}
+
+func TestRateLimitMiddlewareFailsOpen(t *testing.T) {
+	r := setupRateLimitedRouter(&rateLimitMongo{err: errors.New("mongo is down")})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, requestFrom(r, "10.0.0.1:1234").Code)
+	}
+}
@@ -0,0 +1,308 @@
+package auth
+
+import (
+	"api/internal/helpers"
+	"api/internal/middlewares"
+	"api/internal/types"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"shared/config"
+	"shared/logger"
+	"shared/mailer"
+	"shared/models"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/*
+Anyone can register with any email, so a user verifies they own theirs by following a signed link emailed to them.
+Restricted forms only let a user in by their email once it is verified, see mongodb.IsUserEmailInWhitelist.
+
+A forgotten password is reset with a single use link emailed to the user, which signs out all their sessions.
+The routes that send emails or take a token are rate limited per IP.
+*/
+
+const emailVerificationPurpose = "email-verification"
+
+// authRateLimit limits the requests each IP can make to the named account recovery routes per hour
+func authRateLimit(params *types.RouteParams, name string) gin.HandlerFunc {
+	limit := 10
+	if apiConfig, err := config.GetAPIConfig(); err == nil {
+		limit = apiConfig.AUTH_RATE_LIMIT_PER_HOUR
+	}
+
+	return middlewares.RateLimitMiddleware(params.MongoService, name, limit, time.Hour)
+}
+
+// emailVerificationSubject ties a verification token to the user and the email it was sent to, so it can't verify an email they change to later
+func emailVerificationSubject(user *models.User) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(user.Email)))
+	return user.ID.Hex() + "-" + hex.EncodeToString(hash[:8])
+}
+
+// sendVerificationEmail emails the user the link to verify their email
+func sendVerificationEmail(c *gin.Context, params *types.RouteParams, user *models.User) error {
+	apiConfig, err := config.GetAPIConfig()
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Duration(apiConfig.EMAIL_VERIFICATION_TTL_HOURS) * time.Hour
+	token := helpers.NewSignedToken(utils.DeriveSecret(emailVerificationPurpose), emailVerificationSubject(user), time.Now().Add(ttl))
+	link := fmt.Sprintf("%s/auth/verify-email?token=%s", apiConfig.API_PUBLIC_URL, token)
+
+	return params.Mailer.Send(c, mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease verify your email address within %d hours by opening this link:\n%s\n\nIf you didn't create an account you can ignore this email.",
+			user.FirstName, apiConfig.EMAIL_VERIFICATION_TTL_HOURS, link),
+	})
+}
+
+// Send the signed in user a new link to verify their email
+func resendVerificationEmail(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		user, err := params.MongoService.GetUserDetails(c, authenticatedUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user details"})
+			logger.Error("Failed to get user details", err)
+			return
+		}
+
+		if user.EmailVerifiedAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Your email address is already verified"})
+			return
+		}
+
+		if err := sendVerificationEmail(c, params, user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send the verification email"})
+			logger.Error("Failed to send verification email", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Check your email to verify your email address"})
+	}
+}
+
+// Verify a user's email from the link emailed to them
+func verifyEmail(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Verification token is required"})
+			return
+		}
+
+		subject, _, err := helpers.VerifySignedToken(utils.DeriveSecret(emailVerificationPurpose), token, time.Now())
+		if err != nil {
+			if err == helpers.ErrExpiredSignedToken {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This verification link has expired, please request a new one"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This verification link is invalid"})
+			return
+		}
+
+		userID, err := primitive.ObjectIDFromHex(strings.SplitN(subject, "-", 2)[0])
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This verification link is invalid"})
+			return
+		}
+
+		user, err := params.MongoService.GetUserDetails(c, userID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This verification link is invalid"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+			logger.Error("Failed to get user details", err)
+			return
+		}
+
+		if emailVerificationSubject(user) != subject {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This verification link was sent to another email address"})
+			return
+		}
+
+		if user.EmailVerifiedAt == nil {
+			if _, err := params.MongoService.MarkUserEmailVerified(c, user.ID, user.Email); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+				logger.Error("Failed to mark user email as verified", err)
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Your email address has been verified"})
+	}
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+/*
+Email a link to reset a forgotten password, only the latest link sent works.
+The response is the same whether or not there is an account with the email, so it can't be used to find out who has one
+*/
+func forgotPassword(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req forgotPasswordRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		sentMessage := "If there is an account with that email, a link to reset its password has been sent to it"
+
+		user, err := params.MongoService.FindUserByEmail(c, req.Email)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusOK, gin.H{"message": sentMessage})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to find user by email", err)
+			return
+		}
+
+		apiConfig, err := config.GetAPIConfig()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		token, tokenHash, err := newToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to create password reset token", err)
+			return
+		}
+
+		if _, err := params.MongoService.DeletePasswordResets(c, bson.M{"userID": user.ID}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to delete earlier password resets", err)
+			return
+		}
+
+		ttl := time.Duration(apiConfig.PASSWORD_RESET_TTL_MINUTES) * time.Minute
+		reset := models.PasswordReset{
+			UserID:    user.ID,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(ttl),
+		}
+		if _, err := params.MongoService.CreatePasswordReset(c, reset); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to store password reset", err)
+			return
+		}
+
+		link := fmt.Sprintf("%s/reset-password?token=%s", apiConfig.WEBSITE_PUBLIC_URL, token)
+		err = params.Mailer.Send(c, mailer.Message{
+			To:      []string{user.Email},
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. To choose a new password, open this link within %d minutes:\n%s\n\nIf it wasn't you, you can ignore this email and your password won't change.",
+				user.FirstName, apiConfig.PASSWORD_RESET_TTL_MINUTES, link),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send the password reset email"})
+			logger.Error("Failed to send password reset email", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": sentMessage})
+	}
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,securepwd"`
+}
+
+/*
+Set a new password with the token from a password reset link, every session of the user is signed out.
+Following the link shows the user owns their email, so it is verified too
+*/
+func resetPassword(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req resetPasswordRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+
+		reset, err := params.MongoService.ConsumePasswordReset(c, hashToken(req.Token))
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This password reset link is invalid or has expired"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get password reset", err)
+			return
+		}
+
+		user, err := params.MongoService.GetUserDetails(c, reset.UserID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This password reset link is invalid or has expired"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get user details", err)
+			return
+		}
+
+		if err := params.MongoService.ChangeUserPassword(c, user.ID, string(hash), primitive.NilObjectID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			logger.Error("Failed to change user password", err)
+			return
+		}
+
+		if user.EmailVerifiedAt == nil {
+			if _, err := params.MongoService.MarkUserEmailVerified(c, user.ID, user.Email); err != nil {
+				logger.Error("Failed to mark user email as verified", err)
+			}
+		}
+
+		// Resetting the password is how a locked out user gets back in
+		clearLoginFailures(c, params, user.Email)
+		logger.Security("login_unlocked", map[string]interface{}{
+			"userID":   user.ID.Hex(),
+			"clientIP": c.ClientIP(),
+		})
+
+		c.JSON(http.StatusOK, gin.H{"message": "Your password has been reset, please log in with your new password"})
+	}
+}
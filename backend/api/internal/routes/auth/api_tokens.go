@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"api/internal/types"
+	"net/http"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+API tokens let a user's scripts call the API without their password, sent as a bearer token like a JWT.
+Each token is for one event, or one form of it, and only allows the permissions it was created with, on top of the user's role on the event.
+They only work on routes behind RequirePermission, never on the user's account, and only their hash is stored.
+*/
+
+const defaultAPITokenDays = 90
+
+// Lists the signed in user's API tokens, the tokens themselves were only shown when they were created
+func listAPITokens(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		tokens, err := params.MongoService.ListAPITokens(c, authenticatedUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API tokens"})
+			logger.Error("Failed to list API tokens", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"apiTokens": tokens})
+	}
+}
+
+type createAPITokenRequest struct {
+	Name          string              `json:"name" validate:"required,max=100"`
+	EventID       primitive.ObjectID  `json:"eventID" validate:"required"`
+	FormID        *primitive.ObjectID `json:"formID"`
+	Permissions   []models.Permission `json:"permissions" validate:"required,min=1"`
+	ExpiresInDays int                 `json:"expiresInDays" validate:"omitempty,min=1,max=365"`
+}
+
+// Creates an API token for an event the user organizes, the token is only returned this once
+func createAPIToken(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		var req createAPITokenRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		event, err := params.MongoService.FindEvent(c, req.EventID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API token"})
+			logger.Error("Failed to find event", err)
+			return
+		}
+
+		if req.FormID != nil {
+			form, err := params.MongoService.GetForm(c, *req.FormID, true)
+			if err != nil || form.EventID != event.ID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The form isn't one of the event's"})
+				return
+			}
+		}
+
+		// A token can't be given more than the user is allowed
+		for _, permission := range req.Permissions {
+			if !permission.IsValid() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission " + string(permission)})
+				return
+			}
+
+			if !mongodb.HasEventPermission(c, params.MongoService, authenticatedUser, event.ID, event, permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You do not have the " + string(permission) + " permission on this event"})
+				return
+			}
+		}
+
+		days := req.ExpiresInDays
+		if days == 0 {
+			days = defaultAPITokenDays
+		}
+
+		secret, _, err := newToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to generate API token", err)
+			return
+		}
+		plaintext := models.APITokenPrefix + secret
+
+		now := time.Now()
+		token := models.APIToken{
+			UserID:            authenticatedUser.ID,
+			Name:              req.Name,
+			TokenHash:         hashToken(plaintext),
+			Hint:              secret[len(secret)-4:],
+			EventID:           event.ID,
+			FormID:            req.FormID,
+			Permissions:       req.Permissions,
+			TwoFactorVerified: utils.IsTwoFactorVerified(c),
+			CreatedAt:         now,
+			ExpiresAt:         now.AddDate(0, 0, days),
+		}
+
+		result, err := params.MongoService.CreateAPIToken(c, token)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API token"})
+			logger.Error("Failed to create API token", err)
+			return
+		}
+		token.ID = result.InsertedID.(primitive.ObjectID)
+
+		c.JSON(http.StatusCreated, gin.H{"token": plaintext, "apiToken": token})
+	}
+}
+
+// Revokes one of the signed in user's API tokens, it stops working straight away
+func revokeAPIToken(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		tokenID, err := primitive.ObjectIDFromHex(c.Param("token_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API token ID"})
+			return
+		}
+
+		result, err := params.MongoService.DeleteAPIToken(c, authenticatedUser.ID, tokenID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+			logger.Error("Failed to delete API token", err)
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+	}
+}
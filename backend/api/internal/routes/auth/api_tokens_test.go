@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"api/internal/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared/models"
+	"shared/mongodb"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// apiTokenMongo holds a single event and the tokens created for it, only the methods creating a token uses are implemented
+type apiTokenMongo struct {
+	mongodb.MongoService
+	event  models.Event
+	tokens []models.APIToken
+}
+
+func (m *apiTokenMongo) FindEvent(ctx context.Context, eventID primitive.ObjectID) (*models.Event, error) {
+	if eventID != m.event.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	event := m.event
+	return &event, nil
+}
+
+func (m *apiTokenMongo) CreateAPIToken(ctx context.Context, token models.APIToken) (*mongo.InsertOneResult, error) {
+	token.ID = primitive.NewObjectID()
+	m.tokens = append(m.tokens, token)
+	return &mongo.InsertOneResult{InsertedID: token.ID}, nil
+}
+
+func TestCreateAPIToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	organizer := &models.User{ID: primitive.NewObjectID()}
+	m := &apiTokenMongo{event: models.Event{ID: primitive.NewObjectID(), CreatedByID: organizer.ID, OrganizerIDs: []primitive.ObjectID{organizer.ID}}}
+
+	r := gin.New()
+	r.POST("/auth/api-tokens", func(c *gin.Context) { c.Set("user", organizer) }, createAPIToken(&types.RouteParams{MongoService: m}))
+
+	body := `{"name": "Sync", "eventID": "` + m.event.ID.Hex() + `", "permissions": ["` + string(models.PermissionViewResponses) + `"]}`
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/auth/api-tokens", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	var created struct {
+		Token    string          `json:"token"`
+		APIToken models.APIToken `json:"apiToken"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.Token, models.APITokenPrefix))
+
+	// The returned token is the one stored
+	require.Len(t, m.tokens, 1)
+	assert.Equal(t, m.tokens[0].ID, created.APIToken.ID)
+	assert.WithinDuration(t, time.Now(), created.APIToken.CreatedAt, time.Minute)
+	assert.True(t, m.tokens[0].CreatedAt.Equal(created.APIToken.CreatedAt))
+	assert.NotEqual(t, created.Token, m.tokens[0].TokenHash, "only the hash is stored")
+}
@@ -3,6 +3,8 @@ package auth
 import (
 	"api/internal/middlewares"
 	"api/internal/types"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"shared/logger"
@@ -23,7 +25,29 @@ import (
 func RegisterRoutes(r *gin.RouterGroup, params *types.RouteParams) {
 	r.POST("/login", loginUser(params))
 	r.POST("/register", registerUser(params))
-	r.DELETE("/delete", middlewares.JWTAuthMiddleware(), deleteUser(params))
+	r.POST("/refresh", refreshSession(params))
+	r.POST("/logout", middlewares.JWTAuthMiddleware(params.MongoService), logout(params))
+	r.POST("/logout-all", middlewares.JWTAuthMiddleware(params.MongoService), logoutEverywhere(params))
+	r.PUT("/password", middlewares.JWTAuthMiddleware(params.MongoService), changePassword(params))
+	r.DELETE("/delete", middlewares.JWTAuthMiddleware(params.MongoService), deleteUser(params))
+	r.GET("/verify-email", authRateLimit(params, "verify-email"), verifyEmail(params))
+	r.POST("/verify-email/send", middlewares.JWTAuthMiddleware(params.MongoService), authRateLimit(params, "send-verification-email"), resendVerificationEmail(params))
+	r.POST("/forgot-password", authRateLimit(params, "forgot-password"), forgotPassword(params))
+	r.POST("/reset-password", authRateLimit(params, "reset-password"), resetPassword(params))
+	r.GET("/2fa", middlewares.JWTAuthMiddleware(params.MongoService), getTwoFactorStatus(params))
+	r.POST("/2fa/setup", middlewares.JWTAuthMiddleware(params.MongoService), setUpTwoFactor(params))
+	r.POST("/2fa/enable", middlewares.JWTAuthMiddleware(params.MongoService), enableTwoFactor(params))
+	r.POST("/2fa/recovery-codes", middlewares.JWTAuthMiddleware(params.MongoService), regenerateRecoveryCodes(params))
+	r.POST("/2fa/disable", middlewares.JWTAuthMiddleware(params.MongoService), disableTwoFactor(params))
+	r.POST("/2fa/verify", authRateLimit(params, "verify-two-factor"), verifyTwoFactor(params))
+	r.GET("/api-tokens", middlewares.JWTAuthMiddleware(params.MongoService), listAPITokens(params))
+	r.POST("/api-tokens", middlewares.JWTAuthMiddleware(params.MongoService), createAPIToken(params))
+	r.DELETE("/api-tokens/:token_id", middlewares.JWTAuthMiddleware(params.MongoService), revokeAPIToken(params))
+
+	ssoConfigs, providers := ssoProviders()
+	r.GET("/sso/providers", listSSOProviders(ssoConfigs))
+	r.GET("/sso/:provider/login", ssoLogin(providers))
+	r.GET("/sso/:provider/callback", authRateLimit(params, "sso-callback"), ssoCallback(params, providers))
 }
 
 type loginRequest struct {
@@ -39,10 +63,16 @@ func loginUser(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
+		// Checked before the password, so a locked out account is refused even with the right one
+		if !checkLoginThrottle(c, params, req.Email) {
+			return
+		}
+
 		user, err := params.MongoService.FindUserByEmail(c, req.Email)
 		if err != nil {
 			if err == mongo.ErrNoDocuments {
 				// User not found
+				recordLoginFailure(c, params, req.Email)
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Email and password do not match"})
 				return
 			}
@@ -53,18 +83,19 @@ func loginUser(params *types.RouteParams) gin.HandlerFunc {
 
 		// Check password
 		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+			recordLoginFailure(c, params, req.Email)
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Email and password do not match"})
 			return
 		}
 
-		// Generate JWT token
-		token, err := utils.GenerateJWT(user)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		// The session is only started once they enter a code from their authenticator too, see verifyTwoFactor
+		if user.HasTwoFactor() {
+			c.JSON(http.StatusOK, gin.H{"twoFactorRequired": true, "twoFactorToken": newTwoFactorToken(user)})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"token": token})
+		clearLoginFailures(c, params, user.Email)
+		startSession(c, params, user, false)
 	}
 }
 
@@ -126,54 +157,62 @@ func registerUser(params *types.RouteParams) gin.HandlerFunc {
 		}
 		newUser.ID = r.InsertedID.(primitive.ObjectID)
 
-		// Generate JWT token
-		token, err := utils.GenerateJWT(&newUser)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		if err := subscribeToDefaultPlan(c, params, &newUser); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subscription"})
+			logger.Error("Failed to subscribe new user to the default plan", err)
 			return
 		}
 
-		listPlans, err := params.MongoService.ListPlans(c, bson.M{"default": true})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve plans"})
-			return
+		// The account works without a verified email, it is only needed for restricted forms and can be resent
+		if err := sendVerificationEmail(c, params, &newUser); err != nil {
+			logger.Error("Failed to send verification email", err)
 		}
 
-		// Create a new free plan for the user
-		newSubscription := models.Subscription{
-			PlanID:    listPlans[0].ID,
-			UserID:    newUser.ID,
-			Status:    models.SubscriptionStatusActive,
-			StartDate: time.Now(),
-			EndDate:   time.Now().AddDate(0, 1, 0),
-			Limits:    listPlans[0].Limits,
-			Utilization: models.Utilization{
-				EventsCreated: 0,
-				Responses:     0,
-				PipelineRuns:  0,
-			},
-			BillingCycle:             "monthly",
-			NextUtilizationResetDate: time.Now().AddDate(0, 1, 0),
-		}
-
-		subscriptionId, err := params.MongoService.CreateNewSubscription(c, newSubscription)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subscription"})
-			return
-		}
+		utils.SendSlackMessage(fmt.Sprintf("New User: %s %s (%s)", newUser.FirstName, newUser.LastName, newUser.Email))
+		startSession(c, params, &newUser, false)
+	}
+}
 
-		newUser.CurrentSubscriptionID = subscriptionId.InsertedID.(primitive.ObjectID)
+// subscribeToDefaultPlan gives a new user a subscription to the free default plan and saves it as their current one
+func subscribeToDefaultPlan(ctx context.Context, params *types.RouteParams, user *models.User) error {
+	listPlans, err := params.MongoService.ListPlans(ctx, bson.M{"default": true})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve plans: %w", err)
+	}
+	if len(listPlans) == 0 {
+		return errors.New("there is no default plan")
+	}
 
-		// Update the user with the new subscription
-		_, err = params.MongoService.UpdateUser(c, newUser.ID, newUser)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user details"})
-			return
-		}
+	// Create a new free plan for the user
+	newSubscription := models.Subscription{
+		PlanID:    listPlans[0].ID,
+		UserID:    user.ID,
+		Status:    models.SubscriptionStatusActive,
+		StartDate: time.Now(),
+		EndDate:   time.Now().AddDate(0, 1, 0),
+		Limits:    listPlans[0].Limits,
+		Utilization: models.Utilization{
+			EventsCreated: 0,
+			Responses:     0,
+			PipelineRuns:  0,
+		},
+		BillingCycle:             "monthly",
+		NextUtilizationResetDate: time.Now().AddDate(0, 1, 0),
+	}
 
-		utils.SendSlackMessage(fmt.Sprintf("New User: %s %s (%s)", newUser.FirstName, newUser.LastName, newUser.Email))
-		c.JSON(http.StatusOK, gin.H{"token": token})
+	subscriptionId, err := params.MongoService.CreateNewSubscription(ctx, newSubscription)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	user.CurrentSubscriptionID = subscriptionId.InsertedID.(primitive.ObjectID)
+
+	// Update the user with the new subscription
+	if _, err := params.MongoService.UpdateUser(ctx, user.ID, *user); err != nil {
+		return fmt.Errorf("failed to update user details: %w", err)
 	}
+
+	return nil
 }
 
 // TODO: We should base this on the user's id instead of email, and handle deleting all data like responses
@@ -194,6 +233,10 @@ func deleteUser(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
+		if _, err := params.MongoService.DeleteUserSessions(c, authenticatedUser.ID, primitive.NilObjectID); err != nil {
+			logger.Error("Failed to delete sessions of deleted user", err)
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
 	}
 }
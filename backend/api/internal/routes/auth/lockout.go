@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"api/internal/types"
+	"fmt"
+	"math"
+	"net/http"
+	"shared/config"
+	"shared/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Failed sign ins are counted per account and per IP in mongo, so the counts hold across every API instance.
+After a couple of failures an account has to wait longer before each attempt, and too many failures lock the account or IP out
+for LOGIN_LOCKOUT_MINUTES. A locked out account is refused even with the right password so the lockout can't be used to guess it,
+resetting the password unlocks it. Every lockout is logged as a security event.
+*/
+
+const (
+	loginFailureMemory = time.Hour   // failures are forgotten this long after the last one
+	loginFreeFailures  = 2           // failures before an account has to wait between attempts
+	maxLoginDelay      = time.Minute // the longest an account has to wait between attempts
+)
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+// loginDelay is how long an account with the failures has to wait after the last one to try again, it doubles with each failure
+func loginDelay(failures int) time.Duration {
+	if failures <= loginFreeFailures {
+		return 0
+	}
+
+	delay := time.Second
+	for i := loginFreeFailures + 1; i < failures && delay < maxLoginDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxLoginDelay {
+		return maxLoginDelay
+	}
+	return delay
+}
+
+// checkLoginThrottle checks the account and the client's IP are allowed to try to sign in now, it writes the error response when they aren't.
+// If the failures can't be read the attempt is let through
+func checkLoginThrottle(c *gin.Context, params *types.RouteParams, email string) bool {
+	accountKey := accountThrottleKey(email)
+	throttles, err := params.MongoService.GetLoginThrottles(c, []string{accountKey, ipThrottleKey(c.ClientIP())})
+	if err != nil {
+		logger.Error("Failed to get login throttles", err)
+		return true
+	}
+
+	now := time.Now()
+	var lockedUntil, retryAt time.Time
+	for _, throttle := range throttles {
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(lockedUntil) {
+			lockedUntil = *throttle.LockedUntil
+		}
+
+		// Only accounts are slowed down, many people can share an IP
+		if throttle.Key == accountKey {
+			retryAt = throttle.LastFailureAt.Add(loginDelay(throttle.Failures))
+		}
+	}
+
+	if lockedUntil.After(now) {
+		minutes := int(math.Ceil(lockedUntil.Sub(now).Minutes()))
+		c.Header("Retry-After", strconv.Itoa(int(lockedUntil.Sub(now).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("Too many failed sign in attempts, try again in %d minutes or reset your password", minutes)})
+		return false
+	}
+
+	if retryAt.After(now) {
+		c.Header("Retry-After", strconv.Itoa(int(retryAt.Sub(now).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed sign in attempts, please wait a moment before trying again"})
+		return false
+	}
+
+	return true
+}
+
+// recordLoginFailure counts a failed sign in against the account and the client's IP, locking out either that has failed too often
+func recordLoginFailure(c *gin.Context, params *types.RouteParams, email string) {
+	maxFailures, maxFailuresPerIP, lockout := 5, 50, 15*time.Minute
+	if apiConfig, err := config.GetAPIConfig(); err == nil {
+		maxFailures = apiConfig.LOGIN_MAX_FAILURES
+		maxFailuresPerIP = apiConfig.LOGIN_MAX_FAILURES_PER_IP
+		lockout = time.Duration(apiConfig.LOGIN_LOCKOUT_MINUTES) * time.Minute
+	}
+
+	limits := map[string]int{
+		accountThrottleKey(email):   maxFailures,
+		ipThrottleKey(c.ClientIP()): maxFailuresPerIP,
+	}
+
+	now := time.Now()
+	for key, limit := range limits {
+		throttle, err := params.MongoService.RecordLoginFailure(c, key, now.Add(loginFailureMemory))
+		if err != nil {
+			logger.Error("Failed to record login failure", err)
+			continue
+		}
+
+		// Once the lockout is over, the next failure locks it out again until the failures are forgotten
+		alreadyLocked := throttle.LockedUntil != nil && throttle.LockedUntil.After(now)
+		if throttle.Failures < limit || alreadyLocked {
+			continue
+		}
+
+		lockedUntil := now.Add(lockout)
+		if _, err := params.MongoService.LockLogin(c, key, lockedUntil, lockedUntil.Add(loginFailureMemory)); err != nil {
+			logger.Error("Failed to lock out login", err)
+			continue
+		}
+
+		logger.Security("login_lockout", map[string]interface{}{
+			"key":         key,
+			"failures":    throttle.Failures,
+			"clientIP":    c.ClientIP(),
+			"userAgent":   c.Request.UserAgent(),
+			"lockedUntil": lockedUntil,
+		})
+	}
+}
+
+// clearLoginFailures forgets the account's failed sign ins once the user has signed in or reset their password.
+// The IP's are kept, otherwise signing in to one account would let an attacker keep guessing at others
+func clearLoginFailures(c *gin.Context, params *types.RouteParams, email string) {
+	if _, err := params.MongoService.DeleteLoginThrottles(c, []string{accountThrottleKey(email)}); err != nil {
+		logger.Error("Failed to clear login failures", err)
+	}
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"api/internal/types"
+	"context"
+	"fmt"
+	"net/http"
+	"shared/models"
+	"shared/mongodb"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loginThrottles keeps failed sign ins in memory, fakes of routes that sign in embed it
+type loginThrottles struct {
+	mongodb.MongoService
+	throttles map[string]*models.LoginThrottle
+}
+
+func (m *loginThrottles) GetLoginThrottles(ctx context.Context, keys []string) ([]models.LoginThrottle, error) {
+	throttles := []models.LoginThrottle{}
+	for _, key := range keys {
+		if throttle, ok := m.throttles[key]; ok && throttle.ExpiresAt.After(time.Now()) {
+			throttles = append(throttles, *throttle)
+		}
+	}
+	return throttles, nil
+}
+
+func (m *loginThrottles) RecordLoginFailure(ctx context.Context, key string, expiresAt time.Time) (*models.LoginThrottle, error) {
+	if m.throttles == nil {
+		m.throttles = map[string]*models.LoginThrottle{}
+	}
+
+	throttle, ok := m.throttles[key]
+	if !ok || !throttle.ExpiresAt.After(time.Now()) {
+		throttle = &models.LoginThrottle{Key: key}
+		m.throttles[key] = throttle
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = time.Now()
+	throttle.ExpiresAt = expiresAt
+
+	result := *throttle
+	return &result, nil
+}
+
+func (m *loginThrottles) LockLogin(ctx context.Context, key string, lockedUntil time.Time, expiresAt time.Time) (*mongo.UpdateResult, error) {
+	m.throttles[key].LockedUntil = &lockedUntil
+	m.throttles[key].ExpiresAt = expiresAt
+	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
+}
+
+func (m *loginThrottles) DeleteLoginThrottles(ctx context.Context, keys []string) (*mongo.DeleteResult, error) {
+	for _, key := range keys {
+		delete(m.throttles, key)
+	}
+	return &mongo.DeleteResult{DeletedCount: int64(len(keys))}, nil
+}
+
+// waitOutDelay moves the key's last failure back so its next attempt isn't delayed
+func (m *loginThrottles) waitOutDelay(key string) {
+	if throttle, ok := m.throttles[key]; ok {
+		throttle.LastFailureAt = throttle.LastFailureAt.Add(-maxLoginDelay)
+	}
+}
+
+// lockoutMongo adds resetting the password to the two-factor fake
+type lockoutMongo struct {
+	twoFactorMongo
+	reset *models.PasswordReset
+}
+
+func (m *lockoutMongo) ConsumePasswordReset(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	if m.reset == nil || m.reset.TokenHash != tokenHash {
+		return nil, mongo.ErrNoDocuments
+	}
+	reset := m.reset
+	m.reset = nil
+	return reset, nil
+}
+
+func (m *lockoutMongo) ChangeUserPassword(ctx context.Context, userID primitive.ObjectID, passwordHash string, keepSessionID primitive.ObjectID) error {
+	m.user.PasswordHash = passwordHash
+	return nil
+}
+
+func TestLoginDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		0:   0,
+		2:   0,
+		3:   time.Second,
+		4:   2 * time.Second,
+		8:   32 * time.Second,
+		9:   time.Minute,
+		100: time.Minute,
+	}
+
+	for failures, delay := range tests {
+		assert.Equal(t, delay, loginDelay(failures), "%d failures", failures)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	verifiedAt := time.Now()
+	m := &lockoutMongo{twoFactorMongo: twoFactorMongo{user: models.User{
+		ID:              primitive.NewObjectID(),
+		Email:           "ada@example.com",
+		PasswordHash:    string(passwordHash),
+		EmailVerifiedAt: &verifiedAt,
+	}}}
+
+	params := &types.RouteParams{MongoService: m}
+	r := gin.New()
+	r.POST("/login", loginUser(params))
+	r.POST("/reset-password", resetPassword(params))
+
+	login := func(password string) (int, map[string]interface{}) {
+		return postJSON(r, "/login", `{"email": "ada@example.com", "password": "`+password+`"}`)
+	}
+	accountKey := accountThrottleKey("ada@example.com")
+
+	// The first few failures can be retried straight away
+	for i := 0; i < loginFreeFailures; i++ {
+		status, _ := login("wrong")
+		assert.Equal(t, http.StatusBadRequest, status)
+	}
+
+	// After that there's a wait, even for the right password
+	status, _ := login("wrong")
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = login("password")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+
+	// Signing in forgets the failures
+	m.waitOutDelay(accountKey)
+	status, data := login("password")
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, data["refreshToken"])
+	assert.NotContains(t, m.throttles, accountKey)
+
+	// Enough failures lock the account out, however the email is typed
+	for i := 0; i < 5; i++ {
+		m.waitOutDelay(accountKey)
+		status, _ := postJSON(r, "/login", `{"email": "Ada@Example.com", "password": "wrong"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+	}
+	require.NotNil(t, m.throttles[accountKey].LockedUntil)
+
+	m.waitOutDelay(accountKey)
+	status, data = login("password")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Contains(t, data["error"], "reset your password")
+
+	// Resetting the password unlocks it
+	m.reset = &models.PasswordReset{UserID: m.user.ID, TokenHash: hashToken("reset-token")}
+	status, _ = postJSON(r, "/reset-password", `{"token": "reset-token", "newPassword": "N3w-password!"}`)
+	require.Equal(t, http.StatusOK, status)
+
+	status, _ = login("N3w-password!")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestLoginLockoutPerIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := &twoFactorMongo{}
+	params := &types.RouteParams{MongoService: m}
+	r := gin.New()
+	r.POST("/login", loginUser(params))
+
+	// Guessing at many accounts from one IP locks the IP out, without slowing it down before that
+	for i := 0; i < 50; i++ {
+		status, _ := postJSON(r, "/login", fmt.Sprintf(`{"email": "user%d@example.com", "password": "wrong"}`, i))
+		require.Equal(t, http.StatusBadRequest, status, "attempt %d", i)
+	}
+
+	status, _ := postJSON(r, "/login", `{"email": "someone@example.com", "password": "wrong"}`)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+}
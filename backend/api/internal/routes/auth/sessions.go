@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"api/internal/types"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"shared/config"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/*
+Signing in starts a session for the device, which gets a short lived JWT access token and a refresh token.
+
+The access token is only accepted while its session exists, see middlewares.JWTAuthMiddleware. When it expires the client
+exchanges its refresh token for a new pair, the refresh token is replaced every time so one that is used twice must have been copied,
+and its session is signed out. Signing out, or changing the password, deletes sessions.
+*/
+
+// newToken returns a random token for a refresh token or emailed link and the hash of it that is stored
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// refreshTokenExpiry is when a session refreshed now expires if it isn't refreshed again
+func refreshTokenExpiry() (time.Time, error) {
+	apiConfig, err := config.GetAPIConfig()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Now().AddDate(0, 0, apiConfig.REFRESH_TOKEN_TTL_DAYS), nil
+}
+
+// createSession signs the user in on a new device, it returns the access token and refresh token of the session
+func createSession(c *gin.Context, params *types.RouteParams, user *models.User, twoFactorVerified bool) (string, string, error) {
+	refreshToken, refreshTokenHash, err := newToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	expiresAt, err := refreshTokenExpiry()
+	if err != nil {
+		return "", "", err
+	}
+
+	session := models.Session{
+		UserID:            user.ID,
+		RefreshTokenHash:  refreshTokenHash,
+		UserAgent:         c.Request.UserAgent(),
+		IP:                c.ClientIP(),
+		ExpiresAt:         expiresAt,
+		TwoFactorVerified: twoFactorVerified,
+	}
+	result, err := params.MongoService.CreateSession(c, session)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	token, err := utils.GenerateJWT(user, result.InsertedID.(primitive.ObjectID))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return token, refreshToken, nil
+}
+
+// startSession signs the user in on this device and writes the tokens as the response
+func startSession(c *gin.Context, params *types.RouteParams, user *models.User, twoFactorVerified bool) {
+	token, refreshToken, err := createSession(c, params, user, twoFactorVerified)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		logger.Error("Failed to start session", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": token, "refreshToken": refreshToken})
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// Exchange a refresh token for a new access token and refresh token, the old refresh token stops working
+func refreshSession(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req refreshRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		refreshToken, refreshTokenHash, err := newToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			logger.Error("Failed to generate refresh token", err)
+			return
+		}
+
+		expiresAt, err := refreshTokenExpiry()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		session, err := params.MongoService.RotateSession(c, hashToken(req.RefreshToken), refreshTokenHash, expiresAt)
+		if err != nil {
+			if err == mongo.ErrNoDocuments || err == mongodb.ErrRefreshTokenReused {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+			logger.Error("Failed to rotate session", err)
+			return
+		}
+
+		user, err := params.MongoService.GetUserDetails(c, session.UserID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+			logger.Error("Failed to get user of session", err)
+			return
+		}
+
+		token, err := utils.GenerateJWT(user, session.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"token": token, "refreshToken": refreshToken})
+	}
+}
+
+// Sign out the device making the request
+func logout(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		sessionID, _ := utils.GetSessionIDFromContext(c)
+		if _, err := params.MongoService.DeleteSession(c, authenticatedUser.ID, sessionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			logger.Error("Failed to delete session", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	}
+}
+
+// Sign out every device of the user, including the one making the request
+func logoutEverywhere(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		result, err := params.MongoService.DeleteUserSessions(c, authenticatedUser.ID, primitive.NilObjectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			logger.Error("Failed to delete user sessions", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"sessions": result.DeletedCount, "message": "Logged out everywhere successfully"})
+	}
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,securepwd"`
+}
+
+// Change the password of the user, every other device is signed out
+func changePassword(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req changePasswordRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		user, err := params.MongoService.FindUserByEmail(c, authenticatedUser.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user details"})
+			logger.Error("Failed to find user by email", err)
+			return
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+
+		sessionID, _ := utils.GetSessionIDFromContext(c)
+		if err := params.MongoService.ChangeUserPassword(c, user.ID, string(hash), sessionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+			logger.Error("Failed to change user password", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully, you have been logged out on your other devices"})
+	}
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"api/internal/helpers"
+	"api/internal/sso"
+	"api/internal/types"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"shared/config"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+Users can sign in with the SSO providers configured in SSO_PROVIDERS, see the sso package.
+
+The login route sends the browser to the provider with a signed state that is also kept in a cookie, so the callback only accepts
+a sign in this browser started. The PKCE verifier and OIDC nonce are derived from the state, so nothing is stored until the user is back.
+
+The provider's account is linked to the user who signed in with it before, or else to the user with the same email if both the provider
+and the user have verified it. Anyone else gets a new account. The browser is then sent to the website's login page with a refresh token
+in the URL fragment, which it exchanges for its tokens at /auth/refresh. Users with a second factor get a two-factor token instead.
+*/
+
+const (
+	ssoStatePurpose = "sso-state"
+	ssoStateCookie  = "sso_state"
+	ssoStateTTL     = 10 * time.Minute
+)
+
+// ssoProviders sets up the configured SSO providers, a misconfigured provider is logged and none are available
+func ssoProviders() (config.SSOProviders, map[string]sso.Provider) {
+	apiConfig, err := config.GetAPIConfig()
+	if err != nil {
+		logger.Error("Failed to get API config", err)
+		return nil, nil
+	}
+
+	providers, err := sso.NewProviders(apiConfig.SSO_PROVIDERS, &http.Client{Timeout: 10 * time.Second})
+	if err != nil {
+		logger.Error("Failed to set up SSO providers", err)
+		return nil, nil
+	}
+
+	return apiConfig.SSO_PROVIDERS, providers
+}
+
+// ssoRedirectURL is where the provider sends the user back to
+func ssoRedirectURL(providerID string) (string, error) {
+	apiConfig, err := config.GetAPIConfig()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/auth/sso/%s/callback", apiConfig.API_PUBLIC_URL, url.PathEscape(providerID)), nil
+}
+
+// deriveFromState derives a value for the sign in from its state, the secret is different for each purpose
+func deriveFromState(purpose string, state string) string {
+	mac := hmac.New(sha256.New, utils.DeriveSecret(purpose))
+	mac.Write([]byte(state))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// stateProviderTag ties a state to the provider it was sent to, a provider's ID can contain a "." so the tag is a hash of it
+func stateProviderTag(providerID string) string {
+	return "-" + deriveFromState("sso-provider", providerID)[:16]
+}
+
+func codeVerifier(state string) string {
+	return deriveFromState("sso-pkce", state)
+}
+
+func nonce(state string) string {
+	return deriveFromState("sso-nonce", state)
+}
+
+// redirectToWebsite sends the browser back to the website's login page with the values in the URL fragment, which isn't sent to servers
+func redirectToWebsite(c *gin.Context, values url.Values) {
+	apiConfig, err := config.GetAPIConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to get API config", err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, apiConfig.WEBSITE_PUBLIC_URL+"/login#"+values.Encode())
+}
+
+func redirectWithError(c *gin.Context, message string) {
+	redirectToWebsite(c, url.Values{"error": {message}})
+}
+
+// List the providers users can sign in with
+func listSSOProviders(configs config.SSOProviders) gin.HandlerFunc {
+	type provider struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	list := make([]provider, 0, len(configs))
+	for _, providerConfig := range configs {
+		list = append(list, provider{ID: providerConfig.ID, Name: providerConfig.Name})
+	}
+
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// Send the browser to the provider to sign in
+func ssoLogin(providers map[string]sso.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		providerID := c.Param("provider")
+		provider, ok := providers[providerID]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown sign in provider"})
+			return
+		}
+
+		redirectURL, err := ssoRedirectURL(providerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to generate sso state", err)
+			return
+		}
+
+		subject := hex.EncodeToString(random) + stateProviderTag(providerID)
+		state := helpers.NewSignedToken(utils.DeriveSecret(ssoStatePurpose), subject, time.Now().Add(ssoStateTTL))
+
+		authURL, err := provider.AuthCodeURL(c, redirectURL, state, codeVerifier(state), nonce(state))
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "The sign in provider is unavailable"})
+			logger.Error("Failed to get sso authorization URL", err)
+			return
+		}
+
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(ssoStateCookie, state, int(ssoStateTTL.Seconds()), "/", "", strings.HasPrefix(redirectURL, "https://"), true)
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// Sign in the user the provider sent back, the browser is sent on to the website with a refresh token or an error
+func ssoCallback(params *types.RouteParams, providers map[string]sso.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		providerID := c.Param("provider")
+		provider, ok := providers[providerID]
+		if !ok {
+			redirectWithError(c, "Unknown sign in provider")
+			return
+		}
+
+		// The state is only good for one attempt
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(ssoStateCookie, "", -1, "/", "", false, true)
+
+		if c.Query("error") != "" {
+			redirectWithError(c, "Sign in was cancelled")
+			return
+		}
+
+		state := c.Query("state")
+		cookie, err := c.Cookie(ssoStateCookie)
+		if err != nil || state == "" || !hmac.Equal([]byte(cookie), []byte(state)) {
+			redirectWithError(c, "Sign in failed, please try again")
+			return
+		}
+
+		subject, _, err := helpers.VerifySignedToken(utils.DeriveSecret(ssoStatePurpose), state, time.Now())
+		if err != nil || !strings.HasSuffix(subject, stateProviderTag(providerID)) {
+			redirectWithError(c, "Sign in failed, please try again")
+			return
+		}
+
+		redirectURL, err := ssoRedirectURL(providerID)
+		if err != nil {
+			redirectWithError(c, "Internal server error")
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+		defer cancel()
+
+		identity, err := provider.Identify(ctx, redirectURL, c.Query("code"), codeVerifier(state), nonce(state))
+		if err != nil {
+			redirectWithError(c, "Sign in failed, please try again")
+			logger.Error("Failed to identify sso user", err)
+			return
+		}
+
+		user, message, err := findOrCreateSSOUser(c, params, providerID, identity)
+		if err != nil {
+			redirectWithError(c, message)
+			logger.Error("Failed to sign in sso user", err)
+			return
+		}
+		if user == nil {
+			redirectWithError(c, message)
+			return
+		}
+
+		// Users with a second factor still need to enter a code from it, the website finishes signing in at /auth/2fa/verify
+		if user.HasTwoFactor() {
+			redirectToWebsite(c, url.Values{"twoFactorToken": {newTwoFactorToken(user)}})
+			return
+		}
+
+		_, refreshToken, err := createSession(c, params, user, false)
+		if err != nil {
+			redirectWithError(c, "Failed to start session")
+			logger.Error("Failed to start session", err)
+			return
+		}
+
+		redirectToWebsite(c, url.Values{"refreshToken": {refreshToken}})
+	}
+}
+
+/*
+findOrCreateSSOUser returns the user the provider's account belongs to, linking it to the user with its email or creating a new user.
+When the user can't sign in it returns nil and the message to show them, with the error if it wasn't their fault
+*/
+func findOrCreateSSOUser(c *gin.Context, params *types.RouteParams, providerID string, identity *sso.Identity) (*models.User, string, error) {
+	user, err := params.MongoService.FindUserByIdentity(c, providerID, identity.Subject)
+	if err == nil {
+		return user, "", nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, "Failed to sign in", fmt.Errorf("failed to find user by identity: %w", err)
+	}
+
+	// Anyone can claim any email at some providers, so only a verified one is trusted to link or create an account
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, "Your account at the sign in provider doesn't have a verified email address", nil
+	}
+
+	externalIdentity := models.ExternalIdentity{Provider: providerID, Subject: identity.Subject}
+
+	user, err = params.MongoService.FindUserByEmail(c, identity.Email)
+	if err == nil {
+		// Whoever registered an unverified account may not own the email, linking it would let them keep signing in with its password
+		if user.EmailVerifiedAt == nil {
+			return nil, "An account with that email exists but its email isn't verified yet, sign in with your password and verify your email first", nil
+		}
+
+		if _, err := params.MongoService.AddUserIdentity(c, user.ID, externalIdentity); err != nil {
+			return nil, "Failed to sign in", fmt.Errorf("failed to link identity to user: %w", err)
+		}
+
+		return user, "", nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, "Failed to sign in", fmt.Errorf("failed to find user by email: %w", err)
+	}
+
+	now := time.Now()
+	newUser := models.User{
+		FirstName:       identity.FirstName,
+		LastName:        identity.LastName,
+		Email:           identity.Email,
+		EmailVerifiedAt: &now,
+		Identities:      []models.ExternalIdentity{externalIdentity},
+	}
+
+	r, err := params.MongoService.InsertUser(c, newUser)
+	if err != nil {
+		if err == mongodb.ErrUserAlreadyExists {
+			return nil, "An account with that email already exists", nil
+		}
+		return nil, "Failed to register user", fmt.Errorf("failed to insert user: %w", err)
+	}
+	newUser.ID = r.InsertedID.(primitive.ObjectID)
+
+	if err := subscribeToDefaultPlan(c, params, &newUser); err != nil {
+		return nil, "Failed to create subscription", err
+	}
+
+	utils.SendSlackMessage(fmt.Sprintf("New User: %s %s (%s)", newUser.FirstName, newUser.LastName, newUser.Email))
+	return &newUser, "", nil
+}
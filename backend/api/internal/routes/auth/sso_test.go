@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"api/internal/sso"
+	"api/internal/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"shared/models"
+	"shared/mongodb"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ssoMongo keeps users in memory, only the methods signing in with SSO uses are implemented
+type ssoMongo struct {
+	mongodb.MongoService
+	users         []*models.User
+	subscriptions []models.Subscription
+	sessions      []models.Session
+}
+
+func (m *ssoMongo) FindUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error) {
+	for _, user := range m.users {
+		for _, identity := range user.Identities {
+			if identity.Provider == provider && identity.Subject == subject {
+				return user, nil
+			}
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (m *ssoMongo) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (m *ssoMongo) AddUserIdentity(ctx context.Context, userID primitive.ObjectID, identity models.ExternalIdentity) (*mongo.UpdateResult, error) {
+	for _, user := range m.users {
+		if user.ID == userID {
+			user.Identities = append(user.Identities, identity)
+		}
+	}
+	return &mongo.UpdateResult{MatchedCount: 1}, nil
+}
+
+func (m *ssoMongo) InsertUser(ctx context.Context, user models.User) (*mongo.InsertOneResult, error) {
+	user.ID = primitive.NewObjectID()
+	m.users = append(m.users, &user)
+	return &mongo.InsertOneResult{InsertedID: user.ID}, nil
+}
+
+func (m *ssoMongo) UpdateUser(ctx context.Context, userID primitive.ObjectID, user models.User) (*mongo.UpdateResult, error) {
+	for i := range m.users {
+		if m.users[i].ID == userID {
+			*m.users[i] = user
+		}
+	}
+	return &mongo.UpdateResult{MatchedCount: 1}, nil
+}
+
+func (m *ssoMongo) ListPlans(ctx context.Context, filter bson.M) ([]models.Plan, error) {
+	return []models.Plan{{ID: primitive.NewObjectID(), Name: "Free"}}, nil
+}
+
+func (m *ssoMongo) CreateNewSubscription(ctx context.Context, subscription models.Subscription) (*mongo.InsertOneResult, error) {
+	subscription.ID = primitive.NewObjectID()
+	m.subscriptions = append(m.subscriptions, subscription)
+	return &mongo.InsertOneResult{InsertedID: subscription.ID}, nil
+}
+
+func (m *ssoMongo) CreateSession(ctx context.Context, session models.Session) (*mongo.InsertOneResult, error) {
+	session.ID = primitive.NewObjectID()
+	m.sessions = append(m.sessions, session)
+	return &mongo.InsertOneResult{InsertedID: session.ID}, nil
+}
+
+// fakeProvider says the user is whoever the test set, and records what it was asked
+type fakeProvider struct {
+	identity *sso.Identity
+
+	codeVerifier string
+	nonce        string
+}
+
+func (p *fakeProvider) AuthCodeURL(ctx context.Context, redirectURL string, state string, codeVerifier string, nonce string) (string, error) {
+	p.codeVerifier, p.nonce = codeVerifier, nonce
+	return "https://provider.test/authorize?state=" + url.QueryEscape(state), nil
+}
+
+func (p *fakeProvider) Identify(ctx context.Context, redirectURL string, code string, codeVerifier string, nonce string) (*sso.Identity, error) {
+	if codeVerifier != p.codeVerifier || nonce != p.nonce {
+		return nil, sso.ErrInvalidIDToken
+	}
+	return p.identity, nil
+}
+
+func setupSSORouter(m *ssoMongo, provider sso.Provider) *gin.Engine {
+	params := &types.RouteParams{MongoService: m}
+	providers := map[string]sso.Provider{"test": provider}
+
+	r := gin.New()
+	r.GET("/auth/sso/:provider/login", ssoLogin(providers))
+	r.GET("/auth/sso/:provider/callback", ssoCallback(params, providers))
+	return r
+}
+
+// signIn goes through the login and callback routes like a browser would, it returns the fragment the website is sent
+func signIn(t *testing.T, r *gin.Engine) url.Values {
+	login := httptest.NewRecorder()
+	r.ServeHTTP(login, httptest.NewRequest(http.MethodGet, "/auth/sso/test/login", nil))
+	require.Equal(t, http.StatusFound, login.Code)
+
+	authURL, err := url.Parse(login.Header().Get("Location"))
+	require.NoError(t, err)
+	state := authURL.Query().Get("state")
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/sso/test/callback?code=code&state="+url.QueryEscape(state), nil)
+	for _, cookie := range login.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	callback := httptest.NewRecorder()
+	r.ServeHTTP(callback, req)
+	require.Equal(t, http.StatusFound, callback.Code)
+
+	location := callback.Header().Get("Location")
+	fragment, err := url.ParseQuery(location[strings.Index(location, "#")+1:])
+	require.NoError(t, err)
+	return fragment
+}
+
+func TestSSOCreatesUser(t *testing.T) {
+	m := &ssoMongo{}
+	provider := &fakeProvider{identity: &sso.Identity{Subject: "1", Email: "ada@example.com", EmailVerified: true, FirstName: "Ada"}}
+
+	fragment := signIn(t, setupSSORouter(m, provider))
+	assert.NotEmpty(t, fragment.Get("refreshToken"), fragment.Get("error"))
+
+	require.Len(t, m.users, 1)
+	user := m.users[0]
+	assert.Equal(t, "ada@example.com", user.Email)
+	assert.NotNil(t, user.EmailVerifiedAt)
+	assert.Equal(t, []models.ExternalIdentity{{Provider: "test", Subject: "1"}}, user.Identities)
+
+	// They get a subscription the same way users who register do
+	require.Len(t, m.subscriptions, 1)
+	assert.Equal(t, m.subscriptions[0].ID, user.CurrentSubscriptionID)
+	require.Len(t, m.sessions, 1)
+	assert.Equal(t, user.ID, m.sessions[0].UserID)
+
+	// Signing in again finds them by their identity
+	signIn(t, setupSSORouter(m, provider))
+	assert.Len(t, m.users, 1)
+	assert.Len(t, m.sessions, 2)
+}
+
+func TestSSOLinksUserByVerifiedEmail(t *testing.T) {
+	verifiedAt := time.Now()
+	existing := &models.User{ID: primitive.NewObjectID(), Email: "ada@example.com", EmailVerifiedAt: &verifiedAt}
+	m := &ssoMongo{users: []*models.User{existing}}
+	provider := &fakeProvider{identity: &sso.Identity{Subject: "1", Email: "ada@example.com", EmailVerified: true}}
+
+	fragment := signIn(t, setupSSORouter(m, provider))
+	assert.NotEmpty(t, fragment.Get("refreshToken"), fragment.Get("error"))
+
+	assert.Len(t, m.users, 1)
+	assert.Equal(t, []models.ExternalIdentity{{Provider: "test", Subject: "1"}}, existing.Identities)
+	assert.Empty(t, m.subscriptions)
+}
+
+func TestSSORefusesUnverifiedAccount(t *testing.T) {
+	// Someone else may have registered the email, they would keep their password on the linked account
+	existing := &models.User{ID: primitive.NewObjectID(), Email: "ada@example.com", PasswordHash: "hash"}
+	m := &ssoMongo{users: []*models.User{existing}}
+	provider := &fakeProvider{identity: &sso.Identity{Subject: "1", Email: "ada@example.com", EmailVerified: true}}
+
+	fragment := signIn(t, setupSSORouter(m, provider))
+	assert.Empty(t, fragment.Get("refreshToken"))
+	assert.Contains(t, fragment.Get("error"), "sign in with your password and verify your email first")
+	assert.Empty(t, existing.Identities)
+	assert.Empty(t, m.sessions)
+	assert.Len(t, m.users, 1)
+}
+
+func TestSSORefusesUnverifiedEmail(t *testing.T) {
+	existing := &models.User{ID: primitive.NewObjectID(), Email: "ada@example.com"}
+	m := &ssoMongo{users: []*models.User{existing}}
+	provider := &fakeProvider{identity: &sso.Identity{Subject: "1", Email: "ada@example.com"}}
+
+	fragment := signIn(t, setupSSORouter(m, provider))
+	assert.Empty(t, fragment.Get("refreshToken"))
+	assert.NotEmpty(t, fragment.Get("error"))
+	assert.Empty(t, existing.Identities)
+	assert.Empty(t, m.sessions)
+}
+
+func TestSSOCallbackRequiresStateCookie(t *testing.T) {
+	m := &ssoMongo{}
+	provider := &fakeProvider{identity: &sso.Identity{Subject: "1", Email: "ada@example.com", EmailVerified: true}}
+	r := setupSSORouter(m, provider)
+
+	login := httptest.NewRecorder()
+	r.ServeHTTP(login, httptest.NewRequest(http.MethodGet, "/auth/sso/test/login", nil))
+	authURL, err := url.Parse(login.Header().Get("Location"))
+	require.NoError(t, err)
+
+	// The callback comes from a browser that didn't start the sign in
+	callback := httptest.NewRecorder()
+	r.ServeHTTP(callback, httptest.NewRequest(http.MethodGet, "/auth/sso/test/callback?code=code&state="+url.QueryEscape(authURL.Query().Get("state")), nil))
+
+	assert.Equal(t, http.StatusFound, callback.Code)
+	assert.Contains(t, callback.Header().Get("Location"), "#error=")
+	assert.Empty(t, m.users)
+}
@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"api/internal/helpers"
+	"api/internal/types"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"shared/logger"
+	"shared/models"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+Users can add an authenticator app as a second factor, a TOTP code from it is then needed to sign in on top of their password or SSO provider.
+It comes with single use recovery codes for when the app is lost.
+
+Signing in with a second factor returns a short lived two-factor token instead of a session, which is exchanged at /auth/2fa/verify
+with a code for the session. Sessions remember if they were signed in with a second factor, as events can require it of their organizers.
+*/
+
+const (
+	twoFactorLoginPurpose = "two-factor-login"
+	twoFactorLoginTTL     = 5 * time.Minute
+	totpIssuer            = "ApplicantAtlas"
+	recoveryCodeCount     = 10
+)
+
+// newTwoFactorToken is the token a user who signed in with their first factor exchanges with a code for their session
+func newTwoFactorToken(user *models.User) string {
+	return helpers.NewSignedToken(utils.DeriveSecret(twoFactorLoginPurpose), user.ID.Hex(), time.Now().Add(twoFactorLoginTTL))
+}
+
+// newRecoveryCodes returns codes to show the user once and the hashes of them that are stored
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes the code however the user typed it
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashToken(code)
+}
+
+// useSecondFactor checks the code is one from the user's authenticator or one of their recovery codes, and uses it up
+func useSecondFactor(c *gin.Context, params *types.RouteParams, user *models.User, code string, allowRecoveryCode bool) (bool, error) {
+	if !user.HasTwoFactor() {
+		return false, nil
+	}
+
+	secret, err := params.MongoService.OpenTwoFactorSecret(c, user)
+	if err != nil {
+		return false, err
+	}
+
+	if step, ok := helpers.VerifyTOTP(secret, code, time.Now()); ok {
+		return params.MongoService.UseTwoFactorCode(c, user.ID, step)
+	}
+
+	if !allowRecoveryCode {
+		return false, nil
+	}
+	return params.MongoService.UseRecoveryCode(c, user.ID, hashRecoveryCode(code))
+}
+
+// getTwoFactorUser gets the signed in user with their second factor, it writes the error response on failure
+func getTwoFactorUser(c *gin.Context, params *types.RouteParams) (*models.User, bool) {
+	authenticatedUser, ok := utils.GetUserFromContext(c, true)
+	if !ok {
+		return nil, false
+	}
+
+	user, err := params.MongoService.GetUserDetails(c, authenticatedUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user details"})
+		logger.Error("Failed to get user details", err)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// Whether the signed in user has a second factor, and how many recovery codes they have left
+func getTwoFactorStatus(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := getTwoFactorUser(c, params)
+		if !ok {
+			return
+		}
+
+		if !user.HasTwoFactor() {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":                true,
+			"enabledAt":              user.TwoFactor.EnabledAt,
+			"recoveryCodesRemaining": len(user.TwoFactor.RecoveryCodeHashes),
+			"sessionVerified":        utils.IsTwoFactorVerified(c),
+		})
+	}
+}
+
+// Start adding an authenticator, it returns the secret to add to the app which is enabled once a code from it is entered
+func setUpTwoFactor(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := getTwoFactorUser(c, params)
+		if !ok {
+			return
+		}
+
+		secret, err := helpers.NewTOTPSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to generate TOTP secret", err)
+			return
+		}
+
+		result, err := params.MongoService.SetUpTwoFactor(c, user.ID, secret)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+			logger.Error("Failed to set up two-factor authentication", err)
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"secret": secret, "otpauthURL": helpers.TOTPURL(totpIssuer, user.Email, secret)})
+	}
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// Enable the authenticator set up with a code from it, it returns the recovery codes which are only shown this once
+func enableTwoFactor(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req twoFactorCodeRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		user, ok := getTwoFactorUser(c, params)
+		if !ok {
+			return
+		}
+
+		if user.HasTwoFactor() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		if user.TwoFactor == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Set up two-factor authentication first"})
+			return
+		}
+
+		secret, err := params.MongoService.OpenTwoFactorSecret(c, user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to open two-factor secret", err)
+			return
+		}
+
+		step, ok := helpers.VerifyTOTP(secret, req.Code, time.Now())
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The code is incorrect, check the time on your device is right"})
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to generate recovery codes", err)
+			return
+		}
+
+		sessionID, _ := utils.GetSessionIDFromContext(c)
+		enabled, err := params.MongoService.EnableTwoFactor(c, user.ID, step, hashes, sessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+			logger.Error("Failed to enable two-factor authentication", err)
+			return
+		}
+		if !enabled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes, "message": "Two-factor authentication enabled, keep your recovery codes somewhere safe"})
+	}
+}
+
+// Replace the recovery codes with new ones, a code from the authenticator is needed
+func regenerateRecoveryCodes(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req twoFactorCodeRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		user, ok := getTwoFactorUser(c, params)
+		if !ok {
+			return
+		}
+
+		if !user.HasTwoFactor() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+			return
+		}
+
+		used, err := useSecondFactor(c, params, user, req.Code, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to use two-factor code", err)
+			return
+		}
+		if !used {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The code is incorrect"})
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to generate recovery codes", err)
+			return
+		}
+
+		if _, err := params.MongoService.ReplaceRecoveryCodes(c, user.ID, hashes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace recovery codes"})
+			logger.Error("Failed to replace recovery codes", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+	}
+}
+
+// Remove the authenticator, a code from it or a recovery code is needed
+func disableTwoFactor(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req twoFactorCodeRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		user, ok := getTwoFactorUser(c, params)
+		if !ok {
+			return
+		}
+
+		if !user.HasTwoFactor() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+			return
+		}
+
+		used, err := useSecondFactor(c, params, user, req.Code, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to use two-factor code", err)
+			return
+		}
+		if !used {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The code is incorrect"})
+			return
+		}
+
+		if err := params.MongoService.DisableTwoFactor(c, user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+			logger.Error("Failed to disable two-factor authentication", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
+
+type verifyTwoFactorRequest struct {
+	TwoFactorToken string `json:"twoFactorToken" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+// Finish signing in with a code from the authenticator or a recovery code, with the two-factor token the first factor returned
+func verifyTwoFactor(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req verifyTwoFactorRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		subject, _, err := helpers.VerifySignedToken(utils.DeriveSecret(twoFactorLoginPurpose), req.TwoFactorToken, time.Now())
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Your sign in has expired, please sign in again"})
+			return
+		}
+
+		userID, err := primitive.ObjectIDFromHex(subject)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Your sign in has expired, please sign in again"})
+			return
+		}
+
+		user, err := params.MongoService.GetUserDetails(c, userID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Your sign in has expired, please sign in again"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login"})
+			logger.Error("Failed to get user details", err)
+			return
+		}
+
+		// Wrong codes count towards the same lockout as wrong passwords
+		if !checkLoginThrottle(c, params, user.Email) {
+			return
+		}
+
+		used, err := useSecondFactor(c, params, user, req.Code, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login"})
+			logger.Error("Failed to use two-factor code", err)
+			return
+		}
+		if !used {
+			recordLoginFailure(c, params, user.Email)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The code is incorrect"})
+			return
+		}
+
+		clearLoginFailures(c, params, user.Email)
+		startSession(c, params, user, true)
+	}
+}
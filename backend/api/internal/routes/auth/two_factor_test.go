@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"api/internal/types"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"shared/models"
+	"shared/mongodb"
+	"shared/secrets"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// twoFactorMongo holds a single user, only the methods signing in with a second factor uses are implemented
+type twoFactorMongo struct {
+	loginThrottles
+	keys     secrets.KeyProvider
+	user     models.User
+	sessions []models.Session
+}
+
+// OpenTwoFactorSecret decrypts the secret like the service does, it doesn't need the database
+func (m *twoFactorMongo) OpenTwoFactorSecret(ctx context.Context, user *models.User) (string, error) {
+	return (&mongodb.Service{Keys: m.keys}).OpenTwoFactorSecret(ctx, user)
+}
+
+func (m *twoFactorMongo) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	if email != m.user.Email {
+		return nil, mongo.ErrNoDocuments
+	}
+	user := m.user
+	return &user, nil
+}
+
+func (m *twoFactorMongo) GetUserDetails(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	if userID != m.user.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	user := m.user
+	return &user, nil
+}
+
+func (m *twoFactorMongo) UseTwoFactorCode(ctx context.Context, userID primitive.ObjectID, step int64) (bool, error) {
+	if step <= m.user.TwoFactor.LastUsedStep {
+		return false, nil
+	}
+	m.user.TwoFactor.LastUsedStep = step
+	return true, nil
+}
+
+func (m *twoFactorMongo) UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, recoveryCodeHash string) (bool, error) {
+	hashes := m.user.TwoFactor.RecoveryCodeHashes
+	for i, hash := range hashes {
+		if hash == recoveryCodeHash {
+			m.user.TwoFactor.RecoveryCodeHashes = append(hashes[:i:i], hashes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *twoFactorMongo) CreateSession(ctx context.Context, session models.Session) (*mongo.InsertOneResult, error) {
+	session.ID = primitive.NewObjectID()
+	m.sessions = append(m.sessions, session)
+	return &mongo.InsertOneResult{InsertedID: session.ID}, nil
+}
+
+// currentTOTPCode is the code an authenticator app with the secret shows now
+func currentTOTPCode(t *testing.T, secret string) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+func postJSON(r *gin.Engine, path string, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	var data map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &data)
+	return resp.Code, data
+}
+
+func TestLoginWithTwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	codes, hashes, err := newRecoveryCodes()
+	require.NoError(t, err)
+
+	keys, err := secrets.NewKeyring("test", "a", map[string][]byte{"a": make([]byte, 32)})
+	require.NoError(t, err)
+
+	enabledAt := time.Now()
+	userID := primitive.NewObjectID()
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	sealed, err := secrets.Seal(context.Background(), keys, []byte(secret), []byte(userID.Hex()))
+	require.NoError(t, err)
+	m := &twoFactorMongo{keys: keys, user: models.User{
+		ID:           userID,
+		Email:        "ada@example.com",
+		PasswordHash: string(passwordHash),
+		TwoFactor:    &models.TwoFactor{SealedSecret: sealed, EnabledAt: &enabledAt, RecoveryCodeHashes: hashes},
+	}}
+
+	params := &types.RouteParams{MongoService: m}
+	r := gin.New()
+	r.POST("/login", loginUser(params))
+	r.POST("/2fa/verify", verifyTwoFactor(params))
+
+	login := func() string {
+		code, data := postJSON(r, "/login", `{"email": "ada@example.com", "password": "password"}`)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, true, data["twoFactorRequired"])
+		assert.Nil(t, data["token"], "no session until the second factor")
+		return data["twoFactorToken"].(string)
+	}
+	verify := func(token string, code string) (int, map[string]interface{}) {
+		return postJSON(r, "/2fa/verify", fmt.Sprintf(`{"twoFactorToken": %q, "code": %q}`, token, code))
+	}
+
+	token := login()
+	assert.Empty(t, m.sessions)
+
+	status, _ := verify(token, "000000")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	totp := currentTOTPCode(t, secret)
+	status, data := verify(token, totp)
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, data["refreshToken"])
+	require.Len(t, m.sessions, 1)
+	assert.True(t, m.sessions[0].TwoFactorVerified)
+
+	// A code can't be used twice
+	status, _ = verify(login(), totp)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Recovery codes work once, however they are typed
+	status, _ = verify(login(), strings.ToUpper(strings.ReplaceAll(codes[0], "-", "")))
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = verify(login(), codes[0])
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Len(t, m.user.TwoFactor.RecoveryCodeHashes, recoveryCodeCount-1)
+
+	status, _ = verify("not-a-token", totp)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
@@ -15,19 +15,14 @@ import (
 )
 
 func RegisterEmailTemplateRoutes(r *gin.RouterGroup, params *types.RouteParams) {
-	r.GET(":template_id", middlewares.JWTAuthMiddleware(), getEmailTemplate(params))
-	r.POST("", middlewares.JWTAuthMiddleware(), createNewTemplate(params))
-	r.PUT(":template_id", middlewares.JWTAuthMiddleware(), updateTemplate(params))
-	r.DELETE(":template_id", middlewares.JWTAuthMiddleware(), deleteTemplate(params))
+	r.GET(":template_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewEvent), getEmailTemplate(params))
+	r.POST("", middlewares.JWTAuthMiddleware(params.MongoService), createNewTemplate(params))
+	r.PUT(":template_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditEmailTemplates), updateTemplate(params))
+	r.DELETE(":template_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditEmailTemplates), deleteTemplate(params))
 }
 
 func getEmailTemplate(params *types.RouteParams) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
 		templateID, err := primitive.ObjectIDFromHex(c.Param("template_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -44,13 +39,6 @@ func getEmailTemplate(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		if !mongodb.CanUserModifyEmailTemplate(c, params.MongoService, authenticatedUser, templateID, template) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Unauthorized",
-			})
-			return
-		}
-
 		c.JSON(http.StatusOK, template)
 	}
 }
@@ -70,8 +58,8 @@ func createNewTemplate(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authenticatedUser, template.EventID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		if !mongodb.HasEventPermission(c, params.MongoService, authenticatedUser, template.EventID, nil, models.PermissionEditEmailTemplates) {
+			c.JSON(http.StatusForbidden, gin.H{
 				"error": "Unauthorized",
 			})
 			return
@@ -84,6 +72,8 @@ func createNewTemplate(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
+		middlewares.SetAuditTarget(c, template.EventID, "emailTemplate", templateID.InsertedID.(primitive.ObjectID))
+
 		c.JSON(http.StatusOK, gin.H{"id": templateID})
 	}
 }
@@ -102,22 +92,12 @@ func updateTemplate(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
 		emailTemplate, err := params.MongoService.GetEmailTemplate(c, templateID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get email template"})
 			return
 		}
 
-		if !mongodb.CanUserModifyEmailTemplate(c, params.MongoService, authenticatedUser, templateID, emailTemplate) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to update this pipeline"})
-			return
-		}
-
 		if emailTemplate.LastUpdatedAt.After(req.LastUpdatedAt) {
 			c.JSON(http.StatusConflict, gin.H{"error": messages.UpdateAttemptOnChangedEntity})
 			return
@@ -131,6 +111,8 @@ func updateTemplate(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
+		middlewares.SetAuditChanges(c, emailTemplate, req)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Pipeline configuration updated successfully", "lastUpdatedAt": newUpdatedAt})
 	}
 }
@@ -143,16 +125,6 @@ func deleteTemplate(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
-		if !mongodb.CanUserModifyEmailTemplate(c, params.MongoService, authenticatedUser, templateID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to delete this pipeline"})
-			return
-		}
-
 		_, err = params.MongoService.DeleteEmailTemplate(c, templateID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete pipeline"})
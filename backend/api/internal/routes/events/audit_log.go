@@ -0,0 +1,110 @@
+package events
+
+import (
+	"api/internal/types"
+	"net/http"
+	"regexp"
+	"shared/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+List the event's audit log, newest first
+
+query:
+  - actor: the ID or email of the organizer who did it
+  - action: the method and route, eg: DELETE /forms/:form_id, or only the method
+  - targetType, targetID: what it was done to
+  - since, until: RFC 3339 times
+  - page, pageSize: pagination (default: 1, 50)
+*/
+func listAuditLogHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			return
+		}
+
+		filter := bson.M{"eventID": eventID}
+
+		if actor := c.Query("actor"); actor != "" {
+			if actorID, err := primitive.ObjectIDFromHex(actor); err == nil {
+				filter["actorID"] = actorID
+			} else {
+				filter["actorEmail"] = actor
+			}
+		}
+
+		if action := c.Query("action"); action != "" {
+			if strings.Contains(action, " ") {
+				filter["action"] = action
+			} else {
+				filter["action"] = bson.M{"$regex": "^" + regexp.QuoteMeta(strings.ToUpper(action)) + " "}
+			}
+		}
+
+		if targetType := c.Query("targetType"); targetType != "" {
+			filter["target.type"] = targetType
+		}
+		if target := c.Query("targetID"); target != "" {
+			targetID, err := primitive.ObjectIDFromHex(target)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+				return
+			}
+			filter["target.id"] = targetID
+		}
+
+		createdAt := bson.M{}
+		for query, operator := range map[string]string{"since": "$gte", "until": "$lte"} {
+			value := c.Query(query)
+			if value == "" {
+				continue
+			}
+
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + query + " time, it must be RFC 3339"})
+				return
+			}
+			createdAt[operator] = t
+		}
+		if len(createdAt) > 0 {
+			filter["createdAt"] = createdAt
+		}
+
+		// Pagination parameters
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+
+		// Validate page and pageSize
+		if page < 1 {
+			page = 1
+		}
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 50
+		}
+
+		opts := options.Find().
+			SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+			SetSkip(int64((page - 1) * pageSize)).
+			SetLimit(int64(pageSize))
+
+		entries, err := params.MongoService.ListAuditLog(c, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit log"})
+			logger.Error("Failed to list audit log", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"entries": entries, "page": page, "pageSize": pageSize})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"api/internal/routes/events/secrets"
 	"api/internal/types"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"shared/messages"
@@ -17,21 +18,29 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // RegisterRoutes sets up the routes for event management
 func RegisterRoutes(r *gin.RouterGroup, params *types.RouteParams) {
 	r.GET("", listEventsHandler(params))
-	r.POST("", middlewares.JWTAuthMiddleware(), createEventHandler(params))
-	r.GET("my-events", middlewares.JWTAuthMiddleware(), listMyEventsHandler(params))
-	r.PUT(":event_id", middlewares.JWTAuthMiddleware(), updateEventHandler(params))
-	r.DELETE(":event_id", middlewares.JWTAuthMiddleware(), deleteEventHandler(params))
+	r.POST("", middlewares.JWTAuthMiddleware(params.MongoService), createEventHandler(params))
+	r.GET("my-events", middlewares.JWTAuthMiddleware(params.MongoService), listMyEventsHandler(params))
+	r.PUT(":event_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditEvent), updateEventHandler(params))
+	r.DELETE(":event_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionDeleteEvent), deleteEventHandler(params))
 	r.GET(":event_id", getEventHandler(params))
-	r.GET(":event_id/forms", middlewares.JWTAuthMiddleware(), getEventFormsHandler(params))
-	r.GET(":event_id/pipelines", middlewares.JWTAuthMiddleware(), getEventPipelinesHandler(params))
-	r.GET(":event_id/email_templates", middlewares.JWTAuthMiddleware(), getEventEmailTemplatesHandler(params))
-	r.POST(":event_id/organizers/:user_email", middlewares.JWTAuthMiddleware(), addOrganizerHandler(params))
-	r.DELETE(":event_id/organizers/:user_id", middlewares.JWTAuthMiddleware(), removeOrganizerHandler(params))
+	r.GET(":event_id/forms", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewEvent), getEventFormsHandler(params))
+	r.GET(":event_id/pipelines", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewEvent), getEventPipelinesHandler(params))
+	r.GET(":event_id/email_templates", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewEvent), getEventEmailTemplatesHandler(params))
+	r.POST(":event_id/organizers/:user_email", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageOrganizers), addOrganizerHandler(params))
+	r.PUT(":event_id/organizers/:user_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageOrganizers), setOrganizerRoleHandler(params))
+	r.DELETE(":event_id/organizers/:user_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageOrganizers), removeOrganizerHandler(params))
+	r.PUT(":event_id/two-factor", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageSecurity), setTwoFactorRequirementHandler(params))
+	r.GET(":event_id/invites", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageOrganizers), listInvitesHandler(params))
+	r.POST(":event_id/invites/accept", middlewares.JWTAuthMiddleware(params.MongoService), acceptInviteHandler(params))
+	r.POST(":event_id/invites/:invite_id/resend", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageOrganizers), resendInviteHandler(params))
+	r.DELETE(":event_id/invites/:invite_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageOrganizers), revokeInviteHandler(params))
+	r.GET(":event_id/audit-log", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewAuditLog), listAuditLogHandler(params))
 
 	// Register the secrets routes
 	secrets.RegisterRoutes(r.Group(":event_id/secrets"), params)
@@ -92,8 +101,9 @@ func createEventHandler(params *types.RouteParams) gin.HandlerFunc {
 				Name:          req.Name,
 				LastUpdatedAt: lastUpdatedAt,
 			},
-			OrganizerIDs: []primitive.ObjectID{authenticatedUser.ID},
-			CreatedByID:  authenticatedUser.ID,
+			OrganizerIDs:   []primitive.ObjectID{authenticatedUser.ID},
+			OrganizerRoles: map[string]models.EventRole{authenticatedUser.ID.Hex(): models.EventRoleOwner},
+			CreatedByID:    authenticatedUser.ID,
 		}
 
 		// Check billing
@@ -132,6 +142,9 @@ func createEventHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
+		eventID := rec.InsertedID.(primitive.ObjectID)
+		middlewares.SetAuditTarget(c, eventID, "event", eventID)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Event created successfully", "id": rec.InsertedID})
 	}
 }
@@ -186,6 +199,8 @@ func updateEventHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
+		middlewares.SetAuditChanges(c, event.Metadata, req.Metadata)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Event updated successfully", "lastUpdatedAt": newLastUpdatedAt})
 	}
 }
@@ -269,16 +284,6 @@ func getEventFormsHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authenticatedUser, eventID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not allowed to modify this event"})
-			return
-		}
-
 		forms, err := params.MongoService.ListForms(c, bson.M{"eventID": eventID})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving event forms"})
@@ -299,16 +304,6 @@ func getEventPipelinesHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authenticatedUser, eventID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not allowed to modify this event"})
-			return
-		}
-
 		pipelines, err := params.MongoService.ListPipelines(c, bson.M{"eventID": eventID})
 		if err != nil {
 			log.Printf("Error retrieving event pipelines: %v", err)
@@ -329,16 +324,6 @@ func getEventEmailTemplatesHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authenticatedUser, eventID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not allowed to modify this event"})
-			return
-		}
-
 		emailTemplates, err := params.MongoService.ListEmailTemplates(c, bson.M{"eventID": eventID})
 		if err != nil {
 			log.Printf("Error retrieving event email templates: %v", err)
@@ -350,74 +335,179 @@ func getEventEmailTemplatesHandler(params *types.RouteParams) gin.HandlerFunc {
 	}
 }
 
-// Add organizer to event
+type organizerRoleRequest struct {
+	Role models.EventRole `json:"role"`
+}
+
+// getOrganizerEvent loads the event from the path along with the role of the user managing its organizers, it writes the error response on failure
+func getOrganizerEvent(c *gin.Context, params *types.RouteParams) (*models.Event, models.EventRole, bool) {
+	authenticatedUser, ok := utils.GetUserFromContext(c, true)
+	if !ok {
+		return nil, "", false
+	}
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return nil, "", false
+	}
+
+	event, err := params.MongoService.FindEvent(c, eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get event"})
+		return nil, "", false
+	}
+
+	role, _ := event.OrganizerRole(authenticatedUser.ID)
+	return event, role, true
+}
+
+// canChangeOrganizerRole checks an organizer with the role can change another organizer's role from one to the other,
+// an empty role is not being an organizer. Only owners can make someone an owner or change the role of an owner
+func canChangeOrganizerRole(role models.EventRole, from models.EventRole, to models.EventRole) bool {
+	if from == models.EventRoleOwner || to == models.EventRoleOwner {
+		return role == models.EventRoleOwner
+	}
+
+	return role.Can(models.PermissionManageOrganizers)
+}
+
+/*
+Add organizer to event, or change the role of an existing organizer.
+If nobody has an account with the email they are invited instead, see inviteOrganizer
+
+params:
+  - user_email: email of the user to add
+
+body (optional):
+  - role: the organizer's role, defaults to admin which is what organizers could do before roles existed
+*/
 func addOrganizerHandler(params *types.RouteParams) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		eventID := c.Param("event_id")
 		userEmail := c.Param("user_email")
 
-		// Convert eventID to ObjectID
-		objID, err := primitive.ObjectIDFromHex(eventID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		var req organizerRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
+		if req.Role == "" {
+			req.Role = models.EventRoleAdmin
+		}
+
+		if !req.Role.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
 			return
 		}
 
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authenticatedUser, objID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not allowed to modify this event"})
+		event, role, ok := getOrganizerEvent(c, params)
+		if !ok {
 			return
 		}
 
 		// Get user by email
 		user, err := params.MongoService.FindUserByEmail(c, userEmail)
 		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				// They don't have an account yet, they are emailed an invite to accept once they do
+				if !canChangeOrganizerRole(role, "", req.Role) {
+					c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can make someone an owner or change the role of an owner"})
+					return
+				}
+
+				inviteOrganizer(c, params, event, userEmail, req.Role)
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find a user with that email"})
 			return
 		}
 
-		if user == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User not found, please ensure that user has an account"})
+		if user.ID == event.CreatedByID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The creator of the event is always its owner"})
 			return
 		}
 
-		_, err = params.MongoService.AddOrganizerToEvent(c, objID, user.ID)
+		currentRole, _ := event.OrganizerRole(user.ID)
+		if !canChangeOrganizerRole(role, currentRole, req.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can make someone an owner or change the role of an owner"})
+			return
+		}
+
+		_, err = params.MongoService.AddOrganizerToEvent(c, event.ID, user.ID, req.Role)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add organizer to event"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"userID": user.ID, "message": "Organizer added to event successfully"})
+		c.JSON(http.StatusOK, gin.H{"userID": user.ID, "role": req.Role, "message": "Organizer added to event successfully"})
 	}
 }
 
-// Remove organizer from event
-func removeOrganizerHandler(params *types.RouteParams) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		eventID := c.Param("event_id")
-		userID := c.Param("user_id")
+/*
+Change the role of an organizer of the event
 
-		// Convert eventID to ObjectID
-		objID, err := primitive.ObjectIDFromHex(eventID)
+params:
+  - user_id: ID of the organizer
+
+body:
+  - role: the organizer's new role
+*/
+func setOrganizerRoleHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userObjID, err := primitive.ObjectIDFromHex(c.Param("user_id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		var req organizerRoleRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !req.Role.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+			return
+		}
+
+		event, role, ok := getOrganizerEvent(c, params)
 		if !ok {
 			return
 		}
 
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authenticatedUser, objID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not allowed to modify this event"})
+		currentRole, isOrganizer := event.OrganizerRole(userObjID)
+		if !isOrganizer {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User is not an organizer of this event"})
+			return
+		}
+
+		if userObjID == event.CreatedByID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The creator of the event is always its owner"})
+			return
+		}
+
+		if !canChangeOrganizerRole(role, currentRole, req.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can make someone an owner or change the role of an owner"})
+			return
+		}
+
+		_, err = params.MongoService.SetOrganizerRole(c, event.ID, userObjID, req.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organizer role"})
 			return
 		}
 
+		c.JSON(http.StatusOK, gin.H{"role": req.Role, "message": "Organizer role updated successfully"})
+	}
+}
+
+// Remove organizer from event
+func removeOrganizerHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("user_id")
+
 		// Convert userID to ObjectID
 		userObjID, err := primitive.ObjectIDFromHex(userID)
 		if err != nil {
@@ -426,9 +516,8 @@ func removeOrganizerHandler(params *types.RouteParams) gin.HandlerFunc {
 		}
 
 		// Get the event to make sure there are still > 1 organizers
-		event, err := params.MongoService.GetEvent(c, objID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get event"})
+		event, role, ok := getOrganizerEvent(c, params)
+		if !ok {
 			return
 		}
 
@@ -442,7 +531,13 @@ func removeOrganizerHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		_, err = params.MongoService.RemoveOrganizerFromEvent(c, objID, userObjID)
+		currentRole, _ := event.OrganizerRole(userObjID)
+		if !canChangeOrganizerRole(role, currentRole, "") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can remove an owner"})
+			return
+		}
+
+		_, err = params.MongoService.RemoveOrganizerFromEvent(c, event.ID, userObjID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove organizer from event"})
 			return
@@ -451,3 +546,36 @@ func removeOrganizerHandler(params *types.RouteParams) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"message": "Organizer removed from event successfully"})
 	}
 }
+
+type twoFactorRequirementRequest struct {
+	Required bool `json:"required"`
+}
+
+// Require organizers of the event to have signed in with a second factor, whoever turns it on has to have signed in with one so they aren't locked out
+func setTwoFactorRequirementHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req twoFactorRequirementRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			return
+		}
+
+		if req.Required && !utils.IsTwoFactorVerified(c) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Set up two-factor authentication on your account and sign in with it first"})
+			return
+		}
+
+		if _, err := params.MongoService.SetEventRequireTwoFactor(c, eventID, req.Required); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"requireTwoFactor": req.Required})
+	}
+}
@@ -0,0 +1,19 @@
+package events
+
+import (
+	"shared/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanChangeOrganizerRole(t *testing.T) {
+	assert.True(t, canChangeOrganizerRole(models.EventRoleAdmin, "", models.EventRoleReviewer))
+	assert.True(t, canChangeOrganizerRole(models.EventRoleAdmin, models.EventRoleViewer, models.EventRoleAdmin))
+	assert.True(t, canChangeOrganizerRole(models.EventRoleAdmin, models.EventRoleAdmin, ""), "admins can remove admins")
+	assert.False(t, canChangeOrganizerRole(models.EventRoleAdmin, "", models.EventRoleOwner))
+	assert.False(t, canChangeOrganizerRole(models.EventRoleAdmin, models.EventRoleOwner, models.EventRoleAdmin))
+	assert.False(t, canChangeOrganizerRole(models.EventRoleAdmin, models.EventRoleOwner, ""))
+	assert.True(t, canChangeOrganizerRole(models.EventRoleOwner, models.EventRoleOwner, models.EventRoleViewer))
+	assert.False(t, canChangeOrganizerRole(models.EventRoleViewer, "", models.EventRoleViewer))
+}
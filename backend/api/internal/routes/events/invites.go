@@ -0,0 +1,310 @@
+package events
+
+import (
+	"api/internal/helpers"
+	"api/internal/middlewares"
+	"api/internal/types"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"shared/config"
+	"shared/logger"
+	"shared/mailer"
+	"shared/models"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+Organizer invites let someone without an account be added to an event, they are emailed a link to accept the invite once they have registered.
+
+The link carries a token signed for the invite and its expiry (see helpers.NewSignedToken), the invite itself is only stored
+so it can be listed, resent and revoked. Resending gives the invite a new expiry, which stops the links sent before from working.
+*/
+
+const organizerInvitePurpose = "organizer-invite"
+
+// inviteOrganizer invites the email to organize the event with the role and emails them the link to accept it
+func inviteOrganizer(c *gin.Context, params *types.RouteParams, event *models.Event, email string, role models.EventRole) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return
+	}
+
+	authenticatedUser, ok := utils.GetUserFromContext(c, true)
+	if !ok {
+		return
+	}
+
+	apiConfig, err := config.GetAPIConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to get API config", err)
+		return
+	}
+
+	invite, err := params.MongoService.SaveOrganizerInvite(c, models.OrganizerInvite{
+		EventID:     event.ID,
+		Email:       strings.ToLower(address.Address),
+		Role:        role,
+		InvitedByID: authenticatedUser.ID,
+		ExpiresAt:   inviteExpiry(apiConfig),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite"})
+		logger.Error("Failed to save organizer invite", err)
+		return
+	}
+
+	if err := sendOrganizerInvite(c, params, apiConfig, event, invite); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send the invite email"})
+		logger.Error("Failed to send organizer invite email", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"invite": invite, "message": "Nobody has an account with that email yet, they have been emailed an invite"})
+}
+
+// inviteExpiry is when an invite sent now expires, to the second as that is what its token carries
+func inviteExpiry(apiConfig *config.APIConfig) time.Time {
+	ttl := time.Duration(apiConfig.ORGANIZER_INVITE_TTL_HOURS) * time.Hour
+	return time.Now().Add(ttl).Truncate(time.Second)
+}
+
+// sendOrganizerInvite emails the invitee the link to accept the invite
+func sendOrganizerInvite(c *gin.Context, params *types.RouteParams, apiConfig *config.APIConfig, event *models.Event, invite *models.OrganizerInvite) error {
+	token := helpers.NewSignedToken(utils.DeriveSecret(organizerInvitePurpose), invite.ID.Hex(), invite.ExpiresAt)
+	link := fmt.Sprintf("%s/events/%s/invites/accept?token=%s", apiConfig.WEBSITE_PUBLIC_URL, event.ID.Hex(), token)
+
+	return params.Mailer.Send(c, mailer.Message{
+		To:      []string{invite.Email},
+		Subject: fmt.Sprintf("You've been invited to organize %s", event.Metadata.Name),
+		Body: fmt.Sprintf("You've been invited to help organize %s as %s.\n\nTo accept, sign in or create an account with this email address and open this link within %d hours:\n%s\n\nIf you weren't expecting this invite you can ignore this email.",
+			event.Metadata.Name, invite.Role, apiConfig.ORGANIZER_INVITE_TTL_HOURS, link),
+	})
+}
+
+// getInvite loads the invite in the path along with its event and the role of the user managing it, it writes the error response on failure
+func getInvite(c *gin.Context, params *types.RouteParams) (*models.OrganizerInvite, *models.Event, models.EventRole, bool) {
+	inviteID, err := primitive.ObjectIDFromHex(c.Param("invite_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite ID"})
+		return nil, nil, "", false
+	}
+
+	event, role, ok := getOrganizerEvent(c, params)
+	if !ok {
+		return nil, nil, "", false
+	}
+
+	invite, err := params.MongoService.GetOrganizerInvite(c, event.ID, inviteID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+			return nil, nil, "", false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invite"})
+		logger.Error("Failed to get organizer invite", err)
+		return nil, nil, "", false
+	}
+
+	return invite, event, role, true
+}
+
+// List the invites of the event that haven't been accepted yet, expired ones are kept for a while so they can be resent
+func listInvitesHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			return
+		}
+
+		invites, err := params.MongoService.ListOrganizerInvites(c, eventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list invites"})
+			logger.Error("Failed to list organizer invites", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"invites": invites})
+	}
+}
+
+// Send an invite again with a new expiry, the links sent before stop working
+func resendInviteHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invite, event, role, ok := getInvite(c, params)
+		if !ok {
+			return
+		}
+
+		if !canChangeOrganizerRole(role, "", invite.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can manage invites to be an owner"})
+			return
+		}
+
+		apiConfig, err := config.GetAPIConfig()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		invite, err = params.MongoService.RenewOrganizerInvite(c, event.ID, invite.ID, inviteExpiry(apiConfig))
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to renew invite"})
+			logger.Error("Failed to renew organizer invite", err)
+			return
+		}
+
+		if err := sendOrganizerInvite(c, params, apiConfig, event, invite); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send the invite email"})
+			logger.Error("Failed to send organizer invite email", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"invite": invite, "message": "Invite resent successfully"})
+	}
+}
+
+// Revoke an invite, its link stops working
+func revokeInviteHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invite, event, role, ok := getInvite(c, params)
+		if !ok {
+			return
+		}
+
+		if !canChangeOrganizerRole(role, "", invite.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can manage invites to be an owner"})
+			return
+		}
+
+		if _, err := params.MongoService.DeleteOrganizerInvite(c, event.ID, invite.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invite"})
+			logger.Error("Failed to delete organizer invite", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Invite revoked successfully"})
+	}
+}
+
+type acceptInviteRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+/*
+Accept an invite to organize the event, the signed in user must have verified the email the invite was sent to
+
+body:
+  - token: the token from the invite link
+*/
+func acceptInviteHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req acceptInviteRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			return
+		}
+
+		subject, expiresAt, err := helpers.VerifySignedToken(utils.DeriveSecret(organizerInvitePurpose), req.Token, time.Now())
+		if err != nil {
+			if err == helpers.ErrExpiredSignedToken {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This invite has expired, ask an organizer of the event to send it again"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite"})
+			return
+		}
+
+		inviteID, err := primitive.ObjectIDFromHex(subject)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite"})
+			return
+		}
+
+		invite, err := params.MongoService.GetOrganizerInvite(c, eventID, inviteID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "This invite has been revoked or already accepted"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invite"})
+			logger.Error("Failed to get organizer invite", err)
+			return
+		}
+
+		user, err := params.MongoService.GetUserDetails(c, authenticatedUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+			logger.Error("Failed to get user details", err)
+			return
+		}
+
+		if !strings.EqualFold(invite.Email, user.Email) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("This invite was sent to %s, sign in with that email to accept it", invite.Email)})
+			return
+		}
+
+		// Anyone can register with any email, so it only counts once the user has shown they own it
+		if user.EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address before accepting this invite"})
+			return
+		}
+
+		event, err := params.MongoService.FindEvent(c, invite.EventID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+
+		if event.CreatedByID == authenticatedUser.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The creator of the event is always its owner"})
+			return
+		}
+
+		invite, err = params.MongoService.AcceptOrganizerInvite(c, invite.ID, expiresAt, authenticatedUser.ID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				// The invite was resent or accepted since the token was checked, or the event has been deleted
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This invite link is no longer valid"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invite"})
+			logger.Error("Failed to accept organizer invite", err)
+			return
+		}
+
+		middlewares.SetAuditTarget(c, invite.EventID, "invite", invite.ID)
+
+		c.JSON(http.StatusOK, gin.H{"eventID": invite.EventID, "role": invite.Role, "message": "Invite accepted, you are now an organizer of this event"})
+	}
+}
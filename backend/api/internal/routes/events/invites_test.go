@@ -0,0 +1,87 @@
+package events
+
+import (
+	"api/internal/helpers"
+	"api/internal/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// inviteMongo holds one invite and the user accepting it, accepting is recorded
+type inviteMongo struct {
+	mongodb.MongoService
+	invite   models.OrganizerInvite
+	user     models.User
+	accepted bool
+}
+
+func (m *inviteMongo) GetOrganizerInvite(ctx context.Context, eventID primitive.ObjectID, inviteID primitive.ObjectID) (*models.OrganizerInvite, error) {
+	if inviteID != m.invite.ID || eventID != m.invite.EventID {
+		return nil, mongo.ErrNoDocuments
+	}
+	invite := m.invite
+	return &invite, nil
+}
+
+func (m *inviteMongo) GetUserDetails(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	user := m.user
+	return &user, nil
+}
+
+func (m *inviteMongo) FindEvent(ctx context.Context, eventID primitive.ObjectID) (*models.Event, error) {
+	return &models.Event{ID: eventID, CreatedByID: primitive.NewObjectID()}, nil
+}
+
+func (m *inviteMongo) AcceptOrganizerInvite(ctx context.Context, inviteID primitive.ObjectID, expiresAt time.Time, userID primitive.ObjectID) (*models.OrganizerInvite, error) {
+	m.accepted = true
+	invite := m.invite
+	return &invite, nil
+}
+
+func TestAcceptInvite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	m := &inviteMongo{
+		invite: models.OrganizerInvite{ID: primitive.NewObjectID(), EventID: primitive.NewObjectID(), Email: "organizer@example.com", Role: models.EventRoleViewer},
+		user:   models.User{ID: primitive.NewObjectID(), Email: "Organizer@example.com"},
+	}
+	token := helpers.NewSignedToken(utils.DeriveSecret(organizerInvitePurpose), m.invite.ID.Hex(), expiresAt)
+
+	r := gin.New()
+	r.POST("/events/:event_id/invites/accept", func(c *gin.Context) {
+		// Stands in for JWTAuthMiddleware, the token only carries the user's ID and email
+		c.Set("user", &models.User{ID: m.user.ID, Email: m.user.Email})
+	}, acceptInviteHandler(&types.RouteParams{MongoService: m}))
+
+	accept := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/events/"+m.invite.EventID.Hex()+"/invites/accept", strings.NewReader(`{"token": "`+token+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := accept()
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "verify your email")
+	assert.False(t, m.accepted, "someone who registered with the invitee's email can't take the invite")
+
+	verifiedAt := time.Now()
+	m.user.EmailVerifiedAt = &verifiedAt
+	resp = accept()
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.True(t, m.accepted)
+}
@@ -5,8 +5,6 @@ import (
 	"api/internal/types"
 	"net/http"
 	"shared/models"
-	"shared/mongodb"
-	"shared/utils"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -23,36 +21,20 @@ Secret API Operations:
 */
 
 func RegisterRoutes(r *gin.RouterGroup, params *types.RouteParams) {
-	r.GET("", middlewares.JWTAuthMiddleware(), listSecrets(params))
-	r.POST("", middlewares.JWTAuthMiddleware(), createSecret(params))
-	r.PUT("", middlewares.JWTAuthMiddleware(), updateSecret(params))
-	r.DELETE("", middlewares.JWTAuthMiddleware(), deleteSecret(params))
+	r.GET("", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageSecrets), listSecrets(params))
+	r.POST("", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageSecrets), createSecret(params))
+	r.PUT("", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageSecrets), updateSecret(params))
+	r.DELETE("", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageSecrets), deleteSecret(params))
 }
 
 func listSecrets(params *types.RouteParams) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
-		// Ensure user id exists
-		if authenticatedUser.ID == primitive.NilObjectID {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found"})
-			return
-		}
-
 		eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
 			return
 		}
 
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authenticatedUser, eventID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not an organizer of this event"})
-			return
-		}
-
 		// List all secrets for the event
 		secrets, err := params.MongoService.GetEventSecrets(c, bson.M{"eventID": eventID}, true)
 		if err != nil {
@@ -70,22 +52,12 @@ func listSecrets(params *types.RouteParams) gin.HandlerFunc {
 
 func createSecret(params *types.RouteParams) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
 		eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
 			return
 		}
 
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authUser, eventID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not an organizer of this event"})
-			return
-		}
-
 		// Parse Request Body
 		var newSecret models.EventSecrets
 		if err := c.BindJSON(&newSecret); err != nil {
@@ -93,6 +65,8 @@ func createSecret(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
+		// The secrets are always saved on the event in the path
+		newSecret.EventID = eventID
 		_, err = params.MongoService.CreateOrUpdateEventSecrets(c, newSecret)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create secret"})
@@ -105,12 +79,6 @@ func createSecret(params *types.RouteParams) gin.HandlerFunc {
 
 func updateSecret(params *types.RouteParams) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Authenticate and validate user
-		authUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
 		eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
@@ -124,12 +92,8 @@ func updateSecret(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authUser, eventID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not an organizer of this event"})
-			return
-		}
-
-		// Update the secret in the database
+		// Update the secret in the database, on the event in the path
+		updatedSecret.EventID = eventID
 		_, err = params.MongoService.CreateOrUpdateEventSecrets(c, updatedSecret)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update secret"})
@@ -142,22 +106,12 @@ func updateSecret(params *types.RouteParams) gin.HandlerFunc {
 
 func deleteSecret(params *types.RouteParams) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
 		eventID, err := primitive.ObjectIDFromHex(c.Param("event_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
 			return
 		}
 
-		if !mongodb.CanUserModifyEvent(c, params.MongoService, authUser, eventID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not an organizer of this event"})
-			return
-		}
-
 		// Delete the secret from the database
 		_, err = params.MongoService.DeleteEventSecrets(c, eventID)
 		if err != nil {
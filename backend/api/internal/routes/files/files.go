@@ -0,0 +1,58 @@
+package files
+
+import (
+	"api/internal/types"
+	"fmt"
+	"io"
+	"net/http"
+	"shared/logger"
+	"shared/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.RouterGroup, params *types.RouteParams) {
+	r.GET("local", downloadLocalFileHandler(params))
+}
+
+/*
+Download a file from the local object storage backend
+
+This is how signed URLs are served when STORAGE_TYPE is local, other backends sign URLs pointing at themselves.
+The signature in the query is the authorization, so this route is not behind the JWT middleware.
+*/
+func downloadLocalFileHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		localStorage, ok := params.ObjectStorage.(*storage.LocalStorage)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API route not found"})
+			return
+		}
+
+		key, fileName, err := localStorage.VerifySignedURL(c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid download link: " + err.Error()})
+			return
+		}
+
+		file, err := localStorage.Get(c, key)
+		if err != nil {
+			if err == storage.ErrObjectNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "File does not exist"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to read file from local storage", err)
+			return
+		}
+		defer file.Close()
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		c.Header("Content-Type", "application/octet-stream")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Status(http.StatusOK)
+		if _, err := io.Copy(c.Writer, file); err != nil {
+			logger.Error("Failed to stream file from local storage", err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package admissions
+
+import (
+	"api/internal/middlewares"
+	"api/internal/types"
+	"net/http"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/triggers"
+	"shared/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+Admissions manage the seats of forms with a capacity.
+
+Each response to such a form is offered a seat when it is submitted, or put on the waitlist once the form is full.
+Respondents RSVP to their offer, declining it or letting the RSVP deadline pass frees the seat for the next person on the waitlist.
+Promoted respondents trigger the form's WaitlistPromotion pipelines, eg: to send them an acceptance email.
+Lapsed offers are swept whenever the form's admissions are used, organizers can also sweep them on demand.
+*/
+
+func RegisterRoutes(r *gin.RouterGroup, params *types.RouteParams) {
+	r.GET("", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewAdmissions), listAdmissionsHandler(params))
+	r.POST("sweep", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageAdmissions), sweepAdmissionsHandler(params))
+	r.GET("responses/:response_id", middlewares.JWTAuthMiddleware(params.MongoService), getAdmissionHandler(params))
+	r.POST("responses/:response_id/rsvp", middlewares.JWTAuthMiddleware(params.MongoService), rsvpHandler(params))
+	r.POST("responses/:response_id/check-in", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionCheckIn), checkInHandler(params))
+	r.DELETE("responses/:response_id/check-in", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionCheckIn), undoCheckInHandler(params))
+}
+
+// getOrganizerForm loads the form from the path for routes whose permission was already checked, it writes the error response on failure
+func getOrganizerForm(c *gin.Context, params *types.RouteParams) (*models.FormStructure, bool) {
+	formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+		return nil, false
+	}
+
+	form, err := params.MongoService.GetForm(c, formID, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+		return nil, false
+	}
+
+	return form, true
+}
+
+// getOwnAdmission loads the user's own response from the path, sweeping lapsed offers first so its admission is current.
+// It writes the error response on failure
+func getOwnAdmission(c *gin.Context, params *types.RouteParams) (*models.FormStructure, *models.FormResponse, bool) {
+	authenticatedUser, ok := utils.GetUserFromContext(c, true)
+	if !ok {
+		return nil, nil, false
+	}
+
+	formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+		return nil, nil, false
+	}
+
+	responseID, err := primitive.ObjectIDFromHex(c.Param("response_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid response ID"})
+		return nil, nil, false
+	}
+
+	form, err := params.MongoService.GetForm(c, formID, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+		return nil, nil, false
+	}
+
+	if err := triggers.SweepAdmissions(c, params.MessageProducer, params.MongoService, form); err != nil {
+		logger.Error("Failed to sweep form admissions", err)
+	}
+
+	responses, err := params.MongoService.ListResponses(c, bson.M{"_id": responseID, "formID": formID}, nil)
+	if err != nil || len(responses) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Response does not exist"})
+		return nil, nil, false
+	}
+
+	response := responses[0]
+	if response.UserID != authenticatedUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this response"})
+		return nil, nil, false
+	}
+
+	if response.Admission == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This response was not submitted to a form with a capacity"})
+		return nil, nil, false
+	}
+
+	return form, &response, true
+}
+
+// List the admissions of a form, grouped into seats and the ordered waitlist
+func listAdmissionsHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		if err := triggers.SweepAdmissions(c, params.MessageProducer, params.MongoService, form); err != nil {
+			logger.Error("Failed to sweep form admissions", err)
+		}
+
+		opts := options.Find().SetProjection(bson.M{"data": 0})
+		responses, err := params.MongoService.ListResponses(c, bson.M{"formID": form.ID, "admission": bson.M{"$exists": true}}, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list form responses", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"capacity": form.Capacity, "admissions": summarizeAdmissions(responses)})
+	}
+}
+
+// Expire the lapsed offers of a form and promote the waitlist into the freed seats, meant to be called on a schedule
+func sweepAdmissionsHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		promoted, err := params.MongoService.ExpireAdmissionOffers(c, form.ID, form.Capacity)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to expire admission offers", err)
+			return
+		}
+
+		if err := triggers.WaitlistPromotions(c, params.MessageProducer, params.MongoService, form, promoted); err != nil {
+			logger.Error("Failed to trigger waitlist promotion pipelines", err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"promoted": len(promoted)})
+	}
+}
+
+// Get the admission of the user's own response, including their place on the waitlist
+func getAdmissionHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, response, ok := getOwnAdmission(c, params)
+		if !ok {
+			return
+		}
+
+		position, err := params.MongoService.GetWaitlistPosition(c, *response)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get waitlist position", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"admission": response.Admission, "waitlistPosition": position})
+	}
+}
+
+type rsvpRequest struct {
+	Accept *bool `json:"accept" validate:"required"`
+}
+
+/*
+RSVP to the offer of the user's own response
+
+Accepting confirms an offered seat, declining gives up the seat or place on the waitlist.
+A freed seat is offered to the next person on the waitlist.
+*/
+func rsvpHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req rsvpRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		form, response, ok := getOwnAdmission(c, params)
+		if !ok {
+			return
+		}
+
+		if *req.Accept {
+			if _, err := params.MongoService.AcceptAdmission(c, response.ID); err != nil {
+				if err == mongodb.ErrAdmissionStatusChanged {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "You do not have an open offer to accept"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				logger.Error("Failed to accept admission", err)
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{"message": "Your seat is confirmed"})
+			return
+		}
+
+		promoted, err := params.MongoService.DeclineAdmission(c, response.ID, form.Capacity)
+		if err != nil {
+			if err == mongodb.ErrAdmissionStatusChanged {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "You do not hold a seat or a place on the waitlist"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to decline admission", err)
+			return
+		}
+
+		if err := triggers.WaitlistPromotions(c, params.MessageProducer, params.MongoService, form, promoted); err != nil {
+			logger.Error("Failed to trigger waitlist promotion pipelines", err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "You have given up your place"})
+	}
+}
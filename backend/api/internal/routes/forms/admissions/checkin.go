@@ -0,0 +1,114 @@
+package admissions
+
+import (
+	"api/internal/types"
+	"net/http"
+	"shared/logger"
+	"shared/models"
+	"shared/triggers"
+	"shared/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+Check-in staff mark respondents as checked in when they arrive at the event, without being able to change their answers.
+On forms with a capacity only respondents holding a seat can be checked in, each respondent can only be checked in once.
+*/
+
+// getCheckInResponse loads the response from the path, it has to belong to the form the permission was checked on.
+// Lapsed offers are swept first so its admission is current. It writes the error response on failure
+func getCheckInResponse(c *gin.Context, params *types.RouteParams) (*models.FormStructure, *models.FormResponse, bool) {
+	form, ok := getOrganizerForm(c, params)
+	if !ok {
+		return nil, nil, false
+	}
+
+	if form.Capacity.Limit > 0 {
+		if err := triggers.SweepAdmissions(c, params.MessageProducer, params.MongoService, form); err != nil {
+			logger.Error("Failed to sweep form admissions", err)
+		}
+	}
+
+	responseID, err := primitive.ObjectIDFromHex(c.Param("response_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid response ID"})
+		return nil, nil, false
+	}
+
+	responses, err := params.MongoService.ListResponses(c, bson.M{"_id": responseID, "formID": form.ID}, nil)
+	if err != nil || len(responses) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Response does not exist"})
+		return nil, nil, false
+	}
+
+	return form, &responses[0], true
+}
+
+// Check in the respondent of a response
+func checkInHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		form, response, ok := getCheckInResponse(c, params)
+		if !ok {
+			return
+		}
+
+		if response.CheckIn != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "The respondent is already checked in", "checkIn": response.CheckIn})
+			return
+		}
+
+		// Responses submitted before the form had a capacity have no admission and can always be checked in
+		if response.Admission != nil && !response.Admission.Status.HoldsSeat() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The respondent does not hold a seat", "status": response.Admission.Status})
+			return
+		}
+
+		checkIn := models.CheckIn{At: time.Now(), ByID: authenticatedUser.ID}
+		result, err := params.MongoService.SetResponseCheckIn(c, form.ID, response.ID, &checkIn)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to check in response", err)
+			return
+		}
+
+		// Someone else checked them in since the response was loaded
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The respondent is already checked in"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"checkIn": checkIn})
+	}
+}
+
+// Undo the check-in of a response, eg: when the wrong respondent was checked in
+func undoCheckInHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, response, ok := getCheckInResponse(c, params)
+		if !ok {
+			return
+		}
+
+		if response.CheckIn == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The respondent is not checked in"})
+			return
+		}
+
+		if _, err := params.MongoService.SetResponseCheckIn(c, form.ID, response.ID, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to undo response check-in", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Check-in undone"})
+	}
+}
@@ -0,0 +1,115 @@
+package admissions
+
+import (
+	"api/internal/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared/models"
+	"shared/mongodb"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type checkInMongo struct {
+	mongodb.MongoService
+	form      models.FormStructure
+	responses map[primitive.ObjectID]*models.FormResponse
+}
+
+func (m *checkInMongo) GetForm(ctx context.Context, formID primitive.ObjectID, includeExtraFields bool) (*models.FormStructure, error) {
+	if formID != m.form.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &m.form, nil
+}
+
+func (m *checkInMongo) ExpireAdmissionOffers(ctx context.Context, formID primitive.ObjectID, capacity models.FormCapacity) ([]models.FormResponse, error) {
+	return nil, nil
+}
+
+func (m *checkInMongo) ListResponses(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.FormResponse, error) {
+	response, ok := m.responses[filter["_id"].(primitive.ObjectID)]
+	if !ok || response.FormID != filter["formID"] {
+		return nil, nil
+	}
+	return []models.FormResponse{*response}, nil
+}
+
+func (m *checkInMongo) SetResponseCheckIn(ctx context.Context, formID primitive.ObjectID, responseID primitive.ObjectID, checkIn *models.CheckIn) (*mongo.UpdateResult, error) {
+	response, ok := m.responses[responseID]
+	if !ok || response.FormID != formID || (checkIn != nil && response.CheckIn != nil) {
+		return &mongo.UpdateResult{}, nil
+	}
+	response.CheckIn = checkIn
+	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
+}
+
+func TestCheckIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	form := models.FormStructure{ID: primitive.NewObjectID(), Capacity: models.FormCapacity{Limit: 1}}
+	seated := &models.FormResponse{ID: primitive.NewObjectID(), FormID: form.ID, Admission: &models.Admission{Status: models.AdmissionStatusAccepted}}
+	waitlisted := &models.FormResponse{ID: primitive.NewObjectID(), FormID: form.ID, Admission: &models.Admission{Status: models.AdmissionStatusWaitlisted}}
+	otherForm := &models.FormResponse{ID: primitive.NewObjectID(), FormID: primitive.NewObjectID()}
+	m := &checkInMongo{
+		form:      form,
+		responses: map[primitive.ObjectID]*models.FormResponse{seated.ID: seated, waitlisted.ID: waitlisted, otherForm.ID: otherForm},
+	}
+
+	staff := &models.User{ID: primitive.NewObjectID()}
+	params := &types.RouteParams{MongoService: m}
+
+	// Stands in for JWTAuthMiddleware and RequirePermission
+	authenticated := func(c *gin.Context) { c.Set("user", staff) }
+
+	r := gin.New()
+	r.POST("/forms/:form_id/admissions/responses/:response_id/check-in", authenticated, checkInHandler(params))
+	r.DELETE("/forms/:form_id/admissions/responses/:response_id/check-in", authenticated, undoCheckInHandler(params))
+
+	request := func(method string, response *models.FormResponse) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/forms/"+form.ID.Hex()+"/admissions/responses/"+response.ID.Hex()+"/check-in", nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("a seated respondent is checked in once", func(t *testing.T) {
+		resp := request(http.MethodPost, seated)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		var body struct {
+			CheckIn models.CheckIn `json:"checkIn"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, staff.ID, body.CheckIn.ByID)
+		require.NotNil(t, seated.CheckIn)
+
+		assert.Equal(t, http.StatusConflict, request(http.MethodPost, seated).Code)
+	})
+
+	t.Run("a check-in can be undone", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, request(http.MethodDelete, seated).Code)
+		assert.Nil(t, seated.CheckIn)
+		assert.Equal(t, http.StatusBadRequest, request(http.MethodDelete, seated).Code)
+	})
+
+	t.Run("respondents without a seat can't be checked in", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, waitlisted).Code)
+		assert.Nil(t, waitlisted.CheckIn)
+	})
+
+	t.Run("responses of other forms can't be checked in", func(t *testing.T) {
+		resp := request(http.MethodPost, otherForm)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Response does not exist")
+		assert.Nil(t, otherForm.CheckIn)
+	})
+}
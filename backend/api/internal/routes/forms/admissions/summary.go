@@ -0,0 +1,68 @@
+package admissions
+
+import (
+	"shared/models"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type admissionEntry struct {
+	ResponseID       primitive.ObjectID `json:"responseID"`
+	UserID           primitive.ObjectID `json:"userID"`
+	Admission        models.Admission   `json:"admission"`
+	WaitlistPosition int                `json:"waitlistPosition,omitempty"`
+}
+
+type admissionSummary struct {
+	Counts   map[models.AdmissionStatus]int `json:"counts"`
+	Seated   []admissionEntry               `json:"seated"`   // offered and accepted, in the order they were offered
+	Waitlist []admissionEntry               `json:"waitlist"` // in the order seats will be offered
+	Released []admissionEntry               `json:"released"` // declined and expired
+}
+
+// summarizeAdmissions groups the admissions of a form's responses, responses without an admission are skipped
+func summarizeAdmissions(responses []models.FormResponse) admissionSummary {
+	summary := admissionSummary{
+		Counts:   make(map[models.AdmissionStatus]int),
+		Seated:   []admissionEntry{},
+		Waitlist: []admissionEntry{},
+		Released: []admissionEntry{},
+	}
+
+	for _, response := range responses {
+		if response.Admission == nil {
+			continue
+		}
+
+		entry := admissionEntry{ResponseID: response.ID, UserID: response.UserID, Admission: *response.Admission}
+		summary.Counts[entry.Admission.Status]++
+		switch {
+		case entry.Admission.Status.HoldsSeat():
+			summary.Seated = append(summary.Seated, entry)
+		case entry.Admission.Status == models.AdmissionStatusWaitlisted:
+			summary.Waitlist = append(summary.Waitlist, entry)
+		default:
+			summary.Released = append(summary.Released, entry)
+		}
+	}
+
+	sort.SliceStable(summary.Seated, func(i, j int) bool {
+		return summary.Seated[i].Admission.OfferedAt.Before(summary.Seated[j].Admission.OfferedAt)
+	})
+
+	// Ties are broken by ID the same way the waitlist is promoted
+	sort.SliceStable(summary.Waitlist, func(i, j int) bool {
+		a, b := summary.Waitlist[i], summary.Waitlist[j]
+		if !a.Admission.WaitlistedAt.Equal(b.Admission.WaitlistedAt) {
+			return a.Admission.WaitlistedAt.Before(b.Admission.WaitlistedAt)
+		}
+		return a.ResponseID.Hex() < b.ResponseID.Hex()
+	})
+
+	for i := range summary.Waitlist {
+		summary.Waitlist[i].WaitlistPosition = i + 1
+	}
+
+	return summary
+}
@@ -0,0 +1,64 @@
+package admissions
+
+import (
+	"shared/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSummarizeAdmissions(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ids := make([]primitive.ObjectID, 6)
+	for i := range ids {
+		ids[i] = primitive.NewObjectIDFromTimestamp(start.Add(time.Duration(i) * time.Second))
+	}
+
+	responses := []models.FormResponse{
+		{ID: ids[0], Admission: &models.Admission{Status: models.AdmissionStatusAccepted, OfferedAt: start.Add(time.Minute)}},
+		{ID: ids[1], Admission: &models.Admission{Status: models.AdmissionStatusWaitlisted, WaitlistedAt: start.Add(2 * time.Minute)}},
+		{ID: ids[2], Admission: &models.Admission{Status: models.AdmissionStatusOffered, OfferedAt: start}},
+		{ID: ids[3], Admission: &models.Admission{Status: models.AdmissionStatusWaitlisted, WaitlistedAt: start.Add(time.Minute)}},
+		{ID: ids[4], Admission: &models.Admission{Status: models.AdmissionStatusWaitlisted, WaitlistedAt: start.Add(time.Minute)}},
+		{ID: ids[5], Admission: &models.Admission{Status: models.AdmissionStatusExpired}},
+		{ID: primitive.NewObjectID()}, // submitted before the form had a capacity
+	}
+
+	summary := summarizeAdmissions(responses)
+
+	assert.Equal(t, map[models.AdmissionStatus]int{
+		models.AdmissionStatusAccepted:   1,
+		models.AdmissionStatusOffered:    1,
+		models.AdmissionStatusWaitlisted: 3,
+		models.AdmissionStatusExpired:    1,
+	}, summary.Counts)
+
+	var seated []primitive.ObjectID
+	for _, entry := range summary.Seated {
+		seated = append(seated, entry.ResponseID)
+	}
+	assert.Equal(t, []primitive.ObjectID{ids[2], ids[0]}, seated)
+
+	var waitlist []primitive.ObjectID
+	var positions []int
+	for _, entry := range summary.Waitlist {
+		waitlist = append(waitlist, entry.ResponseID)
+		positions = append(positions, entry.WaitlistPosition)
+	}
+	assert.Equal(t, []primitive.ObjectID{ids[3], ids[4], ids[1]}, waitlist)
+	assert.Equal(t, []int{1, 2, 3}, positions)
+
+	assert.Len(t, summary.Released, 1)
+	assert.Equal(t, ids[5], summary.Released[0].ResponseID)
+}
+
+func TestSummarizeAdmissionsEmpty(t *testing.T) {
+	summary := summarizeAdmissions(nil)
+
+	assert.Empty(t, summary.Counts)
+	assert.NotNil(t, summary.Seated)
+	assert.NotNil(t, summary.Waitlist)
+	assert.NotNil(t, summary.Released)
+}
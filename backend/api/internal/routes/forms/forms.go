@@ -2,29 +2,45 @@ package forms
 
 import (
 	"api/internal/middlewares"
+	"api/internal/routes/forms/admissions"
 	"api/internal/routes/forms/responses"
+	"api/internal/routes/forms/reviews"
 	"api/internal/types"
+	"errors"
 	"log"
 	"net/http"
+	"shared/logger"
 	"shared/messages"
 	"shared/models"
 	"shared/mongodb"
+	"shared/storage"
 	"shared/utils"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func RegisterRoutes(r *gin.RouterGroup, params *types.RouteParams) {
-	r.GET(":form_id", middlewares.JWTAuthMiddleware(), getFormDataHandler(params))
-	r.POST("", middlewares.JWTAuthMiddleware(), createFormHandler(params))
-	r.PUT(":form_id", middlewares.JWTAuthMiddleware(), updateFormHandler(params))
-	r.DELETE(":form_id", middlewares.JWTAuthMiddleware(), deleteFormHandler(params))
+	r.GET(":form_id", middlewares.JWTAuthMiddleware(params.MongoService), getFormDataHandler(params))
+	r.POST("", middlewares.JWTAuthMiddleware(params.MongoService), createFormHandler(params))
+	r.PUT(":form_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditForms), updateFormHandler(params))
+	r.DELETE(":form_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditForms), deleteFormHandler(params))
+	r.GET(":form_id/versions", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewEvent), listFormVersionsHandler(params))
 
 	responsesGroup := r.Group(":form_id/responses")
 	responses.RegisterFormResponsesRoutes(responsesGroup, params)
+
+	filesGroup := r.Group(":form_id/files")
+	responses.RegisterFormFileRoutes(filesGroup, params)
+
+	reviewsGroup := r.Group(":form_id/reviews")
+	reviews.RegisterRoutes(reviewsGroup, params)
+
+	admissionsGroup := r.Group(":form_id/admissions")
+	admissions.RegisterRoutes(admissionsGroup, params)
 }
 
 func getFormDataHandler(params *types.RouteParams) gin.HandlerFunc {
@@ -52,7 +68,7 @@ func getFormDataHandler(params *types.RouteParams) gin.HandlerFunc {
 				return
 			}
 
-			if !mongodb.CanUserModifyForm(c, params.MongoService, authenticatedUser, formID, form) {
+			if !mongodb.HasFormPermission(c, params.MongoService, authenticatedUser, formID, form, models.PermissionViewEvent) {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to view this form"})
 				return
 			}
@@ -60,7 +76,7 @@ func getFormDataHandler(params *types.RouteParams) gin.HandlerFunc {
 
 		// Check if the authenticated user's emails are in the form's whitelist, if it exists
 		if form.IsRestricted {
-			allowed, restrictMessage := mongodb.IsUserEmailInWhitelist(c, form.AllowedSubmitters)
+			allowed, restrictMessage := mongodb.IsUserEmailInWhitelist(c, params.MongoService, form.AllowedSubmitters)
 			if !allowed {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": restrictMessage})
 				return
@@ -91,43 +107,31 @@ func createFormHandler(params *types.RouteParams) gin.HandlerFunc {
 		}
 
 		// Check if user is authorized to create form on this event
-		event, err := params.MongoService.GetEvent(c, req.EventID)
+		event, err := params.MongoService.FindEvent(c, req.EventID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 			return
 		}
 
-		var found = false
-		for _, organizerID := range event.OrganizerIDs {
-			if organizerID == authenticatedUser.ID {
-				found = true
-				break
-			}
-		}
-
-		if !found {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "You are not authorized to create a form for this event"})
+		if !mongodb.HasEventPermission(c, params.MongoService, authenticatedUser, event.ID, event, models.PermissionEditForms) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to create a form for this event"})
 			return
 		}
 
-		formID, err := params.MongoService.CreateForm(c, req)
+		formID, err := params.MongoService.CreateForm(c, req, authenticatedUser.ID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create form"})
 			return
 		}
 
+		middlewares.SetAuditTarget(c, event.ID, "form", formID.InsertedID.(primitive.ObjectID))
+
 		c.JSON(http.StatusOK, gin.H{"id": formID})
 	}
 }
 
 func deleteFormHandler(params *types.RouteParams) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
-		// Check if user is authorized to delete form on this event
 		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
@@ -139,17 +143,44 @@ func deleteFormHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		if !mongodb.CanUserModifyForm(c, params.MongoService, authenticatedUser, formID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to delete this form"})
-			return
-		}
-
 		_, err = params.MongoService.DeleteForm(c, formID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete form"})
 			return
 		}
 
+		if err := storage.DeleteFileUploads(c, params.ObjectStorage, params.MongoService, bson.M{"formID": formID}); err != nil {
+			logger.Error("Failed to delete form files", err)
+		}
+
+		if _, err := params.MongoService.DeleteReviews(c, bson.M{"formID": formID}); err != nil {
+			logger.Error("Failed to delete form reviews", err)
+		}
+
+		if _, err := params.MongoService.DeleteFormCapacity(c, formID); err != nil {
+			logger.Error("Failed to delete form capacity", err)
+		}
+
+		if _, err := params.MongoService.DeleteResponseClaims(c, bson.M{"formID": formID}); err != nil {
+			logger.Error("Failed to delete form response claims", err)
+		}
+
+		if _, err := params.MongoService.DeletePendingResponses(c, bson.M{"formID": formID}); err != nil {
+			logger.Error("Failed to delete unconfirmed anonymous responses", err)
+		}
+
+		if _, err := params.MongoService.DeleteBulkJobs(c, bson.M{"formID": formID}); err != nil {
+			logger.Error("Failed to delete form bulk jobs", err)
+		}
+
+		if _, err := params.MongoService.DeleteResponseHistory(c, bson.M{"formID": formID}); err != nil {
+			logger.Error("Failed to delete form response history", err)
+		}
+
+		if _, err := params.MongoService.DeleteFormVersions(c, bson.M{"formID": formID}); err != nil {
+			logger.Error("Failed to delete form versions", err)
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "Form deleted successfully"})
 	}
 }
@@ -173,7 +204,6 @@ func updateFormHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		// Check if user is authorized to update form on this event
 		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
@@ -191,25 +221,67 @@ func updateFormHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		if !mongodb.CanUserModifyForm(c, params.MongoService, authenticatedUser, formID, form) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "You are not authorized to update this form"})
-			return
-		}
-
 		if form.LastUpdatedAt.After(req.LastUpdatedAt) {
 			c.JSON(http.StatusConflict, gin.H{"error": messages.UpdateAttemptOnChangedEntity})
 			return
 		}
 
+		// Responses submitted before unique fields or one response per user were turned on have to follow them too
+		if claimRulesChanged(form, &req) {
+			req.ID = form.ID
+			if err := responses.ReplaceClaims(c, params, &req); err != nil {
+				var claimErr *mongodb.ClaimTakenError
+				if errors.As(err, &claimErr) {
+					c.JSON(http.StatusConflict, gin.H{"error": responses.ExistingClaimTakenMessage(&req, claimErr)})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update form"})
+				logger.Error("Failed to replace form response claims", err)
+				return
+			}
+		}
+
 		newLastUpdatedAt := time.Now()
 		req.LastUpdatedAt = newLastUpdatedAt
-		_, err = params.MongoService.UpdateForm(c, req, formID)
+
+		// Only saves that change the fields make a new version, settings don't change how responses are read
+		version := form.Version
+		if fieldsChanged(form.Attrs, req.Attrs) {
+			version, err = params.MongoService.UpdateFormWithVersion(c, req, formID, authenticatedUser.ID)
+		} else {
+			_, err = params.MongoService.UpdateForm(c, req, formID)
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update form"})
 			log.Fatalf("Failed to update form: %v", err)
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "Form updated successfully", "lastUpdatedAt": newLastUpdatedAt})
+		middlewares.SetAuditChanges(c, form, req)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Form updated successfully", "lastUpdatedAt": newLastUpdatedAt, "version": version})
+	}
+}
+
+// claimRulesChanged reports whether a save changes which responses of the form are allowed to coexist, see responses.ReplaceClaims
+func claimRulesChanged(previous *models.FormStructure, updated *models.FormStructure) bool {
+	if previous.AllowMultipleSubmissions != updated.AllowMultipleSubmissions {
+		return true
+	}
+
+	uniqueFieldKeys := make(map[string]bool, len(previous.UniqueFieldKeys))
+	for _, key := range previous.UniqueFieldKeys {
+		uniqueFieldKeys[key] = true
+	}
+
+	if len(updated.UniqueFieldKeys) != len(uniqueFieldKeys) {
+		return true
 	}
+	for _, key := range updated.UniqueFieldKeys {
+		if !uniqueFieldKeys[key] {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,17 @@
+package forms
+
+import (
+	"shared/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimRulesChanged(t *testing.T) {
+	form := &models.FormStructure{UniqueFieldKeys: []string{"email", "phone"}}
+
+	assert.False(t, claimRulesChanged(form, &models.FormStructure{UniqueFieldKeys: []string{"phone", "email"}}), "the order of unique fields doesn't matter")
+	assert.True(t, claimRulesChanged(form, &models.FormStructure{UniqueFieldKeys: []string{"email"}}))
+	assert.True(t, claimRulesChanged(form, &models.FormStructure{UniqueFieldKeys: []string{"email", "name"}}))
+	assert.True(t, claimRulesChanged(form, &models.FormStructure{UniqueFieldKeys: []string{"email", "phone"}, AllowMultipleSubmissions: true}))
+}
@@ -0,0 +1,354 @@
+package responses
+
+import (
+	"api/internal/helpers"
+	"api/internal/middlewares"
+	"api/internal/routes/forms/responses/validators"
+	"api/internal/types"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"shared/config"
+	"shared/logger"
+	"shared/mailer"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+Anonymous submissions let people respond to a form without an account, when the form enables them.
+
+Loading the form hands out a proof of work challenge (see helpers.ProofOfWorkChallenge) that the submission has to solve,
+and every anonymous route is rate limited per IP. Each challenge can only be spent once.
+
+Forms can also require the respondent to confirm their email, the response is then held as pending and only counts,
+and triggers its pipelines, once the respondent confirms it. The emailed link opens a page on the website that confirms
+with a POST, as mail scanners open the links in emails and a GET would confirm the response for them.
+*/
+
+const proofOfWorkPurpose = "anonymous-submission-proof-of-work"
+
+// anonymousRateLimit limits the requests each IP can make to the named anonymous routes per hour
+func anonymousRateLimit(params *types.RouteParams, name string) gin.HandlerFunc {
+	limit := 20
+	if apiConfig, err := config.GetAPIConfig(); err == nil {
+		limit = apiConfig.ANONYMOUS_RATE_LIMIT_PER_HOUR
+	}
+
+	return middlewares.RateLimitMiddleware(params.MongoService, name, limit, time.Hour)
+}
+
+// anonymousUnavailableReason explains why a form that enables anonymous submissions can't accept them, it is empty when it can
+func anonymousUnavailableReason(form *models.FormStructure) string {
+	switch {
+	case !form.Anonymous.Enabled:
+		return "This form does not accept anonymous submissions"
+	case form.IsRestricted:
+		return "Restricted forms can only be submitted by signed in users"
+	case form.Capacity.Limit > 0:
+		// Admissions are RSVP'd to by the respondent's account
+		return "Forms with a capacity can only be submitted by signed in users"
+	}
+
+	return ""
+}
+
+// getAnonymousForm loads the form from the path and checks it accepts anonymous submissions, it writes the error response on failure
+func getAnonymousForm(c *gin.Context, params *types.RouteParams) (*models.FormStructure, bool) {
+	formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+		return nil, false
+	}
+
+	form, err := params.MongoService.GetForm(c, formID, false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Form not found"})
+		return nil, false
+	}
+
+	if reason := anonymousUnavailableReason(form); reason != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return nil, false
+	}
+
+	return form, true
+}
+
+// verificationEmail returns the address the confirmation link of an anonymous response is sent to
+func verificationEmail(form *models.FormStructure, data map[string]interface{}) (string, bool) {
+	value, ok := data[form.Anonymous.EmailFieldKey].(string)
+	if !ok {
+		return "", false
+	}
+
+	address, err := mail.ParseAddress(strings.TrimSpace(value))
+	if err != nil {
+		return "", false
+	}
+
+	return address.Address, true
+}
+
+// newConfirmationToken returns a random token for a confirmation link and the hash of it that is stored
+func newConfirmationToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(b)
+	return token, hashConfirmationToken(token), nil
+}
+
+func hashConfirmationToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Get a published form that accepts anonymous submissions, along with the proof of work challenge to submit it with
+func getAnonymousFormHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, ok := getAnonymousForm(c, params)
+		if !ok {
+			return
+		}
+
+		if form.Status != "published" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Form not found"})
+			return
+		}
+
+		apiConfig, err := config.GetAPIConfig()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		challenge, err := helpers.NewProofOfWorkChallenge(utils.DeriveSecret(proofOfWorkPurpose), form.ID.Hex(), apiConfig.ANONYMOUS_POW_DIFFICULTY, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to create proof of work challenge", err)
+			return
+		}
+
+		form.StripSecrets()
+		c.JSON(http.StatusOK, gin.H{"form": form, "challenge": challenge})
+	}
+}
+
+type anonymousSubmissionRequest struct {
+	Data      map[string]interface{} `json:"data" validate:"required"`
+	Challenge string                 `json:"challenge" validate:"required"`
+	Solution  string                 `json:"solution" validate:"required"`
+}
+
+/*
+Submit an anonymous response
+
+The response is stored right away, or held until the respondent confirms their email if the form requires it.
+Anonymous respondents can't set internal fields or upload files.
+*/
+func submitAnonymousResponseHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req anonymousSubmissionRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		form, ok := getAnonymousForm(c, params)
+		if !ok {
+			return
+		}
+
+		if !checkFormAcceptsSubmissions(c, params, form) {
+			return
+		}
+
+		expiresAt, err := helpers.VerifyProofOfWork(utils.DeriveSecret(proofOfWorkPurpose), form.ID.Hex(), req.Challenge, req.Solution, time.Now())
+		if err != nil {
+			if err == helpers.ErrExpiredProofOfWork {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The challenge has expired, please reload the form and try again"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proof of work"})
+			return
+		}
+
+		data := withoutInternalFields(form, req.Data)
+		if fieldErrors := validators.ValidateResponses(data, form.Attrs); len(fieldErrors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some fields are invalid", "fieldErrors": fieldErrors})
+			return
+		}
+
+		// Uploads belong to a user, so an anonymous response can't reference any
+		if _, err := validateSubmittedFileUploads(c, params, form, data, primitive.NilObjectID, primitive.NilObjectID); err != nil {
+			if err == errInvalidFileUpload {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Files can only be uploaded by signed in users"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to validate submitted file uploads", err)
+			return
+		}
+
+		// Spent only once the submission is otherwise valid, so fixing a field error doesn't need a new challenge
+		if err := params.MongoService.ConsumeProofOfWorkChallenge(c, req.Challenge, expiresAt); err != nil {
+			if err == mongodb.ErrChallengeAlreadyUsed {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This challenge has already been used, please reload the form and try again"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to consume proof of work challenge", err)
+			return
+		}
+
+		if form.Anonymous.RequireEmailVerification {
+			holdForConfirmation(c, params, form, data)
+			return
+		}
+
+		response := models.FormResponse{
+			FormID:        form.ID,
+			Data:          data,
+			CreatedAt:     time.Now(),
+			LastUpdatedAt: time.Now(),
+		}
+		if _, ok := createResponse(c, params, form, response); !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	}
+}
+
+// holdForConfirmation stores the anonymous response as pending and emails its respondent the link to confirm it
+func holdForConfirmation(c *gin.Context, params *types.RouteParams, form *models.FormStructure, data map[string]interface{}) {
+	email, ok := verificationEmail(form, data)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid email address is needed to confirm your response"})
+		return
+	}
+
+	apiConfig, err := config.GetAPIConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to get API config", err)
+		return
+	}
+
+	token, tokenHash, err := newConfirmationToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to create confirmation token", err)
+		return
+	}
+
+	ttl := time.Duration(apiConfig.ANONYMOUS_VERIFICATION_TTL_HOURS) * time.Hour
+	pending := models.PendingResponse{
+		FormID:    form.ID,
+		Data:      data,
+		Email:     email,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if _, err := params.MongoService.CreatePendingResponse(c, pending); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to store pending anonymous response", err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/forms/%s/confirm?token=%s", apiConfig.WEBSITE_PUBLIC_URL, form.ID.Hex(), token)
+	err = params.Mailer.Send(c, mailer.Message{
+		To:      []string{email},
+		Subject: fmt.Sprintf("Confirm your response to %s", form.Name),
+		Body: fmt.Sprintf("Thanks for responding to %s.\n\nPlease confirm your response within %d hours by opening this link:\n%s\n\nIf you didn't fill out this form you can ignore this email, the response won't be counted.",
+			form.Name, apiConfig.ANONYMOUS_VERIFICATION_TTL_HOURS, link),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send the confirmation email"})
+		logger.Error("Failed to send anonymous response confirmation email", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check your email to confirm your response", "pendingConfirmation": true})
+}
+
+type confirmAnonymousResponseRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+/*
+Confirm an anonymous response, which is when the response counts and its pipelines run
+
+body:
+  - token: the token from the link emailed to the respondent
+*/
+func confirmAnonymousResponseHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req confirmAnonymousResponseRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		form, ok := getAnonymousForm(c, params)
+		if !ok {
+			return
+		}
+
+		if !checkFormAcceptsSubmissions(c, params, form) {
+			return
+		}
+
+		// Taken out so the link can't be confirmed twice at once, it is put back below if the response isn't stored
+		pending, err := params.MongoService.ConsumePendingResponse(c, form.ID, hashConfirmationToken(req.Token))
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This confirmation link is invalid or has expired"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get pending anonymous response", err)
+			return
+		}
+
+		response := models.FormResponse{
+			FormID:        form.ID,
+			Data:          pending.Data,
+			CreatedAt:     time.Now(),
+			LastUpdatedAt: time.Now(),
+		}
+		if _, ok := createResponse(c, params, form, response); !ok {
+			// Kept until it expires, so the respondent can try again once the problem is fixed
+			if _, err := params.MongoService.CreatePendingResponse(c, *pending); err != nil {
+				logger.Error("Failed to restore pending anonymous response", err)
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Your response has been confirmed"})
+	}
+}
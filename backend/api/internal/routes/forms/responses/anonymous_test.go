@@ -0,0 +1,193 @@
+package responses
+
+import (
+	"api/internal/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shared/models"
+	"shared/mongodb"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestAnonymousUnavailableReason(t *testing.T) {
+	form := &models.FormStructure{}
+	assert.Equal(t, "This form does not accept anonymous submissions", anonymousUnavailableReason(form))
+
+	form.Anonymous.Enabled = true
+	assert.Empty(t, anonymousUnavailableReason(form))
+
+	form.Capacity.Limit = 10
+	assert.Equal(t, "Forms with a capacity can only be submitted by signed in users", anonymousUnavailableReason(form))
+
+	form.IsRestricted = true
+	assert.Equal(t, "Restricted forms can only be submitted by signed in users", anonymousUnavailableReason(form))
+}
+
+func TestVerificationEmail(t *testing.T) {
+	form := &models.FormStructure{Anonymous: models.AnonymousSubmissions{Enabled: true, RequireEmailVerification: true, EmailFieldKey: "email"}}
+
+	email, ok := verificationEmail(form, map[string]interface{}{"email": " someone@example.com "})
+	assert.True(t, ok)
+	assert.Equal(t, "someone@example.com", email)
+
+	_, ok = verificationEmail(form, map[string]interface{}{"email": "not an email"})
+	assert.False(t, ok)
+
+	_, ok = verificationEmail(form, map[string]interface{}{"name": "someone"})
+	assert.False(t, ok)
+}
+
+func TestConfirmationToken(t *testing.T) {
+	token, tokenHash, err := newConfirmationToken()
+	assert.NoError(t, err)
+	assert.Len(t, token, 64)
+	assert.Equal(t, tokenHash, hashConfirmationToken(token))
+	assert.NotEqual(t, token, tokenHash)
+
+	other, _, _ := newConfirmationToken()
+	assert.NotEqual(t, token, other)
+}
+
+// submissionMongo stores anonymous submissions in memory, it doesn't implement GetEvent so the event can't be looked up by who is asking
+type submissionMongo struct {
+	mongodb.MongoService
+	form        models.FormStructure
+	event       models.Event
+	owner       models.User
+	sub         models.Subscription
+	pending     []models.PendingResponse
+	responses   []models.FormResponse
+	utilization int
+	submitErr   error
+}
+
+func (m *submissionMongo) GetForm(ctx context.Context, formID primitive.ObjectID, stripSecrets bool) (*models.FormStructure, error) {
+	if formID != m.form.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	form := m.form
+	return &form, nil
+}
+
+func (m *submissionMongo) FindEvent(ctx context.Context, eventID primitive.ObjectID) (*models.Event, error) {
+	event := m.event
+	return &event, nil
+}
+
+func (m *submissionMongo) GetUserDetails(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	if userID != m.owner.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	owner := m.owner
+	return &owner, nil
+}
+
+func (m *submissionMongo) GetSubscription(ctx context.Context, subscriptionID primitive.ObjectID) (*models.Subscription, error) {
+	sub := m.sub
+	return &sub, nil
+}
+
+func (m *submissionMongo) IncrementSubscriptionUtilization(ctx context.Context, subscriptionID primitive.ObjectID, utilizationKey string, limitKey string) (*mongo.UpdateResult, error) {
+	return m.AddSubscriptionUtilization(ctx, subscriptionID, utilizationKey, limitKey, 1)
+}
+
+func (m *submissionMongo) AddSubscriptionUtilization(ctx context.Context, subscriptionID primitive.ObjectID, utilizationKey string, limitKey string, amount int) (*mongo.UpdateResult, error) {
+	m.utilization += amount
+	return &mongo.UpdateResult{ModifiedCount: 1}, nil
+}
+
+func (m *submissionMongo) SubmitResponse(ctx context.Context, response models.FormResponse, submission mongodb.ResponseSubmission) (*models.FormResponse, error) {
+	if m.submitErr != nil {
+		return nil, m.submitErr
+	}
+	response.ID = primitive.NewObjectID()
+	m.responses = append(m.responses, response)
+	return &response, nil
+}
+
+func (m *submissionMongo) ListPipelines(ctx context.Context, filter bson.M) ([]models.PipelineConfiguration, error) {
+	return nil, nil
+}
+
+func (m *submissionMongo) CreatePendingResponse(ctx context.Context, pending models.PendingResponse) (*mongo.InsertOneResult, error) {
+	m.pending = append(m.pending, pending)
+	return &mongo.InsertOneResult{InsertedID: pending.ID}, nil
+}
+
+func (m *submissionMongo) ConsumePendingResponse(ctx context.Context, formID primitive.ObjectID, tokenHash string) (*models.PendingResponse, error) {
+	for i, pending := range m.pending {
+		if pending.FormID == formID && pending.TokenHash == tokenHash {
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			return &pending, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func TestConfirmAnonymousResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	owner := models.User{ID: primitive.NewObjectID(), CurrentSubscriptionID: primitive.NewObjectID()}
+	event := models.Event{ID: primitive.NewObjectID(), CreatedByID: owner.ID}
+	m := &submissionMongo{
+		form: models.FormStructure{
+			ID:        primitive.NewObjectID(),
+			EventID:   event.ID,
+			Status:    "published",
+			Anonymous: models.AnonymousSubmissions{Enabled: true, RequireEmailVerification: true, EmailFieldKey: "email"},
+		},
+		event: event,
+		owner: owner,
+		sub:   models.Subscription{ID: owner.CurrentSubscriptionID, Status: models.SubscriptionStatusActive},
+	}
+
+	// No authentication middleware, the respondent isn't signed in
+	r := gin.New()
+	r.POST("/forms/:form_id/responses/anonymous/confirm", confirmAnonymousResponseHandler(&types.RouteParams{MongoService: m}))
+
+	confirm := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/forms/"+m.form.ID.Hex()+"/responses/anonymous/confirm", strings.NewReader(`{"token": "`+token+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	token, tokenHash, err := newConfirmationToken()
+	require.NoError(t, err)
+	m.pending = []models.PendingResponse{{ID: primitive.NewObjectID(), FormID: m.form.ID, Data: map[string]interface{}{"email": "someone@example.com"}, TokenHash: tokenHash}}
+
+	t.Run("a refused response can be confirmed again", func(t *testing.T) {
+		m.submitErr = mongodb.ErrMaxSubmissionsReached
+		defer func() { m.submitErr = nil }()
+
+		resp := confirm(token)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Len(t, m.pending, 1, "the pending response is put back")
+		assert.Zero(t, m.utilization, "the refused response isn't billed")
+	})
+
+	t.Run("the event owner is billed for the response", func(t *testing.T) {
+		resp := confirm(token)
+		assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		require.Len(t, m.responses, 1)
+		assert.Equal(t, "someone@example.com", m.responses[0].Data["email"])
+		assert.Empty(t, m.pending)
+		assert.Equal(t, 1, m.utilization)
+	})
+
+	t.Run("each link confirms once", func(t *testing.T) {
+		resp := confirm(token)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Len(t, m.responses, 1)
+	})
+}
@@ -0,0 +1,309 @@
+package responses
+
+import (
+	"api/internal/middlewares"
+	"api/internal/routes/forms/responses/validators"
+	"api/internal/types"
+	"errors"
+	"net/http"
+	"shared/formresponses"
+	"shared/logger"
+	"shared/messages"
+	"shared/models"
+	"shared/mongodb"
+	"shared/storage"
+	"shared/triggers"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+Applicant routes let users manage the responses they submitted, across every event.
+
+Applicants never see or change the internal fields of a form, and can only edit a response while its form allows edits.
+Withdrawing an application deletes the response the same way an organizer would.
+*/
+
+// RegisterApplicantResponseRoutes registers the routes for the authenticated user's own responses
+func RegisterApplicantResponseRoutes(r *gin.RouterGroup, params *types.RouteParams) {
+	r.GET("", middlewares.JWTAuthMiddleware(params.MongoService), listMyResponsesHandler(params))
+	r.PUT(":response_id", middlewares.JWTAuthMiddleware(params.MongoService), updateMyResponseHandler(params))
+	r.DELETE(":response_id", middlewares.JWTAuthMiddleware(params.MongoService), withdrawMyResponseHandler(params))
+}
+
+// canApplicantEdit reports whether applicants can still edit their responses to the form
+func canApplicantEdit(form *models.FormStructure, now time.Time) bool {
+	if !form.AllowResponseEdits {
+		return false
+	}
+
+	return form.EditResponsesUntil.IsZero() || now.Before(form.EditResponsesUntil)
+}
+
+// applicantVisibleData returns the data of the form's current non-internal fields, which is what the applicant can see
+func applicantVisibleData(form *models.FormStructure, data map[string]interface{}) map[string]interface{} {
+	visible := make(map[string]interface{})
+	for _, field := range form.Attrs {
+		if field.IsInternal {
+			continue
+		}
+
+		if value, exists := data[field.Key]; exists {
+			visible[field.Key] = value
+		}
+	}
+
+	return visible
+}
+
+// mergeApplicantData replaces the applicant's fields of the existing data with their edit,
+// internal fields and the data of deleted fields are kept as they were
+func mergeApplicantData(form *models.FormStructure, existing map[string]interface{}, edited map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for key, value := range existing {
+		merged[key] = value
+	}
+
+	for _, field := range form.Attrs {
+		if field.IsInternal {
+			continue
+		}
+
+		delete(merged, field.Key)
+		if value, exists := edited[field.Key]; exists {
+			merged[field.Key] = value
+		}
+	}
+
+	return merged
+}
+
+// withoutInternalFields drops the internal fields from data submitted by an applicant
+func withoutInternalFields(form *models.FormStructure, data map[string]interface{}) map[string]interface{} {
+	cleaned := make(map[string]interface{})
+	for key, value := range data {
+		cleaned[key] = value
+	}
+
+	for _, field := range form.Attrs {
+		if field.IsInternal {
+			delete(cleaned, field.Key)
+		}
+	}
+
+	return cleaned
+}
+
+// getMyResponse loads the user's own response from the path along with its form, it writes the error response on failure
+func getMyResponse(c *gin.Context, params *types.RouteParams) (*models.FormStructure, *models.FormResponse, bool) {
+	authenticatedUser, ok := utils.GetUserFromContext(c, true)
+	if !ok {
+		return nil, nil, false
+	}
+
+	responseID, err := primitive.ObjectIDFromHex(c.Param("response_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid response ID"})
+		return nil, nil, false
+	}
+
+	responses, err := params.MongoService.ListResponses(c, bson.M{"_id": responseID, "userID": authenticatedUser.ID}, nil)
+	if err != nil || len(responses) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Response does not exist"})
+		return nil, nil, false
+	}
+
+	form, err := params.MongoService.GetForm(c, responses[0].FormID, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+		return nil, nil, false
+	}
+
+	return form, &responses[0], true
+}
+
+type myResponse struct {
+	ID                 primitive.ObjectID     `json:"id"`
+	FormID             primitive.ObjectID     `json:"formID"`
+	FormName           string                 `json:"formName"`
+	EventID            primitive.ObjectID     `json:"eventID"`
+	Data               map[string]interface{} `json:"data"`
+	Admission          *models.Admission      `json:"admission,omitempty"`
+	CreatedAt          time.Time              `json:"createdAt"`
+	LastUpdatedAt      time.Time              `json:"lastUpdatedAt"`
+	CanEdit            bool                   `json:"canEdit"`
+	EditResponsesUntil time.Time              `json:"editResponsesUntil,omitempty"`
+}
+
+// List the authenticated user's responses across all events, newest first
+func listMyResponsesHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+		responses, err := params.MongoService.ListResponses(c, bson.M{"userID": authenticatedUser.ID}, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list user responses", err)
+			return
+		}
+
+		formIDs := []primitive.ObjectID{}
+		for _, response := range responses {
+			formIDs = append(formIDs, response.FormID)
+		}
+
+		forms, err := params.MongoService.ListForms(c, bson.M{"_id": bson.M{"$in": formIDs}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list forms of user responses", err)
+			return
+		}
+
+		formsByID := make(map[primitive.ObjectID]*models.FormStructure)
+		for i := range forms {
+			formsByID[forms[i].ID] = &forms[i]
+		}
+
+		now := time.Now()
+		results := []myResponse{}
+		for _, response := range responses {
+			form, exists := formsByID[response.FormID]
+			if !exists {
+				continue
+			}
+
+			results = append(results, myResponse{
+				ID:                 response.ID,
+				FormID:             form.ID,
+				FormName:           form.Name,
+				EventID:            form.EventID,
+				Data:               applicantVisibleData(form, response.Data),
+				Admission:          response.Admission,
+				CreatedAt:          response.CreatedAt,
+				LastUpdatedAt:      response.LastUpdatedAt,
+				CanEdit:            canApplicantEdit(form, now),
+				EditResponsesUntil: form.EditResponsesUntil,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"responses": results})
+	}
+}
+
+type updateMyResponseRequest struct {
+	Data          map[string]interface{} `json:"data" validate:"required"`
+	LastUpdatedAt time.Time              `json:"lastUpdatedAt" validate:"required"`
+}
+
+// Edit the authenticated user's own response, the edit is validated like a submission
+func updateMyResponseHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req updateMyResponseRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		form, response, ok := getMyResponse(c, params)
+		if !ok {
+			return
+		}
+
+		if !canApplicantEdit(form, time.Now()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This form no longer accepts edits to responses"})
+			return
+		}
+
+		if response.LastUpdatedAt.After(req.LastUpdatedAt) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": messages.UpdateAttemptOnChangedEntity})
+			return
+		}
+
+		edited := withoutInternalFields(form, req.Data)
+		if fieldErrors := validators.ValidateResponses(edited, form.Attrs); len(fieldErrors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some fields are invalid", "fieldErrors": fieldErrors})
+			return
+		}
+
+		fileUploadIDs, err := validateSubmittedFileUploads(c, params, form, edited, response.UserID, response.ID)
+		if err != nil {
+			if err == errInvalidFileUpload {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to validate submitted file uploads", err)
+			return
+		}
+
+		updated := *response
+		updated.Data = mergeApplicantData(form, response.Data, edited)
+		updated.LastUpdatedAt = time.Now()
+		updated.FormVersion = form.Version
+		change := &models.ResponseChange{ActorID: response.UserID, Source: models.ResponseChangeApplicantEdit, Changes: models.DiffResponseData(response.Data, updated.Data)}
+		if _, err := params.MongoService.UpdateResponseWithClaims(c, updated, response.ID, formresponses.Claims(form, response.UserID, updated.Data), change); err != nil {
+			var claimErr *mongodb.ClaimTakenError
+			if errors.As(err, &claimErr) {
+				c.JSON(http.StatusConflict, gin.H{"error": formresponses.ClaimTakenMessage(form, claimErr)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to update form response", err)
+			return
+		}
+
+		if err := syncResponseFiles(c, params, response.ID, fileUploadIDs); err != nil {
+			logger.Error("Failed to update the files of the form response", err)
+		}
+
+		if err := triggers.FieldChangePipelines(c, params.MessageProducer, params.MongoService, form, response.Data, updated.Data); err != nil {
+			logger.Error("Failed to trigger field change pipelines", err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"id": response.ID, "lastUpdatedAt": updated.LastUpdatedAt})
+	}
+}
+
+// Withdraw the authenticated user's own response, which deletes it and gives up its seat
+func withdrawMyResponseHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, response, ok := getMyResponse(c, params)
+		if !ok {
+			return
+		}
+
+		change := models.ResponseChange{ActorID: response.UserID, Source: models.ResponseChangeWithdrawal}
+		if !removeResponse(c, params, *response, change) {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Response withdrawn successfully"})
+	}
+}
+
+// syncResponseFiles attaches the uploads of an edited response and deletes the ones it no longer references
+func syncResponseFiles(c *gin.Context, params *types.RouteParams, responseID primitive.ObjectID, fileUploadIDs []primitive.ObjectID) error {
+	if len(fileUploadIDs) > 0 {
+		if _, err := params.MongoService.AttachFileUploads(c, fileUploadIDs, responseID); err != nil {
+			return err
+		}
+	}
+
+	referenced := append([]primitive.ObjectID{}, fileUploadIDs...)
+	return storage.DeleteFileUploads(c, params.ObjectStorage, params.MongoService, bson.M{"responseID": responseID, "_id": bson.M{"$nin": referenced}})
+}
@@ -0,0 +1,60 @@
+package responses
+
+import (
+	"shared/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var applicantTestForm = &models.FormStructure{
+	Attrs: []models.FormField{
+		{Key: "name", Question: "Name"},
+		{Key: "bio", Question: "Bio"},
+		{Key: "status", Question: "Status", IsInternal: true},
+	},
+}
+
+func TestCanApplicantEdit(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		form     models.FormStructure
+		expected bool
+	}{
+		{name: "Edits not allowed", form: models.FormStructure{}, expected: false},
+		{name: "No deadline", form: models.FormStructure{AllowResponseEdits: true}, expected: true},
+		{name: "Before deadline", form: models.FormStructure{AllowResponseEdits: true, EditResponsesUntil: now.Add(time.Hour)}, expected: true},
+		{name: "After deadline", form: models.FormStructure{AllowResponseEdits: true, EditResponsesUntil: now.Add(-time.Hour)}, expected: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, canApplicantEdit(&tc.form, now))
+		})
+	}
+}
+
+func TestApplicantVisibleData(t *testing.T) {
+	data := map[string]interface{}{"name": "Ada", "status": "accepted", "removed": "old"}
+
+	assert.Equal(t, map[string]interface{}{"name": "Ada"}, applicantVisibleData(applicantTestForm, data))
+}
+
+func TestWithoutInternalFields(t *testing.T) {
+	data := map[string]interface{}{"name": "Ada", "status": "accepted", "unknown": "x"}
+
+	assert.Equal(t, map[string]interface{}{"name": "Ada", "unknown": "x"}, withoutInternalFields(applicantTestForm, data))
+	assert.Contains(t, data, "status", "the submitted data is not modified")
+}
+
+func TestMergeApplicantData(t *testing.T) {
+	existing := map[string]interface{}{"name": "Ada", "bio": "Hello", "status": "accepted", "removed": "old"}
+	edited := map[string]interface{}{"name": "Ada Lovelace"}
+
+	merged := mergeApplicantData(applicantTestForm, existing, edited)
+
+	assert.Equal(t, map[string]interface{}{"name": "Ada Lovelace", "status": "accepted", "removed": "old"}, merged)
+	assert.Equal(t, "Ada", existing["name"], "the existing data is not modified")
+}
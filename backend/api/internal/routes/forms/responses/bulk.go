@@ -0,0 +1,393 @@
+package responses
+
+import (
+	"api/internal/routes/forms/responses/validators"
+	"api/internal/types"
+	"errors"
+	"fmt"
+	"net/http"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+Bulk operations are stored as jobs with an item for each response, the event listener picks them up and runs them in the
+background (see formresponses.RunBulkJobs). Organizers poll the job for the result of each item.
+*/
+
+const (
+	maxBulkItems     = 5000
+	bulkJobListLimit = 20
+)
+
+// bulkSelection chooses the responses of a bulk operation, either by their IDs or by a search and filter
+type bulkSelection struct {
+	IDs    []primitive.ObjectID `json:"ids"`
+	Q      string               `json:"q"`
+	Filter string               `json:"filter"`
+}
+
+type bulkSetFieldRequest struct {
+	bulkSelection
+	FieldKey string      `json:"fieldKey" binding:"required"`
+	Value    interface{} `json:"value"` // clears the field when null
+}
+
+type bulkDeleteRequest struct {
+	bulkSelection
+}
+
+type bulkMoveRequest struct {
+	bulkSelection
+	ToFormID primitive.ObjectID `json:"toFormID" binding:"required"`
+}
+
+// check reports why the selection can't be used, IDs are deduplicated in place
+func (s *bulkSelection) check() error {
+	byQuery := s.Q != "" || s.Filter != ""
+	if len(s.IDs) > 0 && byQuery {
+		return errors.New("Choose responses either by their IDs or by a search and filter, not both")
+	}
+	if len(s.IDs) == 0 && !byQuery {
+		return errors.New("Choose responses by their IDs or by a search and filter")
+	}
+
+	seen := make(map[primitive.ObjectID]bool)
+	ids := []primitive.ObjectID{}
+	for _, id := range s.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	s.IDs = ids
+
+	if len(s.IDs) > maxBulkItems {
+		return fmt.Errorf("At most %d responses can be changed at once", maxBulkItems)
+	}
+	return nil
+}
+
+// checkBulkFieldValue validates the value a bulk operation sets a field to, organizers fill in internal fields so they are never locked or required
+func checkBulkFieldValue(form *models.FormStructure, fieldKey string, value interface{}) error {
+	field := findField(form, fieldKey)
+	if field == nil {
+		return fmt.Errorf("Unknown field %s", fieldKey)
+	}
+
+	if field.Type == models.FormFieldTypeFile {
+		return fmt.Errorf("Field %s holds uploaded files, it can't be set in bulk", field.Question)
+	}
+
+	checked := *field
+	if checked.IsInternal {
+		checked.IsInternal = false
+		checked.Required = false
+	}
+	return validators.ValidateResponse(value, checked)
+}
+
+// getBulkForm loads the form of the request for routes whose permission was already checked, it writes the error response on failure
+func getBulkForm(c *gin.Context, params *types.RouteParams) (*models.FormStructure, *models.User, bool) {
+	authenticatedUser, ok := utils.GetUserFromContext(c, true)
+	if !ok {
+		return nil, nil, false
+	}
+
+	formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+		return nil, nil, false
+	}
+
+	form, err := params.MongoService.GetForm(c, formID, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+		return nil, nil, false
+	}
+
+	return form, authenticatedUser, true
+}
+
+// selectBulkResponses resolves the selection into the IDs of the responses, it writes the error response on failure
+func selectBulkResponses(c *gin.Context, params *types.RouteParams, form *models.FormStructure, selection bulkSelection) ([]primitive.ObjectID, bool) {
+	if err := selection.check(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	ids := selection.IDs
+	if len(ids) == 0 {
+		query, err := parseResponseQuery(form, selection.Q, selection.Filter, "")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+
+		errTooMany := fmt.Errorf("At most %d responses can be changed at once, narrow down the filter", maxBulkItems)
+		err = params.MongoService.StreamResponses(c, form.ID, query, func(response models.FormResponse) error {
+			if len(ids) == maxBulkItems {
+				return errTooMany
+			}
+			ids = append(ids, response.ID)
+			return nil
+		})
+		switch {
+		case err == errTooMany:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		case err == mongodb.ErrInvalidResponseQuery:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This query can't be used on this form"})
+			return nil, false
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to select form responses", err)
+			return nil, false
+		}
+
+		if len(ids) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No responses match the filter"})
+			return nil, false
+		}
+	}
+
+	return ids, true
+}
+
+// startBulkJob stores the job with an item for each response for the event listener to run, it writes the response
+func startBulkJob(c *gin.Context, params *types.RouteParams, form *models.FormStructure, ids []primitive.ObjectID, job models.BulkJob) {
+	job.FormID = form.ID
+	job.EventID = form.EventID
+	job.Items = make([]models.BulkJobItem, 0, len(ids))
+	for _, id := range ids {
+		job.Items = append(job.Items, models.BulkJobItem{ResponseID: id, Status: models.BulkJobItemPending})
+	}
+
+	result, err := params.MongoService.CreateBulkJob(c, job)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to create bulk job", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"id": result.InsertedID, "total": len(job.Items)})
+}
+
+/*
+Set a field to the same value on many responses, triggering the FieldChange pipelines of the responses whose field changed
+
+params:
+  - form_id: ID of the form
+
+body:
+  - ids: IDs of the responses, or
+  - q, filter: search and filter that select the responses, as used to list them
+  - fieldKey: key of the field to set
+  - value: the value to set it to, null clears the field
+*/
+func bulkSetFieldHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, authenticatedUser, ok := getBulkForm(c, params)
+		if !ok {
+			return
+		}
+
+		var req bulkSetFieldRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := checkBulkFieldValue(form, req.FieldKey, req.Value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ids, ok := selectBulkResponses(c, params, form, req.bulkSelection)
+		if !ok {
+			return
+		}
+
+		startBulkJob(c, params, form, ids, models.BulkJob{
+			CreatedByID: authenticatedUser.ID,
+			Type:        models.BulkJobSetField,
+			FieldKey:    req.FieldKey,
+			Value:       req.Value,
+		})
+	}
+}
+
+/*
+Delete many responses, releasing their seats, files, claims and reviews like deleting them one by one
+
+params:
+  - form_id: ID of the form
+
+body:
+  - ids: IDs of the responses, or
+  - q, filter: search and filter that select the responses, as used to list them
+*/
+func bulkDeleteHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, authenticatedUser, ok := getBulkForm(c, params)
+		if !ok {
+			return
+		}
+
+		var req bulkDeleteRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ids, ok := selectBulkResponses(c, params, form, req.bulkSelection)
+		if !ok {
+			return
+		}
+
+		startBulkJob(c, params, form, ids, models.BulkJob{
+			CreatedByID: authenticatedUser.ID,
+			Type:        models.BulkJobDelete,
+		})
+	}
+}
+
+/*
+Move many responses to another form of the same event, triggering the FormSubmission pipelines of that form for each of them.
+Moved responses keep their data and files, their reviews are deleted
+
+params:
+  - form_id: ID of the form
+
+body:
+  - ids: IDs of the responses, or
+  - q, filter: search and filter that select the responses, as used to list them
+  - toFormID: ID of the form to move them to
+*/
+func bulkMoveHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, authenticatedUser, ok := getBulkForm(c, params)
+		if !ok {
+			return
+		}
+
+		var req bulkMoveRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if req.ToFormID == form.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Responses can't be moved to the form they are on"})
+			return
+		}
+
+		target, err := params.MongoService.GetForm(c, req.ToFormID, true)
+		if err != nil || target.EventID != form.EventID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Responses can only be moved to another form of the same event"})
+			return
+		}
+
+		if !mongodb.HasFormPermission(c, params.MongoService, authenticatedUser, target.ID, target, models.PermissionEditResponses) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access the target form"})
+			return
+		}
+
+		if apiToken, ok := utils.GetAPITokenFromContext(c); ok && !apiToken.Allows(target.EventID, target.ID, models.PermissionEditResponses) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This API token isn't allowed to access the target form"})
+			return
+		}
+
+		if form.Capacity.Limit > 0 || target.Capacity.Limit > 0 {
+			// A seat belongs to the form it was offered on and a move would skip everyone on the target's waitlist
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Responses can't be moved to or from forms with a capacity"})
+			return
+		}
+
+		ids, ok := selectBulkResponses(c, params, form, req.bulkSelection)
+		if !ok {
+			return
+		}
+
+		if target.MaxSubmissions > 0 {
+			count, err := params.MongoService.CountResponses(c, bson.M{"formID": target.ID})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				logger.Error("Failed to count form responses", err)
+				return
+			}
+
+			if int(count)+len(ids) > target.MaxSubmissions {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The target form would exceed its maximum number of submissions"})
+				return
+			}
+		}
+
+		startBulkJob(c, params, form, ids, models.BulkJob{
+			CreatedByID: authenticatedUser.ID,
+			Type:        models.BulkJobMove,
+			ToFormID:    target.ID,
+		})
+	}
+}
+
+// List the newest bulk jobs of a form, without the results of their items
+func listBulkJobsHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, _, ok := getBulkForm(c, params)
+		if !ok {
+			return
+		}
+
+		jobs, err := params.MongoService.ListBulkJobs(c, form.ID, bulkJobListLimit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list bulk jobs", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+	}
+}
+
+/*
+Get a bulk job with the result for each of its responses, poll it until its status is completed
+
+params:
+  - form_id: ID of the form
+  - job_id: ID of the job
+*/
+func getBulkJobHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, _, ok := getBulkForm(c, params)
+		if !ok {
+			return
+		}
+
+		jobID, err := primitive.ObjectIDFromHex(c.Param("job_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+			return
+		}
+
+		job, err := params.MongoService.GetBulkJob(c, form.ID, jobID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Bulk job does not exist"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get bulk job", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, job)
+	}
+}
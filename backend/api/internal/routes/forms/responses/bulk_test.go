@@ -0,0 +1,40 @@
+package responses
+
+import (
+	"shared/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBulkSelectionCheck(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+
+	selection := bulkSelection{IDs: []primitive.ObjectID{a, b, a}}
+	require.NoError(t, selection.check())
+	assert.Equal(t, []primitive.ObjectID{a, b}, selection.IDs)
+
+	assert.NoError(t, (&bulkSelection{Filter: "decision = Pending"}).check())
+	assert.Error(t, (&bulkSelection{}).check(), "nothing selected")
+	assert.Error(t, (&bulkSelection{IDs: []primitive.ObjectID{a}, Q: "stanford"}).check(), "IDs and a search")
+
+	tooMany := bulkSelection{}
+	for i := 0; i <= maxBulkItems; i++ {
+		tooMany.IDs = append(tooMany.IDs, primitive.NewObjectID())
+	}
+	assert.Error(t, tooMany.check())
+}
+
+func TestCheckBulkFieldValue(t *testing.T) {
+	form := testImportForm()
+	form.Attrs = append(form.Attrs, models.FormField{Key: "cv", Question: "CV", Type: models.FormFieldTypeFile})
+
+	assert.NoError(t, checkBulkFieldValue(form, "decision", "Accepted"), "internal fields can be set")
+	assert.NoError(t, checkBulkFieldValue(form, "decision", nil), "internal fields can be cleared")
+	assert.Error(t, checkBulkFieldValue(form, "decision", "Maybe"))
+	assert.Error(t, checkBulkFieldValue(form, "name", nil), "required fields can't be cleared")
+	assert.Error(t, checkBulkFieldValue(form, "cv", nil))
+	assert.Error(t, checkBulkFieldValue(form, "nope", "x"))
+}
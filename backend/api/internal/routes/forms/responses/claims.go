@@ -0,0 +1,45 @@
+package responses
+
+import (
+	"api/internal/types"
+	"context"
+	"fmt"
+	"shared/formresponses"
+	"shared/models"
+	"shared/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReplaceClaims takes the claims of the form's existing responses again under its new rules about what has to be unique,
+// so turning on unique fields or one response per user also applies to the responses it already has.
+// It returns a mongodb.ClaimTakenError when existing responses break the new rules, see ExistingClaimTakenMessage
+func ReplaceClaims(c context.Context, params *types.RouteParams, form *models.FormStructure) error {
+	responses, err := params.MongoService.ListResponses(c, bson.M{"formID": form.ID}, options.Find().SetProjection(bson.M{"userID": 1, "data": 1}))
+	if err != nil {
+		return err
+	}
+
+	claimsByResponse := make(map[primitive.ObjectID][]string, len(responses))
+	for _, response := range responses {
+		claimsByResponse[response.ID] = formresponses.Claims(form, response.UserID, response.Data)
+	}
+
+	return params.MongoService.ReplaceFormClaims(c, form.ID, claimsByResponse)
+}
+
+// ExistingClaimTakenMessage explains to the organizer why the form's existing responses break its new rules
+func ExistingClaimTakenMessage(form *models.FormStructure, err *mongodb.ClaimTakenError) string {
+	if key := formresponses.ClaimFieldKey(err.Claim); key != "" {
+		for _, field := range form.Attrs {
+			if field.Key == key {
+				return fmt.Sprintf("More than one response has the same value for \"%s\", remove the duplicates before making it unique", field.Question)
+			}
+		}
+		return "More than one response has the same value for a unique field, remove the duplicates before making it unique"
+	}
+
+	return "An applicant has already responded more than once, remove their extra responses before allowing only one response per applicant"
+}
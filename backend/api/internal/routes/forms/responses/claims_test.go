@@ -0,0 +1,56 @@
+package responses
+
+import (
+	"api/internal/types"
+	"context"
+	"shared/formresponses"
+	"shared/models"
+	"shared/mongodb"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// claimsMongo has the form's responses, replacing their claims is recorded
+type claimsMongo struct {
+	mongodb.MongoService
+	responses []models.FormResponse
+	replaced  map[primitive.ObjectID][]string
+}
+
+func (m *claimsMongo) ListResponses(ctx context.Context, filter bson.M, options *options.FindOptions) ([]models.FormResponse, error) {
+	return m.responses, nil
+}
+
+func (m *claimsMongo) ReplaceFormClaims(ctx context.Context, formID primitive.ObjectID, claimsByResponse map[primitive.ObjectID][]string) error {
+	m.replaced = claimsByResponse
+	return nil
+}
+
+func TestReplaceClaims(t *testing.T) {
+	userID := primitive.NewObjectID()
+	m := &claimsMongo{responses: []models.FormResponse{
+		{ID: primitive.NewObjectID(), UserID: userID, Data: map[string]interface{}{"email": "someone@example.com"}},
+		{ID: primitive.NewObjectID(), Data: map[string]interface{}{"email": ""}},
+	}}
+	form := &models.FormStructure{ID: primitive.NewObjectID(), UniqueFieldKeys: []string{"email"}}
+
+	require.NoError(t, ReplaceClaims(context.Background(), &types.RouteParams{MongoService: m}, form))
+	assert.Equal(t, formresponses.Claims(form, userID, m.responses[0].Data), m.replaced[m.responses[0].ID])
+	assert.Empty(t, m.replaced[m.responses[1].ID], "anonymous responses without the unique field claim nothing")
+
+	form.AllowMultipleSubmissions = true
+	require.NoError(t, ReplaceClaims(context.Background(), &types.RouteParams{MongoService: m}, form))
+	assert.Len(t, m.replaced[m.responses[0].ID], 1, "the user claim is given up once multiple submissions are allowed")
+}
+
+func TestExistingClaimTakenMessage(t *testing.T) {
+	form := &models.FormStructure{Attrs: []models.FormField{{Key: "email", Question: "Email"}}}
+
+	assert.Equal(t, `More than one response has the same value for "Email", remove the duplicates before making it unique`, ExistingClaimTakenMessage(form, &mongodb.ClaimTakenError{Claim: "field:email:0a1b"}))
+	assert.Contains(t, ExistingClaimTakenMessage(form, &mongodb.ClaimTakenError{Claim: "user:abc"}), "only one response per applicant")
+}
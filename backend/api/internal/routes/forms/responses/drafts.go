@@ -0,0 +1,156 @@
+package responses
+
+import (
+	"api/internal/types"
+	"net/http"
+	"shared/config"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+Drafts let a user save an in-progress response and resume it later.
+
+A draft is stored per user and form, it is not validated against required fields,
+does not trigger pipelines and does not count towards MaxSubmissions or billing utilization.
+Unknown and internal fields are left out. Drafts expire after RESPONSE_DRAFT_TTL_DAYS without being saved,
+and are deleted once the response is submitted.
+*/
+
+func getFormResponseDraftHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		draft, err := params.MongoService.GetResponseDraft(c, formID, authenticatedUser.ID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "No draft found for this form"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get form response draft", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"draft": draft})
+	}
+}
+
+func saveFormResponseDraftHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		var formData map[string]interface{}
+		if err := utils.BindJSON(c, &formData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		form, err := params.MongoService.GetForm(c, formID, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+			return
+		}
+
+		if form.Status != "published" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form is not published, if you believe this is an error message the event admins"})
+			return
+		}
+
+		if !form.CloseSubmissionsAt.IsZero() && form.CloseSubmissionsAt.Before(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form submissions are closed"})
+			return
+		}
+
+		if form.IsRestricted {
+			allowed, restrictMessage := mongodb.IsUserEmailInWhitelist(c, params.MongoService, form.AllowedSubmitters)
+			if !allowed {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": restrictMessage})
+				return
+			}
+		}
+
+		// Only the keys are checked, values are validated in full when the response is submitted.
+		// Keys of fields removed since the form was opened are dropped so the rest of the draft is still saved
+		fieldMap := make(map[string]models.FormField)
+		for _, field := range form.Attrs {
+			fieldMap[field.Key] = field
+		}
+
+		for key := range formData {
+			if field, exists := fieldMap[key]; !exists || field.IsInternal {
+				delete(formData, key)
+			}
+		}
+
+		apiConfig, err := config.GetAPIConfig()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		draft := models.FormResponseDraft{
+			FormID:    formID,
+			UserID:    authenticatedUser.ID,
+			Data:      formData,
+			ExpiresAt: time.Now().AddDate(0, 0, apiConfig.RESPONSE_DRAFT_TTL_DAYS),
+		}
+
+		if _, err := params.MongoService.SaveResponseDraft(c, draft); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to save form response draft", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Draft saved", "expiresAt": draft.ExpiresAt})
+	}
+}
+
+func deleteFormResponseDraftHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		if _, err := params.MongoService.DeleteResponseDraft(c, formID, authenticatedUser.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to delete form response draft", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Draft deleted"})
+	}
+}
@@ -0,0 +1,151 @@
+package responses
+
+import (
+	"api/internal/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// draftMongo keeps the drafts of a form by user on top of submissionMongo, so a draft can be submitted too
+type draftMongo struct {
+	submissionMongo
+	drafts map[primitive.ObjectID]models.FormResponseDraft
+}
+
+// GetResponseDraft leaves out expired drafts, like the service does before the TTL monitor removes them
+func (m *draftMongo) GetResponseDraft(ctx context.Context, formID primitive.ObjectID, userID primitive.ObjectID) (*models.FormResponseDraft, error) {
+	draft, ok := m.drafts[userID]
+	if !ok || draft.FormID != formID || !draft.ExpiresAt.After(time.Now()) {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &draft, nil
+}
+
+func (m *draftMongo) SaveResponseDraft(ctx context.Context, draft models.FormResponseDraft) (*mongo.UpdateResult, error) {
+	m.drafts[draft.UserID] = draft
+	return &mongo.UpdateResult{UpsertedCount: 1}, nil
+}
+
+func (m *draftMongo) DeleteResponseDraft(ctx context.Context, formID primitive.ObjectID, userID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	if draft, ok := m.drafts[userID]; !ok || draft.FormID != formID {
+		return &mongo.DeleteResult{}, nil
+	}
+	delete(m.drafts, userID)
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+// The user hasn't responded before
+func (m *draftMongo) ListResponses(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.FormResponse, error) {
+	return nil, nil
+}
+
+func TestResponseDrafts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	owner := models.User{ID: primitive.NewObjectID(), CurrentSubscriptionID: primitive.NewObjectID()}
+	event := models.Event{ID: primitive.NewObjectID(), CreatedByID: owner.ID}
+	m := &draftMongo{
+		submissionMongo: submissionMongo{
+			form: models.FormStructure{
+				ID:      primitive.NewObjectID(),
+				EventID: event.ID,
+				Status:  "published",
+				Attrs: []models.FormField{
+					{Key: "name", Question: "Full Name", Type: models.FormFieldTypeText, Required: true},
+					{Key: "decision", Question: "Decision", Type: models.FormFieldTypeText, IsInternal: true},
+				},
+			},
+			event: event,
+			owner: owner,
+			sub:   models.Subscription{ID: owner.CurrentSubscriptionID, Status: models.SubscriptionStatusActive},
+		},
+		drafts: map[primitive.ObjectID]models.FormResponseDraft{},
+	}
+
+	applicant, other := &models.User{ID: primitive.NewObjectID()}, &models.User{ID: primitive.NewObjectID()}
+	params := &types.RouteParams{MongoService: m}
+
+	// Stands in for JWTAuthMiddleware, the user is picked by the test
+	var signedIn *models.User
+	authenticated := func(c *gin.Context) { c.Set("user", signedIn) }
+
+	r := gin.New()
+	r.POST("/forms/:form_id/responses", authenticated, submitFormHandler(params))
+	r.GET("/forms/:form_id/responses/draft", authenticated, getFormResponseDraftHandler(params))
+	r.PUT("/forms/:form_id/responses/draft", authenticated, saveFormResponseDraftHandler(params))
+	r.DELETE("/forms/:form_id/responses/draft", authenticated, deleteFormResponseDraftHandler(params))
+
+	request := func(user *models.User, method string, body string) *httptest.ResponseRecorder {
+		signedIn = user
+		path := "/forms/" + m.form.ID.Hex() + "/responses"
+		if method != http.MethodPost {
+			path += "/draft"
+		}
+
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("a saved draft can be resumed", func(t *testing.T) {
+		resp := request(applicant, http.MethodPut, `{"name": "Ada", "decision": "Accepted", "removed": "gone"}`)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+		resp = request(applicant, http.MethodGet, "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Draft models.FormResponseDraft `json:"draft"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, map[string]interface{}{"name": "Ada"}, body.Draft.Data, "unknown and internal fields are left out")
+	})
+
+	t.Run("drafts expire after the TTL", func(t *testing.T) {
+		draft := m.drafts[applicant.ID]
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), draft.ExpiresAt, time.Minute)
+
+		draft.ExpiresAt = time.Now().Add(-time.Second)
+		m.drafts[applicant.ID] = draft
+		assert.Equal(t, http.StatusNotFound, request(applicant, http.MethodGet, "").Code)
+
+		// Saving again pushes it back
+		require.Equal(t, http.StatusOK, request(applicant, http.MethodPut, `{"name": "Ada"}`).Code)
+		assert.Equal(t, http.StatusOK, request(applicant, http.MethodGet, "").Code)
+	})
+
+	t.Run("users only see their own draft", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, request(other, http.MethodGet, "").Code)
+
+		require.Equal(t, http.StatusOK, request(other, http.MethodDelete, "").Code)
+		assert.Contains(t, m.drafts, applicant.ID, "deleting their own draft leaves the others")
+	})
+
+	t.Run("a draft can be deleted", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, request(other, http.MethodPut, `{"name": "Grace"}`).Code)
+		require.Equal(t, http.StatusOK, request(other, http.MethodDelete, "").Code)
+		assert.Equal(t, http.StatusNotFound, request(other, http.MethodGet, "").Code)
+	})
+
+	t.Run("submitting the response deletes the draft", func(t *testing.T) {
+		resp := request(applicant, http.MethodPost, `{"name": "Ada"}`)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		require.Len(t, m.responses, 1)
+		assert.NotContains(t, m.drafts, applicant.ID)
+	})
+}
@@ -0,0 +1,348 @@
+package responses
+
+import (
+	"api/internal/export"
+	"api/internal/types"
+	"fmt"
+	"mime"
+	"net/http"
+	"shared/config"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+Exports download the responses of a form as a CSV, XLSX, JSON Lines or Parquet file.
+
+Responses are read from a cursor and written out as they arrive, so a form's responses are never all held in memory.
+Tabular formats flatten structured values into text (see flatten.go), JSON Lines keeps them as they are stored.
+*/
+
+// exportBatchSize is how many responses are buffered to resolve their file links together
+const exportBatchSize = 500
+
+// exportColumn is a column of an export and how its value is read from a response
+type exportColumn struct {
+	column export.Column
+	value  func(response models.FormResponse) interface{}
+}
+
+// exportMetaColumns are the columns every response has, by the name they are selected with
+var exportMetaColumns = map[string]exportColumn{
+	"id": {
+		column: export.Column{Name: "Response ID", Type: export.ColumnString},
+		value:  func(response models.FormResponse) interface{} { return response.ID.Hex() },
+	},
+	"userid": {
+		column: export.Column{Name: "User ID", Type: export.ColumnString},
+		value: func(response models.FormResponse) interface{} {
+			if response.UserID.IsZero() {
+				// Anonymous response
+				return ""
+			}
+			return response.UserID.Hex()
+		},
+	},
+	"createdat": {
+		column: export.Column{Name: "Submitted At", Type: export.ColumnTime},
+		value:  func(response models.FormResponse) interface{} { return response.CreatedAt },
+	},
+	"lastupdatedat": {
+		column: export.Column{Name: "Last Updated At", Type: export.ColumnTime},
+		value:  func(response models.FormResponse) interface{} { return response.LastUpdatedAt },
+	},
+	"formversion": {
+		column: export.Column{Name: "Form Version", Type: export.ColumnNumber},
+		value: func(response models.FormResponse) interface{} {
+			if response.FormVersion == 0 {
+				// Submitted before the form was versioned or moved from another form
+				return nil
+			}
+			return response.FormVersion
+		},
+	},
+}
+
+var defaultExportMetaColumns = []string{"id", "userid", "createdat", "lastupdatedat"}
+
+// fieldColumnType returns the type a field's values are exported as, structured values are exported as text
+func fieldColumnType(field models.FormField) export.ColumnType {
+	switch field.Type {
+	case models.FormFieldTypeNumber, models.FormFieldTypeRating:
+		return export.ColumnNumber
+	case models.FormFieldTypeDate, models.FormFieldTypeTimestamp:
+		return export.ColumnTime
+	case models.FormFieldTypeCheckbox:
+		// Checkboxes with options hold the checked options instead
+		if len(field.Options) == 0 {
+			return export.ColumnBool
+		}
+	}
+
+	return export.ColumnString
+}
+
+// fieldExportColumns returns the export columns of a field, headed by its question
+func fieldExportColumns(field models.FormField, flatten bool) []exportColumn {
+	columns := []exportColumn{}
+	for _, column := range fieldColumns(field, flatten) {
+		column := column
+		columns = append(columns, exportColumn{
+			column: export.Column{Name: column.header, Type: fieldColumnType(field)},
+			value:  func(response models.FormResponse) interface{} { return column.value(response.Data) },
+		})
+	}
+	return columns
+}
+
+/*
+buildExportColumns returns the columns of an export in order.
+
+selection is a comma separated list of id, userID, createdAt, lastUpdatedAt, formVersion and fields named by their key or question,
+when it is empty every column is exported along with the given deleted field keys, typed by their definition in removedFields.
+*/
+func buildExportColumns(form *models.FormStructure, selection string, deletedKeys []string, removedFields []models.FormField, flatten bool) ([]exportColumn, error) {
+	columns := []exportColumn{}
+
+	if strings.TrimSpace(selection) == "" {
+		for _, name := range defaultExportMetaColumns {
+			columns = append(columns, exportMetaColumns[name])
+		}
+
+		fieldKeys := make(map[string]struct{})
+		for _, field := range form.Attrs {
+			columns = append(columns, fieldExportColumns(field, flatten)...)
+			fieldKeys[field.Key] = struct{}{}
+		}
+
+		for _, key := range deletedKeys {
+			if _, exists := fieldKeys[key]; exists {
+				continue
+			}
+			field, ok := deletedField(removedFields, key)
+			if !ok {
+				field = models.FormField{Question: fmt.Sprintf("%s (deleted)", key), Key: key}
+			}
+			columns = append(columns, fieldExportColumns(field, flatten)...)
+		}
+
+		return columns, nil
+	}
+
+	for _, name := range strings.Split(selection, ",") {
+		name = strings.Trim(strings.TrimSpace(name), "\"")
+		if name == "" {
+			continue
+		}
+
+		if column, ok := exportMetaColumns[strings.ToLower(name)]; ok {
+			columns = append(columns, column)
+			continue
+		}
+
+		_, field, err := resolveQueryField(form, name)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, fieldExportColumns(*field, flatten)...)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns selected")
+	}
+
+	return columns, nil
+}
+
+// negotiateExportFormat picks the export format from the format query param, then the Accept header, defaulting to CSV
+func negotiateExportFormat(formatParam string, accept string) (export.Format, error) {
+	if formatParam != "" {
+		return export.ParseFormat(formatParam)
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if format, err := export.ParseFormat(mediaType); err == nil {
+			return format, nil
+		}
+	}
+
+	return export.FormatCSV, nil
+}
+
+// exportFileName names the export after the form, keeping only characters that are safe in a file name
+func exportFileName(form *models.FormStructure, format export.Format) string {
+	var name strings.Builder
+	for _, r := range strings.TrimSpace(form.Name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			name.WriteRune(r)
+		case r == ' ':
+			name.WriteRune('_')
+		}
+	}
+
+	if name.Len() == 0 {
+		name.WriteString("form")
+	}
+
+	return fmt.Sprintf("%s_responses.%s", name.String(), format.Extension())
+}
+
+/*
+Export form responses
+
+params:
+  - form_id: ID of the form
+
+query params:
+  - format: csv, xlsx, ndjson or parquet, otherwise picked from the Accept header (default: csv)
+  - columns: comma separated columns to export in order, id, userID, createdAt, lastUpdatedAt, formVersion or fields by key or question (default: all)
+  - getDeletedColumnData: whether to include deleted column data when exporting all columns (default: false)
+  - q, filter, sort: only export the matching responses, in order (see query.go)
+*/
+func exportFormResponsesHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		getDeletedColumnData := c.DefaultQuery("getDeletedColumnData", "false")
+		getDeletedColumnDataBool := (getDeletedColumnData == "true")
+
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		form, err := params.MongoService.GetForm(c, formID, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+			return
+		}
+
+		format, err := negotiateExportFormat(c.Query("format"), c.GetHeader("Accept"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown export format, use csv, xlsx, ndjson or parquet"})
+			return
+		}
+
+		query, err := parseResponseQuery(form, c.Query("q"), c.Query("filter"), c.Query("sort"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var deletedKeys []string
+		var removedFields []models.FormField
+		if getDeletedColumnDataBool {
+			deletedKeys, err = params.MongoService.ListResponseDataKeys(c, formID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				logger.Error("Failed to list response data keys", err)
+				return
+			}
+
+			var ok bool
+			removedFields, ok = listRemovedFields(c, params, form)
+			if !ok {
+				return
+			}
+		}
+
+		// JSON Lines can hold structured values, so they are only flattened for the tabular formats
+		flatten := format != export.FormatNDJSON
+		columns, err := buildExportColumns(form, c.Query("columns"), deletedKeys, removedFields, flatten)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		apiConfig, err := config.GetAPIConfig()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		// Exports are shared around so the file links last longer than usual
+		expiresIn := time.Duration(apiConfig.STORAGE_EXPORT_URL_TTL_HOURS) * time.Hour
+
+		exportColumns := make([]export.Column, 0, len(columns))
+		for _, column := range columns {
+			exportColumns = append(exportColumns, column.column)
+		}
+
+		c.Writer.Header().Set("Content-Type", format.ContentType())
+		c.Writer.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": exportFileName(form, format)}))
+
+		writer, err := export.NewWriter(format, c.Writer, export.UniqueColumnNames(exportColumns))
+		if err != nil {
+			failExport(c, "Failed to start export", err)
+			return
+		}
+
+		batch := make([]models.FormResponse, 0, exportBatchSize)
+		writeBatch := func() error {
+			if err := resolveFileLinks(c, params, form, batch, expiresIn); err != nil {
+				return err
+			}
+
+			for _, response := range batch {
+				row := make([]interface{}, len(columns))
+				for i, column := range columns {
+					row[i] = column.value(response)
+				}
+				if err := writer.WriteRow(row); err != nil {
+					return err
+				}
+			}
+
+			batch = batch[:0]
+			return nil
+		}
+
+		err = params.MongoService.StreamResponses(c, formID, query, func(response models.FormResponse) error {
+			batch = append(batch, response)
+			if len(batch) < exportBatchSize {
+				return nil
+			}
+			return writeBatch()
+		})
+		if err == nil {
+			err = writeBatch()
+		}
+		if err != nil {
+			if err == mongodb.ErrInvalidResponseQuery && !c.Writer.Written() {
+				c.Writer.Header().Del("Content-Disposition")
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This query can't be used on this form"})
+				return
+			}
+			failExport(c, "Failed to export form responses", err)
+			return
+		}
+
+		if err := writer.Close(); err != nil {
+			failExport(c, "Failed to finish export", err)
+		}
+	}
+}
+
+// failExport reports an export error, once the file has started streaming the status can't change so the download is cut short instead
+func failExport(c *gin.Context, desc string, err error) {
+	logger.Error(desc, err)
+
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+
+	c.Writer.Header().Del("Content-Disposition")
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+}
@@ -0,0 +1,102 @@
+package responses
+
+import (
+	"api/internal/export"
+	"shared/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuildExportColumns(t *testing.T) {
+	form := &models.FormStructure{
+		Attrs: []models.FormField{
+			{Key: "name", Question: "Name", Type: models.FormFieldTypeText},
+			{Key: "age", Question: "Age", Type: models.FormFieldTypeNumber},
+			{Key: "agree", Question: "I agree", Type: models.FormFieldTypeCheckbox},
+			{Key: "matrix", Question: "Workshops", Type: models.FormFieldTypeMatrix, MatrixOptions: models.MatrixOptions{Rows: []string{"Go", "Rust"}, Columns: []string{"1", "2"}}},
+		},
+	}
+
+	names := func(columns []exportColumn) []string {
+		result := []string{}
+		for _, column := range columns {
+			result = append(result, column.column.Name)
+		}
+		return result
+	}
+
+	t.Run("All columns", func(t *testing.T) {
+		columns, err := buildExportColumns(form, "", []string{"name", "removed"}, nil, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Response ID", "User ID", "Submitted At", "Last Updated At", "Name", "Age", "I agree", "Workshops [Go]", "Workshops [Rust]", "removed (deleted)"}, names(columns))
+		assert.Equal(t, export.ColumnNumber, columns[5].column.Type)
+		assert.Equal(t, export.ColumnBool, columns[6].column.Type)
+	})
+
+	t.Run("Not flattened", func(t *testing.T) {
+		columns, err := buildExportColumns(form, "", nil, nil, false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Response ID", "User ID", "Submitted At", "Last Updated At", "Name", "Age", "I agree", "Workshops"}, names(columns))
+	})
+
+	t.Run("Selected columns", func(t *testing.T) {
+		columns, err := buildExportColumns(form, `age, "name", userID, createdAt`, nil, nil, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Age", "Name", "User ID", "Submitted At"}, names(columns))
+
+		response := models.FormResponse{ID: primitive.NewObjectID(), Data: map[string]interface{}{"age": 30, "name": "Ada"}}
+		assert.Equal(t, 30, columns[0].value(response))
+		assert.Equal(t, "Ada", columns[1].value(response))
+		assert.Equal(t, "", columns[2].value(response), "anonymous responses have no user")
+	})
+
+	t.Run("Removed fields", func(t *testing.T) {
+		removed := []models.FormField{{Key: "score", Question: "Score", Type: models.FormFieldTypeNumber}}
+		columns, err := buildExportColumns(form, "", []string{"score", "unknown"}, removed, true)
+		require.NoError(t, err)
+
+		last := columns[len(columns)-2:]
+		assert.Equal(t, []string{"Score (deleted)", "unknown (deleted)"}, names(last))
+		assert.Equal(t, export.ColumnNumber, last[0].column.Type, "removed fields keep their type")
+	})
+
+	t.Run("Form version", func(t *testing.T) {
+		columns, err := buildExportColumns(form, "id,formVersion", nil, nil, true)
+		require.NoError(t, err)
+		assert.Equal(t, 3, columns[1].value(models.FormResponse{FormVersion: 3}))
+		assert.Nil(t, columns[1].value(models.FormResponse{}))
+	})
+
+	t.Run("Unknown column", func(t *testing.T) {
+		_, err := buildExportColumns(form, "name,nope", nil, nil, true)
+		assert.Error(t, err)
+
+		_, err = buildExportColumns(form, " , ", nil, nil, true)
+		assert.Error(t, err)
+	})
+}
+
+func TestNegotiateExportFormat(t *testing.T) {
+	format, err := negotiateExportFormat("xlsx", "application/x-ndjson")
+	require.NoError(t, err)
+	assert.Equal(t, export.FormatXLSX, format)
+
+	format, err = negotiateExportFormat("", "text/html, application/x-ndjson;q=0.9")
+	require.NoError(t, err)
+	assert.Equal(t, export.FormatNDJSON, format)
+
+	format, err = negotiateExportFormat("", "*/*")
+	require.NoError(t, err)
+	assert.Equal(t, export.FormatCSV, format)
+
+	_, err = negotiateExportFormat("pdf", "")
+	assert.ErrorIs(t, err, export.ErrUnknownFormat)
+}
+
+func TestExportFileName(t *testing.T) {
+	assert.Equal(t, "Spring_Hackathon_2024_responses.xlsx", exportFileName(&models.FormStructure{Name: "Spring Hackathon: 2024!"}, export.FormatXLSX))
+	assert.Equal(t, "form_responses.csv", exportFileName(&models.FormStructure{Name: "\"/\\"}, export.FormatCSV))
+}
@@ -0,0 +1,470 @@
+package responses
+
+import (
+	"api/internal/middlewares"
+	"api/internal/types"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"shared/config"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+Files are uploaded for a "file" field before the response is submitted.
+
+The upload endpoint stores the file and returns its ID, the client then submits the
+list of IDs as the value of the field. On submission the uploads are checked to belong
+to the submitting user and field and are attached to the response.
+*/
+
+var errInvalidFileUpload = errors.New("one or more uploaded files are invalid, please upload them again")
+
+func RegisterFormFileRoutes(r *gin.RouterGroup, params *types.RouteParams) {
+	r.POST("", middlewares.JWTAuthMiddleware(params.MongoService), uploadFormFileHandler(params))
+}
+
+func uploadFormFileHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		form, err := params.MongoService.GetForm(c, formID, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+			return
+		}
+
+		if form.Status != "published" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form is not published, if you believe this is an error message the event admins"})
+			return
+		}
+
+		if !form.CloseSubmissionsAt.IsZero() && form.CloseSubmissionsAt.Before(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form submissions are closed"})
+			return
+		}
+
+		if !form.OpenSubmissionsAt.IsZero() && form.OpenSubmissionsAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form submissions are not open yet"})
+			return
+		}
+
+		if form.IsRestricted {
+			allowed, restrictMessage := mongodb.IsUserEmailInWhitelist(c, params.MongoService, form.AllowedSubmitters)
+			if !allowed {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": restrictMessage})
+				return
+			}
+		}
+
+		apiConfig, err := config.GetAPIConfig()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		// Cap the request body before it is parsed, the extra megabyte leaves room for the multipart envelope
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, apiConfig.STORAGE_MAX_UPLOAD_BYTES+1<<20)
+
+		fieldKey := c.PostForm("fieldKey")
+		var field *models.FormField
+		for i := range form.Attrs {
+			if form.Attrs[i].Key == fieldKey {
+				field = &form.Attrs[i]
+				break
+			}
+		}
+
+		if field == nil || field.Type != "file" || field.IsInternal {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field key, form may have just changed"})
+			return
+		}
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A file must be uploaded in the file form field"})
+			return
+		}
+
+		maxSize := apiConfig.STORAGE_MAX_UPLOAD_BYTES
+		if field.AdditionalValidation.MaxFileSizeBytes > 0 && field.AdditionalValidation.MaxFileSizeBytes < maxSize {
+			maxSize = field.AdditionalValidation.MaxFileSizeBytes
+		}
+
+		if fileHeader.Size > maxSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File is too large, the maximum size is %d bytes", maxSize)})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+
+		contentType, err := detectContentType(file, fileHeader.Size, fileHeader.Header.Get("Content-Type"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+
+		if !isMimeTypeAllowed(contentType, field.AdditionalValidation.AllowedMimeTypes) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Files of type %s are not allowed for this field", contentType)})
+			return
+		}
+
+		upload := models.FileUpload{
+			ID:          primitive.NewObjectID(),
+			FormID:      formID,
+			FieldKey:    field.Key,
+			UserID:      authenticatedUser.ID,
+			FileName:    filepath.Base(fileHeader.Filename),
+			ContentType: contentType,
+			Size:        fileHeader.Size,
+		}
+		upload.StorageKey = fmt.Sprintf("forms/%s/%s/%s", formID.Hex(), field.Key, upload.ID.Hex())
+
+		if err := params.ObjectStorage.Put(c, upload.StorageKey, file, fileHeader.Size, contentType); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to store uploaded file", err)
+			return
+		}
+
+		if _, err := params.MongoService.CreateFileUpload(c, upload); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to create file upload", err)
+
+			if err := params.ObjectStorage.Delete(c, upload.StorageKey); err != nil {
+				logger.Error("Failed to delete orphaned uploaded file", err)
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"file": upload})
+	}
+}
+
+/*
+Get a signed download URL for a file attached to a response
+
+params:
+  - form_id: ID of the form
+  - response_id: ID of the response
+  - file_id: ID of the file upload
+*/
+func getFormResponseFileHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		responseID, err := primitive.ObjectIDFromHex(c.Param("response_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid response ID"})
+			return
+		}
+
+		fileID, err := primitive.ObjectIDFromHex(c.Param("file_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+			return
+		}
+
+		uploads, err := params.MongoService.ListFileUploads(c, bson.M{"_id": fileID, "formID": formID, "responseID": responseID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list file uploads", err)
+			return
+		}
+
+		if len(uploads) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File does not exist"})
+			return
+		}
+
+		apiConfig, err := config.GetAPIConfig()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get API config", err)
+			return
+		}
+
+		expiresIn := time.Duration(apiConfig.STORAGE_SIGNED_URL_TTL_MINUTES) * time.Minute
+		url, err := params.ObjectStorage.SignedURL(c, uploads[0].StorageKey, uploads[0].FileName, expiresIn)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to sign file download URL", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"file": uploads[0], "url": url, "expiresAt": time.Now().Add(expiresIn)})
+	}
+}
+
+// validateSubmittedFileUploads checks that every file referenced in the form data was uploaded by the user
+// for that field and is not already attached to another response, it returns the IDs to attach.
+// responseID is the response being edited, or the zero ID for a new response
+func validateSubmittedFileUploads(c context.Context, params *types.RouteParams, form *models.FormStructure, formData map[string]interface{}, userID primitive.ObjectID, responseID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	fieldKeyByUploadID := make(map[primitive.ObjectID]string)
+	for _, field := range form.Attrs {
+		if field.Type != "file" {
+			continue
+		}
+
+		for _, uploadID := range fileUploadIDs(formData[field.Key]) {
+			fieldKeyByUploadID[uploadID] = field.Key
+		}
+	}
+
+	if len(fieldKeyByUploadID) == 0 {
+		return nil, nil
+	}
+
+	uploadIDs := make([]primitive.ObjectID, 0, len(fieldKeyByUploadID))
+	for uploadID := range fieldKeyByUploadID {
+		uploadIDs = append(uploadIDs, uploadID)
+	}
+
+	uploads, err := params.MongoService.ListFileUploads(c, bson.M{"_id": bson.M{"$in": uploadIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[primitive.ObjectID]struct{})
+	for _, upload := range uploads {
+		if upload.FormID != form.ID || upload.UserID != userID || upload.FieldKey != fieldKeyByUploadID[upload.ID] || (!upload.ResponseID.IsZero() && upload.ResponseID != responseID) {
+			return nil, errInvalidFileUpload
+		}
+		found[upload.ID] = struct{}{}
+	}
+
+	if len(found) != len(uploadIDs) {
+		return nil, errInvalidFileUpload
+	}
+
+	return uploadIDs, nil
+}
+
+// resolveFileLinks replaces the file IDs of file fields with signed download links, for exports, it can be called on each batch of a streamed export
+func resolveFileLinks(c context.Context, params *types.RouteParams, form *models.FormStructure, responses []models.FormResponse, expiresIn time.Duration) error {
+	fileFields := []string{}
+	for _, field := range form.Attrs {
+		if field.Type == "file" {
+			fileFields = append(fileFields, field.Key)
+		}
+	}
+
+	if len(fileFields) == 0 {
+		return nil
+	}
+
+	responseIDs := make([]primitive.ObjectID, 0, len(responses))
+	for _, response := range responses {
+		responseIDs = append(responseIDs, response.ID)
+	}
+
+	uploads, err := params.MongoService.ListFileUploads(c, bson.M{"formID": form.ID, "responseID": bson.M{"$in": responseIDs}})
+	if err != nil {
+		return err
+	}
+
+	uploadsByID := make(map[primitive.ObjectID]models.FileUpload)
+	for _, upload := range uploads {
+		uploadsByID[upload.ID] = upload
+	}
+
+	for _, response := range responses {
+		for _, key := range fileFields {
+			value, exists := response.Data[key]
+			if !exists {
+				continue
+			}
+
+			links := []string{}
+			for _, uploadID := range fileUploadIDs(value) {
+				upload, ok := uploadsByID[uploadID]
+				if !ok || upload.ResponseID != response.ID {
+					continue
+				}
+
+				link, err := params.ObjectStorage.SignedURL(c, upload.StorageKey, upload.FileName, expiresIn)
+				if err != nil {
+					return err
+				}
+				links = append(links, link)
+			}
+
+			response.Data[key] = strings.Join(links, " ")
+		}
+	}
+
+	return nil
+}
+
+// fileUploadIDs extracts the upload IDs from the value of a file field, invalid entries are skipped
+func fileUploadIDs(value interface{}) []primitive.ObjectID {
+	var ids []primitive.ObjectID
+
+	var values []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		values = v
+	case primitive.A:
+		values = v
+	default:
+		return ids
+	}
+
+	for _, raw := range values {
+		idStr, ok := raw.(string)
+		if !ok {
+			continue
+		}
+
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// zipContainerTypes are the formats stored as zip archives that a client may declare, with the entry only their archives have
+var zipContainerTypes = map[string]string{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   "word/document.xml",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         "xl/workbook.xml",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": "ppt/presentation.xml",
+}
+
+// openDocumentTypes are stored as zip archives whose first entry, named mimetype, holds their content type
+var openDocumentTypes = map[string]bool{
+	"application/vnd.oasis.opendocument.text":         true,
+	"application/vnd.oasis.opendocument.spreadsheet":  true,
+	"application/vnd.oasis.opendocument.presentation": true,
+}
+
+// compoundFileTypes are the legacy office formats stored as OLE compound files, which all start with compoundFileSignature
+var compoundFileTypes = map[string]bool{
+	"application/msword":            true,
+	"application/vnd.ms-excel":      true,
+	"application/vnd.ms-powerpoint": true,
+}
+
+var compoundFileSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// uploadedFile is what detectContentType needs of a multipart.File
+type uploadedFile interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+}
+
+// detectContentType sniffs the content type of the file. Sniffing can't tell apart the formats stored in containers
+// (eg: office documents are zip archives), so the type declared by the client is used for those, but only once the
+// contents of the file are checked to be in that format
+func detectContentType(file uploadedFile, size int64, declared string) (string, error) {
+	buf := make([]byte, 512)
+	n, err := file.Read(buf)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	declared, _, _ = mime.ParseMediaType(declared)
+
+	switch sniffed {
+	case "application/zip":
+		if isZipContainer(file, size, declared) {
+			return declared, nil
+		}
+	case "application/octet-stream":
+		if compoundFileTypes[declared] && bytes.HasPrefix(buf[:n], compoundFileSignature) {
+			return declared, nil
+		}
+	}
+
+	return sniffed, nil
+}
+
+// isZipContainer checks that the zip archive holds a file of the declared format
+func isZipContainer(file io.ReaderAt, size int64, declared string) bool {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return false
+	}
+
+	if entry, ok := zipContainerTypes[declared]; ok {
+		for _, f := range archive.File {
+			if f.Name == entry {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !openDocumentTypes[declared] || len(archive.File) == 0 || archive.File[0].Name != "mimetype" {
+		return false
+	}
+
+	entry, err := archive.File[0].Open()
+	if err != nil {
+		return false
+	}
+	defer entry.Close()
+
+	contentType, err := io.ReadAll(io.LimitReader(entry, 128))
+	return err == nil && string(contentType) == declared
+}
+
+// isMimeTypeAllowed checks the content type against the allowed list, which supports wildcards like image/*
+func isMimeTypeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, pattern := range allowed {
+		if pattern == contentType {
+			return true
+		}
+
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+package responses
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectContentType(t *testing.T) {
+	const (
+		docx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+		xlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		odt  = "application/vnd.oasis.opendocument.text"
+	)
+
+	zipFile := func(entries ...string) []byte {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for i := 0; i+1 < len(entries); i += 2 {
+			f, err := w.Create(entries[i])
+			require.NoError(t, err)
+			_, err = f.Write([]byte(entries[i+1]))
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+
+	compoundFile := append(append([]byte{}, compoundFileSignature...), make([]byte, 504)...)
+
+	tests := []struct {
+		name     string
+		content  []byte
+		declared string
+		expected string
+	}{
+		{"sniffed type wins", []byte("%PDF-1.7\n"), "image/png", "application/pdf"},
+		{"word document", zipFile("[Content_Types].xml", "<Types/>", "word/document.xml", "<w:document/>"), docx, docx},
+		{"spreadsheet declared as a word document", zipFile("[Content_Types].xml", "<Types/>", "xl/workbook.xml", "<workbook/>"), docx, "application/zip"},
+		{"spreadsheet", zipFile("xl/workbook.xml", "<workbook/>"), xlsx, xlsx},
+		{"zip declared as an allowed type", zipFile("payload.exe", "MZ"), "application/pdf", "application/zip"},
+		{"opendocument", zipFile("mimetype", odt, "content.xml", "<office:document-content/>"), odt, odt},
+		{"opendocument of another type", zipFile("mimetype", "application/vnd.oasis.opendocument.spreadsheet"), odt, "application/zip"},
+		{"legacy word document", compoundFile, "application/msword", "application/msword"},
+		{"binary declared as a legacy word document", make([]byte, 512), "application/msword", "application/octet-stream"},
+		{"binary declared as an allowed type", []byte{0x7F, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, "application/pdf", "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := bytes.NewReader(tt.content)
+			contentType, err := detectContentType(file, int64(len(tt.content)), tt.declared)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, contentType)
+
+			offset, _ := file.Seek(0, io.SeekCurrent)
+			assert.Zero(t, offset, "the file is read again from the start when it is stored")
+		})
+	}
+}
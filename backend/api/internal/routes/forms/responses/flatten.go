@@ -0,0 +1,147 @@
+package responses
+
+import (
+	"fmt"
+	"shared/models"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+Flattening turns structured field values into plain cells for tabular views like the CSV export.
+
+Most values are kept as is, lists are joined and the structured field types get their own layout:
+  - matrix: one column per row, holding the answer for that row
+  - ranking: the options in ranked order, eg: "A > B > C"
+  - address: the filled in parts of the address joined by commas
+*/
+
+// responseColumn is a column of the processed responses and how its value is read from the response data
+type responseColumn struct {
+	name   string
+	header string // the human readable name used by exports
+	value  func(data map[string]interface{}) interface{}
+}
+
+// fieldColumns returns the columns a field is shown in
+func fieldColumns(field models.FormField, flatten bool) []responseColumn {
+	name := field.Question + "_attr_key:" + field.Key
+	if !flatten {
+		return []responseColumn{{name: name, header: field.Question, value: rawValue(field.Key)}}
+	}
+
+	switch field.Type {
+	case models.FormFieldTypeMatrix:
+		columns := []responseColumn{}
+		for _, row := range field.MatrixOptions.Rows {
+			row := row
+			columns = append(columns, responseColumn{
+				name:   fmt.Sprintf("%s [%s]_attr_key:%s", field.Question, row, field.Key),
+				header: fmt.Sprintf("%s [%s]", field.Question, row),
+				value: func(data map[string]interface{}) interface{} {
+					answers, ok := asMap(data[field.Key])
+					if !ok {
+						return ""
+					}
+					return flattenValue(answers[row])
+				},
+			})
+		}
+		return columns
+	case models.FormFieldTypeRanking:
+		return []responseColumn{{name: name, header: field.Question, value: func(data map[string]interface{}) interface{} {
+			ranked, ok := asList(data[field.Key])
+			if !ok {
+				return flattenValue(data[field.Key])
+			}
+			return joinValues(ranked, " > ")
+		}}}
+	case models.FormFieldTypeAddress:
+		return []responseColumn{{name: name, header: field.Question, value: func(data map[string]interface{}) interface{} {
+			address, ok := asMap(data[field.Key])
+			if !ok {
+				return flattenValue(data[field.Key])
+			}
+			return formatAddress(address)
+		}}}
+	default:
+		return []responseColumn{{name: name, header: field.Question, value: func(data map[string]interface{}) interface{} {
+			return flattenValue(data[field.Key])
+		}}}
+	}
+}
+
+// rawValue reads the value of a field as it was stored
+func rawValue(key string) func(data map[string]interface{}) interface{} {
+	return func(data map[string]interface{}) interface{} {
+		value, exists := data[key]
+		if !exists {
+			return ""
+		}
+		return value
+	}
+}
+
+// flattenValue joins lists so they read naturally in a single cell
+func flattenValue(value interface{}) interface{} {
+	if value == nil {
+		return ""
+	}
+
+	if list, ok := asList(value); ok {
+		return joinValues(list, ", ")
+	}
+
+	return value
+}
+
+func formatAddress(address map[string]interface{}) string {
+	parts := []string{}
+	for _, key := range []string{"streetAddress", "city", "region", "zipCode", "country"} {
+		if part, ok := address[key].(string); ok && part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func joinValues(values []interface{}, separator string) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		parts = append(parts, fmt.Sprintf("%v", value))
+	}
+	return strings.Join(parts, separator)
+}
+
+// asMap reads an embedded document, which decodes differently depending on whether it came from JSON or MongoDB
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case primitive.M:
+		return v, true
+	case primitive.D:
+		return v.Map(), true
+	default:
+		return nil, false
+	}
+}
+
+// asList reads an array, which decodes differently depending on whether it came from JSON or MongoDB
+func asList(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case primitive.A:
+		return v, true
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,69 @@
+package responses
+
+import (
+	"shared/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProcessResponsesFlatten(t *testing.T) {
+	form := &models.FormStructure{
+		Attrs: []models.FormField{
+			{Key: "rating", Question: "Rating", Type: models.FormFieldTypeRating},
+			{Key: "matrix", Question: "Workshops", Type: models.FormFieldTypeMatrix, MatrixOptions: models.MatrixOptions{Rows: []string{"Go", "Rust"}, Columns: []string{"1", "2", "3"}}},
+			{Key: "ranking", Question: "Ranking", Type: models.FormFieldTypeRanking, Options: []string{"a", "b", "c"}},
+			{Key: "address", Question: "Address", Type: models.FormFieldTypeAddress},
+			{Key: "skills", Question: "Skills", Type: models.FormFieldTypeMultiSelect},
+		},
+	}
+
+	// Values as they are decoded from MongoDB
+	responses := []models.FormResponse{
+		{
+			ID: primitive.NewObjectID(),
+			Data: map[string]interface{}{
+				"rating":  int32(4),
+				"matrix":  bson.D{{Key: "Go", Value: "3"}},
+				"ranking": bson.A{"c", "a", "b"},
+				"address": bson.D{{Key: "streetAddress", Value: "1 Main St"}, {Key: "city", Value: "Springfield"}, {Key: "region", Value: ""}, {Key: "country", Value: "US"}},
+				"skills":  bson.A{"go", "sql"},
+				"removed": "old value",
+			},
+		},
+	}
+
+	t.Run("Flattened", func(t *testing.T) {
+		rows, columnOrder := processResponses(form, &responses, nil, true, true)
+
+		assert.Equal(t, []string{
+			"Response ID", "User ID", "Submitted At", "Last Updated At",
+			"Rating_attr_key:rating",
+			"Workshops [Go]_attr_key:matrix",
+			"Workshops [Rust]_attr_key:matrix",
+			"Ranking_attr_key:ranking",
+			"Address_attr_key:address",
+			"Skills_attr_key:skills",
+			"deleted column_attr_key:removed",
+		}, columnOrder)
+
+		row := rows[1]
+		assert.Equal(t, int32(4), row["Rating_attr_key:rating"])
+		assert.Equal(t, "3", row["Workshops [Go]_attr_key:matrix"])
+		assert.Equal(t, "", row["Workshops [Rust]_attr_key:matrix"])
+		assert.Equal(t, "c > a > b", row["Ranking_attr_key:ranking"])
+		assert.Equal(t, "1 Main St, Springfield, US", row["Address_attr_key:address"])
+		assert.Equal(t, "go, sql", row["Skills_attr_key:skills"])
+		assert.Equal(t, "old value", row["deleted column_attr_key:removed"])
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		rows, columnOrder := processResponses(form, &responses, nil, false, false)
+
+		assert.Len(t, columnOrder, 4+len(form.Attrs))
+		assert.Equal(t, responses[0].Data["matrix"], rows[1]["Workshops_attr_key:matrix"])
+		assert.Equal(t, responses[0].Data["ranking"], rows[1]["Ranking_attr_key:ranking"])
+	})
+}
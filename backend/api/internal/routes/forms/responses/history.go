@@ -0,0 +1,90 @@
+package responses
+
+import (
+	"api/internal/types"
+	"context"
+	"net/http"
+	"shared/logger"
+	"shared/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// responseChangeActor is who made a change to a response, as shown to organizers
+type responseChangeActor struct {
+	ID        primitive.ObjectID `json:"id"`
+	FirstName string             `json:"firstName"`
+	LastName  string             `json:"lastName"`
+	Email     string             `json:"email"`
+}
+
+// responseHistoryEntry is a change with its actor, the actor is left out when their account no longer exists
+type responseHistoryEntry struct {
+	models.ResponseChange
+	Actor *responseChangeActor `json:"actor,omitempty"`
+}
+
+// withActors looks up the actor of each change once, however many changes they made
+func withActors(c context.Context, params *types.RouteParams, history []models.ResponseChange) ([]responseHistoryEntry, error) {
+	actors := make(map[primitive.ObjectID]*responseChangeActor)
+	entries := make([]responseHistoryEntry, 0, len(history))
+	for _, change := range history {
+		actor, seen := actors[change.ActorID]
+		if !seen && !change.ActorID.IsZero() {
+			user, err := params.MongoService.GetUserDetails(c, change.ActorID)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return nil, err
+			}
+			if user != nil {
+				actor = &responseChangeActor{ID: user.ID, FirstName: user.FirstName, LastName: user.LastName, Email: user.Email}
+			}
+			actors[change.ActorID] = actor
+		}
+
+		entries = append(entries, responseHistoryEntry{ResponseChange: change, Actor: actor})
+	}
+
+	return entries, nil
+}
+
+/*
+Get the history of a response, newest first. Each change lists the old and new value of the fields it changed,
+including internal fields, along with who made it and how
+
+params:
+  - form_id: ID of the form
+  - response_id: ID of the response
+*/
+func getFormResponseHistoryHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		responseID, err := primitive.ObjectIDFromHex(c.Param("response_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid response ID"})
+			return
+		}
+
+		history, err := params.MongoService.ListResponseHistory(c, formID, responseID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list form response history", err)
+			return
+		}
+
+		entries, err := withActors(c, params, history)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get the actors of form response changes", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"history": entries})
+	}
+}
@@ -0,0 +1,54 @@
+package responses
+
+import (
+	"api/internal/types"
+	"context"
+	"shared/models"
+	"shared/mongodb"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// historyMongo serves users from memory, only the user lookup of the service is implemented
+type historyMongo struct {
+	mongodb.MongoService
+	users   map[primitive.ObjectID]models.User
+	lookups int
+}
+
+func (m *historyMongo) GetUserDetails(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	m.lookups++
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &user, nil
+}
+
+func TestWithActors(t *testing.T) {
+	organizer := models.User{ID: primitive.NewObjectID(), FirstName: "Grace", LastName: "Hopper", Email: "grace@example.com"}
+	deleted := primitive.NewObjectID()
+	mongo := &historyMongo{users: map[primitive.ObjectID]models.User{organizer.ID: organizer}}
+
+	history := []models.ResponseChange{
+		{ActorID: organizer.ID, Source: models.ResponseChangeOrganizerEdit},
+		{ActorID: deleted, Source: models.ResponseChangeApplicantEdit},
+		{ActorID: organizer.ID, Source: models.ResponseChangeBulkEdit},
+		{Source: models.ResponseChangeResubmission},
+	}
+
+	entries, err := withActors(context.Background(), &types.RouteParams{MongoService: mongo}, history)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, "grace@example.com", entries[0].Actor.Email)
+	assert.Nil(t, entries[1].Actor, "the actor's account was deleted")
+	assert.Same(t, entries[0].Actor, entries[2].Actor)
+	assert.Nil(t, entries[3].Actor)
+	assert.Equal(t, models.ResponseChangeBulkEdit, entries[2].Source)
+	assert.Equal(t, 2, mongo.lookups, "each actor is looked up once")
+}
@@ -0,0 +1,552 @@
+package responses
+
+import (
+	"api/internal/imports"
+	"api/internal/routes/forms/responses/validators"
+	"api/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"shared/formresponses"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/triggers"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+Imports bring responses from another system or a partner's spreadsheet into a form, from a CSV or XLSX file.
+
+Each column is mapped to the field it fills, by default the mapping is suggested by matching the column names with
+the field questions. Every row is validated like a submission and the import is all or nothing, so organizers run
+a dry run first which reports the errors of each row. Imported responses have no user and count against the
+event's monthly response limit like any other response.
+
+Address, matrix and file fields can't be imported as they don't fit in a single cell.
+*/
+
+const (
+	maxImportFileSize = 10 << 20
+	maxImportRows     = 5000
+
+	// maxReportedImportErrors bounds the report, a file in the wrong layout would otherwise fail every cell
+	maxReportedImportErrors = 500
+
+	// importCreatedAtTarget maps a column to the time the response was originally submitted
+	importCreatedAtTarget = "createdAt"
+
+	// importDateFormat is how submissions store dates, see validators.validateDate
+	importDateFormat = "2006-01-02T15:04:05.000Z"
+)
+
+// importRowError is a problem with a row of an import, row is the row number in the spreadsheet
+type importRowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Field  string `json:"field,omitempty"`
+	Error  string `json:"error"`
+}
+
+// importReport describes what an import does, or would do on a dry run
+type importReport struct {
+	Columns    []string          `json:"columns"`
+	Mapping    map[string]string `json:"mapping"` // column name to field key
+	Rows       int               `json:"rows"`
+	ValidRows  int               `json:"validRows"`
+	ErrorCount int               `json:"errorCount"`
+	Errors     []importRowError  `json:"errors"`
+}
+
+func (r *importReport) addError(err importRowError) {
+	r.ErrorCount++
+	if len(r.Errors) < maxReportedImportErrors {
+		r.Errors = append(r.Errors, err)
+	}
+}
+
+// importedResponse is a valid row of an import
+type importedResponse struct {
+	row      int
+	response models.FormResponse
+	claims   []string
+}
+
+// isImportableField reports whether a field's value can be read from a single cell
+func isImportableField(field models.FormField) bool {
+	switch field.Type {
+	case models.FormFieldTypeAddress, models.FormFieldTypeMatrix, models.FormFieldTypeFile:
+		return false
+	}
+	return true
+}
+
+func normalizeImportName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// suggestImportMapping maps the columns named like a field's question or key, and the submission time of exports, to it
+func suggestImportMapping(form *models.FormStructure, columns []string) map[string]string {
+	mapping := make(map[string]string)
+	mapped := make(map[string]bool)
+
+	for _, column := range columns {
+		name := normalizeImportName(column)
+		if name == "" {
+			continue
+		}
+
+		target := ""
+		if name == "submitted at" || name == "createdat" {
+			target = importCreatedAtTarget
+		}
+		for _, field := range form.Attrs {
+			if target == "" && isImportableField(field) && (normalizeImportName(field.Question) == name || strings.ToLower(field.Key) == name) {
+				target = field.Key
+			}
+		}
+
+		// The first column named like a field wins, the others are left for the organizer to map
+		if target != "" && !mapped[target] {
+			mapping[column] = target
+			mapped[target] = true
+		}
+	}
+
+	return mapping
+}
+
+// checkImportMapping checks the organizer's mapping only uses columns of the file and fills each importable field once
+func checkImportMapping(form *models.FormStructure, columns []string, mapping map[string]string) error {
+	known := make(map[string]bool)
+	for _, column := range columns {
+		known[column] = true
+	}
+
+	mapped := make(map[string]string)
+	for column, target := range mapping {
+		if !known[column] {
+			return fmt.Errorf("the file has no column \"%s\"", column)
+		}
+
+		if previous, ok := mapped[target]; ok {
+			return fmt.Errorf("the columns \"%s\" and \"%s\" are both mapped to %s", previous, column, target)
+		}
+		mapped[target] = column
+
+		if target == importCreatedAtTarget {
+			continue
+		}
+
+		field := findField(form, target)
+		if field == nil {
+			return fmt.Errorf("the column \"%s\" is mapped to %s which isn't a field of the form", column, target)
+		}
+		if !isImportableField(*field) {
+			return fmt.Errorf("%s fields like \"%s\" can't be imported", field.Type, field.Question)
+		}
+	}
+
+	return nil
+}
+
+func findField(form *models.FormStructure, key string) *models.FormField {
+	for i, field := range form.Attrs {
+		if field.Key == key {
+			return &form.Attrs[i]
+		}
+	}
+	return nil
+}
+
+// splitImportList splits a cell holding several values, as exports join them
+func splitImportList(text string, separator string) []interface{} {
+	values := []interface{}{}
+	for _, part := range strings.Split(text, separator) {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// parseImportTime reads the dates and timestamps spreadsheets hold
+func parseImportTime(text string) (time.Time, error) {
+	for _, layout := range []string{importDateFormat, time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("\"%s\" is not a date, use YYYY-MM-DD", text)
+}
+
+// parseImportValue converts the text of a cell to the value the field stores
+func parseImportValue(field models.FormField, text string) (interface{}, error) {
+	switch field.Type {
+	case models.FormFieldTypeNumber, models.FormFieldTypeRating:
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("\"%s\" is not a number", text)
+		}
+		return number, nil
+	case models.FormFieldTypeCheckbox:
+		if len(field.Options) > 0 {
+			return splitImportList(text, ","), nil
+		}
+		switch strings.ToLower(text) {
+		case "yes", "y", "x", "checked":
+			return true, nil
+		case "no", "n", "unchecked":
+			return false, nil
+		}
+		checked, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("\"%s\" is not yes or no", text)
+		}
+		return checked, nil
+	case models.FormFieldTypeMultiSelect, models.FormFieldTypeCustomMultiSelect:
+		return splitImportList(text, ","), nil
+	case models.FormFieldTypeRanking:
+		return splitImportList(text, ">"), nil
+	case models.FormFieldTypeDate, models.FormFieldTypeTimestamp:
+		t, err := parseImportTime(text)
+		if err != nil {
+			return nil, err
+		}
+		return t.UTC().Format(importDateFormat), nil
+	}
+
+	return text, nil
+}
+
+/*
+readImportRows turns the rows of the table into responses, reporting the rows that are invalid.
+
+Rows are validated like submissions, except organizers may fill internal fields. Unique fields are checked between
+the rows of the file here, and against the form's existing responses by the caller.
+*/
+func readImportRows(form *models.FormStructure, table *imports.Table, mapping map[string]string, report *importReport) []importedResponse {
+	columnIndex := make(map[string]int)
+	for i, column := range table.Header {
+		columnIndex[column] = i
+	}
+
+	fieldColumn := make(map[string]string)
+	for column, target := range mapping {
+		fieldColumn[target] = column
+	}
+
+	claimRows := make(map[string]int)
+	rows := []importedResponse{}
+	for i, cells := range table.Rows {
+		if imports.IsEmptyRow(cells) {
+			continue
+		}
+		report.Rows++
+
+		rowNumber := table.RowNumber(i)
+		valid := true
+		rowError := func(column string, field string, message string) {
+			report.addError(importRowError{Row: rowNumber, Column: column, Field: field, Error: message})
+			valid = false
+		}
+
+		response := models.FormResponse{FormID: form.ID, Data: map[string]interface{}{}, CreatedAt: time.Now(), FormVersion: form.Version}
+		if column, ok := fieldColumn[importCreatedAtTarget]; ok {
+			if text := strings.TrimSpace(cells[columnIndex[column]]); text != "" {
+				createdAt, err := parseImportTime(text)
+				if err != nil {
+					rowError(column, "", err.Error())
+				}
+				response.CreatedAt = createdAt
+			}
+		}
+
+		for _, field := range form.Attrs {
+			column, mapped := fieldColumn[field.Key]
+
+			var value interface{}
+			if mapped {
+				if text := strings.TrimSpace(cells[columnIndex[column]]); text != "" {
+					parsed, err := parseImportValue(field, text)
+					if err != nil {
+						rowError(column, field.Key, fmt.Sprintf("%s: %s", field.Question, err.Error()))
+						continue
+					}
+					value = parsed
+				}
+			}
+
+			// Organizers fill in internal fields themselves, so they are checked like any other but never required
+			checked := field
+			if checked.IsInternal {
+				checked.IsInternal = false
+				checked.Required = false
+			}
+			if err := validators.ValidateResponse(value, checked); err != nil {
+				rowError(column, field.Key, err.Error())
+				continue
+			}
+
+			if value != nil {
+				response.Data[field.Key] = value
+			}
+		}
+
+		claims := formresponses.Claims(form, primitive.NilObjectID, response.Data)
+		for _, claim := range claims {
+			if first, ok := claimRows[claim]; ok {
+				key := formresponses.ClaimFieldKey(claim)
+				field := findField(form, key)
+				rowError(fieldColumn[key], key, fmt.Sprintf("Row %d has the same value for \"%s\"", first, field.Question))
+				continue
+			}
+			claimRows[claim] = rowNumber
+		}
+
+		if valid {
+			rows = append(rows, importedResponse{row: rowNumber, response: response, claims: claims})
+		}
+	}
+
+	return rows
+}
+
+/*
+Import responses from a CSV or XLSX file
+
+params:
+  - form_id: ID of the form
+
+multipart form:
+  - file: the CSV or XLSX file, the first row names the columns
+  - mapping: optional JSON object of column names to the field keys they fill, or createdAt, suggested from the column names when left out
+
+query params:
+  - dryRun: only validate the file and report the errors of each row (default: false)
+  - triggerPipelines: trigger the form's FormSubmission pipelines for each imported response (default: false)
+*/
+func importFormResponsesHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dryRun := c.DefaultQuery("dryRun", "false") == "true"
+		triggerPipelines := c.DefaultQuery("triggerPipelines", "false") == "true"
+
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		form, err := params.MongoService.GetForm(c, formID, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+			return
+		}
+
+		if form.Capacity.Limit > 0 {
+			// Admissions are RSVP'd to by the respondent's account, which imported responses don't have
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Responses can't be imported into forms with a capacity"})
+			return
+		}
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A CSV or XLSX file must be uploaded in the file form field"})
+			return
+		}
+
+		if fileHeader.Size > maxImportFileSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File is too large, the maximum size is %d bytes", maxImportFileSize)})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+
+		table, err := imports.ReadTable(fileHeader.Filename, data, maxImportRows)
+		if err != nil {
+			if err == imports.ErrTooManyRows {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d rows can be imported at once", maxImportRows)})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to read the file: %s", err.Error())})
+			return
+		}
+
+		mapping := suggestImportMapping(form, table.Header)
+		if rawMapping := c.PostForm("mapping"); rawMapping != "" {
+			var requested map[string]string
+			if err := json.Unmarshal([]byte(rawMapping), &requested); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The mapping must be a JSON object of column names to field keys"})
+				return
+			}
+
+			// Columns mapped to nothing are skipped
+			mapping = make(map[string]string)
+			for column, target := range requested {
+				if target != "" {
+					mapping[column] = target
+				}
+			}
+
+			if err := checkImportMapping(form, table.Header, mapping); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if len(mapping) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "None of the columns match a field of the form, choose the field each column fills"})
+			return
+		}
+
+		report := importReport{Columns: table.Header, Mapping: mapping, Errors: []importRowError{}}
+		rows := readImportRows(form, table, mapping, &report)
+
+		// Unique values can't repeat those of the form's existing responses either
+		allClaims := []string{}
+		for _, row := range rows {
+			allClaims = append(allClaims, row.claims...)
+		}
+		takenClaims, err := params.MongoService.ListTakenClaims(c, formID, allClaims)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list taken response claims", err)
+			return
+		}
+
+		taken := make(map[string]bool)
+		for _, claim := range takenClaims {
+			taken[claim] = true
+		}
+
+		validRows := []importedResponse{}
+		for _, row := range rows {
+			valid := true
+			for _, claim := range row.claims {
+				if taken[claim] {
+					message := formresponses.ClaimTakenMessage(form, &mongodb.ClaimTakenError{Claim: claim})
+					report.addError(importRowError{Row: row.row, Field: formresponses.ClaimFieldKey(claim), Error: message})
+					valid = false
+				}
+			}
+			if valid {
+				validRows = append(validRows, row)
+			}
+		}
+		report.ValidRows = len(validRows)
+
+		if form.MaxSubmissions > 0 {
+			count, err := params.MongoService.CountResponses(c, bson.M{"formID": formID})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				logger.Error("Failed to count form responses", err)
+				return
+			}
+
+			if remaining := int64(form.MaxSubmissions) - count; int64(report.Rows) > remaining {
+				if remaining < 0 {
+					remaining = 0
+				}
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The form only accepts %d more responses, the file has %d", remaining, report.Rows), "report": report})
+				return
+			}
+		}
+
+		if dryRun {
+			c.JSON(http.StatusOK, gin.H{"report": report})
+			return
+		}
+
+		if report.ErrorCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some rows are invalid, nothing was imported", "report": report})
+			return
+		}
+
+		if len(validRows) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The file has no rows to import", "report": report})
+			return
+		}
+
+		sub, err := triggers.GetEventSubscription(c, params.MongoService, form.EventID)
+		if err != nil {
+			if err == triggers.ErrNoActiveSubscription {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "User subscription is not active"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get event subscription", err)
+			return
+		}
+
+		// The whole import is counted at once, so it never gets halfway through the limit
+		if _, err := params.MongoService.AddSubscriptionUtilization(c, sub.ID, "responses", "maxMonthlyResponses", len(validRows)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Importing these responses would go over the event's monthly response limit, please contact the event admin to upgrade their plan."})
+			return
+		}
+
+		responses := make([]models.FormResponse, len(validRows))
+		claims := make([][]string, len(validRows))
+		for i, row := range validRows {
+			responses[i] = row.response
+			claims[i] = row.claims
+		}
+
+		imported, err := params.MongoService.ImportResponses(c, formID, responses, claims, form.MaxSubmissions)
+		if err != nil {
+			if _, refundErr := params.MongoService.AddSubscriptionUtilization(c, sub.ID, "responses", "maxMonthlyResponses", -len(validRows)); refundErr != nil {
+				logger.Error("Failed to give back the response utilization of a failed import", refundErr)
+			}
+
+			var claimErr *mongodb.ClaimTakenError
+			switch {
+			case errors.As(err, &claimErr):
+				c.JSON(http.StatusConflict, gin.H{"error": formresponses.ClaimTakenMessage(form, claimErr)})
+			case err == mongodb.ErrMaxSubmissionsReached:
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Form has reached maximum number of submissions"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				logger.Error("Failed to import form responses", err)
+			}
+			return
+		}
+
+		pipelinesTriggered := false
+		if triggerPipelines {
+			pipelines, err := params.MongoService.ListPipelines(c, bson.M{"eventID": form.EventID, "event.type": "FormSubmission", "event.formSubmission.onFormID": form.ID})
+			if err != nil {
+				logger.Error("Failed to list pipelines for this event", err)
+			} else {
+				pipelinesTriggered = true
+				for _, response := range imported {
+					if err := triggers.BilledPipelines(c, params.MessageProducer, params.MongoService, sub, pipelines, response.Data); err != nil {
+						// The responses are kept, the organizer is responsible for their pipeline limits
+						logger.Error("Failed to trigger form submission pipelines for imported responses", err)
+						pipelinesTriggered = false
+						break
+					}
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Success", "imported": len(imported), "pipelinesTriggered": pipelinesTriggered, "report": report})
+	}
+}
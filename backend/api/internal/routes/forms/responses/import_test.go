@@ -0,0 +1,117 @@
+package responses
+
+import (
+	"api/internal/imports"
+	"shared/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImportForm() *models.FormStructure {
+	return &models.FormStructure{
+		Attrs: []models.FormField{
+			{Key: "name", Question: "Full Name", Type: models.FormFieldTypeText, Required: true},
+			{Key: "email", Question: "Email", Type: models.FormFieldTypeText},
+			{Key: "age", Question: "Age", Type: models.FormFieldTypeNumber},
+			{Key: "skills", Question: "Skills", Type: models.FormFieldTypeMultiSelect, Options: []string{"go", "sql", "rust"}},
+			{Key: "address", Question: "Address", Type: models.FormFieldTypeAddress},
+			{Key: "decision", Question: "Decision", Type: models.FormFieldTypeSelect, Options: []string{"Pending", "Accepted"}, IsInternal: true, Required: true},
+		},
+		UniqueFieldKeys: []string{"email"},
+	}
+}
+
+func TestSuggestImportMapping(t *testing.T) {
+	mapping := suggestImportMapping(testImportForm(), []string{"full  name", "EMAIL", "Email", "Address", "Submitted At", "Notes", "decision"})
+
+	assert.Equal(t, map[string]string{
+		"full  name":   "name",
+		"EMAIL":        "email",
+		"Submitted At": importCreatedAtTarget,
+		"decision":     "decision",
+	}, mapping)
+}
+
+func TestCheckImportMapping(t *testing.T) {
+	form := testImportForm()
+	columns := []string{"Name", "Mail", "Home", "Signed up"}
+
+	assert.NoError(t, checkImportMapping(form, columns, map[string]string{"Name": "name", "Mail": "email", "Signed up": importCreatedAtTarget}))
+	assert.Error(t, checkImportMapping(form, columns, map[string]string{"Phone": "name"}), "unknown column")
+	assert.Error(t, checkImportMapping(form, columns, map[string]string{"Name": "nope"}), "unknown field")
+	assert.Error(t, checkImportMapping(form, columns, map[string]string{"Name": "name", "Mail": "name"}), "field mapped twice")
+	assert.Error(t, checkImportMapping(form, columns, map[string]string{"Home": "address"}), "address fields aren't importable")
+}
+
+func TestParseImportValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   models.FormField
+		text    string
+		want    interface{}
+		wantErr bool
+	}{
+		{"Number", models.FormField{Type: models.FormFieldTypeNumber}, "4.5", 4.5, false},
+		{"Not a number", models.FormField{Type: models.FormFieldTypeRating}, "four", nil, true},
+		{"Checkbox yes", models.FormField{Type: models.FormFieldTypeCheckbox}, "Yes", true, false},
+		{"Checkbox true", models.FormField{Type: models.FormFieldTypeCheckbox}, "FALSE", false, false},
+		{"Checkbox options", models.FormField{Type: models.FormFieldTypeCheckbox, Options: []string{"a", "b"}}, "a, b", []interface{}{"a", "b"}, false},
+		{"Multiselect", models.FormField{Type: models.FormFieldTypeMultiSelect}, "go, ,sql", []interface{}{"go", "sql"}, false},
+		{"Ranking", models.FormField{Type: models.FormFieldTypeRanking}, "c > a > b", []interface{}{"c", "a", "b"}, false},
+		{"Date", models.FormField{Type: models.FormFieldTypeDate}, "2001-02-03", "2001-02-03T00:00:00.000Z", false},
+		{"Timestamp", models.FormField{Type: models.FormFieldTypeTimestamp}, "2024-03-01T12:30:00+01:00", "2024-03-01T11:30:00.000Z", false},
+		{"Bad date", models.FormField{Type: models.FormFieldTypeDate}, "03/02/2001", nil, true},
+		{"Text", models.FormField{Type: models.FormFieldTypeText}, "hello", "hello", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseImportValue(tt.field, tt.text)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadImportRows(t *testing.T) {
+	form := testImportForm()
+	table := &imports.Table{
+		Header: []string{"Full Name", "Email", "Age", "Skills", "Decision", "Submitted At"},
+		Rows: [][]string{
+			{"Ada", "ada@example.com", "36", "go, sql", "Accepted", "2024-03-01"},
+			{"", "", "", "", "", ""},
+			{"", "grace@example.com", "old", "cobol", "", ""},
+			{"Linus", " ADA@example.com", "", "", "", ""},
+			{"Ken", "", "", "", "", ""},
+		},
+	}
+	mapping := suggestImportMapping(form, table.Header)
+
+	report := importReport{}
+	rows := readImportRows(form, table, mapping, &report)
+
+	assert.Equal(t, 4, report.Rows, "empty rows are skipped")
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, 2, rows[0].row)
+	assert.Equal(t, map[string]interface{}{"name": "Ada", "email": "ada@example.com", "age": 36.0, "skills": []interface{}{"go", "sql"}, "decision": "Accepted"}, rows[0].response.Data)
+	assert.Equal(t, "2024-03-01", rows[0].response.CreatedAt.Format("2006-01-02"))
+	assert.Len(t, rows[0].claims, 1)
+
+	assert.Equal(t, 6, rows[1].row)
+	assert.Empty(t, rows[1].claims, "empty unique fields aren't claimed")
+
+	errorsByRow := map[int][]string{}
+	for _, err := range report.Errors {
+		errorsByRow[err.Row] = append(errorsByRow[err.Row], err.Field)
+	}
+	assert.ElementsMatch(t, []string{"name", "age", "skills"}, errorsByRow[4])
+	assert.Equal(t, []string{"email"}, errorsByRow[5], "the email repeats row 2's")
+	assert.Equal(t, 4, report.ErrorCount)
+}
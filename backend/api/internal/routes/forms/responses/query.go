@@ -0,0 +1,227 @@
+package responses
+
+import (
+	"fmt"
+	"shared/models"
+	"shared/mongodb"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Response queries let organizers search, filter and sort the responses list.
+
+  - q: full text search over the text of the responses, eg: q=stanford
+  - filter: conditions joined with AND, eg: filter=decision = Pending AND "Shirt size" = L
+  - sort: comma separated fields, prefix a field with - to sort it descending, eg: sort=-score,createdAt
+
+Fields are named by their question or key, or are createdAt and lastUpdatedAt. Names and values with spaces are quoted.
+The operators are = != > >= < <= and ~ for contains, number and checkbox fields compare their value as a number or boolean.
+*/
+
+var filterOperators = []mongodb.ResponseFilterOperator{
+	// Longest first so >= isn't read as >
+	mongodb.ResponseFilterNotEquals,
+	mongodb.ResponseFilterGreaterThanOrEqual,
+	mongodb.ResponseFilterLessThanOrEqual,
+	mongodb.ResponseFilterEquals,
+	mongodb.ResponseFilterGreaterThan,
+	mongodb.ResponseFilterLessThan,
+	mongodb.ResponseFilterContains,
+}
+
+// filterToken is a word, quoted string or operator of a filter expression
+type filterToken struct {
+	text       string
+	quoted     bool
+	isOperator bool
+}
+
+// tokenizeFilter splits a filter expression into its tokens
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	for i := 0; i < len(expression); {
+		switch c := expression[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			var text strings.Builder
+			closed := false
+			for i++; i < len(expression); i++ {
+				if expression[i] == '\\' && i+1 < len(expression) {
+					i++
+					text.WriteByte(expression[i])
+					continue
+				}
+				if expression[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				text.WriteByte(expression[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unclosed quote in filter")
+			}
+			tokens = append(tokens, filterToken{text: text.String(), quoted: true})
+		case strings.ContainsRune("=!<>~", rune(c)):
+			matched := false
+			for _, operator := range filterOperators {
+				if strings.HasPrefix(expression[i:], string(operator)) {
+					tokens = append(tokens, filterToken{text: string(operator), isOperator: true})
+					i += len(operator)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unknown operator in filter at \"%s\"", expression[i:])
+			}
+		default:
+			start := i
+			for i < len(expression) && !strings.ContainsRune(" \t\"=!<>~", rune(expression[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{text: expression[start:i]})
+		}
+	}
+
+	return tokens, nil
+}
+
+// resolveQueryField finds the document path of a field named in a query, the form field is nil for createdAt and lastUpdatedAt
+func resolveQueryField(form *models.FormStructure, name string) (string, *models.FormField, error) {
+	switch strings.ToLower(name) {
+	case "createdat":
+		return "createdAt", nil, nil
+	case "lastupdatedat":
+		return "lastUpdatedAt", nil, nil
+	}
+
+	for i, field := range form.Attrs {
+		if field.Key == name {
+			return mongodb.ResponseDataPath(field.Key), &form.Attrs[i], nil
+		}
+	}
+
+	for i, field := range form.Attrs {
+		if strings.EqualFold(field.Question, name) {
+			return mongodb.ResponseDataPath(field.Key), &form.Attrs[i], nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("unknown field \"%s\"", name)
+}
+
+// queryValue converts a value from a filter to the type the field is stored as
+func queryValue(field *models.FormField, operator mongodb.ResponseFilterOperator, raw string) (interface{}, error) {
+	if operator == mongodb.ResponseFilterContains {
+		return raw, nil
+	}
+
+	if field == nil {
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if date, err := time.Parse(layout, raw); err == nil {
+				return date, nil
+			}
+		}
+		return nil, fmt.Errorf("\"%s\" is not a date, use YYYY-MM-DD", raw)
+	}
+
+	switch field.Type {
+	case models.FormFieldTypeNumber, models.FormFieldTypeRating:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s is compared to a number, \"%s\" is not one", field.Question, raw)
+		}
+		return number, nil
+	case models.FormFieldTypeCheckbox:
+		if len(field.Options) > 0 {
+			return raw, nil
+		}
+		checked, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s is compared to true or false, \"%s\" is not one", field.Question, raw)
+		}
+		return checked, nil
+	}
+
+	return raw, nil
+}
+
+// parseResponseFilter parses a filter expression into the filters of a response query
+func parseResponseFilter(form *models.FormStructure, expression string) ([]mongodb.ResponseFilter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []mongodb.ResponseFilter{}
+	for i := 0; i < len(tokens); {
+		if len(filters) > 0 {
+			if tokens[i].quoted || tokens[i].isOperator || !strings.EqualFold(tokens[i].text, "AND") {
+				return nil, fmt.Errorf("expected AND before \"%s\"", tokens[i].text)
+			}
+			i++
+		}
+
+		if i+2 >= len(tokens) || tokens[i].isOperator || !tokens[i+1].isOperator || tokens[i+2].isOperator {
+			return nil, fmt.Errorf("conditions must look like: field = value")
+		}
+
+		path, field, err := resolveQueryField(form, tokens[i].text)
+		if err != nil {
+			return nil, err
+		}
+
+		operator := mongodb.ResponseFilterOperator(tokens[i+1].text)
+		value, err := queryValue(field, operator, tokens[i+2].text)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, mongodb.ResponseFilter{Path: path, Operator: operator, Value: value})
+		i += 3
+	}
+
+	return filters, nil
+}
+
+// parseResponseSort parses a comma separated sort into the sort of a response query
+func parseResponseSort(form *models.FormStructure, sort string) ([]mongodb.ResponseSort, error) {
+	sorts := []mongodb.ResponseSort{}
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		descending := strings.HasPrefix(part, "-")
+		name := strings.Trim(strings.TrimLeft(part, "+-"), "\"")
+
+		path, _, err := resolveQueryField(form, name)
+		if err != nil {
+			return nil, err
+		}
+
+		sorts = append(sorts, mongodb.ResponseSort{Path: path, Descending: descending})
+	}
+
+	return sorts, nil
+}
+
+// parseResponseQuery builds the response query from the search, filter and sort of the request
+func parseResponseQuery(form *models.FormStructure, search string, filter string, sort string) (mongodb.ResponseQuery, error) {
+	filters, err := parseResponseFilter(form, filter)
+	if err != nil {
+		return mongodb.ResponseQuery{}, err
+	}
+
+	sorts, err := parseResponseSort(form, sort)
+	if err != nil {
+		return mongodb.ResponseQuery{}, err
+	}
+
+	return mongodb.ResponseQuery{Search: search, Filters: filters, Sort: sorts}, nil
+}
@@ -0,0 +1,83 @@
+package responses
+
+import (
+	"shared/models"
+	"shared/mongodb"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func queryTestForm() *models.FormStructure {
+	return &models.FormStructure{Attrs: []models.FormField{
+		{Key: "k-decision", Question: "Decision", Type: models.FormFieldTypeSelect},
+		{Key: "k-shirt", Question: "Shirt size", Type: models.FormFieldTypeSelect},
+		{Key: "k-age", Question: "Age", Type: models.FormFieldTypeNumber},
+		{Key: "k-terms", Question: "Accepts terms", Type: models.FormFieldTypeCheckbox},
+		{Key: "k-school", Question: "School", Type: models.FormFieldTypeText},
+	}}
+}
+
+func TestParseResponseFilter(t *testing.T) {
+	form := queryTestForm()
+
+	filters, err := parseResponseFilter(form, `decision = Pending AND "Shirt size" = L and age>=18 AND k-terms != false AND school ~ "new \"york\""`)
+	assert.NoError(t, err)
+	assert.Equal(t, []mongodb.ResponseFilter{
+		{Path: "data.k-decision", Operator: mongodb.ResponseFilterEquals, Value: "Pending"},
+		{Path: "data.k-shirt", Operator: mongodb.ResponseFilterEquals, Value: "L"},
+		{Path: "data.k-age", Operator: mongodb.ResponseFilterGreaterThanOrEqual, Value: 18.0},
+		{Path: "data.k-terms", Operator: mongodb.ResponseFilterNotEquals, Value: false},
+		{Path: "data.k-school", Operator: mongodb.ResponseFilterContains, Value: `new "york"`},
+	}, filters)
+
+	filters, err = parseResponseFilter(form, "createdAt < 2024-03-01")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), filters[0].Value)
+
+	filters, err = parseResponseFilter(form, "  ")
+	assert.NoError(t, err)
+	assert.Empty(t, filters)
+}
+
+func TestParseResponseFilterErrors(t *testing.T) {
+	form := queryTestForm()
+
+	invalid := map[string]string{
+		"unknown field":      "team = a",
+		"not a number":       "age > old",
+		"not a bool":         "k-terms = maybe",
+		"not a date":         "createdAt > yesterday",
+		"missing value":      "decision =",
+		"missing operator":   "decision Pending",
+		"missing AND":        "decision = a age = 1",
+		"OR isn't supported": "decision = a OR decision = b",
+		"unclosed quote":     `decision = "Pending`,
+		"unknown operator":   "decision ! a",
+	}
+
+	for name, expression := range invalid {
+		_, err := parseResponseFilter(form, expression)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestParseResponseSort(t *testing.T) {
+	form := queryTestForm()
+
+	sorts, err := parseResponseSort(form, `-age, "Shirt size",+createdAt`)
+	assert.NoError(t, err)
+	assert.Equal(t, []mongodb.ResponseSort{
+		{Path: "data.k-age", Descending: true},
+		{Path: "data.k-shirt"},
+		{Path: "createdAt"},
+	}, sorts)
+
+	sorts, err = parseResponseSort(form, "")
+	assert.NoError(t, err)
+	assert.Empty(t, sorts)
+
+	_, err = parseResponseSort(form, "-team")
+	assert.Error(t, err)
+}
@@ -1,17 +1,18 @@
 package responses
 
 import (
-	"api/internal/helpers"
 	"api/internal/middlewares"
+	"api/internal/routes/forms/responses/validators"
 	"api/internal/types"
-	"encoding/csv"
-	"fmt"
+	"errors"
 	"net/http"
+	"shared/formresponses"
 	"shared/kafka"
 	"shared/logger"
 	"shared/messages"
 	"shared/models"
 	"shared/mongodb"
+	"shared/triggers"
 	"shared/utils"
 	"strconv"
 	"strings"
@@ -20,15 +21,35 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func RegisterFormResponsesRoutes(r *gin.RouterGroup, params *types.RouteParams) {
-	r.POST("", middlewares.JWTAuthMiddleware(), submitFormHandler(params))
-	r.GET("", middlewares.JWTAuthMiddleware(), listFormResponsesHandler(params))
-	r.GET("csv", middlewares.JWTAuthMiddleware(), downloadFormResponsesAsCSVHandler(params))
-
-	r.PUT(":response_id", middlewares.JWTAuthMiddleware(), updateFormResponseHandler(params))
+	r.POST("", middlewares.JWTAuthMiddleware(params.MongoService), submitFormHandler(params))
+	r.GET("", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewResponses), listFormResponsesHandler(params))
+	r.GET("export", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionExportResponses), exportFormResponsesHandler(params))
+	// Kept for older clients, the format still follows the format param and Accept header
+	r.GET("csv", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionExportResponses), exportFormResponsesHandler(params))
+	r.POST("import", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditResponses), importFormResponsesHandler(params))
+
+	r.GET("bulk", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditResponses), listBulkJobsHandler(params))
+	r.GET("bulk/:job_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditResponses), getBulkJobHandler(params))
+	r.POST("bulk/set-field", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditResponses), bulkSetFieldHandler(params))
+	r.POST("bulk/delete", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditResponses), bulkDeleteHandler(params))
+	r.POST("bulk/move", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditResponses), bulkMoveHandler(params))
+
+	r.GET("draft", middlewares.JWTAuthMiddleware(params.MongoService), getFormResponseDraftHandler(params))
+	r.PUT("draft", middlewares.JWTAuthMiddleware(params.MongoService), saveFormResponseDraftHandler(params))
+	r.DELETE("draft", middlewares.JWTAuthMiddleware(params.MongoService), deleteFormResponseDraftHandler(params))
+
+	r.PUT(":response_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditResponses), updateFormResponseHandler(params))
+	r.DELETE(":response_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditResponses), deleteFormResponseHandler(params))
+	r.GET(":response_id/history", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewResponses), getFormResponseHistoryHandler(params))
+	r.GET(":response_id/files/:file_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewResponses), getFormResponseFileHandler(params))
+
+	// Anonymous submissions are open to anyone, they are protected by proof of work and a per IP rate limit instead
+	r.GET("anonymous", anonymousRateLimit(params, "anonymous-challenges"), getAnonymousFormHandler(params))
+	r.POST("anonymous", anonymousRateLimit(params, "anonymous-submissions"), submitAnonymousResponseHandler(params))
+	r.POST("anonymous/confirm", anonymousRateLimit(params, "anonymous-submissions"), confirmAnonymousResponseHandler(params))
 }
 
 func submitFormHandler(params *types.RouteParams) gin.HandlerFunc {
@@ -62,193 +83,263 @@ func submitFormHandler(params *types.RouteParams) gin.HandlerFunc {
 		}
 
 		form, err := params.MongoService.GetForm(c, formID, false)
-		if err != nil && form != nil {
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
 			return
 		}
 
-		if form.Status != "published" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Form is not published, if you believe this is an error message the event admins"})
-			return
-		}
-
-		if !form.CloseSubmissionsAt.IsZero() && form.CloseSubmissionsAt.Before(time.Now()) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Form submissions are closed"})
-			return
-		}
-
-		if !form.OpenSubmissionsAt.IsZero() && form.OpenSubmissionsAt.After(time.Now()) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Form submissions are not open yet"})
+		if !checkFormAcceptsSubmissions(c, params, form) {
 			return
 		}
 
-		// Check if form has reached max submissions
-		var submissions []models.FormResponse
-		if form.MaxSubmissions > 0 {
-			submissions, err = params.MongoService.ListResponses(c, bson.M{"formID": formID}, nil)
+		// A resubmission is either refused or, if the form allows it, edits the user's existing response
+		var existingResponse *models.FormResponse
+		if !form.AllowMultipleSubmissions {
+			submissions, err := params.MongoService.ListResponses(c, bson.M{"formID": formID, "userID": authenticatedUser.ID}, nil)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-				logger.Error("Failed to list form responses", err)
-				return
-			}
-
-			if len(submissions) >= form.MaxSubmissions {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Form has reached maximum number of submissions"})
+				logger.Error("Failed to list form responses for duplicate submission check", err)
 				return
 			}
-		}
-
-		if !form.AllowMultipleSubmissions {
-			if submissions == nil {
-				submissions, err = params.MongoService.ListResponses(c, bson.M{"formID": formID, "userID": authenticatedUser.ID}, nil)
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-					logger.Error("Failed to list form responses for duplicate submission check", err)
-					return
-				}
-			}
 
-			// TODO: Test efficiency of this vs just potentially re-querying the database
-			// my guess is this is more efficient if both max and allow multiple submissions are false
-			// because probably less than 1000 submissions per form
-			for _, submission := range submissions {
-				if submission.UserID == authenticatedUser.ID {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "You have already submitted this form"})
+			if len(submissions) > 0 {
+				if !form.ResubmitAsEdit {
+					c.JSON(http.StatusConflict, gin.H{"error": "You have already submitted this form"})
 					return
 				}
+				existingResponse = &submissions[0]
 			}
 		}
 
 		// If the form is restricted, check if the user is in the whitelist
 		if form.IsRestricted {
-			allowed, restrictMessage := mongodb.IsUserEmailInWhitelist(c, form.AllowedSubmitters)
+			allowed, restrictMessage := mongodb.IsUserEmailInWhitelist(c, params.MongoService, form.AllowedSubmitters)
 			if !allowed {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": restrictMessage})
 				return
 			}
 		}
 
-		// Build map of field id to field
-		fieldMap := make(map[string]models.FormField)
-		for _, field := range form.Attrs {
-			fieldMap[field.Key] = field
-		}
-
 		// Validate form data itself and the additional validators on it and such
-		for key, value := range formData {
-			field := fieldMap[key]
-			if field.Key == "" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field key, form may have just changed"})
-			}
-
-			err = ValidateResponse(value, field)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-				return
-			}
+		if fieldErrors := validators.ValidateResponses(formData, form.Attrs); len(fieldErrors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some fields are invalid", "fieldErrors": fieldErrors})
+			return
 		}
 
-		// Validate required fields again, to cover the case of the data doesnt even have that key
-		for _, field := range form.Attrs {
-			if field.Required {
-				if _, exists := formData[field.Key]; !exists {
-					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("field %s is required", field.Question)})
-					return
-				}
-			}
+		var editedResponseID primitive.ObjectID
+		if existingResponse != nil {
+			editedResponseID = existingResponse.ID
 		}
 
-		// TODO: We should do this in a transaction
-
-		// Check billing
-		eventDetails, err := params.MongoService.GetEvent(c, form.EventID)
+		fileUploadIDs, err := validateSubmittedFileUploads(c, params, form, formData, authenticatedUser.ID, editedResponseID)
 		if err != nil {
+			if err == errInvalidFileUpload {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			logger.Error("Failed to get event details", err)
+			logger.Error("Failed to validate submitted file uploads", err)
 			return
 		}
 
-		u, err := params.MongoService.GetUserDetails(c, eventDetails.CreatedByID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		if existingResponse != nil {
+			resubmitAsEdit(c, params, form, *existingResponse, formData, fileUploadIDs)
 			return
 		}
 
-		if u.CurrentSubscriptionID == primitive.NilObjectID {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User does not have a subscription"})
+		req.UserID = authenticatedUser.ID
+		submitted, ok := createResponse(c, params, form, req)
+		if !ok {
 			return
 		}
+		responseID := submitted.ID
 
-		sub, err := params.MongoService.GetSubscription(c, u.CurrentSubscriptionID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscription"})
-			return
+		if len(fileUploadIDs) > 0 {
+			if _, err := params.MongoService.AttachFileUploads(c, fileUploadIDs, responseID); err != nil {
+				logger.Error("Failed to attach file uploads to form response", err)
+			}
 		}
 
-		if sub.Status != models.SubscriptionStatusActive {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User subscription is not active"})
+		// The draft has served its purpose once the response is submitted
+		if _, err := params.MongoService.DeleteResponseDraft(c, formID, authenticatedUser.ID); err != nil {
+			logger.Error("Failed to delete form response draft", err)
+		}
+
+		if submitted.Admission != nil {
+			c.JSON(http.StatusOK, gin.H{"message": "Success", "id": responseID, "admission": submitted.Admission})
 			return
 		}
 
-		// Check pipeline
-		pipelines, err := params.MongoService.ListPipelines(c, bson.M{"eventID": form.EventID})
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	}
+}
+
+// checkFormAcceptsSubmissions checks the form is published, open and not full, it writes the error response when it isn't
+func checkFormAcceptsSubmissions(c *gin.Context, params *types.RouteParams, form *models.FormStructure) bool {
+	if form.Status != "published" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form is not published, if you believe this is an error message the event admins"})
+		return false
+	}
+
+	if !form.CloseSubmissionsAt.IsZero() && form.CloseSubmissionsAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form submissions are closed"})
+		return false
+	}
+
+	if !form.OpenSubmissionsAt.IsZero() && form.OpenSubmissionsAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form submissions are not open yet"})
+		return false
+	}
+
+	// Check if form has reached max submissions, this is checked again when the response is created
+	if form.MaxSubmissions > 0 {
+		count, err := params.MongoService.CountResponses(c, bson.M{"formID": form.ID})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			logger.Error("Failed to list pipelines for this event", err)
-			return
+			logger.Error("Failed to count form responses", err)
+			return false
 		}
 
-		for _, pipeline := range pipelines {
-			if pipeline.Event.Type == "FormSubmission" {
-				// Sanity check
-				if pipeline.Event.FormSubmission.OnFormID != formID {
-					continue
-				}
+		if count >= int64(form.MaxSubmissions) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form has reached maximum number of submissions"})
+			return false
+		}
+	}
 
-				_, err = params.MongoService.IncrementSubscriptionUtilization(c, sub.ID, "pipelineRuns", "maxMonthlyPipelineRuns")
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Pipeline limit reached, please contact the event admin to upgrade their plan."})
-					// TODO: send out email to admin
-					return
-				}
+	return true
+}
 
-				err := helpers.TriggerPipeline(c, params.MessageProducer, params.MongoService, pipeline, req.Data)
+// createResponse bills the event owner for a new response, stores it and triggers the form's FormSubmission pipelines.
+// It is shared by signed in and anonymous submissions, it writes the error response on failure
+func createResponse(c *gin.Context, params *types.RouteParams, form *models.FormStructure, response models.FormResponse) (*models.FormResponse, bool) {
+	// Check billing, not GetEvent as that only has the event's metadata for applicants and anonymous respondents
+	eventDetails, err := params.MongoService.FindEvent(c, form.EventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to get event details", err)
+		return nil, false
+	}
 
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-					logger.Error("Failed to trigger pipeline", err)
-					return
-				}
-			}
-		}
+	u, err := params.MongoService.GetUserDetails(c, eventDetails.CreatedByID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return nil, false
+	}
 
-		_, err = params.MongoService.IncrementSubscriptionUtilization(c, sub.ID, "responses", "maxMonthlyResponses")
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Event submission limit reached, please contact the event admin to upgrade their plan."})
-			return
+	if u.CurrentSubscriptionID == primitive.NilObjectID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User does not have a subscription"})
+		return nil, false
+	}
+
+	sub, err := params.MongoService.GetSubscription(c, u.CurrentSubscriptionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscription"})
+		return nil, false
+	}
+
+	if sub.Status != models.SubscriptionStatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User subscription is not active"})
+		return nil, false
+	}
+
+	_, err = params.MongoService.IncrementSubscriptionUtilization(c, sub.ID, "responses", "maxMonthlyResponses")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Event submission limit reached, please contact the event admin to upgrade their plan."})
+		return nil, false
+	}
+
+	// Submit form, on forms with a capacity the response either takes a seat or joins the waitlist
+	if form.Capacity.Limit > 0 {
+		// Seats whose offers lapsed go to the people already waiting before this response
+		if err := triggers.SweepAdmissions(c, params.MessageProducer, params.MongoService, form); err != nil {
+			logger.Error("Failed to sweep form admissions", err)
 		}
+	}
 
-		// Submit form
-		req.UserID = authenticatedUser.ID
-		if _, err := params.MongoService.CreateResponse(c, req); err != nil {
+	response.FormVersion = form.Version
+	submission := mongodb.ResponseSubmission{Claims: formresponses.Claims(form, response.UserID, response.Data), MaxSubmissions: form.MaxSubmissions, Capacity: form.Capacity}
+	submitted, err := params.MongoService.SubmitResponse(c, response, submission)
+	if err != nil {
+		// A refused response doesn't count against the owner's limit
+		if _, refundErr := params.MongoService.AddSubscriptionUtilization(c, sub.ID, "responses", "maxMonthlyResponses", -1); refundErr != nil {
+			logger.Error("Failed to give back the response utilization of a refused response", refundErr)
+		}
+
+		var claimErr *mongodb.ClaimTakenError
+		switch {
+		case errors.As(err, &claimErr):
+			c.JSON(http.StatusConflict, gin.H{"error": formresponses.ClaimTakenMessage(form, claimErr)})
+		case err == mongodb.ErrMaxSubmissionsReached:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form has reached maximum number of submissions"})
+		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 			logger.Error("Failed to create form response", err)
-			return
 		}
+		return nil, false
+	}
 
-		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	// Pipelines only run once the response is stored, so a refused submission never triggers them
+	pipelines, err := params.MongoService.ListPipelines(c, bson.M{"eventID": form.EventID, "event.type": "FormSubmission", "event.formSubmission.onFormID": form.ID})
+	if err != nil {
+		logger.Error("Failed to list pipelines for this event", err)
+	} else if err := triggers.BilledPipelines(c, params.MessageProducer, params.MongoService, sub, pipelines, response.Data); err != nil {
+		// The response is kept, the organizer is responsible for their pipeline limits
+		logger.Error("Failed to trigger form submission pipelines", err)
+	}
+
+	return submitted, true
+}
+
+// resubmitAsEdit replaces the user's part of their existing response with their resubmission, it doesn't count as a new response
+func resubmitAsEdit(c *gin.Context, params *types.RouteParams, form *models.FormStructure, response models.FormResponse, formData map[string]interface{}, fileUploadIDs []primitive.ObjectID) {
+	updated := response
+	updated.Data = mergeApplicantData(form, response.Data, withoutInternalFields(form, formData))
+	updated.LastUpdatedAt = time.Now()
+	updated.FormVersion = form.Version
+	change := &models.ResponseChange{ActorID: response.UserID, Source: models.ResponseChangeResubmission, Changes: models.DiffResponseData(response.Data, updated.Data)}
+	if _, err := params.MongoService.UpdateResponseWithClaims(c, updated, response.ID, formresponses.Claims(form, response.UserID, updated.Data), change); err != nil {
+		var claimErr *mongodb.ClaimTakenError
+		if errors.As(err, &claimErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": formresponses.ClaimTakenMessage(form, claimErr)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to update form response from resubmission", err)
+		return
+	}
+
+	if err := syncResponseFiles(c, params, response.ID, fileUploadIDs); err != nil {
+		logger.Error("Failed to update the files of the form response", err)
 	}
+
+	if err := triggers.FieldChangePipelines(c, params.MessageProducer, params.MongoService, form, response.Data, updated.Data); err != nil {
+		logger.Error("Failed to trigger field change pipelines", err)
+	}
+
+	if _, err := params.MongoService.DeleteResponseDraft(c, form.ID, response.UserID); err != nil {
+		logger.Error("Failed to delete form response draft", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "id": response.ID, "edited": true})
 }
 
-func processResponses(form *models.FormStructure, responses *[]models.FormResponse, getDeletedColumnData bool) ([]map[string]interface{}, []string) {
+/*
+processResponses builds the rows for the tabular views of the responses, the first row is the header.
+
+When flatten is set structured values are spread across columns and joined into text (see flatten.go),
+otherwise every field keeps a single column with its stored value so rows can be edited and saved back.
+
+Deleted column data is labelled and laid out with the field's last definition in removedFields, see deletedField.
+*/
+func processResponses(form *models.FormStructure, responses *[]models.FormResponse, removedFields []models.FormField, getDeletedColumnData bool, flatten bool) ([]map[string]interface{}, []string) {
 	var processedResponses []map[string]interface{}
 
 	// Define the order of columns
 	columnOrder := []string{"Response ID", "User ID", "Submitted At", "Last Updated At"}
-	defaultColumnNum := len(columnOrder)
+	var columns []responseColumn
 	colKeyMap := make(map[string]struct{})
 	for _, attr := range form.Attrs {
-		columnOrder = append(columnOrder, attr.Question+"_attr_key:"+attr.Key)
+		columns = append(columns, fieldColumns(attr, flatten)...)
 		colKeyMap[attr.Key] = struct{}{}
 	}
 
@@ -258,14 +349,21 @@ func processResponses(form *models.FormStructure, responses *[]models.FormRespon
 			for key := range response.Data {
 				if _, exists := colKeyMap[key]; !exists {
 					// Add column
-					newColumn := fmt.Sprintf("deleted column_attr_key:%s", key)
-					columnOrder = append(columnOrder, newColumn)
+					field, ok := deletedField(removedFields, key)
+					if !ok {
+						field = models.FormField{Question: "deleted column", Key: key}
+					}
+					columns = append(columns, fieldColumns(field, flatten)...)
 					colKeyMap[key] = struct{}{}
 				}
 			}
 		}
 	}
 
+	for _, column := range columns {
+		columnOrder = append(columnOrder, column.name)
+	}
+
 	// Create header row based on column order
 	headerRow := make(map[string]interface{})
 	for _, col := range columnOrder {
@@ -282,16 +380,8 @@ func processResponses(form *models.FormStructure, responses *[]models.FormRespon
 		processedResponse["Last Updated At"] = response.LastUpdatedAt
 
 		// Add other attributes
-		for _, fullKeyName := range columnOrder[defaultColumnNum:] {
-			split := strings.Split(fullKeyName, "_attr_key:")
-			uniqueKey := split[len(split)-1]
-
-			value, exists := response.Data[uniqueKey]
-			if exists {
-				processedResponse[fullKeyName] = value
-			} else {
-				processedResponse[fullKeyName] = ""
-			}
+		for _, column := range columns {
+			processedResponse[column.name] = column.value(response.Data)
 		}
 
 		processedResponses = append(processedResponses, processedResponse)
@@ -300,15 +390,23 @@ func processResponses(form *models.FormStructure, responses *[]models.FormRespon
 	return processedResponses, columnOrder
 }
 
+/*
+List form responses
+
+params:
+  - form_id: ID of the form
+
+query params:
+  - page, pageSize: pagination (default: 1, 10)
+  - getDeletedColumnData: whether to include deleted column data (default: false)
+  - flatten: whether to flatten structured values like matrix and address fields into text (default: false)
+  - q, filter, sort: search, filter and sort the responses (see query.go), newest first by default
+*/
 func listFormResponsesHandler(params *types.RouteParams) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		getDeletedColumnData := c.DefaultQuery("getDeletedColumnData", "false")
 		getDeletedColumnDataBool := (getDeletedColumnData == "true")
-
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
+		flattenBool := (c.DefaultQuery("flatten", "false") == "true")
 
 		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
 		if err != nil {
@@ -322,11 +420,6 @@ func listFormResponsesHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		if !mongodb.CanUserModifyForm(c, params.MongoService, authenticatedUser, form.ID, form) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this form"})
-			return
-		}
-
 		// Pagination parameters
 		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 		pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
@@ -339,90 +432,39 @@ func listFormResponsesHandler(params *types.RouteParams) gin.HandlerFunc {
 			pageSize = 10
 		}
 
-		// Pagination options
-		skip := (page - 1) * pageSize
-		options := options.Find()
-		options.SetLimit(int64(pageSize))
-		options.SetSkip(int64(skip))
-		options.SetSort(bson.D{{Key: "createdAt", Value: -1}})
-
-		responses, err := params.MongoService.ListResponses(c, bson.M{"formID": formID}, options)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			logger.Error("Failed to list form responses", err)
-			return
-		}
-
-		processedResponses, columnOrder := processResponses(form, &responses, getDeletedColumnDataBool)
-
-		c.JSON(http.StatusOK, gin.H{"responses": processedResponses, "columnOrder": columnOrder, "page": page, "pageSize": pageSize})
-	}
-}
-
-/*
-Download form responses as CSV
-
-params:
-  - form_id: ID of the form
-
-query params:
-  - getDeletedColumnData: whether to include deleted column data in the CSV (default: false)
-*/
-func downloadFormResponsesAsCSVHandler(params *types.RouteParams) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		getDeletedColumnData := c.DefaultQuery("getDeletedColumnData", "false")
-		getDeletedColumnDataBool := (getDeletedColumnData == "true")
-
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
-		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		query, err := parseResponseQuery(form, c.Query("q"), c.Query("filter"), c.Query("sort"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
-			return
-		}
-
-		// Fetch form and responses similar to your existing logic
-		form, err := params.MongoService.GetForm(c, formID, true)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		if !mongodb.CanUserModifyForm(c, params.MongoService, authenticatedUser, form.ID, form) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this form"})
-			return
-		}
+		// Pagination options
+		query.Skip = int64((page - 1) * pageSize)
+		query.Limit = int64(pageSize)
 
-		responses, err := params.MongoService.ListResponses(c, bson.M{"formID": formID}, nil)
+		responses, total, err := params.MongoService.QueryResponses(c, formID, query)
 		if err != nil {
+			if err == mongodb.ErrInvalidResponseQuery {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This query can't be used on this form"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			logger.Error("Failed to list form responses", err)
+			logger.Error("Failed to query form responses", err)
 			return
 		}
-		processedResponses, columnOrder := processResponses(form, &responses, getDeletedColumnDataBool)
-
-		c.Writer.Header().Set("Content-Type", "text/csv")
-		c.Writer.Header().Set("Content-Disposition", "attachment;filename=form_responses.csv")
-		writer := csv.NewWriter(c.Writer)
 
-		// Write data rows
-		for _, processedResponse := range processedResponses {
-			var row []string
-			for _, col := range columnOrder {
-				value, ok := processedResponse[col]
-				if !ok {
-					row = append(row, "")
-				} else {
-					row = append(row, fmt.Sprintf("%v", value))
-				}
+		var removedFields []models.FormField
+		if getDeletedColumnDataBool {
+			var ok bool
+			removedFields, ok = listRemovedFields(c, params, form)
+			if !ok {
+				return
 			}
-			writer.Write(row)
 		}
 
-		writer.Flush()
+		processedResponses, columnOrder := processResponses(form, &responses, removedFields, getDeletedColumnDataBool, flattenBool)
+
+		c.JSON(http.StatusOK, gin.H{"responses": processedResponses, "columnOrder": columnOrder, "page": page, "pageSize": pageSize, "total": total})
 	}
 }
 
@@ -440,26 +482,22 @@ func updateFormResponseHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		responses, err := params.MongoService.ListResponses(c, bson.M{"_id": responseID}, nil)
-		if err != nil || len(responses) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Response does not exist"})
-			return
-		}
-
 		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
 			return
 		}
 
-		form, err := params.MongoService.GetForm(c, formID, true)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+		// The permission was checked on the form in the path, so the response has to be one of its own
+		responses, err := params.MongoService.ListResponses(c, bson.M{"_id": responseID, "formID": formID}, nil)
+		if err != nil || len(responses) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Response does not exist"})
 			return
 		}
 
-		if !mongodb.CanUserModifyForm(c, params.MongoService, authenticatedUser, form.ID, form) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this form"})
+		form, err := params.MongoService.GetForm(c, formID, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
 			return
 		}
 
@@ -471,7 +509,9 @@ func updateFormResponseHandler(params *types.RouteParams) gin.HandlerFunc {
 
 		formData := req.Data // in form attr_id -> value format
 		response := responses[0]
+		previousData := response.Data
 		response.Data = formData
+		response.FormVersion = form.Version
 
 		if errors := utils.ValidateStruct(utils.Validator, response); len(errors) > 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
@@ -539,7 +579,7 @@ func updateFormResponseHandler(params *types.RouteParams) gin.HandlerFunc {
 					return
 				}
 
-				err := helpers.TriggerPipeline(c, params.MessageProducer, params.MongoService, pipeline, response.Data)
+				err := triggers.Pipeline(c, params.MessageProducer, params.MongoService, pipeline, response.Data)
 
 				if err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
@@ -551,8 +591,15 @@ func updateFormResponseHandler(params *types.RouteParams) gin.HandlerFunc {
 
 		newUpdatedAt := time.Now()
 		response.LastUpdatedAt = newUpdatedAt
-		_, err = params.MongoService.UpdateResponse(c, response, responseID)
+		// The claims follow the new data so unique fields stay unique when admins edit them
+		change := &models.ResponseChange{ActorID: authenticatedUser.ID, Source: models.ResponseChangeOrganizerEdit, Changes: models.DiffResponseData(previousData, response.Data)}
+		_, err = params.MongoService.UpdateResponseWithClaims(c, response, responseID, formresponses.Claims(form, response.UserID, response.Data), change)
 		if err != nil {
+			var claimErr *mongodb.ClaimTakenError
+			if errors.As(err, &claimErr) {
+				c.JSON(http.StatusConflict, gin.H{"error": formresponses.ClaimTakenMessage(form, claimErr)})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 			logger.Error("Failed to update form response", err)
 			return
@@ -561,3 +608,65 @@ func updateFormResponseHandler(params *types.RouteParams) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"id": responseID, "lastUpdatedAt": newUpdatedAt})
 	}
 }
+
+// removeResponse deletes a response with formresponses.Delete and offers its seat to the waitlist, the change says who deleted it.
+// It writes the error response on failure
+func removeResponse(c *gin.Context, params *types.RouteParams, response models.FormResponse, change models.ResponseChange) bool {
+	form := &models.FormStructure{ID: response.FormID}
+	if admission := response.Admission; admission != nil && admission.Status.HoldsSeat() {
+		var err error
+		form, err = params.MongoService.GetForm(c, response.FormID, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+			return false
+		}
+	}
+
+	promoted, err := formresponses.Delete(c, params.MongoService, params.ObjectStorage, form.Capacity, response, change)
+	if err := triggers.WaitlistPromotions(c, params.MessageProducer, params.MongoService, form, promoted); err != nil {
+		logger.Error("Failed to trigger waitlist promotion pipelines", err)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to delete form response", err)
+		return false
+	}
+
+	return true
+}
+
+// Note: this only allows event admins to delete responses, any files, reviews, claims and the seat of the response are released too.
+// The response's history is kept
+func deleteFormResponseHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		responseID, err := primitive.ObjectIDFromHex(c.Param("response_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid response ID"})
+			return
+		}
+
+		responses, err := params.MongoService.ListResponses(c, bson.M{"_id": responseID, "formID": formID}, nil)
+		if err != nil || len(responses) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Response does not exist"})
+			return
+		}
+
+		authenticatedUser, ok := utils.GetUserFromContext(c, true)
+		if !ok {
+			return
+		}
+
+		change := models.ResponseChange{ActorID: authenticatedUser.ID, Source: models.ResponseChangeOrganizerDeletion}
+		if !removeResponse(c, params, responses[0], change) {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Response deleted successfully"})
+	}
+}
@@ -0,0 +1,67 @@
+package responses
+
+import (
+	"api/internal/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shared/models"
+	"shared/mongodb"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// otherFormMongo holds responses of several forms, only the lookups before a response is changed are implemented
+type otherFormMongo struct {
+	mongodb.MongoService
+	responses []models.FormResponse
+	updated   bool
+}
+
+func (m *otherFormMongo) ListResponses(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.FormResponse, error) {
+	responses := []models.FormResponse{}
+	for _, response := range m.responses {
+		if response.ID == filter["_id"] && (filter["formID"] == nil || response.FormID == filter["formID"]) {
+			responses = append(responses, response)
+		}
+	}
+	return responses, nil
+}
+
+func (m *otherFormMongo) UpdateResponseWithClaims(ctx context.Context, response models.FormResponse, responseID primitive.ObjectID, claims []string, change *models.ResponseChange) (*mongo.UpdateResult, error) {
+	m.updated = true
+	return &mongo.UpdateResult{MatchedCount: 1}, nil
+}
+
+func TestUpdateFormResponseOfOtherForm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ownForm := primitive.NewObjectID()
+	// A response of another event, which the organizer can't edit
+	other := models.FormResponse{ID: primitive.NewObjectID(), FormID: primitive.NewObjectID(), Data: map[string]interface{}{"name": "Ada"}}
+	m := &otherFormMongo{responses: []models.FormResponse{other}}
+
+	// Stands in for JWTAuthMiddleware and RequirePermission, which only checked the form in the path
+	r := gin.New()
+	r.PUT("/forms/:form_id/responses/:response_id", func(c *gin.Context) {
+		c.Set("user", &models.User{ID: primitive.NewObjectID()})
+	}, updateFormResponseHandler(&types.RouteParams{MongoService: m}))
+
+	body := `{"data": {"name": "Grace"}, "lastUpdatedAt": "` + time.Now().Format(time.RFC3339) + `"}`
+	req := httptest.NewRequest(http.MethodPut, "/forms/"+ownForm.Hex()+"/responses/"+other.ID.Hex(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Response does not exist")
+	assert.False(t, m.updated)
+}
@@ -0,0 +1,43 @@
+package validators
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const emailPattern = `(?:[a-z0-9!#$%&'*+/=?^_` + "`" + `{|}~-]+(?:\.[a-z0-9!#$%&'*+/=?^_` + "`" + `{|}~-]+)*|"(?:[\x01-\x08\x0b\x0c\x0e-\x1f\x21\x23-\x5b\x5d-\x7f]|\\[\x01-\x09\x0b\x0c\x0e-\x7f])*")@(?:(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z0-9](?:[a-z0-9-]*[a-z0-9])?|\[(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?|[a-z0-9-]*[a-z0-9]:(?:[\x01-\x08\x0b\x0c\x0e-\x1f\x21-\x5a\x53-\x7f]|\\[\x01-\x09\x0b\x0c\x0e-\x7f])+)\])`
+
+var (
+	emailRegex = regexp.MustCompile(emailPattern)
+)
+
+// Email Validator Helpers
+
+// validateDomain checks if the email has an allowed domain
+func validateDomain(email string, requireDomain []string, allowSubdomains bool) bool {
+	domainRegexPattern := ""
+	if allowSubdomains {
+		domainRegexPattern = `@([a-zA-Z0-9.-]+\.)?(` + joinDomains(requireDomain) + `)$`
+	} else {
+		domainRegexPattern = `@(` + joinDomains(requireDomain) + `)$`
+	}
+	domainRegex := regexp.MustCompile(domainRegexPattern)
+	return domainRegex.MatchString(email)
+}
+
+// validateTLD checks if the email has an allowed top-level domain
+func validateTLD(email string, allowTLDs []string) bool {
+	tldRegexPattern := `\.(` + joinDomains(allowTLDs) + `)$`
+	tldRegex := regexp.MustCompile(tldRegexPattern)
+	return tldRegex.MatchString(email)
+}
+
+// joinDomains joins the domains into a regex pattern, quoting them so a "." only matches a literal dot
+func joinDomains(domains []string) string {
+	quoted := make([]string, len(domains))
+	for i, domain := range domains {
+		quoted[i] = regexp.QuoteMeta(domain)
+	}
+	return fmt.Sprintf("(%s)", strings.Join(quoted, "|"))
+}
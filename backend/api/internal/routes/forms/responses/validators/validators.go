@@ -0,0 +1,476 @@
+package validators
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"shared/models"
+	"time"
+
+	"github.com/nyaruka/phonenumbers"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const dateFormat = "2006-01-02T15:04:05.000Z"
+
+// fieldValidator validates a non-nil value submitted for a field of a given type
+type fieldValidator func(value interface{}, field models.FormField) error
+
+// fieldValidators is the validation strategy for each field type,
+// types without an entry only get the required and internal checks
+var fieldValidators = map[models.FormFieldType]fieldValidator{
+	models.FormFieldTypeText:              validateText,
+	models.FormFieldTypeTextarea:          validateString,
+	models.FormFieldTypeRichText:          validateString,
+	models.FormFieldTypeColorPicker:       validateString,
+	models.FormFieldTypeNumber:            validateNumber,
+	models.FormFieldTypeDate:              validateDate,
+	models.FormFieldTypeTimestamp:         validateDate,
+	models.FormFieldTypeTelephone:         validateTelephone,
+	models.FormFieldTypeSelect:            validateSelect,
+	models.FormFieldTypeRadio:             validateSelect,
+	models.FormFieldTypeCustomSelect:      validateString,
+	models.FormFieldTypeMultiSelect:       validateMultiSelect,
+	models.FormFieldTypeCustomMultiSelect: validateStringList,
+	models.FormFieldTypeCheckbox:          validateCheckbox,
+	models.FormFieldTypeAddress:           validateAddress,
+	models.FormFieldTypeFile:              validateFile,
+	models.FormFieldTypeRating:            validateRating,
+	models.FormFieldTypeMatrix:            validateMatrix,
+	models.FormFieldTypeRanking:           validateRanking,
+}
+
+// ValidateResponse validates the value submitted for a single field
+func ValidateResponse(value interface{}, field models.FormField) error {
+	// Internal fields are filled in by organizers, so they are never required from the submitter
+	if field.IsInternal {
+		if value != nil {
+			return fmt.Errorf("field %s is internal, you're not allowed to specify this", field.Question)
+		}
+		return nil
+	}
+
+	if isEmpty(value) {
+		if field.Required {
+			return fmt.Errorf("field %s is required", field.Question)
+		}
+		return nil
+	}
+
+	validator, ok := fieldValidators[field.Type]
+	if !ok {
+		return nil
+	}
+
+	return validator(value, field)
+}
+
+// ValidateResponses validates a whole submission against the form fields.
+// The returned map is keyed by FormField.Key and is empty when the submission is valid.
+func ValidateResponses(formData map[string]interface{}, fields []models.FormField) map[string]string {
+	fieldErrors := make(map[string]string)
+
+	fieldMap := make(map[string]models.FormField)
+	for _, field := range fields {
+		fieldMap[field.Key] = field
+	}
+
+	for key := range formData {
+		if _, exists := fieldMap[key]; !exists {
+			fieldErrors[key] = "Invalid field key, form may have just changed"
+		}
+	}
+
+	// Iterate the fields rather than the data so missing required fields are caught too
+	for _, field := range fields {
+		if err := ValidateResponse(formData[field.Key], field); err != nil {
+			fieldErrors[field.Key] = err.Error()
+		}
+	}
+
+	return fieldErrors
+}
+
+// isEmpty reports whether a value should be treated as not submitted
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case []string:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+func validateString(value interface{}, field models.FormField) error {
+	if _, ok := value.(string); !ok {
+		return fmt.Errorf("field %s must be text", field.Question)
+	}
+	return nil
+}
+
+func validateText(value interface{}, field models.FormField) error {
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("field %s must be text", field.Question)
+	}
+
+	emailOptions := field.AdditionalValidation.IsEmail
+	if !emailOptions.IsEmail {
+		return nil
+	}
+
+	if !emailRegex.MatchString(text) {
+		return fmt.Errorf("field %s is not a valid email", field.Question)
+	}
+
+	if len(emailOptions.RequireDomain) > 0 && !validateDomain(text, emailOptions.RequireDomain, emailOptions.AllowSubdomains) {
+		return fmt.Errorf("field %s has a disallowed domain", field.Question)
+	}
+
+	if len(emailOptions.AllowTLDs) > 0 && !validateTLD(text, emailOptions.AllowTLDs) {
+		return fmt.Errorf("field %s has a disallowed top-level domain", field.Question)
+	}
+
+	return nil
+}
+
+func validateNumber(value interface{}, field models.FormField) error {
+	number, ok := toFloat64(value)
+	if !ok {
+		return fmt.Errorf("field %s must be a number", field.Question)
+	}
+
+	if field.AdditionalValidation.Min != 0 && number < float64(field.AdditionalValidation.Min) {
+		return fmt.Errorf("field %s is less than the minimum value allowed of %d", field.Question, field.AdditionalValidation.Min)
+	}
+
+	if field.AdditionalValidation.Max != 0 && number > float64(field.AdditionalValidation.Max) {
+		return fmt.Errorf("field %s is greater than the maximum value allowed of %d", field.Question, field.AdditionalValidation.Max)
+	}
+
+	return nil
+}
+
+// validateDate checks the date format and the min & max age in years, relative to now or DateAndTimestampFromTimeField
+func validateDate(value interface{}, field models.FormField) error {
+	dateStr, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("field %s has an invalid date format", field.Question)
+	}
+
+	date, err := time.Parse(dateFormat, dateStr)
+	if err != nil {
+		date, err = time.Parse(time.RFC3339, dateStr)
+		if err != nil {
+			return fmt.Errorf("field %s has an invalid date format", field.Question)
+		}
+	}
+
+	againstDate := time.Now()
+	if !field.AdditionalValidation.DateAndTimestampFromTimeField.IsZero() {
+		againstDate = field.AdditionalValidation.DateAndTimestampFromTimeField
+	}
+
+	if field.AdditionalValidation.Min != 0 {
+		minDate := againstDate.AddDate(-field.AdditionalValidation.Min, 0, 0)
+		if date.After(minDate) {
+			return fmt.Errorf("field %s is not older than the minimum age allowed of %d", field.Question, field.AdditionalValidation.Min)
+		}
+	}
+
+	if field.AdditionalValidation.Max != 0 {
+		maxDate := againstDate.AddDate(-field.AdditionalValidation.Max, 0, 0)
+		if date.Before(maxDate) {
+			return fmt.Errorf("field %s is not younger than the maximum age allowed of %d", field.Question, field.AdditionalValidation.Max)
+		}
+	}
+
+	return nil
+}
+
+func validateTelephone(value interface{}, field models.FormField) error {
+	phoneStr, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("field %s has an invalid phone number", field.Question)
+	}
+
+	// Blank region tells the library to figure it out
+	phoneNumber, err := phonenumbers.Parse(phoneStr, "")
+	if err != nil || !phonenumbers.IsValidNumber(phoneNumber) {
+		return fmt.Errorf("field %s has an invalid phone number", field.Question)
+	}
+
+	return nil
+}
+
+func validateSelect(value interface{}, field models.FormField) error {
+	option, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("field %s is not a valid option", field.Question)
+	}
+
+	if len(field.Options) > 0 && !containsString(field.Options, option) {
+		return fmt.Errorf("field %s is not a valid option", field.Question)
+	}
+
+	return nil
+}
+
+func validateStringList(value interface{}, field models.FormField) error {
+	_, err := toStringSlice(value, field)
+	return err
+}
+
+func validateMultiSelect(value interface{}, field models.FormField) error {
+	selected, err := toStringSlice(value, field)
+	if err != nil {
+		return err
+	}
+
+	return validateOptions(selected, field)
+}
+
+// validateCheckbox accepts a single boolean checkbox, or a list of checked options when the field has options
+func validateCheckbox(value interface{}, field models.FormField) error {
+	if checked, ok := value.(bool); ok {
+		if field.Required && !checked {
+			return fmt.Errorf("field %s is required", field.Question)
+		}
+		return nil
+	}
+
+	if len(field.Options) == 0 {
+		return fmt.Errorf("field %s must be true or false", field.Question)
+	}
+
+	selected, err := toStringSlice(value, field)
+	if err != nil {
+		return err
+	}
+
+	return validateOptions(selected, field)
+}
+
+func validateAddress(value interface{}, field models.FormField) error {
+	address, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("field %s must be an address", field.Question)
+	}
+
+	allowedKeys := map[string]struct{}{"streetAddress": {}, "city": {}, "region": {}, "zipCode": {}, "country": {}}
+	for key, part := range address {
+		if _, allowed := allowedKeys[key]; !allowed {
+			return fmt.Errorf("field %s has an unknown address part %s", field.Question, key)
+		}
+
+		if _, ok := part.(string); !ok && part != nil {
+			return fmt.Errorf("field %s has an invalid %s", field.Question, key)
+		}
+	}
+
+	for _, key := range field.AddressOptions.RequiredParts {
+		if part, _ := address[key].(string); part == "" {
+			return fmt.Errorf("field %s is missing the %s", field.Question, key)
+		}
+	}
+
+	return nil
+}
+
+// validateRating checks the value is a whole number on the rating scale
+func validateRating(value interface{}, field models.FormField) error {
+	rating, ok := toFloat64(value)
+	if !ok || rating != math.Trunc(rating) {
+		return fmt.Errorf("field %s must be a whole number", field.Question)
+	}
+
+	min, max := ratingScale(field.RatingOptions)
+	if rating < float64(min) || rating > float64(max) {
+		return fmt.Errorf("field %s must be between %d and %d", field.Question, min, max)
+	}
+
+	return nil
+}
+
+// validateMatrix checks the value maps rows to a column, or a list of columns when AllowMultiple is set
+func validateMatrix(value interface{}, field models.FormField) error {
+	answers, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("field %s must map each row to an answer", field.Question)
+	}
+
+	options := field.MatrixOptions
+	for row, answer := range answers {
+		if !containsString(options.Rows, row) {
+			return fmt.Errorf("field %s has an unknown row %s", field.Question, row)
+		}
+
+		var columns []string
+		if options.AllowMultiple {
+			selected, err := toStringSlice(answer, field)
+			if err != nil {
+				return err
+			}
+			columns = selected
+		} else {
+			column, ok := answer.(string)
+			if !ok {
+				return fmt.Errorf("field %s must have a single answer for row %s", field.Question, row)
+			}
+			columns = []string{column}
+		}
+
+		for _, column := range columns {
+			if !containsString(options.Columns, column) {
+				return fmt.Errorf("field %s has an invalid answer for row %s", field.Question, row)
+			}
+		}
+	}
+
+	if field.Required && options.RequireAllRows {
+		for _, row := range options.Rows {
+			if isEmpty(answers[row]) {
+				return fmt.Errorf("field %s is missing an answer for row %s", field.Question, row)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateRanking checks the value is an ordered list of distinct options, most preferred first
+func validateRanking(value interface{}, field models.FormField) error {
+	ranked, err := toStringSlice(value, field)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{})
+	for _, option := range ranked {
+		if !containsString(field.Options, option) {
+			return fmt.Errorf("field %s has an invalid option", field.Question)
+		}
+
+		if _, duplicate := seen[option]; duplicate {
+			return fmt.Errorf("field %s ranks %s more than once", field.Question, option)
+		}
+		seen[option] = struct{}{}
+	}
+
+	if field.RankingOptions.RequireAll && len(ranked) != len(field.Options) {
+		return fmt.Errorf("field %s must rank every option", field.Question)
+	}
+
+	if field.RankingOptions.MaxRanked > 0 && len(ranked) > field.RankingOptions.MaxRanked {
+		return fmt.Errorf("field %s allows at most %d ranked options", field.Question, field.RankingOptions.MaxRanked)
+	}
+
+	return nil
+}
+
+// ratingScale returns the bounds of a rating scale, defaulting to 1-5 when it isn't configured or was saved without a max above its min
+func ratingScale(options models.RatingOptions) (int, int) {
+	if options.Max <= options.Min {
+		return 1, 5
+	}
+	return options.Min, options.Max
+}
+
+// validateFile checks the value is a list of upload IDs, ownership of the uploads is checked on submission
+func validateFile(value interface{}, field models.FormField) error {
+	files, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("field %s must be a list of uploaded files", field.Question)
+	}
+
+	maxFiles := field.AdditionalValidation.MaxFiles
+	if maxFiles == 0 {
+		maxFiles = 1
+	}
+
+	if len(files) > maxFiles {
+		return fmt.Errorf("field %s allows at most %d files", field.Question, maxFiles)
+	}
+
+	for _, file := range files {
+		fileID, ok := file.(string)
+		if !ok || !primitive.IsValidObjectID(fileID) {
+			return fmt.Errorf("field %s has an invalid file", field.Question)
+		}
+	}
+
+	return nil
+}
+
+// validateOptions checks every selected value is one of the field options, when the field has options
+func validateOptions(selected []string, field models.FormField) error {
+	if len(field.Options) == 0 {
+		return nil
+	}
+
+	for _, option := range selected {
+		if !containsString(field.Options, option) {
+			return fmt.Errorf("field %s has an invalid option", field.Question)
+		}
+	}
+
+	return nil
+}
+
+// toFloat64 converts the numeric types a value can decode to, JSON numbers decode to float64
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toStringSlice converts a decoded JSON array to a slice of strings
+func toStringSlice(value interface{}, field models.FormField) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("field %s has an invalid option", field.Question)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("field %s must be a list of options", field.Question)
+	}
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package validators
+
+import (
+	"encoding/json"
+	"shared/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateResponse(t *testing.T) {
+	now := time.Now().UTC()
+	yearsAgo := func(years int) string {
+		return now.AddDate(-years, 0, 0).Format(dateFormat)
+	}
+
+	cases := []struct {
+		name        string
+		field       models.FormField
+		value       interface{}
+		expectError bool
+	}{
+		// Required & internal
+		{name: "Required missing", field: models.FormField{Type: models.FormFieldTypeText, Required: true}, value: nil, expectError: true},
+		{name: "Required empty string", field: models.FormField{Type: models.FormFieldTypeText, Required: true}, value: "", expectError: true},
+		{name: "Optional missing", field: models.FormField{Type: models.FormFieldTypeNumber}, value: nil, expectError: false},
+		{name: "Internal set", field: models.FormField{Type: models.FormFieldTypeText, IsInternal: true}, value: "x", expectError: true},
+		{name: "Internal required missing", field: models.FormField{Type: models.FormFieldTypeText, IsInternal: true, Required: true}, value: nil, expectError: false},
+		{name: "Unknown type", field: models.FormField{Type: "unknown"}, value: 1.0, expectError: false},
+
+		// Text
+		{name: "Text valid", field: models.FormField{Type: models.FormFieldTypeText}, value: "hello", expectError: false},
+		{name: "Text as number", field: models.FormField{Type: models.FormFieldTypeText, Required: true}, value: 5.0, expectError: true},
+		{name: "Text email valid", field: models.FormField{Type: models.FormFieldTypeText, AdditionalValidation: models.FieldValidation{IsEmail: models.EmailValidationOptions{IsEmail: true}}}, value: "a@example.com", expectError: false},
+		{name: "Text email invalid", field: models.FormField{Type: models.FormFieldTypeText, AdditionalValidation: models.FieldValidation{IsEmail: models.EmailValidationOptions{IsEmail: true}}}, value: "not an email", expectError: true},
+		{name: "Text email required domain", field: models.FormField{Type: models.FormFieldTypeText, AdditionalValidation: models.FieldValidation{IsEmail: models.EmailValidationOptions{IsEmail: true, RequireDomain: []string{"example.edu"}}}}, value: "a@example.edu", expectError: false},
+		{name: "Text email wrong domain", field: models.FormField{Type: models.FormFieldTypeText, AdditionalValidation: models.FieldValidation{IsEmail: models.EmailValidationOptions{IsEmail: true, RequireDomain: []string{"example.edu"}}}}, value: "a@exampleXedu", expectError: true},
+		{name: "Text email subdomain", field: models.FormField{Type: models.FormFieldTypeText, AdditionalValidation: models.FieldValidation{IsEmail: models.EmailValidationOptions{IsEmail: true, RequireDomain: []string{"example.edu"}, AllowSubdomains: true}}}, value: "a@cs.example.edu", expectError: false},
+		{name: "Text email subdomain disallowed", field: models.FormField{Type: models.FormFieldTypeText, AdditionalValidation: models.FieldValidation{IsEmail: models.EmailValidationOptions{IsEmail: true, RequireDomain: []string{"example.edu"}}}}, value: "a@cs.example.edu", expectError: true},
+		{name: "Text email allowed TLD", field: models.FormField{Type: models.FormFieldTypeText, AdditionalValidation: models.FieldValidation{IsEmail: models.EmailValidationOptions{IsEmail: true, AllowTLDs: []string{"edu"}}}}, value: "a@example.edu", expectError: false},
+		{name: "Text email disallowed TLD", field: models.FormField{Type: models.FormFieldTypeText, AdditionalValidation: models.FieldValidation{IsEmail: models.EmailValidationOptions{IsEmail: true, AllowTLDs: []string{"edu"}}}}, value: "a@example.com", expectError: true},
+
+		// Plain string types
+		{name: "Textarea valid", field: models.FormField{Type: models.FormFieldTypeTextarea}, value: "long text", expectError: false},
+		{name: "Textarea invalid", field: models.FormField{Type: models.FormFieldTypeTextarea}, value: true, expectError: true},
+		{name: "Richtext valid", field: models.FormField{Type: models.FormFieldTypeRichText}, value: "<p>hi</p>", expectError: false},
+		{name: "Colorpicker invalid", field: models.FormField{Type: models.FormFieldTypeColorPicker}, value: 123.0, expectError: true},
+		{name: "Customselect valid", field: models.FormField{Type: models.FormFieldTypeCustomSelect, Options: []string{"a"}}, value: "anything", expectError: false},
+
+		// Number
+		{name: "Number float64", field: models.FormField{Type: models.FormFieldTypeNumber, AdditionalValidation: models.FieldValidation{Min: 1, Max: 10}}, value: 5.0, expectError: false},
+		{name: "Number int", field: models.FormField{Type: models.FormFieldTypeNumber, AdditionalValidation: models.FieldValidation{Min: 1, Max: 10}}, value: 5, expectError: false},
+		{name: "Number json.Number", field: models.FormField{Type: models.FormFieldTypeNumber, AdditionalValidation: models.FieldValidation{Min: 1, Max: 10}}, value: json.Number("7"), expectError: false},
+		{name: "Number below min", field: models.FormField{Type: models.FormFieldTypeNumber, AdditionalValidation: models.FieldValidation{Min: 1}}, value: 0.5, expectError: true},
+		{name: "Number above max", field: models.FormField{Type: models.FormFieldTypeNumber, AdditionalValidation: models.FieldValidation{Max: 10}}, value: 10.5, expectError: true},
+		{name: "Number as string", field: models.FormField{Type: models.FormFieldTypeNumber}, value: "5", expectError: true},
+
+		// Date & timestamp
+		{name: "Date valid", field: models.FormField{Type: models.FormFieldTypeDate}, value: "2000-01-01T00:00:00.000Z", expectError: false},
+		{name: "Date RFC3339", field: models.FormField{Type: models.FormFieldTypeDate}, value: "2000-01-01T00:00:00Z", expectError: false},
+		{name: "Date invalid format", field: models.FormField{Type: models.FormFieldTypeDate}, value: "01/01/2000", expectError: true},
+		{name: "Date as number", field: models.FormField{Type: models.FormFieldTypeDate}, value: 946684800.0, expectError: true},
+		{name: "Date old enough", field: models.FormField{Type: models.FormFieldTypeDate, AdditionalValidation: models.FieldValidation{Min: 18}}, value: yearsAgo(20), expectError: false},
+		{name: "Date too young", field: models.FormField{Type: models.FormFieldTypeDate, AdditionalValidation: models.FieldValidation{Min: 18}}, value: yearsAgo(16), expectError: true},
+		{name: "Timestamp too old", field: models.FormField{Type: models.FormFieldTypeTimestamp, AdditionalValidation: models.FieldValidation{Max: 30}}, value: yearsAgo(40), expectError: true},
+		{name: "Date relative to field", field: models.FormField{Type: models.FormFieldTypeDate, AdditionalValidation: models.FieldValidation{Min: 18, DateAndTimestampFromTimeField: now.AddDate(-10, 0, 0)}}, value: yearsAgo(20), expectError: true},
+
+		// Telephone
+		{name: "Telephone valid", field: models.FormField{Type: models.FormFieldTypeTelephone}, value: "+14155552671", expectError: false},
+		{name: "Telephone invalid", field: models.FormField{Type: models.FormFieldTypeTelephone}, value: "12345", expectError: true},
+		{name: "Telephone as number", field: models.FormField{Type: models.FormFieldTypeTelephone}, value: 14155552671.0, expectError: true},
+
+		// Select & radio
+		{name: "Select valid", field: models.FormField{Type: models.FormFieldTypeSelect, Options: []string{"a", "b"}}, value: "a", expectError: false},
+		{name: "Select invalid option", field: models.FormField{Type: models.FormFieldTypeSelect, Options: []string{"a", "b"}}, value: "c", expectError: true},
+		{name: "Select as list", field: models.FormField{Type: models.FormFieldTypeSelect, Options: []string{"a"}}, value: []interface{}{"a"}, expectError: true},
+		{name: "Radio valid", field: models.FormField{Type: models.FormFieldTypeRadio, Options: []string{"yes", "no"}}, value: "no", expectError: false},
+		{name: "Radio invalid option", field: models.FormField{Type: models.FormFieldTypeRadio, Options: []string{"yes", "no"}}, value: "maybe", expectError: true},
+
+		// Multiselect
+		{name: "Multiselect valid", field: models.FormField{Type: models.FormFieldTypeMultiSelect, Options: []string{"a", "b"}}, value: []interface{}{"a", "b"}, expectError: false},
+		{name: "Multiselect invalid option", field: models.FormField{Type: models.FormFieldTypeMultiSelect, Options: []string{"a", "b"}}, value: []interface{}{"a", "c"}, expectError: true},
+		{name: "Multiselect non string", field: models.FormField{Type: models.FormFieldTypeMultiSelect, Options: []string{"a"}}, value: []interface{}{1.0}, expectError: true},
+		{name: "Multiselect as string", field: models.FormField{Type: models.FormFieldTypeMultiSelect, Options: []string{"a"}}, value: "a", expectError: true},
+		{name: "Multiselect required empty", field: models.FormField{Type: models.FormFieldTypeMultiSelect, Required: true}, value: []interface{}{}, expectError: true},
+		{name: "Custom multiselect valid", field: models.FormField{Type: models.FormFieldTypeCustomMultiSelect, Options: []string{"a"}}, value: []interface{}{"a", "z"}, expectError: false},
+		{name: "Custom multiselect invalid", field: models.FormField{Type: models.FormFieldTypeCustomMultiSelect}, value: map[string]interface{}{"a": "b"}, expectError: true},
+
+		// Checkbox
+		{name: "Checkbox checked", field: models.FormField{Type: models.FormFieldTypeCheckbox}, value: true, expectError: false},
+		{name: "Checkbox optional unchecked", field: models.FormField{Type: models.FormFieldTypeCheckbox}, value: false, expectError: false},
+		{name: "Checkbox required unchecked", field: models.FormField{Type: models.FormFieldTypeCheckbox, Required: true}, value: false, expectError: true},
+		{name: "Checkbox as string", field: models.FormField{Type: models.FormFieldTypeCheckbox}, value: "true", expectError: true},
+		{name: "Checkbox options valid", field: models.FormField{Type: models.FormFieldTypeCheckbox, Options: []string{"a", "b"}}, value: []interface{}{"b"}, expectError: false},
+		{name: "Checkbox options invalid", field: models.FormField{Type: models.FormFieldTypeCheckbox, Options: []string{"a", "b"}}, value: []interface{}{"c"}, expectError: true},
+
+		// Address
+		{name: "Address valid", field: models.FormField{Type: models.FormFieldTypeAddress}, value: map[string]interface{}{"streetAddress": "1 Main St", "city": "Springfield", "country": "US"}, expectError: false},
+		{name: "Address unknown part", field: models.FormField{Type: models.FormFieldTypeAddress}, value: map[string]interface{}{"planet": "Earth"}, expectError: true},
+		{name: "Address non string part", field: models.FormField{Type: models.FormFieldTypeAddress}, value: map[string]interface{}{"zipCode": 12345.0}, expectError: true},
+		{name: "Address as string", field: models.FormField{Type: models.FormFieldTypeAddress}, value: "1 Main St", expectError: true},
+
+		// Address parts
+		{name: "Address required parts", field: models.FormField{Type: models.FormFieldTypeAddress, AddressOptions: models.AddressOptions{RequiredParts: []string{"city", "country"}}}, value: map[string]interface{}{"city": "Springfield", "country": "US"}, expectError: false},
+		{name: "Address missing required part", field: models.FormField{Type: models.FormFieldTypeAddress, AddressOptions: models.AddressOptions{RequiredParts: []string{"city", "country"}}}, value: map[string]interface{}{"city": "Springfield", "country": ""}, expectError: true},
+		{name: "Address required empty", field: models.FormField{Type: models.FormFieldTypeAddress, Required: true}, value: map[string]interface{}{}, expectError: true},
+
+		// Rating
+		{name: "Rating default scale", field: models.FormField{Type: models.FormFieldTypeRating}, value: 5.0, expectError: false},
+		{name: "Rating above default scale", field: models.FormField{Type: models.FormFieldTypeRating}, value: 6.0, expectError: true},
+		{name: "Rating below default scale", field: models.FormField{Type: models.FormFieldTypeRating}, value: 0.0, expectError: true},
+		{name: "Rating custom scale", field: models.FormField{Type: models.FormFieldTypeRating, RatingOptions: models.RatingOptions{Min: 0, Max: 10}}, value: 0.0, expectError: false},
+		{name: "Rating scale without a max", field: models.FormField{Type: models.FormFieldTypeRating, RatingOptions: models.RatingOptions{Min: 3}}, value: 1.0, expectError: false},
+		{name: "Rating fractional", field: models.FormField{Type: models.FormFieldTypeRating}, value: 2.5, expectError: true},
+		{name: "Rating as string", field: models.FormField{Type: models.FormFieldTypeRating}, value: "3", expectError: true},
+
+		// Matrix
+		{name: "Matrix valid", field: models.FormField{Type: models.FormFieldTypeMatrix, MatrixOptions: models.MatrixOptions{Rows: []string{"Go", "Rust"}, Columns: []string{"1", "2", "3"}}}, value: map[string]interface{}{"Go": "3", "Rust": "1"}, expectError: false},
+		{name: "Matrix partial", field: models.FormField{Type: models.FormFieldTypeMatrix, MatrixOptions: models.MatrixOptions{Rows: []string{"Go", "Rust"}, Columns: []string{"1", "2", "3"}}}, value: map[string]interface{}{"Go": "3"}, expectError: false},
+		{name: "Matrix required all rows", field: models.FormField{Type: models.FormFieldTypeMatrix, Required: true, MatrixOptions: models.MatrixOptions{Rows: []string{"Go", "Rust"}, Columns: []string{"1", "2", "3"}, RequireAllRows: true}}, value: map[string]interface{}{"Go": "3"}, expectError: true},
+		{name: "Matrix unknown row", field: models.FormField{Type: models.FormFieldTypeMatrix, MatrixOptions: models.MatrixOptions{Rows: []string{"Go"}, Columns: []string{"1"}}}, value: map[string]interface{}{"Zig": "1"}, expectError: true},
+		{name: "Matrix invalid column", field: models.FormField{Type: models.FormFieldTypeMatrix, MatrixOptions: models.MatrixOptions{Rows: []string{"Go"}, Columns: []string{"1"}}}, value: map[string]interface{}{"Go": "9"}, expectError: true},
+		{name: "Matrix list without multiple", field: models.FormField{Type: models.FormFieldTypeMatrix, MatrixOptions: models.MatrixOptions{Rows: []string{"Go"}, Columns: []string{"1", "2"}}}, value: map[string]interface{}{"Go": []interface{}{"1"}}, expectError: true},
+		{name: "Matrix multiple", field: models.FormField{Type: models.FormFieldTypeMatrix, MatrixOptions: models.MatrixOptions{Rows: []string{"Go"}, Columns: []string{"1", "2"}, AllowMultiple: true}}, value: map[string]interface{}{"Go": []interface{}{"1", "2"}}, expectError: false},
+		{name: "Matrix as list", field: models.FormField{Type: models.FormFieldTypeMatrix, MatrixOptions: models.MatrixOptions{Rows: []string{"Go"}, Columns: []string{"1"}}}, value: []interface{}{"1"}, expectError: true},
+
+		// Ranking
+		{name: "Ranking valid", field: models.FormField{Type: models.FormFieldTypeRanking, Options: []string{"a", "b", "c"}}, value: []interface{}{"c", "a"}, expectError: false},
+		{name: "Ranking duplicate", field: models.FormField{Type: models.FormFieldTypeRanking, Options: []string{"a", "b", "c"}}, value: []interface{}{"a", "a"}, expectError: true},
+		{name: "Ranking invalid option", field: models.FormField{Type: models.FormFieldTypeRanking, Options: []string{"a", "b"}}, value: []interface{}{"z"}, expectError: true},
+		{name: "Ranking require all", field: models.FormField{Type: models.FormFieldTypeRanking, Options: []string{"a", "b", "c"}, RankingOptions: models.RankingOptions{RequireAll: true}}, value: []interface{}{"b", "a"}, expectError: true},
+		{name: "Ranking max ranked", field: models.FormField{Type: models.FormFieldTypeRanking, Options: []string{"a", "b", "c"}, RankingOptions: models.RankingOptions{MaxRanked: 2}}, value: []interface{}{"b", "a", "c"}, expectError: true},
+
+		// File
+		{name: "File valid", field: models.FormField{Type: models.FormFieldTypeFile}, value: []interface{}{"65f1c2a4b3e4d5f6a7b8c9d0"}, expectError: false},
+		{name: "File too many", field: models.FormField{Type: models.FormFieldTypeFile}, value: []interface{}{"65f1c2a4b3e4d5f6a7b8c9d0", "65f1c2a4b3e4d5f6a7b8c9d1"}, expectError: true},
+		{name: "File max files", field: models.FormField{Type: models.FormFieldTypeFile, AdditionalValidation: models.FieldValidation{MaxFiles: 2}}, value: []interface{}{"65f1c2a4b3e4d5f6a7b8c9d0", "65f1c2a4b3e4d5f6a7b8c9d1"}, expectError: false},
+		{name: "File invalid id", field: models.FormField{Type: models.FormFieldTypeFile}, value: []interface{}{"not-an-id"}, expectError: true},
+		{name: "File as string", field: models.FormField{Type: models.FormFieldTypeFile}, value: "65f1c2a4b3e4d5f6a7b8c9d0", expectError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.field.Question = tc.name
+			err := ValidateResponse(tc.value, tc.field)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateResponses(t *testing.T) {
+	fields := []models.FormField{
+		{Key: "name", Question: "Name", Type: models.FormFieldTypeText, Required: true},
+		{Key: "age", Question: "Age", Type: models.FormFieldTypeNumber, AdditionalValidation: models.FieldValidation{Min: 13}},
+		{Key: "notes", Question: "Notes", Type: models.FormFieldTypeText, IsInternal: true},
+	}
+
+	cases := []struct {
+		name     string
+		formData map[string]interface{}
+		expected map[string]string
+	}{
+		{
+			name:     "Valid",
+			formData: map[string]interface{}{"name": "Ada", "age": 20.0},
+			expected: map[string]string{},
+		},
+		{
+			name:     "Missing required field",
+			formData: map[string]interface{}{"age": 20.0},
+			expected: map[string]string{"name": "field Name is required"},
+		},
+		{
+			name:     "Multiple errors are keyed by field",
+			formData: map[string]interface{}{"name": 1.0, "age": 5.0, "notes": "hi", "unknown": "x"},
+			expected: map[string]string{
+				"name":    "field Name must be text",
+				"age":     "field Age is less than the minimum value allowed of 13",
+				"notes":   "field Notes is internal, you're not allowed to specify this",
+				"unknown": "Invalid field key, form may have just changed",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ValidateResponses(tc.formData, fields))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package responses
+
+import (
+	"api/internal/types"
+	"fmt"
+	"net/http"
+	"shared/logger"
+	"shared/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deletedField returns the last definition of a field that was removed from the form, labelled as deleted.
+// It reports false for data that no version of the form has a field for
+func deletedField(removedFields []models.FormField, key string) (models.FormField, bool) {
+	for _, field := range removedFields {
+		if field.Key == key {
+			field.Question = fmt.Sprintf("%s (deleted)", field.Question)
+			return field, true
+		}
+	}
+
+	return models.FormField{}, false
+}
+
+// listRemovedFields returns the fields earlier versions of the form had, it writes the error response on failure
+func listRemovedFields(c *gin.Context, params *types.RouteParams, form *models.FormStructure) ([]models.FormField, bool) {
+	versions, err := params.MongoService.ListFormVersions(c, form.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to list form versions", err)
+		return nil, false
+	}
+
+	return models.RemovedFormFields(form.Attrs, versions), true
+}
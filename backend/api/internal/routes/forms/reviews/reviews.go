@@ -0,0 +1,630 @@
+package reviews
+
+import (
+	"api/internal/middlewares"
+	"api/internal/types"
+	"math"
+	"net/http"
+	"shared/logger"
+	"shared/messages"
+	"shared/models"
+	"shared/triggers"
+	"shared/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+Reviews let organizers score the responses to a form against a rubric.
+
+Organizers define the rubric and assign batches of responses to reviewers, who must be organizers of the event.
+Reviewers only see the responses assigned to them, without the user who submitted them or the rubric's hidden fields.
+Reviews are stored apart from the response data and aggregated into a leaderboard.
+Once a response has ReviewsPerResponse reviews, ReviewCompleted pipelines for the form are triggered.
+*/
+
+func RegisterRoutes(r *gin.RouterGroup, params *types.RouteParams) {
+	r.GET("rubric", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewReviews), getReviewRubricHandler(params))
+	r.PUT("rubric", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageReviews), saveReviewRubricHandler(params))
+	r.GET("assignments", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewReviews), listReviewAssignmentsHandler(params))
+	r.POST("assignments", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionManageReviews), createReviewAssignmentsHandler(params))
+	r.GET("queue", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionSubmitReviews), getReviewQueueHandler(params))
+	r.GET("leaderboard", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewReviews), getLeaderboardHandler(params))
+	r.GET("responses/:response_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewReviews), listResponseReviewsHandler(params))
+	r.PUT("responses/:response_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionSubmitReviews), submitReviewHandler(params))
+}
+
+// getOrganizerForm loads the form from the path for routes whose permission was already checked, it writes the error response on failure
+func getOrganizerForm(c *gin.Context, params *types.RouteParams) (*models.FormStructure, *models.User, bool) {
+	authenticatedUser, ok := utils.GetUserFromContext(c, true)
+	if !ok {
+		return nil, nil, false
+	}
+
+	formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+		return nil, nil, false
+	}
+
+	form, err := params.MongoService.GetForm(c, formID, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form does not exist"})
+		return nil, nil, false
+	}
+
+	return form, authenticatedUser, true
+}
+
+// getRubric loads the rubric of the form, it writes the error response on failure
+func getRubric(c *gin.Context, params *types.RouteParams, formID primitive.ObjectID) (*models.ReviewRubric, bool) {
+	rubric, err := params.MongoService.GetReviewRubric(c, formID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This form does not have a review rubric"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Error("Failed to get review rubric", err)
+		return nil, false
+	}
+
+	return rubric, true
+}
+
+func getReviewRubricHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, _, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		rubric, ok := getRubric(c, params, form.ID)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"rubric": rubric})
+	}
+}
+
+func saveReviewRubricHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ReviewRubric
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		form, _, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		criteria := make(map[string]models.ReviewCriterion)
+		for _, criterion := range req.Criteria {
+			if _, duplicate := criteria[criterion.Key]; duplicate {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Criterion keys must be unique"})
+				return
+			}
+			criteria[criterion.Key] = criterion
+		}
+
+		fieldKeys := make(map[string]struct{})
+		for _, field := range form.Attrs {
+			fieldKeys[field.Key] = struct{}{}
+		}
+
+		for _, key := range req.HiddenFieldKeys {
+			if _, exists := fieldKeys[key]; !exists {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Hidden field keys must be fields on the form"})
+				return
+			}
+		}
+
+		existing, err := params.MongoService.GetReviewRubric(c, form.ID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get review rubric", err)
+			return
+		}
+
+		if existing != nil {
+			if existing.LastUpdatedAt.After(req.LastUpdatedAt) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": messages.UpdateAttemptOnChangedEntity})
+				return
+			}
+
+			// Scores are only comparable while the criteria and their ranges stay the same, weights can change freely
+			if !sameCriteriaScales(existing.Criteria, req.Criteria) {
+				reviews, err := params.MongoService.ListReviews(c, bson.M{"formID": form.ID})
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+					logger.Error("Failed to list reviews", err)
+					return
+				}
+
+				if len(reviews) > 0 {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Criteria and score ranges can't be changed once reviews have been submitted"})
+					return
+				}
+			}
+		}
+
+		req.FormID = form.ID
+		req.EventID = form.EventID
+		req.LastUpdatedAt = time.Now()
+		if _, err := params.MongoService.SaveReviewRubric(c, req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to save review rubric", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Rubric saved", "lastUpdatedAt": req.LastUpdatedAt})
+	}
+}
+
+/*
+List the review assignments of a form
+
+query params:
+  - reviewerID: only list the assignments of this reviewer
+*/
+func listReviewAssignmentsHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, _, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		filter := bson.M{"formID": form.ID}
+		if reviewerIDStr := c.Query("reviewerID"); reviewerIDStr != "" {
+			reviewerID, err := primitive.ObjectIDFromHex(reviewerIDStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reviewer ID"})
+				return
+			}
+			filter["reviewerID"] = reviewerID
+		}
+
+		assignments, err := params.MongoService.ListReviewAssignments(c, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list review assignments", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"assignments": assignments})
+	}
+}
+
+type createReviewAssignmentsRequest struct {
+	ReviewerIDs []primitive.ObjectID `json:"reviewerIDs" validate:"required,min=1"`
+	ResponseIDs []primitive.ObjectID `json:"responseIDs"`                         // assigned to every reviewer
+	BatchSize   int                  `json:"batchSize" validate:"gte=0,lte=1000"` // when no response IDs are given, pick this many per reviewer
+}
+
+/*
+Assign batches of responses to reviewers
+
+Either an explicit list of responses is assigned to every reviewer, or each reviewer is given a batch of
+the responses that still need reviewers, least covered first. Responses are never assigned to the same reviewer twice.
+*/
+func createReviewAssignmentsHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createReviewAssignmentsRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		if len(req.ResponseIDs) == 0 && req.BatchSize == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Either responseIDs or batchSize must be provided"})
+			return
+		}
+
+		form, authenticatedUser, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		rubric, ok := getRubric(c, params, form.ID)
+		if !ok {
+			return
+		}
+
+		event, err := params.MongoService.FindEvent(c, form.EventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to get event", err)
+			return
+		}
+
+		for _, reviewerID := range req.ReviewerIDs {
+			if role, isOrganizer := event.OrganizerRole(reviewerID); !isOrganizer || !role.Can(models.PermissionSubmitReviews) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Reviewers must be organizers of the event whose role can submit reviews"})
+				return
+			}
+		}
+
+		existingAssignments, err := params.MongoService.ListReviewAssignments(c, bson.M{"formID": form.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list review assignments", err)
+			return
+		}
+
+		assignedCounts := make(map[primitive.ObjectID]int)
+		assignedToReviewer := make(map[primitive.ObjectID]map[primitive.ObjectID]bool)
+		for _, reviewerID := range req.ReviewerIDs {
+			assignedToReviewer[reviewerID] = make(map[primitive.ObjectID]bool)
+		}
+
+		for _, assignment := range existingAssignments {
+			for _, responseID := range assignment.ResponseIDs {
+				assignedCounts[responseID]++
+				if assigned, ok := assignedToReviewer[assignment.ReviewerID]; ok {
+					assigned[responseID] = true
+				}
+			}
+		}
+
+		filter := bson.M{"formID": form.ID}
+		if len(req.ResponseIDs) > 0 {
+			filter["_id"] = bson.M{"$in": req.ResponseIDs}
+		}
+
+		opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}).SetProjection(bson.M{"_id": 1})
+		responses, err := params.MongoService.ListResponses(c, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list form responses", err)
+			return
+		}
+
+		if len(req.ResponseIDs) > 0 && len(responses) != len(req.ResponseIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more responses do not belong to this form"})
+			return
+		}
+
+		responseIDs := make([]primitive.ObjectID, 0, len(responses))
+		for _, response := range responses {
+			responseIDs = append(responseIDs, response.ID)
+		}
+
+		reviewsPerResponse := rubric.ReviewsPerResponse
+		batchSize := req.BatchSize
+		if len(req.ResponseIDs) > 0 {
+			// Explicit assignments aren't limited by coverage
+			reviewsPerResponse = math.MaxInt32
+			batchSize = len(responseIDs)
+		} else if reviewsPerResponse == 0 {
+			reviewsPerResponse = 1
+		}
+
+		created := []models.ReviewAssignment{}
+		for _, reviewerID := range req.ReviewerIDs {
+			batch := pickBatch(responseIDs, assignedCounts, assignedToReviewer[reviewerID], reviewsPerResponse, batchSize)
+			if len(batch) == 0 {
+				continue
+			}
+
+			assignment := models.ReviewAssignment{
+				FormID:      form.ID,
+				ReviewerID:  reviewerID,
+				ResponseIDs: batch,
+				CreatedByID: authenticatedUser.ID,
+			}
+
+			result, err := params.MongoService.CreateReviewAssignment(c, assignment)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				logger.Error("Failed to create review assignment", err)
+				return
+			}
+
+			assignment.ID = result.InsertedID.(primitive.ObjectID)
+			created = append(created, assignment)
+			for _, responseID := range batch {
+				assignedCounts[responseID]++
+				assignedToReviewer[reviewerID][responseID] = true
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"assignments": created})
+	}
+}
+
+// reviewQueueItem is a response as a reviewer sees it
+type reviewQueueItem struct {
+	ResponseID primitive.ObjectID     `json:"responseID"`
+	Data       map[string]interface{} `json:"data"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	Review     *models.Review         `json:"review"` // the reviewer's own review, if they have submitted one
+}
+
+// Get the responses assigned to the authenticated reviewer, with the rubric's hidden fields removed
+func getReviewQueueHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, authenticatedUser, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		rubric, ok := getRubric(c, params, form.ID)
+		if !ok {
+			return
+		}
+
+		assignments, err := params.MongoService.ListReviewAssignments(c, bson.M{"formID": form.ID, "reviewerID": authenticatedUser.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list review assignments", err)
+			return
+		}
+
+		responseIDs := []primitive.ObjectID{}
+		for _, assignment := range assignments {
+			responseIDs = append(responseIDs, assignment.ResponseIDs...)
+		}
+
+		queue := []reviewQueueItem{}
+		if len(responseIDs) == 0 {
+			c.JSON(http.StatusOK, gin.H{"rubric": rubric, "queue": queue})
+			return
+		}
+
+		opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+		responses, err := params.MongoService.ListResponses(c, bson.M{"_id": bson.M{"$in": responseIDs}, "formID": form.ID}, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list form responses", err)
+			return
+		}
+
+		reviews, err := params.MongoService.ListReviews(c, bson.M{"formID": form.ID, "reviewerID": authenticatedUser.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list reviews", err)
+			return
+		}
+
+		reviewsByResponse := make(map[primitive.ObjectID]models.Review)
+		for _, review := range reviews {
+			reviewsByResponse[review.ResponseID] = review
+		}
+
+		for _, response := range responses {
+			for _, key := range rubric.HiddenFieldKeys {
+				delete(response.Data, key)
+			}
+
+			item := reviewQueueItem{ResponseID: response.ID, Data: response.Data, CreatedAt: response.CreatedAt}
+			if review, reviewed := reviewsByResponse[response.ID]; reviewed {
+				item.Review = &review
+			}
+			queue = append(queue, item)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"rubric": rubric, "queue": queue})
+	}
+}
+
+type submitReviewRequest struct {
+	Scores  map[string]float64 `json:"scores" validate:"required"`
+	Comment string             `json:"comment" validate:"max=5000"`
+}
+
+// Submit or update the authenticated reviewer's review of a response assigned to them
+func submitReviewHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req submitReviewRequest
+		if err := utils.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors := utils.ValidateStruct(utils.Validator, req); len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(errors, "\n")})
+			return
+		}
+
+		responseID, err := primitive.ObjectIDFromHex(c.Param("response_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid response ID"})
+			return
+		}
+
+		form, authenticatedUser, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		assignments, err := params.MongoService.ListReviewAssignments(c, bson.M{"formID": form.ID, "reviewerID": authenticatedUser.ID, "responseIDs": responseID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list review assignments", err)
+			return
+		}
+
+		if len(assignments) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This response is not assigned to you for review"})
+			return
+		}
+
+		rubric, ok := getRubric(c, params, form.ID)
+		if !ok {
+			return
+		}
+
+		score, err := weightedScore(rubric, req.Scores)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		responses, err := params.MongoService.ListResponses(c, bson.M{"_id": responseID, "formID": form.ID}, nil)
+		if err != nil || len(responses) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Response does not exist"})
+			return
+		}
+
+		review := models.Review{
+			FormID:        form.ID,
+			ResponseID:    responseID,
+			ReviewerID:    authenticatedUser.ID,
+			Scores:        req.Scores,
+			Comment:       req.Comment,
+			WeightedScore: score,
+		}
+
+		result, err := params.MongoService.SaveReview(c, review)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to save review", err)
+			return
+		}
+
+		// Only a reviewer's first review of a response can complete it, edits don't re-trigger pipelines
+		reviewCompleted := false
+		if result.UpsertedCount > 0 {
+			reviewCompleted, err = onReviewAdded(c, params, form, rubric, responses[0])
+			if err != nil {
+				logger.Error("Failed to trigger review completed pipelines", err)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Review saved", "weightedScore": score, "reviewCompleted": reviewCompleted})
+	}
+}
+
+// onReviewAdded triggers the ReviewCompleted pipelines when the response has just received its last required review
+func onReviewAdded(c *gin.Context, params *types.RouteParams, form *models.FormStructure, rubric *models.ReviewRubric, response models.FormResponse) (bool, error) {
+	reviewsPerResponse := rubric.ReviewsPerResponse
+	if reviewsPerResponse == 0 {
+		reviewsPerResponse = 1
+	}
+
+	reviews, err := params.MongoService.ListReviews(c, bson.M{"responseID": response.ID})
+	if err != nil {
+		return false, err
+	}
+
+	if len(reviews) < reviewsPerResponse {
+		return false, nil
+	}
+
+	// Reviews saved at the same time can all see the required count, only the one that marks the response triggers the pipelines
+	completed, err := params.MongoService.MarkReviewCompleted(c, form.ID, response.ID)
+	if err != nil || !completed {
+		return false, err
+	}
+
+	pipelines, err := params.MongoService.ListPipelines(c, bson.M{"eventID": form.EventID, "event.type": "ReviewCompleted", "event.reviewCompleted.onFormID": form.ID})
+	if err != nil {
+		return true, err
+	}
+
+	if len(pipelines) == 0 {
+		return true, nil
+	}
+
+	sub, err := triggers.GetEventSubscription(c, params.MongoService, form.EventID)
+	if err != nil {
+		return true, err
+	}
+
+	return true, triggers.BilledPipelines(c, params.MessageProducer, params.MongoService, sub, pipelines, response.Data)
+}
+
+// List every review of a response
+func listResponseReviewsHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		responseID, err := primitive.ObjectIDFromHex(c.Param("response_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid response ID"})
+			return
+		}
+
+		form, _, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		reviews, err := params.MongoService.ListReviews(c, bson.M{"formID": form.ID, "responseID": responseID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list reviews", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+	}
+}
+
+// Get the responses ranked by their mean weighted score, scores are recalculated with the current rubric weights
+func getLeaderboardHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, _, ok := getOrganizerForm(c, params)
+		if !ok {
+			return
+		}
+
+		rubric, ok := getRubric(c, params, form.ID)
+		if !ok {
+			return
+		}
+
+		reviews, err := params.MongoService.ListReviews(c, bson.M{"formID": form.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list reviews", err)
+			return
+		}
+
+		for i := range reviews {
+			if score, err := weightedScore(rubric, reviews[i].Scores); err == nil {
+				reviews[i].WeightedScore = score
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"leaderboard": buildLeaderboard(reviews)})
+	}
+}
+
+// sameCriteriaScales reports whether both rubrics score the same criteria on the same ranges
+func sameCriteriaScales(a []models.ReviewCriterion, b []models.ReviewCriterion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	scales := make(map[string]models.ReviewCriterion)
+	for _, criterion := range a {
+		scales[criterion.Key] = criterion
+	}
+
+	for _, criterion := range b {
+		existing, ok := scales[criterion.Key]
+		if !ok || existing.MinScore != criterion.MinScore || existing.MaxScore != criterion.MaxScore {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,150 @@
+package reviews
+
+import (
+	"fmt"
+	"math"
+	"shared/models"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LeaderboardEntry is the aggregate of every review of a response
+type LeaderboardEntry struct {
+	Rank          int                `json:"rank"`
+	ResponseID    primitive.ObjectID `json:"responseID"`
+	ReviewCount   int                `json:"reviewCount"`
+	MeanScore     float64            `json:"meanScore"`
+	ScoreVariance float64            `json:"scoreVariance"` // population variance of the reviewers' weighted scores
+	MinScore      float64            `json:"minScore"`
+	MaxScore      float64            `json:"maxScore"`
+	CriteriaMeans map[string]float64 `json:"criteriaMeans"`
+}
+
+// weightedScore validates the scores against the rubric and returns the weighted score out of 100.
+// Each criterion is normalized to its own range first so criteria on different scales are comparable.
+func weightedScore(rubric *models.ReviewRubric, scores map[string]float64) (float64, error) {
+	criteria := make(map[string]models.ReviewCriterion)
+	for _, criterion := range rubric.Criteria {
+		criteria[criterion.Key] = criterion
+	}
+
+	for key := range scores {
+		if _, exists := criteria[key]; !exists {
+			return 0, fmt.Errorf("unknown criterion %s", key)
+		}
+	}
+
+	var total, totalWeight float64
+	for _, criterion := range rubric.Criteria {
+		score, exists := scores[criterion.Key]
+		if !exists {
+			return 0, fmt.Errorf("criterion %s must be scored", criterion.Name)
+		}
+
+		if score < criterion.MinScore || score > criterion.MaxScore {
+			return 0, fmt.Errorf("criterion %s must be scored between %g and %g", criterion.Name, criterion.MinScore, criterion.MaxScore)
+		}
+
+		normalized := (score - criterion.MinScore) / (criterion.MaxScore - criterion.MinScore)
+		total += normalized * criterion.Weight
+		totalWeight += criterion.Weight
+	}
+
+	if totalWeight == 0 {
+		return 0, fmt.Errorf("rubric has no weighted criteria")
+	}
+
+	return roundScore(total / totalWeight * 100), nil
+}
+
+// buildLeaderboard aggregates the reviews per response and ranks the responses by mean score, highest first
+func buildLeaderboard(reviews []models.Review) []LeaderboardEntry {
+	byResponse := make(map[primitive.ObjectID][]models.Review)
+	for _, review := range reviews {
+		byResponse[review.ResponseID] = append(byResponse[review.ResponseID], review)
+	}
+
+	leaderboard := make([]LeaderboardEntry, 0, len(byResponse))
+	for responseID, responseReviews := range byResponse {
+		entry := LeaderboardEntry{
+			ResponseID:    responseID,
+			ReviewCount:   len(responseReviews),
+			MinScore:      math.Inf(1),
+			MaxScore:      math.Inf(-1),
+			CriteriaMeans: make(map[string]float64),
+		}
+
+		var sum float64
+		criteriaCounts := make(map[string]int)
+		for _, review := range responseReviews {
+			sum += review.WeightedScore
+			entry.MinScore = math.Min(entry.MinScore, review.WeightedScore)
+			entry.MaxScore = math.Max(entry.MaxScore, review.WeightedScore)
+
+			for key, score := range review.Scores {
+				entry.CriteriaMeans[key] += score
+				criteriaCounts[key]++
+			}
+		}
+
+		entry.MeanScore = sum / float64(len(responseReviews))
+		for _, review := range responseReviews {
+			entry.ScoreVariance += math.Pow(review.WeightedScore-entry.MeanScore, 2)
+		}
+		entry.ScoreVariance /= float64(len(responseReviews))
+
+		for key, count := range criteriaCounts {
+			entry.CriteriaMeans[key] = roundScore(entry.CriteriaMeans[key] / float64(count))
+		}
+		entry.MeanScore = roundScore(entry.MeanScore)
+		entry.ScoreVariance = roundScore(entry.ScoreVariance)
+
+		leaderboard = append(leaderboard, entry)
+	}
+
+	// Ties are broken by the number of reviews then the response ID so the order is stable
+	sort.Slice(leaderboard, func(i, j int) bool {
+		a, b := leaderboard[i], leaderboard[j]
+		if a.MeanScore != b.MeanScore {
+			return a.MeanScore > b.MeanScore
+		}
+		if a.ReviewCount != b.ReviewCount {
+			return a.ReviewCount > b.ReviewCount
+		}
+		return a.ResponseID.Hex() < b.ResponseID.Hex()
+	})
+
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+
+	return leaderboard
+}
+
+// pickBatch chooses up to batchSize responses for a reviewer, skipping responses already assigned to them and
+// responses that already have enough reviewers. The least assigned responses are picked first so coverage stays even.
+func pickBatch(responseIDs []primitive.ObjectID, assignedCounts map[primitive.ObjectID]int, assignedToReviewer map[primitive.ObjectID]bool, reviewsPerResponse int, batchSize int) []primitive.ObjectID {
+	candidates := []primitive.ObjectID{}
+	for _, responseID := range responseIDs {
+		if assignedToReviewer[responseID] || assignedCounts[responseID] >= reviewsPerResponse {
+			continue
+		}
+		candidates = append(candidates, responseID)
+	}
+
+	// Stable so responses with equal coverage keep their original (submission) order
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return assignedCounts[candidates[i]] < assignedCounts[candidates[j]]
+	})
+
+	if len(candidates) > batchSize {
+		candidates = candidates[:batchSize]
+	}
+
+	return candidates
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
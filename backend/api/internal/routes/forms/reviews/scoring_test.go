@@ -0,0 +1,127 @@
+package reviews
+
+import (
+	"shared/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testRubric = &models.ReviewRubric{
+	Criteria: []models.ReviewCriterion{
+		{Key: "technical", Name: "Technical", Weight: 3, MinScore: 1, MaxScore: 5},
+		{Key: "passion", Name: "Passion", Weight: 1, MinScore: 0, MaxScore: 10},
+	},
+}
+
+func TestWeightedScore(t *testing.T) {
+	cases := []struct {
+		name        string
+		scores      map[string]float64
+		expected    float64
+		expectError bool
+	}{
+		{name: "All max", scores: map[string]float64{"technical": 5, "passion": 10}, expected: 100},
+		{name: "All min", scores: map[string]float64{"technical": 1, "passion": 0}, expected: 0},
+		{name: "Weighted", scores: map[string]float64{"technical": 5, "passion": 0}, expected: 75},
+		{name: "Normalized per criterion", scores: map[string]float64{"technical": 3, "passion": 5}, expected: 50},
+		{name: "Missing criterion", scores: map[string]float64{"technical": 5}, expectError: true},
+		{name: "Unknown criterion", scores: map[string]float64{"technical": 5, "passion": 5, "vibes": 1}, expectError: true},
+		{name: "Below range", scores: map[string]float64{"technical": 0, "passion": 5}, expectError: true},
+		{name: "Above range", scores: map[string]float64{"technical": 5, "passion": 11}, expectError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			score, err := weightedScore(testRubric, tc.scores)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, score)
+		})
+	}
+}
+
+func TestBuildLeaderboard(t *testing.T) {
+	first := primitive.NewObjectID()
+	second := primitive.NewObjectID()
+	third := primitive.NewObjectID()
+
+	reviews := []models.Review{
+		{ResponseID: second, WeightedScore: 70, Scores: map[string]float64{"technical": 3}},
+		{ResponseID: first, WeightedScore: 90, Scores: map[string]float64{"technical": 5}},
+		{ResponseID: second, WeightedScore: 90, Scores: map[string]float64{"technical": 4}},
+		{ResponseID: first, WeightedScore: 70, Scores: map[string]float64{"technical": 4}},
+		{ResponseID: third, WeightedScore: 50, Scores: map[string]float64{"technical": 2}},
+	}
+
+	leaderboard := buildLeaderboard(reviews)
+	assert.Len(t, leaderboard, 3)
+
+	// first and second both average 80 over two reviews, the tie is broken by response ID
+	assert.Equal(t, 80.0, leaderboard[0].MeanScore)
+	assert.Equal(t, 80.0, leaderboard[1].MeanScore)
+	assert.ElementsMatch(t, []primitive.ObjectID{first, second}, []primitive.ObjectID{leaderboard[0].ResponseID, leaderboard[1].ResponseID})
+
+	for _, entry := range leaderboard[:2] {
+		assert.Equal(t, 2, entry.ReviewCount)
+		assert.Equal(t, 100.0, entry.ScoreVariance)
+	}
+
+	byID := map[primitive.ObjectID]LeaderboardEntry{}
+	for _, entry := range leaderboard {
+		byID[entry.ResponseID] = entry
+	}
+	assert.Equal(t, 70.0, byID[first].MinScore)
+	assert.Equal(t, 90.0, byID[first].MaxScore)
+	assert.Equal(t, 4.5, byID[first].CriteriaMeans["technical"])
+
+	assert.Equal(t, third, leaderboard[2].ResponseID)
+	assert.Equal(t, 3, leaderboard[2].Rank)
+	assert.Equal(t, 0.0, leaderboard[2].ScoreVariance)
+}
+
+func TestPickBatch(t *testing.T) {
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+
+	cases := []struct {
+		name               string
+		assignedCounts     map[primitive.ObjectID]int
+		assignedToReviewer map[primitive.ObjectID]bool
+		reviewsPerResponse int
+		batchSize          int
+		expected           []primitive.ObjectID
+	}{
+		{
+			name:               "Fresh form keeps submission order",
+			reviewsPerResponse: 1,
+			batchSize:          2,
+			expected:           []primitive.ObjectID{ids[0], ids[1]},
+		},
+		{
+			name:               "Fully covered responses are skipped",
+			assignedCounts:     map[primitive.ObjectID]int{ids[0]: 1, ids[1]: 1},
+			reviewsPerResponse: 1,
+			batchSize:          10,
+			expected:           []primitive.ObjectID{ids[2], ids[3]},
+		},
+		{
+			name:               "Least covered first and never the same reviewer twice",
+			assignedCounts:     map[primitive.ObjectID]int{ids[0]: 1, ids[1]: 2, ids[2]: 1},
+			assignedToReviewer: map[primitive.ObjectID]bool{ids[2]: true},
+			reviewsPerResponse: 3,
+			batchSize:          3,
+			expected:           []primitive.ObjectID{ids[3], ids[0], ids[1]},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			batch := pickBatch(ids, tc.assignedCounts, tc.assignedToReviewer, tc.reviewsPerResponse, tc.batchSize)
+			assert.Equal(t, tc.expected, batch)
+		})
+	}
+}
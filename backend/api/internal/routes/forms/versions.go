@@ -0,0 +1,87 @@
+package forms
+
+import (
+	"api/internal/types"
+	"encoding/json"
+	"net/http"
+	"shared/logger"
+	"shared/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// formVersionSummary is a version of a form's fields and what changed since the version before it
+type formVersionSummary struct {
+	Version     int                     `json:"version"`
+	Attrs       []models.FormField      `json:"attrs"`
+	Changes     models.FormFieldChanges `json:"changes"`
+	CreatedByID primitive.ObjectID      `json:"createdByID"`
+	CreatedAt   time.Time               `json:"createdAt"`
+}
+
+// fieldsChanged reports whether a save changes the form's fields. They are compared as the API serializes them,
+// so fields that only differ between a missing and an empty list don't count as a change
+func fieldsChanged(previous []models.FormField, updated []models.FormField) bool {
+	previousJSON, err := json.Marshal(previous)
+	if err != nil {
+		return true
+	}
+
+	updatedJSON, err := json.Marshal(updated)
+	if err != nil {
+		return true
+	}
+
+	return string(previousJSON) != string(updatedJSON)
+}
+
+// summarizeFormVersions lists the versions with the fields added, renamed and removed by each, the first version adds all its fields
+func summarizeFormVersions(versions []models.FormVersion) []formVersionSummary {
+	summaries := make([]formVersionSummary, 0, len(versions))
+	var previous []models.FormField
+	for _, version := range versions {
+		summaries = append(summaries, formVersionSummary{
+			Version:     version.Version,
+			Attrs:       version.Attrs,
+			Changes:     models.DiffFormFields(previous, version.Attrs),
+			CreatedByID: version.CreatedByID,
+			CreatedAt:   version.CreatedAt,
+		})
+		previous = version.Attrs
+	}
+
+	return summaries
+}
+
+/*
+List the versions of a form's fields, oldest first, with the fields added, renamed and removed by each
+
+params:
+  - form_id: ID of the form
+*/
+func listFormVersionsHandler(params *types.RouteParams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		formID, err := primitive.ObjectIDFromHex(c.Param("form_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form ID"})
+			return
+		}
+
+		form, err := params.MongoService.GetForm(c, formID, true)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Form not found"})
+			return
+		}
+
+		versions, err := params.MongoService.ListFormVersions(c, formID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			logger.Error("Failed to list form versions", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"currentVersion": form.Version, "versions": summarizeFormVersions(versions)})
+	}
+}
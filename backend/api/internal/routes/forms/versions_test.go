@@ -0,0 +1,31 @@
+package forms
+
+import (
+	"shared/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldsChanged(t *testing.T) {
+	fields := []models.FormField{{Key: "name", Question: "Name", Type: models.FormFieldTypeText}}
+
+	assert.False(t, fieldsChanged(fields, []models.FormField{{Key: "name", Question: "Name", Type: models.FormFieldTypeText, Options: []string{}}}), "an empty list is the same as none")
+	assert.True(t, fieldsChanged(fields, []models.FormField{{Key: "name", Question: "Full name", Type: models.FormFieldTypeText}}))
+	assert.True(t, fieldsChanged(fields, nil))
+}
+
+func TestSummarizeFormVersions(t *testing.T) {
+	versions := []models.FormVersion{
+		{Version: 1, Attrs: []models.FormField{{Key: "name", Question: "Name"}}},
+		{Version: 2, Attrs: []models.FormField{{Key: "name", Question: "Full name"}, {Key: "team", Question: "Team"}}},
+	}
+
+	summaries := summarizeFormVersions(versions)
+	require.Len(t, summaries, 2)
+	assert.Len(t, summaries[0].Changes.Added, 1)
+	assert.Equal(t, "team", summaries[1].Changes.Added[0].Key)
+	assert.Equal(t, "Full name", summaries[1].Changes.Renamed[0].ToQuestion)
+	assert.Empty(t, summaries[1].Changes.Removed)
+}
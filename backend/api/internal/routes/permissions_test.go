@@ -0,0 +1,399 @@
+package routes
+
+import (
+	"api/internal/types"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"shared/models"
+	"shared/mongodb"
+	"shared/utils"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// permissionsMongo holds a single event with a form, pipeline and email template, only the lookups the permission checks use are implemented
+type permissionsMongo struct {
+	mongodb.MongoService
+	event    models.Event
+	form     models.FormStructure
+	pipeline models.PipelineConfiguration
+	template models.EmailTemplate
+	sessions map[primitive.ObjectID]primitive.ObjectID // session ID to user ID
+	verified map[primitive.ObjectID]bool               // sessions signed in with a second factor
+	tokens   map[string]models.APIToken                // API tokens by their hash
+}
+
+func (m *permissionsMongo) FindEvent(ctx context.Context, eventID primitive.ObjectID) (*models.Event, error) {
+	if eventID != m.event.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	event := m.event
+	return &event, nil
+}
+
+// GetSession accepts the sessions the test signed tokens for
+func (m *permissionsMongo) GetSession(ctx context.Context, sessionID primitive.ObjectID) (*models.Session, error) {
+	return &models.Session{ID: sessionID, UserID: m.sessions[sessionID], TwoFactorVerified: m.verified[sessionID]}, nil
+}
+
+func (m *permissionsMongo) GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &token, nil
+}
+
+func (m *permissionsMongo) TouchAPIToken(ctx context.Context, tokenID primitive.ObjectID) error {
+	return nil
+}
+
+func (m *permissionsMongo) InsertAuditLogEntry(ctx context.Context, entry models.AuditLogEntry) (*mongo.InsertOneResult, error) {
+	return &mongo.InsertOneResult{InsertedID: primitive.NewObjectID()}, nil
+}
+
+func (m *permissionsMongo) GetUserDetails(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	return &models.User{ID: userID}, nil
+}
+
+func (m *permissionsMongo) GetForm(ctx context.Context, formID primitive.ObjectID, stripSecrets bool) (*models.FormStructure, error) {
+	if formID != m.form.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	form := m.form
+	return &form, nil
+}
+
+func (m *permissionsMongo) GetPipeline(ctx context.Context, pipelineID primitive.ObjectID) (*models.PipelineConfiguration, error) {
+	if pipelineID != m.pipeline.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	pipeline := m.pipeline
+	return &pipeline, nil
+}
+
+func (m *permissionsMongo) GetEmailTemplate(ctx context.Context, templateID primitive.ObjectID) (*models.EmailTemplate, error) {
+	if templateID != m.template.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	template := m.template
+	return &template, nil
+}
+
+// organizerRoutes are the routes on an event's resources and the permission each requires
+var organizerRoutes = []struct {
+	method     string
+	path       string
+	permission models.Permission
+}{
+	{http.MethodPut, "/events/:event_id", models.PermissionEditEvent},
+	{http.MethodDelete, "/events/:event_id", models.PermissionDeleteEvent},
+	{http.MethodGet, "/events/:event_id/forms", models.PermissionViewEvent},
+	{http.MethodGet, "/events/:event_id/pipelines", models.PermissionViewEvent},
+	{http.MethodGet, "/events/:event_id/email_templates", models.PermissionViewEvent},
+	{http.MethodPost, "/events/:event_id/organizers/:user_email", models.PermissionManageOrganizers},
+	{http.MethodPut, "/events/:event_id/organizers/:user_id", models.PermissionManageOrganizers},
+	{http.MethodDelete, "/events/:event_id/organizers/:user_id", models.PermissionManageOrganizers},
+	{http.MethodPut, "/events/:event_id/two-factor", models.PermissionManageSecurity},
+	{http.MethodGet, "/events/:event_id/invites", models.PermissionManageOrganizers},
+	{http.MethodPost, "/events/:event_id/invites/:invite_id/resend", models.PermissionManageOrganizers},
+	{http.MethodDelete, "/events/:event_id/invites/:invite_id", models.PermissionManageOrganizers},
+	{http.MethodGet, "/events/:event_id/audit-log", models.PermissionViewAuditLog},
+	{http.MethodGet, "/events/:event_id/secrets", models.PermissionManageSecrets},
+	{http.MethodPost, "/events/:event_id/secrets", models.PermissionManageSecrets},
+	{http.MethodPut, "/events/:event_id/secrets", models.PermissionManageSecrets},
+	{http.MethodDelete, "/events/:event_id/secrets", models.PermissionManageSecrets},
+
+	{http.MethodPut, "/forms/:form_id", models.PermissionEditForms},
+	{http.MethodDelete, "/forms/:form_id", models.PermissionEditForms},
+	{http.MethodGet, "/forms/:form_id/versions", models.PermissionViewEvent},
+	{http.MethodGet, "/forms/:form_id/responses", models.PermissionViewResponses},
+	{http.MethodGet, "/forms/:form_id/responses/export", models.PermissionExportResponses},
+	{http.MethodGet, "/forms/:form_id/responses/csv", models.PermissionExportResponses},
+	{http.MethodPost, "/forms/:form_id/responses/import", models.PermissionEditResponses},
+	{http.MethodGet, "/forms/:form_id/responses/bulk", models.PermissionEditResponses},
+	{http.MethodGet, "/forms/:form_id/responses/bulk/:job_id", models.PermissionEditResponses},
+	{http.MethodPost, "/forms/:form_id/responses/bulk/set-field", models.PermissionEditResponses},
+	{http.MethodPost, "/forms/:form_id/responses/bulk/delete", models.PermissionEditResponses},
+	{http.MethodPost, "/forms/:form_id/responses/bulk/move", models.PermissionEditResponses},
+	{http.MethodPut, "/forms/:form_id/responses/:response_id", models.PermissionEditResponses},
+	{http.MethodDelete, "/forms/:form_id/responses/:response_id", models.PermissionEditResponses},
+	{http.MethodGet, "/forms/:form_id/responses/:response_id/history", models.PermissionViewResponses},
+	{http.MethodGet, "/forms/:form_id/responses/:response_id/files/:file_id", models.PermissionViewResponses},
+	{http.MethodGet, "/forms/:form_id/reviews/rubric", models.PermissionViewReviews},
+	{http.MethodPut, "/forms/:form_id/reviews/rubric", models.PermissionManageReviews},
+	{http.MethodGet, "/forms/:form_id/reviews/assignments", models.PermissionViewReviews},
+	{http.MethodPost, "/forms/:form_id/reviews/assignments", models.PermissionManageReviews},
+	{http.MethodGet, "/forms/:form_id/reviews/queue", models.PermissionSubmitReviews},
+	{http.MethodGet, "/forms/:form_id/reviews/leaderboard", models.PermissionViewReviews},
+	{http.MethodGet, "/forms/:form_id/reviews/responses/:response_id", models.PermissionViewReviews},
+	{http.MethodPut, "/forms/:form_id/reviews/responses/:response_id", models.PermissionSubmitReviews},
+	{http.MethodGet, "/forms/:form_id/admissions", models.PermissionViewAdmissions},
+	{http.MethodPost, "/forms/:form_id/admissions/sweep", models.PermissionManageAdmissions},
+	{http.MethodPost, "/forms/:form_id/admissions/responses/:response_id/check-in", models.PermissionCheckIn},
+	{http.MethodDelete, "/forms/:form_id/admissions/responses/:response_id/check-in", models.PermissionCheckIn},
+
+	{http.MethodGet, "/pipelines/:pipeline_id", models.PermissionViewEvent},
+	{http.MethodPut, "/pipelines/:pipeline_id", models.PermissionEditPipelines},
+	{http.MethodDelete, "/pipelines/:pipeline_id", models.PermissionEditPipelines},
+	{http.MethodGet, "/pipelines/:pipeline_id/runs", models.PermissionViewEvent},
+
+	{http.MethodGet, "/email_templates/:template_id", models.PermissionViewEvent},
+	{http.MethodPut, "/email_templates/:template_id", models.PermissionEditEmailTemplates},
+	{http.MethodDelete, "/email_templates/:template_id", models.PermissionEditEmailTemplates},
+}
+
+// openRoutes are the routes on an event's resources that applicants or the public use, they check who is asking themselves
+var openRoutes = map[string]bool{
+	"GET /events/:event_id":                                       true,
+	"POST /events/:event_id/invites/accept":                       true,
+	"GET /forms/:form_id":                                         true,
+	"POST /forms/:form_id/responses":                              true,
+	"GET /forms/:form_id/responses/draft":                         true,
+	"PUT /forms/:form_id/responses/draft":                         true,
+	"DELETE /forms/:form_id/responses/draft":                      true,
+	"GET /forms/:form_id/responses/anonymous":                     true,
+	"POST /forms/:form_id/responses/anonymous":                    true,
+	"POST /forms/:form_id/responses/anonymous/confirm":            true,
+	"POST /forms/:form_id/files":                                  true,
+	"GET /forms/:form_id/admissions/responses/:response_id":       true,
+	"POST /forms/:form_id/admissions/responses/:response_id/rsvp": true,
+}
+
+// isEventRoute reports whether the route is on an event or one of its forms, pipelines or email templates
+func isEventRoute(path string) bool {
+	for _, param := range []string{":event_id", ":form_id", ":pipeline_id", ":template_id"} {
+		if strings.Contains(path, param) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEveryEventRouteChecksPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	SetupRoutes(r, &types.RouteParams{MongoService: &permissionsMongo{}})
+
+	checked := make(map[string]bool)
+	for _, route := range organizerRoutes {
+		checked[route.method+" "+route.path] = true
+	}
+
+	for _, route := range r.Routes() {
+		key := route.Method + " " + route.Path
+		if !isEventRoute(route.Path) {
+			continue
+		}
+		assert.True(t, checked[key] || openRoutes[key], "%s has no permission in organizerRoutes", key)
+	}
+}
+
+func TestRoutePermissionsByRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	creatorID := primitive.NewObjectID()
+	m := &permissionsMongo{event: models.Event{ID: primitive.NewObjectID(), CreatedByID: creatorID}}
+	m.form = models.FormStructure{ID: primitive.NewObjectID(), EventID: m.event.ID}
+	m.pipeline = models.PipelineConfiguration{ID: primitive.NewObjectID(), EventID: m.event.ID}
+	m.template = models.EmailTemplate{ID: primitive.NewObjectID(), EventID: m.event.ID}
+
+	// The creator has no saved role, like events created before roles existed
+	users := map[models.EventRole]primitive.ObjectID{models.EventRoleOwner: creatorID}
+	m.event.OrganizerIDs = []primitive.ObjectID{creatorID}
+	m.event.OrganizerRoles = map[string]models.EventRole{}
+	for _, role := range models.EventRoles[1:] {
+		userID := primitive.NewObjectID()
+		users[role] = userID
+		m.event.OrganizerIDs = append(m.event.OrganizerIDs, userID)
+		m.event.OrganizerRoles[userID.Hex()] = role
+	}
+
+	m.sessions = map[primitive.ObjectID]primitive.ObjectID{}
+	generateJWT := func(user *models.User) string {
+		sessionID := primitive.NewObjectID()
+		m.sessions[sessionID] = user.ID
+		token, err := utils.GenerateJWT(user, sessionID)
+		require.NoError(t, err)
+		return token
+	}
+
+	tokens := map[models.EventRole]string{}
+	for role, userID := range users {
+		tokens[role] = generateJWT(&models.User{ID: userID, Email: fmt.Sprintf("%s@example.com", role)})
+	}
+	outsiderToken := generateJWT(&models.User{ID: primitive.NewObjectID(), Email: "outsider@example.com"})
+
+	// Handlers that get past the check use methods the fake doesn't implement, the panic is turned into a teapot
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusTeapot)
+	}))
+	SetupRoutes(r, &types.RouteParams{MongoService: m})
+
+	replacer := strings.NewReplacer(
+		":event_id", m.event.ID.Hex(),
+		":form_id", m.form.ID.Hex(),
+		":pipeline_id", m.pipeline.ID.Hex(),
+		":template_id", m.template.ID.Hex(),
+		":user_email", "someone@example.com",
+		":user_id", primitive.NewObjectID().Hex(),
+		":invite_id", primitive.NewObjectID().Hex(),
+		":response_id", primitive.NewObjectID().Hex(),
+		":job_id", primitive.NewObjectID().Hex(),
+		":file_id", primitive.NewObjectID().Hex(),
+	)
+
+	request := func(method string, path string, token string) int {
+		req := httptest.NewRequest(method, replacer.Replace(path), strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	for _, route := range organizerRoutes {
+		for _, role := range models.EventRoles {
+			t.Run(fmt.Sprintf("%s %s as %s", route.method, route.path, role), func(t *testing.T) {
+				code := request(route.method, route.path, tokens[role])
+				if role.Can(route.permission) {
+					assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound}, code)
+				} else {
+					assert.Equal(t, http.StatusForbidden, code)
+				}
+			})
+		}
+
+		t.Run(fmt.Sprintf("%s %s as a non organizer", route.method, route.path), func(t *testing.T) {
+			assert.Equal(t, http.StatusForbidden, request(route.method, route.path, outsiderToken))
+		})
+	}
+}
+
+func TestEventRequiringTwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	organizerID := primitive.NewObjectID()
+	m := &permissionsMongo{
+		event: models.Event{
+			ID:               primitive.NewObjectID(),
+			CreatedByID:      organizerID,
+			OrganizerIDs:     []primitive.ObjectID{organizerID},
+			RequireTwoFactor: true,
+		},
+		sessions: map[primitive.ObjectID]primitive.ObjectID{},
+		verified: map[primitive.ObjectID]bool{},
+	}
+
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusTeapot)
+	}))
+	SetupRoutes(r, &types.RouteParams{MongoService: m})
+
+	request := func(verified bool, method string, path string, body string) *httptest.ResponseRecorder {
+		sessionID := primitive.NewObjectID()
+		m.sessions[sessionID] = organizerID
+		m.verified[sessionID] = verified
+		token, err := utils.GenerateJWT(&models.User{ID: organizerID}, sessionID)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	listForms := "/events/" + m.event.ID.Hex() + "/forms"
+	resp := request(false, http.MethodGet, listForms, "")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "twoFactorRequired")
+
+	assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound}, request(true, http.MethodGet, listForms, "").Code)
+
+	// Routes that find the event in the body check the permission themselves
+	template := `{"eventID": "` + m.event.ID.Hex() + `", "name": "Welcome", "from": "events@example.com"}`
+	assert.Equal(t, http.StatusForbidden, request(false, http.MethodPost, "/email_templates", template).Code)
+	assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound}, request(true, http.MethodPost, "/email_templates", template).Code)
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminID, viewerID := primitive.NewObjectID(), primitive.NewObjectID()
+	m := &permissionsMongo{
+		event: models.Event{
+			ID:             primitive.NewObjectID(),
+			CreatedByID:    primitive.NewObjectID(),
+			OrganizerIDs:   []primitive.ObjectID{adminID, viewerID},
+			OrganizerRoles: map[string]models.EventRole{adminID.Hex(): models.EventRoleAdmin, viewerID.Hex(): models.EventRoleViewer},
+		},
+		tokens: map[string]models.APIToken{},
+	}
+	m.form = models.FormStructure{ID: primitive.NewObjectID(), EventID: m.event.ID}
+
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusTeapot)
+	}))
+	SetupRoutes(r, &types.RouteParams{MongoService: m})
+
+	newAPIToken := func(token models.APIToken) string {
+		secret := models.APITokenPrefix + primitive.NewObjectID().Hex()
+		hash := sha256.Sum256([]byte(secret))
+		token.ID = primitive.NewObjectID()
+		token.EventID = m.event.ID
+		m.tokens[hex.EncodeToString(hash[:])] = token
+		return secret
+	}
+	request := func(method string, path string, token string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	responses := "/forms/" + m.form.ID.Hex() + "/responses"
+	response := responses + "/" + primitive.NewObjectID().Hex()
+	denied := []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound}
+
+	readOnly := newAPIToken(models.APIToken{UserID: adminID, Permissions: []models.Permission{models.PermissionViewResponses}})
+	assert.NotContains(t, denied, request(http.MethodGet, responses, readOnly))
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPut, response, readOnly), "a permission the token wasn't given")
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/events/"+m.event.ID.Hex()+"/forms", readOnly))
+
+	formID := m.form.ID
+	formWriter := newAPIToken(models.APIToken{UserID: adminID, FormID: &formID, Permissions: []models.Permission{models.PermissionEditResponses}})
+	assert.NotContains(t, denied, request(http.MethodPut, response, formWriter))
+
+	otherFormID := primitive.NewObjectID()
+	otherForm := newAPIToken(models.APIToken{UserID: adminID, FormID: &otherFormID, Permissions: []models.Permission{models.PermissionEditResponses}})
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPut, response, otherForm), "another form's token")
+
+	// The token is limited by the user's role too, eg: once they are made a viewer
+	viewer := newAPIToken(models.APIToken{UserID: viewerID, Permissions: []models.Permission{models.PermissionEditResponses}})
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPut, response, viewer))
+
+	// API tokens don't work on the user's account, like making more of them
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/auth/api-tokens", readOnly))
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/auth/logout-all", readOnly))
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, responses, models.APITokenPrefix+"revoked"))
+}
@@ -19,12 +19,12 @@ import (
 )
 
 func RegisterRoutes(r *gin.RouterGroup, params *types.RouteParams) {
-	r.GET(":pipeline_id", middlewares.JWTAuthMiddleware(), getPipelineConfigHandler(params))
-	r.POST("", middlewares.JWTAuthMiddleware(), createPipelineConfigHandler(params))
-	r.PUT(":pipeline_id", middlewares.JWTAuthMiddleware(), updatePipelineConfigHandler(params))
-	r.DELETE(":pipeline_id", middlewares.JWTAuthMiddleware(), deletePipelineConfigHandler(params))
+	r.GET(":pipeline_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewEvent), getPipelineConfigHandler(params))
+	r.POST("", middlewares.JWTAuthMiddleware(params.MongoService), createPipelineConfigHandler(params))
+	r.PUT(":pipeline_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditPipelines), updatePipelineConfigHandler(params))
+	r.DELETE(":pipeline_id", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionEditPipelines), deletePipelineConfigHandler(params))
 
-	r.GET(":pipeline_id/runs", middlewares.JWTAuthMiddleware(), getPipelineRunsHandler(params))
+	r.GET(":pipeline_id/runs", middlewares.JWTAuthMiddleware(params.MongoService), middlewares.RequirePermission(params.MongoService, models.PermissionViewEvent), getPipelineRunsHandler(params))
 }
 
 func getPipelineConfigHandler(params *types.RouteParams) gin.HandlerFunc {
@@ -35,23 +35,12 @@ func getPipelineConfigHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
 		pipelineConfig, err := params.MongoService.GetPipeline(c, pipelineID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Pipeline configuration not found"})
 			return
 		}
 
-		canModify := mongodb.CanUserModifyPipeline(c, params.MongoService, authenticatedUser, primitive.NilObjectID, pipelineConfig)
-		if !canModify {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to view this pipeline"})
-			return
-		}
-
 		c.JSON(http.StatusOK, pipelineConfig)
 	}
 }
@@ -70,15 +59,15 @@ func createPipelineConfigHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		event, err := params.MongoService.GetEvent(c, req.EventID)
+		event, err := params.MongoService.FindEvent(c, req.EventID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 			return
 		}
 
-		canUserModifyEvent := mongodb.CanUserModifyEvent(c, params.MongoService, authenticatedUser, primitive.NilObjectID, event)
-		if !canUserModifyEvent {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You cannot create a pipeline on this event"})
+		if !mongodb.HasEventPermission(c, params.MongoService, authenticatedUser, event.ID, event, models.PermissionEditPipelines) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot create a pipeline on this event"})
+			return
 		}
 
 		pipelineID, err := params.MongoService.CreatePipeline(c, req)
@@ -87,6 +76,8 @@ func createPipelineConfigHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
+		middlewares.SetAuditTarget(c, event.ID, "pipeline", pipelineID.InsertedID.(primitive.ObjectID))
+
 		c.JSON(http.StatusOK, gin.H{"id": pipelineID})
 	}
 }
@@ -106,21 +97,12 @@ func updatePipelineConfigHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
 		// Pull configuration
 		pipelineConfig, err := params.MongoService.GetPipeline(c, pipelineID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Pipeline configuration not found"})
 			return
 		}
-		if !mongodb.CanUserModifyPipeline(c, params.MongoService, authenticatedUser, pipelineID, pipelineConfig) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to update this pipeline"})
-			return
-		}
 
 		// Check if the configuration has changed since the user last fetched it
 		if pipelineConfig.LastUpdatedAt.After(req.LastUpdatedAt) {
@@ -136,6 +118,8 @@ func updatePipelineConfigHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
+		middlewares.SetAuditChanges(c, pipelineConfig, req)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Pipeline configuration updated successfully", "lastUpdatedAt": newLastUpdatedAt})
 	}
 }
@@ -148,16 +132,6 @@ func deletePipelineConfigHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
-		if !mongodb.CanUserModifyPipeline(c, params.MongoService, authenticatedUser, pipelineID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to delete this pipeline"})
-			return
-		}
-
 		_, err = params.MongoService.DeletePipeline(c, pipelineID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete pipeline configuration"})
@@ -176,16 +150,6 @@ func getPipelineRunsHandler(params *types.RouteParams) gin.HandlerFunc {
 			return
 		}
 
-		authenticatedUser, ok := utils.GetUserFromContext(c, true)
-		if !ok {
-			return
-		}
-
-		if !mongodb.CanUserModifyPipeline(c, params.MongoService, authenticatedUser, pipelineID, nil) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to view this pipeline's runs"})
-			return
-		}
-
 		// Pagination parameters
 		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 		pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
@@ -1,9 +1,11 @@
 package routes
 
 import (
+	"api/internal/middlewares"
 	"api/internal/routes/auth"
 	"api/internal/routes/emails"
 	"api/internal/routes/events"
+	"api/internal/routes/files"
 	"api/internal/routes/forms"
 	"api/internal/routes/pipelines"
 	"api/internal/routes/users"
@@ -16,6 +18,9 @@ import (
 
 // SetupRoutes configures the API routes
 func SetupRoutes(r *gin.Engine, params *types.RouteParams) {
+	// Before any route, so it sees the request once the route's own middlewares and handler are done
+	r.Use(middlewares.AuditLog(params.MongoService))
+
 	authGroup := r.Group("/auth")
 	auth.RegisterRoutes(authGroup, params)
 
@@ -35,6 +40,9 @@ func SetupRoutes(r *gin.Engine, params *types.RouteParams) {
 	emailTemplateGroup := r.Group("/email_templates")
 	emails.RegisterEmailTemplateRoutes(emailTemplateGroup, params)
 
+	fileGroup := r.Group("/files")
+	files.RegisterRoutes(fileGroup, params)
+
 	r.GET("/version", getVersion)
 }
 
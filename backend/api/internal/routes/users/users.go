@@ -2,6 +2,7 @@ package users
 
 import (
 	"api/internal/middlewares"
+	"api/internal/routes/forms/responses"
 	"api/internal/types"
 	"net/http"
 	"shared/models"
@@ -14,11 +15,13 @@ import (
 
 // RegisterRoutes sets up the routes for user management
 func RegisterRoutes(r *gin.RouterGroup, params *types.RouteParams) {
-	r.GET("/me", middlewares.JWTAuthMiddleware(), getUserMyself(params))
-	r.PUT("/me", middlewares.JWTAuthMiddleware(), updateUserMyself(params))
-	r.GET("/me/subscription", middlewares.JWTAuthMiddleware(), getSubscriptionUtilization(params))
+	r.GET("/me", middlewares.JWTAuthMiddleware(params.MongoService), getUserMyself(params))
+	r.PUT("/me", middlewares.JWTAuthMiddleware(params.MongoService), updateUserMyself(params))
+	r.GET("/me/subscription", middlewares.JWTAuthMiddleware(params.MongoService), getSubscriptionUtilization(params))
 	r.GET("/:id", getUserDetails(params))
 
+	myResponsesGroup := r.Group("/me/responses")
+	responses.RegisterApplicantResponseRoutes(myResponsesGroup, params)
 }
 
 func getUserMyself(params *types.RouteParams) gin.HandlerFunc {
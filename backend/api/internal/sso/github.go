@@ -0,0 +1,87 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"shared/config"
+	"strconv"
+)
+
+var defaultGitHubScopes = []string{"read:user", "user:email"}
+
+// gitHubProvider signs in with a GitHub OAuth app, GitHub has no ID tokens so the user and their emails are fetched from its API
+type gitHubProvider struct {
+	config config.SSOProviderConfig
+	client *http.Client
+
+	authURL  string
+	tokenURL string
+	apiURL   string
+}
+
+func newGitHubProvider(providerConfig config.SSOProviderConfig, client *http.Client) *gitHubProvider {
+	return &gitHubProvider{
+		config:   providerConfig,
+		client:   client,
+		authURL:  "https://github.com/login/oauth/authorize",
+		tokenURL: "https://github.com/login/oauth/access_token",
+		apiURL:   "https://api.github.com",
+	}
+}
+
+func (p *gitHubProvider) AuthCodeURL(ctx context.Context, redirectURL string, state string, codeVerifier string, nonce string) (string, error) {
+	scopes := p.config.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultGitHubScopes
+	}
+
+	// GitHub has no use for the nonce, the state and PKCE protect the sign in
+	return authCodeURL(p.authURL, p.config, scopes, redirectURL, state, codeVerifier, "")
+}
+
+func (p *gitHubProvider) Identify(ctx context.Context, redirectURL string, code string, codeVerifier string, nonce string) (*Identity, error) {
+	token, err := exchangeCode(ctx, p.client, p.tokenURL, p.config, redirectURL, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getJSON(ctx, p.client, p.apiURL+"/user", token.AccessToken, &user); err != nil {
+		return nil, fmt.Errorf("failed to get github user: %w", err)
+	}
+	if user.ID == 0 {
+		return nil, errors.New("github returned no user")
+	}
+
+	// The profile's public email may not be verified, the primary email from the emails API says if it is
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, p.client, p.apiURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		return nil, fmt.Errorf("failed to get github user emails: %w", err)
+	}
+
+	identity := &Identity{Subject: strconv.FormatInt(user.ID, 10)}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+			break
+		}
+	}
+
+	identity.FirstName, identity.LastName = splitName(user.Name)
+	if identity.FirstName == "" {
+		identity.FirstName = user.Login
+	}
+
+	return identity, nil
+}
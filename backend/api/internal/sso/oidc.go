@@ -0,0 +1,257 @@
+package sso
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"shared/config"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var defaultOIDCScopes = []string{"openid", "email", "profile"}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// oidcProvider finds its endpoints and signing keys from the issuer, they are fetched when first needed and kept
+type oidcProvider struct {
+	config config.SSOProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]interface{} // by key ID
+}
+
+func newOIDCProvider(providerConfig config.SSOProviderConfig, client *http.Client) *oidcProvider {
+	return &oidcProvider{config: providerConfig, client: client}
+}
+
+func (p *oidcProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	endpoint := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, p.client, endpoint, "", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to get the oidc discovery document of %s: %w", p.config.Issuer, err)
+	}
+
+	// The ID tokens are checked against the issuer in the document, so it has to be the one configured
+	if discovery.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("oidc discovery document is for issuer %q instead of %q", discovery.Issuer, p.config.Issuer)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// getKey returns the issuer's signing key with the ID, the keys are fetched again for one we haven't seen as they are rotated
+func (p *oidcProvider) getKey(ctx context.Context, discovery *oidcDiscovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, p.client, discovery.JWKSURI, "", &jwks); err != nil {
+		return nil, fmt.Errorf("failed to get the signing keys of %s: %w", p.config.Issuer, err)
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Keys of a type we don't use can't have signed the token
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys = keys
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+	}
+	return key, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, redirectURL string, state string, codeVerifier string, nonce string) (string, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.config.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultOIDCScopes
+	}
+
+	return authCodeURL(discovery.AuthorizationEndpoint, p.config, scopes, redirectURL, state, codeVerifier, nonce)
+}
+
+func (p *oidcProvider) Identify(ctx context.Context, redirectURL string, code string, codeVerifier string, nonce string) (*Identity, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := exchangeCode(ctx, p.client, discovery.TokenEndpoint, p.config, redirectURL, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := p.verifyIDToken(ctx, discovery, token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some providers only put the profile in the userinfo response
+	if stringClaim(claims, "email") == "" && discovery.UserinfoEndpoint != "" {
+		var userinfo jwt.MapClaims
+		if err := getJSON(ctx, p.client, discovery.UserinfoEndpoint, token.AccessToken, &userinfo); err != nil {
+			return nil, fmt.Errorf("failed to get userinfo: %w", err)
+		}
+		if stringClaim(userinfo, "sub") != stringClaim(claims, "sub") {
+			return nil, errors.New("userinfo is for another user than the id token")
+		}
+		claims = userinfo
+	}
+
+	identity := &Identity{
+		Subject:       stringClaim(claims, "sub"),
+		Email:         stringClaim(claims, "email"),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		FirstName:     stringClaim(claims, "given_name"),
+		LastName:      stringClaim(claims, "family_name"),
+	}
+	if identity.FirstName == "" {
+		identity.FirstName, identity.LastName = splitName(stringClaim(claims, "name"))
+	}
+
+	return identity, nil
+}
+
+// verifyIDToken checks the ID token was signed by the issuer for us, for this sign in, and hasn't expired
+func (p *oidcProvider) verifyIDToken(ctx context.Context, discovery *oidcDiscovery, rawIDToken string, nonce string) (jwt.MapClaims, error) {
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("%w: the provider returned no id token", ErrInvalidIDToken)
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(ctx, discovery, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIDToken, err)
+	}
+
+	if stringClaim(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce doesn't match", ErrInvalidIDToken)
+	}
+	if stringClaim(claims, "sub") == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+func stringClaim(claims jwt.MapClaims, key string) string {
+	value, _ := claims[key].(string)
+	return value
+}
+
+// boolClaim reads a boolean claim, some providers send it as a string
+func boolClaim(claims jwt.MapClaims, key string) bool {
+	switch value := claims[key].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
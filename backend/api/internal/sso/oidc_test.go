@@ -0,0 +1,185 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"shared/config"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIssuer is a local OIDC issuer, its token endpoint returns an ID token with the claims set by the test
+type mockIssuer struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+
+	// what the last token request sent
+	code         string
+	codeVerifier string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := &mockIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test-key",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		issuer.code = r.PostForm.Get("code")
+		issuer.codeVerifier = r.PostForm.Get("code_verifier")
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, issuer.claims)
+		token.Header["kid"] = "test-key"
+		idToken, err := token.SignedString(key)
+		require.NoError(t, err)
+
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "id_token": idToken})
+	})
+
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// validClaims are the claims of an ID token for the test client, the test changes them to make it invalid
+func (m *mockIssuer) validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            m.URL,
+		"aud":            "client",
+		"sub":            "user-1",
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"nonce":          "nonce",
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"name":           "Ada Lovelace",
+	}
+}
+
+func (m *mockIssuer) provider(t *testing.T) Provider {
+	providers, err := NewProviders([]config.SSOProviderConfig{{
+		ID:       "test",
+		Type:     "oidc",
+		Issuer:   m.URL,
+		ClientID: "client",
+	}}, m.Client())
+	require.NoError(t, err)
+	return providers["test"]
+}
+
+func TestOIDCAuthCodeURL(t *testing.T) {
+	issuer := newMockIssuer(t)
+
+	authURL, err := issuer.provider(t).AuthCodeURL(context.Background(), "https://api.test/callback", "state", "verifier", "nonce")
+	require.NoError(t, err)
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, issuer.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "client", u.Query().Get("client_id"))
+	assert.Equal(t, "https://api.test/callback", u.Query().Get("redirect_uri"))
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+	assert.Equal(t, "state", u.Query().Get("state"))
+	assert.Equal(t, "nonce", u.Query().Get("nonce"))
+	assert.Equal(t, CodeChallenge("verifier"), u.Query().Get("code_challenge"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+}
+
+func TestOIDCIdentify(t *testing.T) {
+	issuer := newMockIssuer(t)
+	issuer.claims = issuer.validClaims()
+
+	identity, err := issuer.provider(t).Identify(context.Background(), "https://api.test/callback", "code", "verifier", "nonce")
+	require.NoError(t, err)
+
+	assert.Equal(t, &Identity{
+		Subject:       "user-1",
+		Email:         "ada@example.com",
+		EmailVerified: true,
+		FirstName:     "Ada",
+		LastName:      "Lovelace",
+	}, identity)
+	assert.Equal(t, "code", issuer.code)
+	assert.Equal(t, "verifier", issuer.codeVerifier)
+}
+
+func TestOIDCIdentifyRejectsInvalidIDTokens(t *testing.T) {
+	tests := map[string]func(claims jwt.MapClaims){
+		"another client": func(claims jwt.MapClaims) { claims["aud"] = "another-client" },
+		"another issuer": func(claims jwt.MapClaims) { claims["iss"] = "https://issuer.test" },
+		"another nonce":  func(claims jwt.MapClaims) { claims["nonce"] = "another-nonce" },
+		"expired":        func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"no expiry":      func(claims jwt.MapClaims) { delete(claims, "exp") },
+		"no subject":     func(claims jwt.MapClaims) { delete(claims, "sub") },
+	}
+
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			issuer := newMockIssuer(t)
+			issuer.claims = issuer.validClaims()
+			change(issuer.claims)
+
+			_, err := issuer.provider(t).Identify(context.Background(), "https://api.test/callback", "code", "verifier", "nonce")
+			assert.ErrorIs(t, err, ErrInvalidIDToken)
+		})
+	}
+}
+
+func TestOIDCIdentifyRejectsTokensSignedByAnotherKey(t *testing.T) {
+	issuer := newMockIssuer(t)
+	issuer.claims = issuer.validClaims()
+
+	// The issuer's keys no longer have the key that signs its tokens
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer.key.PublicKey = otherKey.PublicKey
+
+	_, err = issuer.provider(t).Identify(context.Background(), "https://api.test/callback", "code", "verifier", "nonce")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
+
+func TestOIDCEmailVerifiedAsString(t *testing.T) {
+	issuer := newMockIssuer(t)
+	issuer.claims = issuer.validClaims()
+	issuer.claims["email_verified"] = "true"
+
+	identity, err := issuer.provider(t).Identify(context.Background(), "https://api.test/callback", "code", "verifier", "nonce")
+	require.NoError(t, err)
+	assert.True(t, identity.EmailVerified)
+}
+
+func TestNewProvidersRejectsUnknownTypes(t *testing.T) {
+	_, err := NewProviders([]config.SSOProviderConfig{{ID: "test", Type: "saml"}}, http.DefaultClient)
+	assert.ErrorIs(t, err, ErrUnknownProviderType)
+}
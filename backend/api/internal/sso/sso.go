@@ -0,0 +1,176 @@
+package sso
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"shared/config"
+	"strings"
+)
+
+/*
+SSO signs users in with an OIDC or OAuth2 provider using the authorization code flow with PKCE.
+
+The user is sent to the provider's AuthCodeURL and comes back to the redirect URL with a code, which Identify exchanges
+for who they are. OIDC providers are found from their issuer's discovery document and prove who the user is with a signed ID token,
+GitHub only speaks OAuth2 so its API is asked instead.
+*/
+
+var (
+	// ErrUnknownProviderType is returned for a provider configured with a type we don't support
+	ErrUnknownProviderType = errors.New("unknown sso provider type")
+
+	// ErrInvalidIDToken is returned when the provider's ID token isn't signed by it, is for another client or has expired
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Identity is who the provider says the user is
+type Identity struct {
+	Subject       string // the provider's ID for the user, it never changes unlike their email
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// Provider is somewhere users can sign in
+type Provider interface {
+	// AuthCodeURL is where to send the user to sign in, they come back to redirectURL with the state and a code
+	AuthCodeURL(ctx context.Context, redirectURL string, state string, codeVerifier string, nonce string) (string, error)
+
+	// Identify exchanges the code the user came back with for who they are
+	Identify(ctx context.Context, redirectURL string, code string, codeVerifier string, nonce string) (*Identity, error)
+}
+
+// NewProviders sets up the configured providers by their ID
+func NewProviders(configs []config.SSOProviderConfig, client *http.Client) (map[string]Provider, error) {
+	providers := make(map[string]Provider, len(configs))
+	for _, providerConfig := range configs {
+		switch providerConfig.Type {
+		case "oidc":
+			providers[providerConfig.ID] = newOIDCProvider(providerConfig, client)
+		case "github":
+			providers[providerConfig.ID] = newGitHubProvider(providerConfig, client)
+		default:
+			return nil, fmt.Errorf("%w %q for provider %q", ErrUnknownProviderType, providerConfig.Type, providerConfig.ID)
+		}
+	}
+
+	return providers, nil
+}
+
+// CodeChallenge is the PKCE S256 challenge sent with the user to the provider, only the holder of the verifier can exchange the code
+func CodeChallenge(codeVerifier string) string {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// authCodeURL adds the authorization code request to the provider's authorization endpoint
+func authCodeURL(endpoint string, providerConfig config.SSOProviderConfig, scopes []string, redirectURL string, state string, codeVerifier string, nonce string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", providerConfig.ClientID)
+	query.Set("redirect_uri", redirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", CodeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+	if nonce != "" {
+		query.Set("nonce", nonce)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeCode redeems the authorization code at the provider's token endpoint
+func exchangeCode(ctx context.Context, client *http.Client, endpoint string, providerConfig config.SSOProviderConfig, redirectURL string, code string, codeVerifier string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {providerConfig.ClientID},
+		"client_secret": {providerConfig.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token tokenResponse
+	if err := doJSON(client, req, &token); err != nil && token.Error == "" {
+		return nil, err
+	}
+
+	if token.Error != "" {
+		return nil, fmt.Errorf("sso token exchange failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("sso token exchange returned no access token")
+	}
+
+	return &token, nil
+}
+
+// getJSON fetches a JSON document, with the access token if there is one
+func getJSON(ctx context.Context, client *http.Client, endpoint string, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	return doJSON(client, req, v)
+}
+
+func doJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	// Error responses are decoded too, the token endpoint explains what went wrong in them
+	decodeErr := json.Unmarshal(body, v)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s returned %d", req.Method, req.URL.Redacted(), resp.StatusCode)
+	}
+
+	return decodeErr
+}
+
+// splitName splits a full name into a first and last name, for providers that only have the full name
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return first, strings.TrimSpace(last)
+}
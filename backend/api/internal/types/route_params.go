@@ -2,10 +2,14 @@ package types
 
 import (
 	"shared/kafka/producer"
+	"shared/mailer"
 	"shared/mongodb"
+	"shared/storage"
 )
 
 type RouteParams struct {
 	MongoService    mongodb.MongoService
 	MessageProducer producer.MessageProducer
+	ObjectStorage   storage.ObjectStorage
+	Mailer          mailer.Mailer
 }
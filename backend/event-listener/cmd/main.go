@@ -4,9 +4,15 @@ import (
 	"context"
 	"event-listener/internal/consumer"
 	"event-listener/internal/handlers"
+	"event-listener/internal/scheduler"
+	"event-listener/internal/tasks"
 	"event-listener/internal/types"
 	"log"
+	"shared/config"
+	"shared/kafka/producer"
 	"shared/mongodb"
+	"shared/storage"
+	"time"
 )
 
 var actionHandlers = map[string]types.EventHandler{}
@@ -37,7 +43,30 @@ func main() {
 		"Webhook":         handlers.NewWebhookHandler(mongoService),
 	}
 
-	messageConsumer, err := consumer.NewMessageConsumer(mongoService, actionHandlers)
+	eventListenerConfig, err := config.GetEventListenerConfig()
+	if err != nil {
+		log.Fatalf("Error getting event listener config: %v", err)
+	}
+
+	// Uploaded files are deleted from the same storage the API writes them to, so it uses the API's STORAGE_ options
+	objectStorage, err := storage.NewObjectStorage()
+	if err != nil {
+		log.Fatalf("Failed to create object storage: %v", err)
+	}
+
+	// Tasks that trigger pipelines produce their messages like the API does, so it uses the API's broker options
+	messageProducer, err := producer.NewMessageProducer()
+	if err != nil {
+		log.Fatalf("Failed to create message producer: %v", err)
+	}
+	defer messageProducer.Close()
+
+	taskScheduler := scheduler.New()
+	taskScheduler.Add("DeleteUnattachedUploads", time.Hour, tasks.NewDeleteUnattachedUploadsTask(mongoService, objectStorage, time.Duration(eventListenerConfig.FILE_UPLOAD_UNATTACHED_TTL_HOURS)*time.Hour))
+	taskScheduler.Add("ExpireLapsedOffers", 5*time.Minute, tasks.NewExpireLapsedOffersTask(mongoService, messageProducer))
+	taskScheduler.Add("RunBulkJobs", time.Minute, tasks.NewRunBulkJobsTask(mongoService, messageProducer, objectStorage, eventListenerConfig.BULK_PIPELINE_TRIGGERS_PER_SECOND))
+
+	messageConsumer, err := consumer.NewMessageConsumer(mongoService, actionHandlers, taskScheduler)
 	if err != nil {
 		log.Fatalf("Failed to create message consumer: %v", err)
 	}
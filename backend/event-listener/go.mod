@@ -9,7 +9,11 @@ require (
 	shared v0.0.0
 )
 
-require github.com/sirupsen/logrus v1.9.3 // indirect
+require (
+	github.com/aws/aws-sdk-go v1.54.11 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/sirupsen/logrus v1.9.3 // indirect
+)
 
 require (
 	github.com/aws/aws-lambda-go v1.47.0
@@ -3,6 +3,7 @@ package consumer
 import (
 	"context"
 	"errors"
+	"event-listener/internal/scheduler"
 	"event-listener/internal/types"
 	"shared/config"
 	"shared/mongodb"
@@ -12,7 +13,8 @@ type MessageConsumer interface {
 	Consume(ctx context.Context) error
 }
 
-func NewMessageConsumer(mongoService *mongodb.Service, actionHandlers map[string]types.EventHandler) (MessageConsumer, error) {
+// NewMessageConsumer creates the consumer of the configured message broker, which also runs the scheduler's tasks
+func NewMessageConsumer(mongoService *mongodb.Service, actionHandlers map[string]types.EventHandler, scheduler *scheduler.Scheduler) (MessageConsumer, error) {
 	cfg, err := config.GetEventListenerConfig()
 	if err != nil {
 		return nil, err
@@ -23,9 +25,9 @@ func NewMessageConsumer(mongoService *mongodb.Service, actionHandlers map[string
 		if cfg.KAFKA_BROKER_URLS == nil || len(cfg.KAFKA_BROKER_URLS) == 0 {
 			return nil, errors.New("KAFKA_BROKER_URLS is required for Kafka message broker")
 		}
-		return NewKafkaConsumer(mongoService, actionHandlers)
+		return NewKafkaConsumer(mongoService, actionHandlers, scheduler)
 	case "sqs":
-		return NewSQSConsumer(mongoService, actionHandlers)
+		return NewSQSConsumer(mongoService, actionHandlers, scheduler)
 	default:
 		return nil, errors.New("invalid message broker type specified")
 	}
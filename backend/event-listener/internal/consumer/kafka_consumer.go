@@ -4,6 +4,7 @@ package consumer
 import (
 	"context"
 	"event-listener/internal/helpers"
+	"event-listener/internal/scheduler"
 	"event-listener/internal/types"
 	"log"
 	"shared/config"
@@ -19,9 +20,10 @@ type KafkaConsumer struct {
 	topic          string
 	mongoService   *mongodb.Service
 	actionHandlers map[string]types.EventHandler
+	scheduler      *scheduler.Scheduler
 }
 
-func NewKafkaConsumer(mongoService *mongodb.Service, actionHandlers map[string]types.EventHandler) (*KafkaConsumer, error) {
+func NewKafkaConsumer(mongoService *mongodb.Service, actionHandlers map[string]types.EventHandler, scheduler *scheduler.Scheduler) (*KafkaConsumer, error) {
 	eventListenerCfg, err := config.GetEventListenerConfig()
 	if err != nil {
 		return nil, err
@@ -36,10 +38,12 @@ func NewKafkaConsumer(mongoService *mongodb.Service, actionHandlers map[string]t
 		return nil, err
 	}
 
-	return &KafkaConsumer{group: group, topic: kafka.PipelineActionTopic, mongoService: mongoService, actionHandlers: actionHandlers}, nil
+	return &KafkaConsumer{group: group, topic: kafka.PipelineActionTopic, mongoService: mongoService, actionHandlers: actionHandlers, scheduler: scheduler}, nil
 }
 
 func (k *KafkaConsumer) Consume(ctx context.Context) error {
+	go k.scheduler.Start(ctx)
+
 	handler := &consumerGroupHandler{mongoService: k.mongoService, actionHandlers: k.actionHandlers}
 	for {
 		if err := k.group.Consume(ctx, []string{k.topic}, handler); err != nil {
@@ -3,6 +3,7 @@ package consumer
 import (
 	"context"
 	"event-listener/internal/helpers"
+	"event-listener/internal/scheduler"
 	"event-listener/internal/types"
 	"log"
 	"shared/mongodb"
@@ -14,12 +15,14 @@ import (
 type SQSConsumer struct {
 	mongoService   *mongodb.Service
 	actionHandlers map[string]types.EventHandler
+	scheduler      *scheduler.Scheduler
 }
 
-func NewSQSConsumer(mongoService *mongodb.Service, actionHandlers map[string]types.EventHandler) (*SQSConsumer, error) {
+func NewSQSConsumer(mongoService *mongodb.Service, actionHandlers map[string]types.EventHandler, scheduler *scheduler.Scheduler) (*SQSConsumer, error) {
 	return &SQSConsumer{
 		mongoService:   mongoService,
 		actionHandlers: actionHandlers,
+		scheduler:      scheduler,
 	}, nil
 }
 
@@ -36,5 +39,8 @@ func (s *SQSConsumer) handleSQSEvent(ctx context.Context, sqsEvent events.SQSEve
 			return err
 		}
 	}
+
+	// There is no process between invocations to run the tasks, a scheduled invocation without records runs them when the queue is quiet
+	s.scheduler.RunDue(ctx)
 	return nil
 }
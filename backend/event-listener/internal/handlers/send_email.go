@@ -3,15 +3,11 @@ package handlers
 import (
 	"context"
 	"errors"
-	"fmt"
-	"net/smtp"
-	"strings"
-	"time"
 
 	"shared/kafka"
+	"shared/mailer"
 	"shared/mongodb"
 
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -66,51 +62,23 @@ func (s *SendEmailHandler) HandleAction(action kafka.PipelineActionMessage) erro
 		return ErrNoToEmailFound
 	}
 
-	toAddresses := []string{to}
-	ccAddresses := emailTemplate.CC
-	bccAddresses := emailTemplate.BCC
-
-	// Create headers
-	toHeader := "To: " + strings.Join(toAddresses, ", ") + "\r\n"
-	ccHeader := "Cc: " + strings.Join(ccAddresses, ", ") + "\r\n"
-
-	subject := "Subject: " + emailTemplate.Subject + "\r\n"
-	from := "From: " + emailTemplate.From + "\r\n"
-
-	if emailTemplate.ReplyTo == "" {
-		emailTemplate.ReplyTo = emailTemplate.From
-	}
-	replyTo := "Reply-To: " + emailTemplate.ReplyTo + "\r\n"
-
-	dateHeader := "Date: " + time.Now().Format(time.RFC1123Z) + "\r\n"
-	messageID := fmt.Sprintf("Message-ID: <%s@%s>\r\n", uuid.NewString(), smtpConfig.SMTPServer)
-
-	// MIME and Body
-	mime := "MIME-Version: 1.0\r\n"
-	contentType := "Content-Type: text/plain; charset=\"UTF-8\"\r\n"
-	body := emailTemplate.Body
-	if emailTemplate.IsHTML {
-		contentType = "Content-Type: text/html; charset=\"UTF-8\"\r\n"
-		body = "<html><body>" + body + "</body></html>"
+	smtpMailer := &mailer.SMTPMailer{
+		Host:     smtpConfig.SMTPServer,
+		Port:     smtpConfig.Port,
+		Username: smtpConfig.Username,
+		Password: smtpConfig.Password,
 	}
 
-	// Compile email message
-	message := []byte(subject + from + toHeader + ccHeader + replyTo + dateHeader + messageID + mime + contentType + "\r\n" + body)
-
-	// SMTP server configuration
-	smtpHost := smtpConfig.SMTPServer
-	smtpPort := fmt.Sprintf("%d", smtpConfig.Port)
-	address := smtpHost + ":" + smtpPort
-
-	// Authentication
-	auth := smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpHost)
-
-	// Combine all recipients for sending
-	allRecipients := append(toAddresses, ccAddresses...)
-	allRecipients = append(allRecipients, bccAddresses...)
-
-	// Send email
-	err = smtp.SendMail(address, auth, emailTemplate.From, allRecipients, message)
+	err = smtpMailer.Send(context.TODO(), mailer.Message{
+		From:    emailTemplate.From,
+		To:      []string{to},
+		CC:      emailTemplate.CC,
+		BCC:     emailTemplate.BCC,
+		ReplyTo: emailTemplate.ReplyTo,
+		Subject: emailTemplate.Subject,
+		Body:    emailTemplate.Body,
+		IsHTML:  emailTemplate.IsHTML,
+	})
 	if err != nil {
 		return err
 	}
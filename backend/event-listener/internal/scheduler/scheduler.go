@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"event-listener/internal/types"
+	"shared/logger"
+	"sync"
+	"time"
+)
+
+// tickInterval is how often Start checks for tasks that are due
+const tickInterval = time.Minute
+
+/*
+Scheduler runs the event listener's periodic tasks, like cleaning up after users or expiring things nobody looks at.
+
+Listeners consuming from Kafka run continuously and call Start. On Lambda there is no process between invocations,
+so the SQS consumer calls RunDue after each batch of messages instead. Several listeners may run a task at the same
+time, so tasks must be safe to run concurrently.
+*/
+type Scheduler struct {
+	mu      sync.Mutex
+	entries []*entry
+}
+
+type entry struct {
+	name     string
+	interval time.Duration
+	task     types.Task
+	nextRun  time.Time
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add schedules the task to run every interval, the first run is as soon as the scheduler runs
+func (s *Scheduler) Add(name string, interval time.Duration, task types.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, &entry{name: name, interval: interval, task: task})
+}
+
+// RunDue runs the tasks whose interval has passed since they last ran, a failed task is logged and runs again at its next interval
+func (s *Scheduler) RunDue(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.entries {
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := time.Now()
+		if now.Before(e.nextRun) {
+			continue
+		}
+		e.nextRun = now.Add(e.interval)
+
+		if err := e.task.Run(ctx); err != nil {
+			logger.Error("Failed to run scheduled task "+e.name, err)
+		}
+	}
+}
+
+// Start runs the tasks as they fall due until the context is done
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package tasks
+
+import (
+	"context"
+	"shared/formresponses"
+	"shared/kafka/producer"
+	"shared/mongodb"
+	"shared/storage"
+	"time"
+)
+
+// RunBulkJobsTask runs the bulk operations on responses that organizers started, and resumes those whose runner stopped
+type RunBulkJobsTask struct {
+	mongo           *mongodb.Service
+	producer        producer.MessageProducer
+	objectStorage   storage.ObjectStorage
+	triggerInterval time.Duration
+}
+
+// NewRunBulkJobsTask creates the task, the jobs trigger at most triggersPerSecond pipelines
+func NewRunBulkJobsTask(mongo *mongodb.Service, producer producer.MessageProducer, objectStorage storage.ObjectStorage, triggersPerSecond int) *RunBulkJobsTask {
+	triggerInterval := time.Second
+	if triggersPerSecond > 0 {
+		triggerInterval = time.Second / time.Duration(triggersPerSecond)
+	}
+
+	return &RunBulkJobsTask{mongo: mongo, producer: producer, objectStorage: objectStorage, triggerInterval: triggerInterval}
+}
+
+func (t *RunBulkJobsTask) Run(ctx context.Context) error {
+	return formresponses.RunBulkJobs(ctx, t.mongo, t.producer, t.objectStorage, t.triggerInterval)
+}
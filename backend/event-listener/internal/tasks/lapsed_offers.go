@@ -0,0 +1,42 @@
+package tasks
+
+import (
+	"context"
+	"shared/kafka/producer"
+	"shared/logger"
+	"shared/mongodb"
+	"shared/triggers"
+)
+
+// ExpireLapsedOffersTask expires the offers of forms with a capacity whose RSVP deadline has passed and offers the freed seats
+// to the waitlist, so they move on even when nobody looks at the form
+type ExpireLapsedOffersTask struct {
+	mongo    *mongodb.Service
+	producer producer.MessageProducer
+}
+
+func NewExpireLapsedOffersTask(mongo *mongodb.Service, producer producer.MessageProducer) *ExpireLapsedOffersTask {
+	return &ExpireLapsedOffersTask{mongo: mongo, producer: producer}
+}
+
+func (t *ExpireLapsedOffersTask) Run(ctx context.Context) error {
+	formIDs, err := t.mongo.ListFormsWithLapsedOffers(ctx)
+	if err != nil {
+		return err
+	}
+
+	// A form that fails is tried again on the next run, it doesn't hold up the others
+	for _, formID := range formIDs {
+		form, err := t.mongo.GetForm(ctx, formID, true)
+		if err != nil {
+			logger.Error("Failed to get form with lapsed offers", err)
+			continue
+		}
+
+		if err := triggers.SweepAdmissions(ctx, t.producer, t.mongo, form); err != nil {
+			logger.Error("Failed to expire lapsed offers", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package tasks
+
+import (
+	"context"
+	"shared/mongodb"
+	"shared/storage"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DeleteUnattachedUploadsTask deletes the files uploaded for responses that were never submitted once they are older
+// than maxAge. Files the uploader's draft still holds are kept until the draft expires
+type DeleteUnattachedUploadsTask struct {
+	mongo         *mongodb.Service
+	objectStorage storage.ObjectStorage
+	maxAge        time.Duration
+}
+
+func NewDeleteUnattachedUploadsTask(mongo *mongodb.Service, objectStorage storage.ObjectStorage, maxAge time.Duration) *DeleteUnattachedUploadsTask {
+	return &DeleteUnattachedUploadsTask{mongo: mongo, objectStorage: objectStorage, maxAge: maxAge}
+}
+
+func (t *DeleteUnattachedUploadsTask) Run(ctx context.Context) error {
+	filter := bson.M{"responseID": bson.M{"$exists": false}, "createdAt": bson.M{"$lt": time.Now().Add(-t.maxAge)}}
+	uploads, err := t.mongo.ListFileUploads(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	type draftKey struct{ formID, userID primitive.ObjectID }
+	drafted := make(map[draftKey]map[string]bool)
+
+	stale := []primitive.ObjectID{}
+	for _, upload := range uploads {
+		key := draftKey{upload.FormID, upload.UserID}
+		if _, ok := drafted[key]; !ok {
+			drafted[key], err = t.draftedUploads(ctx, upload.FormID, upload.UserID)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !drafted[key][upload.ID.Hex()] {
+			stale = append(stale, upload.ID)
+		}
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+
+	// An upload attached since it was listed no longer matches the filter, so it is kept
+	filter["_id"] = bson.M{"$in": stale}
+	return storage.DeleteFileUploads(ctx, t.objectStorage, t.mongo, filter)
+}
+
+// draftedUploads are the IDs of the uploads held by the user's draft of the form
+func (t *DeleteUnattachedUploadsTask) draftedUploads(ctx context.Context, formID primitive.ObjectID, userID primitive.ObjectID) (map[string]bool, error) {
+	ids := make(map[string]bool)
+
+	draft, err := t.mongo.GetResponseDraft(ctx, formID, userID)
+	if err == mongo.ErrNoDocuments {
+		return ids, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range draft.Data {
+		values, ok := value.(primitive.A)
+		if !ok {
+			continue
+		}
+
+		for _, v := range values {
+			if id, ok := v.(string); ok {
+				ids[id] = true
+			}
+		}
+	}
+
+	return ids, nil
+}
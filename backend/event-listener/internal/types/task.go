@@ -0,0 +1,8 @@
+package types
+
+import "context"
+
+// Task is work the event listener does periodically, rather than in response to a message
+type Task interface {
+	Run(ctx context.Context) error
+}
@@ -13,9 +13,36 @@ type APIConfig struct {
 	// JWT_SECRET_TOKEN is the secret key to use for JWT tokens
 	JWT_SECRET_TOKEN string `env:"JWT_SECRET_TOKEN"`
 
+	// ACCESS_TOKEN_TTL_MINUTES is how long a JWT access token is valid for, clients use their refresh token to get a new one
+	ACCESS_TOKEN_TTL_MINUTES int `env:"ACCESS_TOKEN_TTL_MINUTES" envDefault:"15"`
+
+	// REFRESH_TOKEN_TTL_DAYS is how long a session lasts without being refreshed before it has to sign in again
+	REFRESH_TOKEN_TTL_DAYS int `env:"REFRESH_TOKEN_TTL_DAYS" envDefault:"30"`
+
+	// EMAIL_VERIFICATION_TTL_HOURS is how long the link to verify a user's email address is valid for
+	EMAIL_VERIFICATION_TTL_HOURS int `env:"EMAIL_VERIFICATION_TTL_HOURS" envDefault:"48"`
+
+	// PASSWORD_RESET_TTL_MINUTES is how long the link to reset a forgotten password is valid for
+	PASSWORD_RESET_TTL_MINUTES int `env:"PASSWORD_RESET_TTL_MINUTES" envDefault:"60"`
+
+	// AUTH_RATE_LIMIT_PER_HOUR is how many password reset and verification email requests a single IP can make in an hour
+	AUTH_RATE_LIMIT_PER_HOUR int `env:"AUTH_RATE_LIMIT_PER_HOUR" envDefault:"10"`
+
+	// LOGIN_MAX_FAILURES is how many failed sign ins an account can have before it is locked out for LOGIN_LOCKOUT_MINUTES
+	LOGIN_MAX_FAILURES int `env:"LOGIN_MAX_FAILURES" envDefault:"5"`
+
+	// LOGIN_MAX_FAILURES_PER_IP is how many failed sign ins to any account a single IP can make before it is locked out
+	LOGIN_MAX_FAILURES_PER_IP int `env:"LOGIN_MAX_FAILURES_PER_IP" envDefault:"50"`
+
+	// LOGIN_LOCKOUT_MINUTES is how long an account or IP is locked out of signing in, resetting the password unlocks an account
+	LOGIN_LOCKOUT_MINUTES int `env:"LOGIN_LOCKOUT_MINUTES" envDefault:"15"`
+
 	// CORS_ALLOW_ORIGINS is a comma-separated list of origins to allow CORS requests from
 	CORS_ALLOW_ORIGINS []string `env:"CORS_ALLOW_ORIGINS" envSeparator:","`
 
+	// TRUSTED_PROXIES is a comma-separated list of proxy IPs or CIDRs whose X-Forwarded-For header is believed when rate limiting by IP
+	TRUSTED_PROXIES []string `env:"TRUSTED_PROXIES" envSeparator:","`
+
 	// Kafka options
 
 	// KAFKA_BROKER_URLS is the URL of the Kafka broker
@@ -25,6 +52,70 @@ type APIConfig struct {
 	SQS_AWS_REGION string `env:"SQS_AWS_REGION"`
 	SQS_QUEUE_URL  string `env:"SQS_QUEUE_URL"`
 
+	// AUDIT_LOG_RETENTION_DAYS is how long entries of the events' audit logs are kept, 0 keeps them forever
+	AUDIT_LOG_RETENTION_DAYS int `env:"AUDIT_LOG_RETENTION_DAYS" envDefault:"365"`
+
+	// RESPONSE_DRAFT_TTL_DAYS is how long an untouched form response draft is kept before it expires
+	RESPONSE_DRAFT_TTL_DAYS int `env:"RESPONSE_DRAFT_TTL_DAYS" envDefault:"30"`
+
+	// API_PUBLIC_URL is the externally reachable URL of this API, used to build links back to it
+	API_PUBLIC_URL string `env:"API_PUBLIC_URL" envDefault:"http://localhost:8080"`
+
+	// Object storage options for uploaded files
+
+	// STORAGE_TYPE is the object storage backend to use
+	STORAGE_TYPE string `env:"STORAGE_TYPE" envDefault:"local"` // local | s3
+
+	// STORAGE_MAX_UPLOAD_BYTES is the largest file we accept regardless of the form field's own limit
+	STORAGE_MAX_UPLOAD_BYTES int64 `env:"STORAGE_MAX_UPLOAD_BYTES" envDefault:"10485760"`
+
+	// STORAGE_SIGNED_URL_TTL_MINUTES is how long a signed download URL handed to an organizer is valid for
+	STORAGE_SIGNED_URL_TTL_MINUTES int `env:"STORAGE_SIGNED_URL_TTL_MINUTES" envDefault:"15"`
+
+	// STORAGE_EXPORT_URL_TTL_HOURS is how long the download links written into exports are valid for
+	STORAGE_EXPORT_URL_TTL_HOURS int `env:"STORAGE_EXPORT_URL_TTL_HOURS" envDefault:"168"`
+
+	// STORAGE_LOCAL_PATH is the directory files are written to when using the local backend
+	STORAGE_LOCAL_PATH string `env:"STORAGE_LOCAL_PATH" envDefault:"./uploads"`
+
+	// STORAGE_SIGNING_SECRET is the key used to sign download URLs for the local backend
+	STORAGE_SIGNING_SECRET string `env:"STORAGE_SIGNING_SECRET"`
+
+	// S3 compatible storage options, leave the credentials empty to use the default AWS credential chain
+	STORAGE_S3_BUCKET            string `env:"STORAGE_S3_BUCKET"`
+	STORAGE_S3_REGION            string `env:"STORAGE_S3_REGION"`
+	STORAGE_S3_ENDPOINT          string `env:"STORAGE_S3_ENDPOINT"` // e.g. a MinIO or R2 endpoint
+	STORAGE_S3_FORCE_PATH_STYLE  bool   `env:"STORAGE_S3_FORCE_PATH_STYLE" envDefault:"false"`
+	STORAGE_S3_ACCESS_KEY_ID     string `env:"STORAGE_S3_ACCESS_KEY_ID"`
+	STORAGE_S3_SECRET_ACCESS_KEY string `env:"STORAGE_S3_SECRET_ACCESS_KEY"`
+
+	// WEBSITE_PUBLIC_URL is the externally reachable URL of the website, used to build links to its pages in emails
+	WEBSITE_PUBLIC_URL string `env:"WEBSITE_PUBLIC_URL" envDefault:"http://localhost:3000"`
+
+	// ORGANIZER_INVITE_TTL_HOURS is how long the link in an invitation to organize an event is valid for
+	ORGANIZER_INVITE_TTL_HOURS int `env:"ORGANIZER_INVITE_TTL_HOURS" envDefault:"168"`
+
+	// Anonymous submission options
+
+	// ANONYMOUS_POW_DIFFICULTY is how many leading zero bits the proof of work for an anonymous submission needs
+	ANONYMOUS_POW_DIFFICULTY int `env:"ANONYMOUS_POW_DIFFICULTY" envDefault:"18"`
+
+	// ANONYMOUS_RATE_LIMIT_PER_HOUR is how many anonymous submission requests a single IP can make in an hour
+	ANONYMOUS_RATE_LIMIT_PER_HOUR int `env:"ANONYMOUS_RATE_LIMIT_PER_HOUR" envDefault:"20"`
+
+	// ANONYMOUS_VERIFICATION_TTL_HOURS is how long the email confirmation link of an anonymous response is valid for
+	ANONYMOUS_VERIFICATION_TTL_HOURS int `env:"ANONYMOUS_VERIFICATION_TTL_HOURS" envDefault:"24"`
+
+	// Outgoing email options for the platform's own emails, without an SMTP host emails are only logged
+	SMTP_HOST     string `env:"SMTP_HOST"`
+	SMTP_PORT     int    `env:"SMTP_PORT" envDefault:"587"`
+	SMTP_USERNAME string `env:"SMTP_USERNAME"`
+	SMTP_PASSWORD string `env:"SMTP_PASSWORD"`
+	MAIL_FROM     string `env:"MAIL_FROM" envDefault:"no-reply@localhost"`
+
+	// SSO_PROVIDERS is a JSON list of the OIDC and OAuth2 providers users can sign in with, see SSOProviderConfig
+	SSO_PROVIDERS SSOProviders `env:"SSO_PROVIDERS"`
+
 	// Optional Slack Integration
 	SLACK_WEBHOOK_URL string `env:"SLACK_WEBHOOK_URL" envDefault:""`
 }
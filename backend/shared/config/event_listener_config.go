@@ -17,6 +17,11 @@ type EventListenerConfig struct {
 
 	// SQS options
 
+	// FILE_UPLOAD_UNATTACHED_TTL_HOURS is how long a file uploaded for a response that is never submitted is kept
+	FILE_UPLOAD_UNATTACHED_TTL_HOURS int `env:"FILE_UPLOAD_UNATTACHED_TTL_HOURS" envDefault:"24"`
+
+	// BULK_PIPELINE_TRIGGERS_PER_SECOND is how fast a bulk operation on responses triggers their pipelines, so one job doesn't flood the pipeline queue
+	BULK_PIPELINE_TRIGGERS_PER_SECOND int `env:"BULK_PIPELINE_TRIGGERS_PER_SECOND" envDefault:"10"`
 }
 
 var (
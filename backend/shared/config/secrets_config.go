@@ -0,0 +1,48 @@
+package config
+
+import (
+	"sync"
+
+	"github.com/caarlos0/env/v6"
+)
+
+// SecretsConfig is where the master keys event secrets are encrypted with come from, the API and event listener need the same keys
+type SecretsConfig struct {
+	// SECRETS_KEY_PROVIDER is where the master keys are kept
+	SECRETS_KEY_PROVIDER string `env:"SECRETS_KEY_PROVIDER" envDefault:"env"` // env | file
+
+	// SECRETS_MASTER_KEYS is a comma-separated list of id:base64 master keys of 32 bytes, old keys are kept here until rotated away from
+	SECRETS_MASTER_KEYS string `env:"SECRETS_MASTER_KEYS"`
+
+	// SECRETS_MASTER_KEY_ID is the master key new secrets are encrypted with, the first in SECRETS_MASTER_KEYS if not set
+	SECRETS_MASTER_KEY_ID string `env:"SECRETS_MASTER_KEY_ID"`
+
+	// SECRETS_KEY_FILE is the JSON keyring the file provider reads, {"activeKeyID": "...", "keys": {"<id>": "<base64>"}}
+	SECRETS_KEY_FILE string `env:"SECRETS_KEY_FILE"`
+}
+
+var (
+	secretsCfg  *SecretsConfig
+	secretsOnce sync.Once
+)
+
+func loadSecretsConfig() (*SecretsConfig, error) {
+	cfg := &SecretsConfig{}
+	err := env.Parse(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// GetSecretsConfig returns the configuration, loading it once if necessary
+func GetSecretsConfig() (*SecretsConfig, error) {
+	var err error
+	secretsOnce.Do(func() {
+		secretsCfg, err = loadSecretsConfig()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secretsCfg, nil
+}
@@ -0,0 +1,23 @@
+package config
+
+import "encoding/json"
+
+// SSOProviderConfig is a provider users can sign in with, eg:
+//
+//	[{"id": "google", "name": "Google", "type": "oidc", "issuer": "https://accounts.google.com", "clientId": "...", "clientSecret": "..."}]
+type SSOProviderConfig struct {
+	ID           string   `json:"id"`               // used in the provider's login and callback URLs
+	Name         string   `json:"name"`             // shown on the sign in button
+	Type         string   `json:"type"`             // oidc | github
+	Issuer       string   `json:"issuer,omitempty"` // oidc only, its discovery document is at <issuer>/.well-known/openid-configuration
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	Scopes       []string `json:"scopes,omitempty"` // defaults to what's needed for the user's name and verified email
+}
+
+// SSOProviders is parsed from the JSON in SSO_PROVIDERS
+type SSOProviders []SSOProviderConfig
+
+func (p *SSOProviders) UnmarshalText(text []byte) error {
+	return json.Unmarshal(text, (*[]SSOProviderConfig)(p))
+}
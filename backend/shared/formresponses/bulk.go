@@ -0,0 +1,348 @@
+package formresponses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shared/kafka/producer"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/storage"
+	"shared/triggers"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+Bulk jobs are operations on many responses of a form that the event listener runs in the background, the API only stores them.
+
+The job stores its items so a runner can pick up where another left off: the runner holds a lease on the job which it renews
+after each chunk of items and each item whose pipelines it sent. Once the lease lapses, because the listener running it stopped,
+the next runner resumes it.
+
+The pipeline runs an item causes are recorded along with its result and only cleared once they are sent, so a resumed job sends
+those a stopped runner hadn't. A runner stopping while it sends them can send the runs of that one item again.
+*/
+
+const (
+	bulkChunkSize = 50
+	bulkJobLease  = 2 * time.Minute
+)
+
+var errBulkItemInternal = errors.New("Internal server error")
+
+// RunBulkJobs runs the unfinished jobs that no runner holds one after another, until there are none left or the context is done.
+// Pipelines are triggered at most once per triggerInterval, billed to the event owner
+func RunBulkJobs(ctx context.Context, mongoService mongodb.MongoService, messageProducer producer.MessageProducer, objectStorage storage.ObjectStorage, triggerInterval time.Duration) error {
+	for ctx.Err() == nil {
+		job, err := mongoService.ClaimBulkJob(ctx, bulkJobLease)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil
+			}
+			return err
+		}
+
+		r := &bulkRunner{ctx: ctx, mongo: mongoService, producer: messageProducer, storage: objectStorage, job: job, interval: triggerInterval}
+		if err := r.load(); err != nil {
+			// The lease lapses and the job is tried again by a later run
+			logger.Error("Failed to load bulk job forms", err)
+			continue
+		}
+
+		if err := r.run(); err != nil {
+			logger.Error("Failed to run bulk job", err)
+		}
+	}
+
+	return nil
+}
+
+// bulkRunner works through the items of a bulk job it holds the lease of
+type bulkRunner struct {
+	ctx       context.Context
+	mongo     mongodb.MongoService
+	producer  producer.MessageProducer
+	storage   storage.ObjectStorage
+	job       *models.BulkJob
+	form      *models.FormStructure
+	target    *models.FormStructure // move
+	pipelines []models.PipelineConfiguration
+	byID      map[primitive.ObjectID]models.PipelineConfiguration // pipelines by their ID
+
+	interval      time.Duration // between pipeline triggers
+	lastTrigger   time.Time
+	sub           *models.Subscription
+	pipelineError string
+}
+
+// load reads the forms and pipelines the job's items need
+func (r *bulkRunner) load() error {
+	var err error
+	r.form, err = r.mongo.GetForm(r.ctx, r.job.FormID, true)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"eventID": r.job.EventID}
+	switch r.job.Type {
+	case models.BulkJobSetField:
+		filter["event.type"] = "FieldChange"
+		filter["event.fieldChange.onFormID"] = r.job.FormID
+		filter["event.fieldChange.onFieldID"] = r.job.FieldKey
+	case models.BulkJobDelete:
+		filter["event.type"] = "WaitlistPromotion"
+		filter["event.waitlistPromotion.onFormID"] = r.job.FormID
+	case models.BulkJobMove:
+		r.target, err = r.mongo.GetForm(r.ctx, r.job.ToFormID, true)
+		if err != nil {
+			return err
+		}
+		filter["event.type"] = "FormSubmission"
+		filter["event.formSubmission.onFormID"] = r.job.ToFormID
+	default:
+		return fmt.Errorf("unknown bulk job type %s", r.job.Type)
+	}
+
+	r.pipelines, err = r.mongo.ListPipelines(r.ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	r.byID = make(map[primitive.ObjectID]models.PipelineConfiguration, len(r.pipelines))
+	for _, pipeline := range r.pipelines {
+		r.byID[pipeline.ID] = pipeline
+	}
+	return nil
+}
+
+// run processes the pending items a chunk at a time, recording their results and pipeline runs before sending the runs
+func (r *bulkRunner) run() error {
+	pending := []int{}
+	for i, item := range r.job.Items {
+		switch {
+		case item.Status == models.BulkJobItemPending:
+			pending = append(pending, i)
+		case len(item.Triggers) > 0:
+			// Recorded by a runner that stopped before sending them
+			if err := r.trigger(i, item.Triggers); err != nil {
+				return err
+			}
+		}
+	}
+
+	for start := 0; start < len(pending); start += bulkChunkSize {
+		end := start + bulkChunkSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		results := make(map[int]models.BulkJobItem)
+		for _, index := range pending[start:end] {
+			item := r.job.Items[index]
+			queued, err := r.processItem(item.ResponseID)
+			if err != nil {
+				item.Status = models.BulkJobItemFailed
+				item.Error = err.Error()
+			} else {
+				item.Status = models.BulkJobItemSucceeded
+			}
+			// A delete that failed after freeing its seat still promoted someone
+			item.Triggers = queued
+			results[index] = item
+		}
+
+		if err := r.mongo.RecordBulkJobResults(r.ctx, r.job.ID, results, bulkJobLease); err != nil {
+			return err
+		}
+
+		for _, index := range pending[start:end] {
+			if queued := results[index].Triggers; len(queued) > 0 {
+				if err := r.trigger(index, queued); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return r.mongo.FinishBulkJob(r.ctx, r.job.ID, r.pipelineError)
+}
+
+// processItem applies the job to one response and returns the pipeline runs it causes
+func (r *bulkRunner) processItem(responseID primitive.ObjectID) ([]models.BulkJobTrigger, error) {
+	responses, err := r.mongo.ListResponses(r.ctx, bson.M{"_id": responseID, "formID": r.job.FormID}, nil)
+	if err != nil {
+		logger.Error("Failed to get form response", err)
+		return nil, errBulkItemInternal
+	}
+	if len(responses) == 0 {
+		return nil, errors.New("Response not found")
+	}
+	response := responses[0]
+
+	switch r.job.Type {
+	case models.BulkJobSetField:
+		return r.setField(response)
+	case models.BulkJobDelete:
+		return r.remove(response)
+	default:
+		return r.move(response)
+	}
+}
+
+func (r *bulkRunner) setField(response models.FormResponse) ([]models.BulkJobTrigger, error) {
+	previous := response.Data
+	updated := make(map[string]interface{}, len(previous)+1)
+	for key, value := range previous {
+		updated[key] = value
+	}
+	if r.job.Value == nil {
+		delete(updated, r.job.FieldKey)
+	} else {
+		updated[r.job.FieldKey] = r.job.Value
+	}
+
+	response.Data = updated
+	response.LastUpdatedAt = time.Now()
+	change := &models.ResponseChange{ActorID: r.job.CreatedByID, Source: models.ResponseChangeBulkEdit, BulkJobID: r.job.ID, Changes: models.DiffResponseData(previous, updated)}
+	if _, err := r.mongo.UpdateResponseWithClaims(r.ctx, response, response.ID, Claims(r.form, response.UserID, updated), change); err != nil {
+		var claimErr *mongodb.ClaimTakenError
+		if errors.As(err, &claimErr) {
+			return nil, errors.New(ClaimTakenMessage(r.form, claimErr))
+		}
+		logger.Error("Failed to update form response", err)
+		return nil, errBulkItemInternal
+	}
+
+	return r.queue(triggers.ChangedFieldPipelines(r.pipelines, previous, updated), response.ID), nil
+}
+
+func (r *bulkRunner) remove(response models.FormResponse) ([]models.BulkJobTrigger, error) {
+	change := models.ResponseChange{ActorID: r.job.CreatedByID, Source: models.ResponseChangeBulkDeletion, BulkJobID: r.job.ID}
+	promoted, err := Delete(r.ctx, r.mongo, r.storage, r.form.Capacity, response, change)
+
+	queued := []models.BulkJobTrigger{}
+	for _, promotedResponse := range promoted {
+		queued = append(queued, r.queue(r.pipelines, promotedResponse.ID)...)
+	}
+
+	if err != nil {
+		logger.Error("Failed to delete form response", err)
+		return queued, errBulkItemInternal
+	}
+	return queued, nil
+}
+
+func (r *bulkRunner) move(response models.FormResponse) ([]models.BulkJobTrigger, error) {
+	if response.Admission != nil {
+		return nil, errors.New("Responses with an admission can't be moved")
+	}
+
+	if err := r.mongo.MoveResponse(r.ctx, response, r.target.ID, Claims(r.target, response.UserID, response.Data), r.target.MaxSubmissions); err != nil {
+		// The target can have filled up with submissions since the job was created
+		if err == mongodb.ErrMaxSubmissionsReached {
+			return nil, errors.New("The target form has reached its maximum number of submissions")
+		}
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("Response not found")
+		}
+
+		var claimErr *mongodb.ClaimTakenError
+		if errors.As(err, &claimErr) {
+			if ClaimFieldKey(claimErr.Claim) == "" {
+				return nil, errors.New("The applicant has already responded to the target form")
+			}
+			return nil, errors.New(ClaimTakenMessage(r.target, claimErr))
+		}
+		logger.Error("Failed to move form response", err)
+		return nil, errBulkItemInternal
+	}
+
+	return r.queue(r.pipelines, response.ID), nil
+}
+
+// queue returns a run of each enabled pipeline for the response
+func (r *bulkRunner) queue(pipelines []models.PipelineConfiguration, responseID primitive.ObjectID) []models.BulkJobTrigger {
+	queued := []models.BulkJobTrigger{}
+	for _, pipeline := range pipelines {
+		if pipeline.Enabled {
+			queued = append(queued, models.BulkJobTrigger{PipelineID: pipeline.ID, ResponseID: responseID})
+		}
+	}
+	return queued
+}
+
+// trigger sends the pipeline runs of the item with the response data as it is now, billed to the event owner, and clears them from the item.
+// Once the owner can't be billed the rest of the job's pipelines are skipped and the reason is kept on the job
+func (r *bulkRunner) trigger(index int, queued []models.BulkJobTrigger) error {
+	if r.pipelineError == "" {
+		r.send(queued)
+	}
+
+	return r.mongo.ClearBulkJobItemTriggers(r.ctx, r.job.ID, index, bulkJobLease)
+}
+
+func (r *bulkRunner) send(queued []models.BulkJobTrigger) {
+	if r.sub == nil {
+		sub, err := triggers.GetEventSubscription(r.ctx, r.mongo, r.job.EventID)
+		if err != nil {
+			if err != triggers.ErrNoActiveSubscription {
+				logger.Error("Failed to get event subscription", err)
+			}
+			r.pipelineError = "The event owner does not have an active subscription, pipelines were not triggered"
+			return
+		}
+		r.sub = sub
+	}
+
+	responseIDs := []primitive.ObjectID{}
+	for _, trigger := range queued {
+		responseIDs = append(responseIDs, trigger.ResponseID)
+	}
+
+	// Moved responses are on the target form by now
+	responses, err := r.mongo.ListResponses(r.ctx, bson.M{"_id": bson.M{"$in": responseIDs}}, nil)
+	if err != nil {
+		logger.Error("Failed to get the responses of bulk job pipelines", err)
+		return
+	}
+	data := make(map[primitive.ObjectID]map[string]interface{}, len(responses))
+	for _, response := range responses {
+		data[response.ID] = response.Data
+	}
+
+	for _, trigger := range queued {
+		pipeline, ok := r.byID[trigger.PipelineID]
+		responseData, found := data[trigger.ResponseID]
+		if !ok || !found {
+			// The pipeline or response was deleted since
+			continue
+		}
+
+		r.wait()
+
+		err := triggers.BilledPipelines(r.ctx, r.producer, r.mongo, r.sub, []models.PipelineConfiguration{pipeline}, responseData)
+		if err == triggers.ErrPipelineLimitReached {
+			r.pipelineError = "Pipeline limit reached, the remaining pipelines were not triggered"
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to trigger bulk job pipeline", err)
+		}
+	}
+}
+
+// wait holds the runner until interval has passed since the last pipeline it triggered
+func (r *bulkRunner) wait() {
+	if wait := time.Until(r.lastTrigger.Add(r.interval)); wait > 0 {
+		select {
+		case <-r.ctx.Done():
+		case <-time.After(wait):
+		}
+	}
+	r.lastTrigger = time.Now()
+}
@@ -0,0 +1,264 @@
+package formresponses
+
+import (
+	"context"
+	"shared/models"
+	"shared/mongodb"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func testForm() *models.FormStructure {
+	return &models.FormStructure{
+		ID: primitive.NewObjectID(),
+		Attrs: []models.FormField{
+			{Key: "name", Question: "Full Name", Type: models.FormFieldTypeText, Required: true},
+			{Key: "email", Question: "Email", Type: models.FormFieldTypeText},
+		},
+		UniqueFieldKeys: []string{"email"},
+	}
+}
+
+// bulkMongo keeps responses and the job's results in memory, only the methods a set field job and its pipelines use are implemented
+type bulkMongo struct {
+	mongodb.MongoService
+	responses    map[primitive.ObjectID]models.FormResponse
+	claims       map[string]primitive.ObjectID
+	results      map[int]models.BulkJobItem
+	cleared      []int
+	pipelineRuns []primitive.ObjectID
+	finished     bool
+}
+
+func newBulkMongo() *bulkMongo {
+	return &bulkMongo{responses: map[primitive.ObjectID]models.FormResponse{}, claims: map[string]primitive.ObjectID{}, results: map[int]models.BulkJobItem{}}
+}
+
+func (m *bulkMongo) ListResponses(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.FormResponse, error) {
+	ids := []primitive.ObjectID{}
+	switch id := filter["_id"].(type) {
+	case primitive.ObjectID:
+		ids = append(ids, id)
+	case bson.M:
+		ids = id["$in"].([]primitive.ObjectID)
+	}
+
+	responses := []models.FormResponse{}
+	for _, id := range ids {
+		response, ok := m.responses[id]
+		if !ok || (filter["formID"] != nil && response.FormID != filter["formID"]) {
+			continue
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func (m *bulkMongo) UpdateResponseWithClaims(ctx context.Context, response models.FormResponse, responseID primitive.ObjectID, claims []string, change *models.ResponseChange) (*mongo.UpdateResult, error) {
+	for _, claim := range claims {
+		if holder, ok := m.claims[claim]; ok && holder != responseID {
+			return nil, &mongodb.ClaimTakenError{Claim: claim}
+		}
+	}
+	for _, claim := range claims {
+		m.claims[claim] = responseID
+	}
+	m.responses[responseID] = response
+	return &mongo.UpdateResult{MatchedCount: 1}, nil
+}
+
+func (m *bulkMongo) RecordBulkJobResults(ctx context.Context, jobID primitive.ObjectID, results map[int]models.BulkJobItem, lease time.Duration) error {
+	for index, item := range results {
+		m.results[index] = item
+	}
+	return nil
+}
+
+func (m *bulkMongo) ClearBulkJobItemTriggers(ctx context.Context, jobID primitive.ObjectID, index int, lease time.Duration) error {
+	m.cleared = append(m.cleared, index)
+	return nil
+}
+
+func (m *bulkMongo) FinishBulkJob(ctx context.Context, jobID primitive.ObjectID, pipelineError string) error {
+	m.finished = true
+	return nil
+}
+
+// MoveResponse refuses the move like the service does, when the target is full or the response has left its form
+func (m *bulkMongo) MoveResponse(ctx context.Context, response models.FormResponse, toFormID primitive.ObjectID, claims []string, maxSubmissions int) error {
+	onTarget := 0
+	for _, other := range m.responses {
+		if other.FormID == toFormID {
+			onTarget++
+		}
+	}
+	if maxSubmissions > 0 && onTarget+1 > maxSubmissions {
+		return mongodb.ErrMaxSubmissionsReached
+	}
+
+	if current, ok := m.responses[response.ID]; !ok || current.FormID != response.FormID {
+		return mongo.ErrNoDocuments
+	}
+
+	response.FormID = toFormID
+	m.responses[response.ID] = response
+	return nil
+}
+
+// The event owner is billed for pipelines
+func (m *bulkMongo) FindEvent(ctx context.Context, eventID primitive.ObjectID) (*models.Event, error) {
+	return &models.Event{ID: eventID}, nil
+}
+
+func (m *bulkMongo) GetUserDetails(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	return &models.User{ID: userID, CurrentSubscriptionID: primitive.NewObjectID()}, nil
+}
+
+func (m *bulkMongo) GetSubscription(ctx context.Context, subscriptionID primitive.ObjectID) (*models.Subscription, error) {
+	return &models.Subscription{ID: subscriptionID, Status: models.SubscriptionStatusActive}, nil
+}
+
+func (m *bulkMongo) IncrementSubscriptionUtilization(ctx context.Context, subscriptionID primitive.ObjectID, utilizationKey string, limitKey string) (*mongo.UpdateResult, error) {
+	return &mongo.UpdateResult{ModifiedCount: 1}, nil
+}
+
+func (m *bulkMongo) CreatePipelineRun(ctx context.Context, run models.PipelineRun) (*mongo.InsertOneResult, error) {
+	m.pipelineRuns = append(m.pipelineRuns, run.PipelineID)
+	return &mongo.InsertOneResult{InsertedID: primitive.NewObjectID()}, nil
+}
+
+func TestBulkRunnerSetField(t *testing.T) {
+	form := testForm()
+
+	mongo := newBulkMongo()
+	addResponse := func(formID primitive.ObjectID, data map[string]interface{}) primitive.ObjectID {
+		id := primitive.NewObjectID()
+		mongo.responses[id] = models.FormResponse{ID: id, FormID: formID, Data: data}
+		for _, claim := range Claims(form, primitive.NilObjectID, data) {
+			mongo.claims[claim] = id
+		}
+		return id
+	}
+
+	first := addResponse(form.ID, map[string]interface{}{"name": "Ada", "email": "ada@example.com"})
+	second := addResponse(form.ID, map[string]interface{}{"name": "Grace"})
+	otherForm := addResponse(primitive.NewObjectID(), map[string]interface{}{"name": "Linus"})
+
+	job := &models.BulkJob{
+		ID:       primitive.NewObjectID(),
+		FormID:   form.ID,
+		Type:     models.BulkJobSetField,
+		FieldKey: "email",
+		Value:    "ADA@example.com",
+		Items: []models.BulkJobItem{
+			{ResponseID: first, Status: models.BulkJobItemPending},
+			{ResponseID: second, Status: models.BulkJobItemPending},
+			{ResponseID: otherForm, Status: models.BulkJobItemPending},
+			{ResponseID: second, Status: models.BulkJobItemSucceeded},
+		},
+	}
+
+	r := &bulkRunner{ctx: context.Background(), mongo: mongo, job: job, form: form}
+	require.NoError(t, r.run())
+
+	assert.True(t, mongo.finished)
+	require.Len(t, mongo.results, 3, "finished items aren't run again")
+	assert.Equal(t, models.BulkJobItemSucceeded, mongo.results[0].Status)
+	assert.Equal(t, "ADA@example.com", mongo.responses[first].Data["email"])
+	assert.Equal(t, "Ada", mongo.responses[first].Data["name"])
+
+	assert.Equal(t, models.BulkJobItemFailed, mongo.results[1].Status, "the email is unique")
+	assert.Contains(t, mongo.results[1].Error, "Email")
+	assert.Nil(t, mongo.responses[second].Data["email"])
+
+	assert.Equal(t, models.BulkJobItemFailed, mongo.results[2].Status)
+	assert.Equal(t, "Response not found", mongo.results[2].Error)
+}
+
+func TestBulkRunnerTriggers(t *testing.T) {
+	form := testForm()
+	pipeline := models.PipelineConfiguration{ID: primitive.NewObjectID(), Enabled: true, Event: models.PipelineEvent{
+		Type:        "FieldChange",
+		FieldChange: &models.FieldChange{OnFormID: form.ID, OnFieldID: "name", Condition: models.FieldChangeCondition{Comparison: models.ComparisonEq, Value: "Grace"}},
+	}}
+
+	mongo := newBulkMongo()
+	sent := primitive.NewObjectID()
+	unsent := primitive.NewObjectID()
+	pending := primitive.NewObjectID()
+	for _, id := range []primitive.ObjectID{sent, unsent, pending} {
+		mongo.responses[id] = models.FormResponse{ID: id, FormID: form.ID, Data: map[string]interface{}{"name": "Ada"}}
+	}
+
+	job := &models.BulkJob{
+		ID:       primitive.NewObjectID(),
+		FormID:   form.ID,
+		Type:     models.BulkJobSetField,
+		FieldKey: "name",
+		Value:    "Grace",
+		Items: []models.BulkJobItem{
+			{ResponseID: sent, Status: models.BulkJobItemSucceeded},
+			// Recorded by a runner that stopped before sending its pipelines
+			{ResponseID: unsent, Status: models.BulkJobItemSucceeded, Triggers: []models.BulkJobTrigger{{PipelineID: pipeline.ID, ResponseID: unsent}}},
+			{ResponseID: pending, Status: models.BulkJobItemPending},
+		},
+	}
+
+	r := &bulkRunner{
+		ctx:       context.Background(),
+		mongo:     mongo,
+		job:       job,
+		form:      form,
+		pipelines: []models.PipelineConfiguration{pipeline},
+		byID:      map[primitive.ObjectID]models.PipelineConfiguration{pipeline.ID: pipeline},
+	}
+	require.NoError(t, r.run())
+
+	assert.Equal(t, []primitive.ObjectID{pipeline.ID, pipeline.ID}, mongo.pipelineRuns, "the unsent run is sent, the sent one isn't sent again")
+	assert.Equal(t, []models.BulkJobTrigger{{PipelineID: pipeline.ID, ResponseID: pending}}, mongo.results[2].Triggers, "runs are recorded with the item's result")
+	assert.Equal(t, []int{1, 2}, mongo.cleared, "runs are cleared once they are sent")
+	assert.Empty(t, r.pipelineError)
+}
+
+func TestBulkRunnerMove(t *testing.T) {
+	form := testForm()
+	target := testForm()
+	target.MaxSubmissions = 2
+
+	mongo := newBulkMongo()
+	moved, full := primitive.NewObjectID(), primitive.NewObjectID()
+	for _, id := range []primitive.ObjectID{moved, full} {
+		mongo.responses[id] = models.FormResponse{ID: id, FormID: form.ID, Data: map[string]interface{}{"name": "Ada"}}
+	}
+	// Submitted to the target while the job was waiting
+	submitted := primitive.NewObjectID()
+	mongo.responses[submitted] = models.FormResponse{ID: submitted, FormID: target.ID}
+
+	job := &models.BulkJob{
+		ID:       primitive.NewObjectID(),
+		FormID:   form.ID,
+		Type:     models.BulkJobMove,
+		ToFormID: target.ID,
+		Items: []models.BulkJobItem{
+			{ResponseID: moved, Status: models.BulkJobItemPending},
+			{ResponseID: full, Status: models.BulkJobItemPending},
+		},
+	}
+
+	r := &bulkRunner{ctx: context.Background(), mongo: mongo, job: job, form: form, target: target}
+	require.NoError(t, r.run())
+
+	assert.Equal(t, models.BulkJobItemSucceeded, mongo.results[0].Status)
+	assert.Equal(t, target.ID, mongo.responses[moved].FormID)
+
+	assert.Equal(t, models.BulkJobItemFailed, mongo.results[1].Status)
+	assert.Equal(t, "The target form has reached its maximum number of submissions", mongo.results[1].Error)
+	assert.Equal(t, form.ID, mongo.responses[full].FormID)
+}
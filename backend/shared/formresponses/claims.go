@@ -0,0 +1,72 @@
+package formresponses
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"shared/models"
+	"shared/mongodb"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+Claims enforce the form's one response per user and unique field rules atomically, see mongodb.ResponseSubmission.
+
+  - user:<userID> when the form doesn't allow multiple submissions, anonymous responses have no user to claim
+  - field:<fieldKey>:<hash> for each unique field that has a value, values are compared case-insensitively
+*/
+
+const (
+	userClaimPrefix  = "user:"
+	fieldClaimPrefix = "field:"
+)
+
+// Claims returns the claims a response with this data takes on the form
+func Claims(form *models.FormStructure, userID primitive.ObjectID, data map[string]interface{}) []string {
+	claims := []string{}
+	if !form.AllowMultipleSubmissions && !userID.IsZero() {
+		claims = append(claims, userClaimPrefix+userID.Hex())
+	}
+
+	for _, key := range form.UniqueFieldKeys {
+		value, ok := data[key]
+		if !ok || value == nil {
+			continue
+		}
+
+		normalized := strings.ToLower(strings.TrimSpace(fmt.Sprint(value)))
+		if normalized == "" {
+			continue
+		}
+
+		// Hashed so the claim has a bounded size and doesn't copy the response data
+		hash := sha256.Sum256([]byte(normalized))
+		claims = append(claims, fieldClaimPrefix+key+":"+hex.EncodeToString(hash[:]))
+	}
+
+	return claims
+}
+
+// ClaimFieldKey returns the key of the unique field a claim is for, it is empty for other claims
+func ClaimFieldKey(claim string) string {
+	if !strings.HasPrefix(claim, fieldClaimPrefix) {
+		return ""
+	}
+	return strings.SplitN(strings.TrimPrefix(claim, fieldClaimPrefix), ":", 2)[0]
+}
+
+// ClaimTakenMessage explains to the user why their response conflicts with an existing one
+func ClaimTakenMessage(form *models.FormStructure, err *mongodb.ClaimTakenError) string {
+	if key := ClaimFieldKey(err.Claim); key != "" {
+		for _, field := range form.Attrs {
+			if field.Key == key {
+				return fmt.Sprintf("A response with this value for \"%s\" has already been submitted", field.Question)
+			}
+		}
+		return "A response with one of these values has already been submitted"
+	}
+
+	return "You have already submitted this form"
+}
@@ -0,0 +1,47 @@
+package formresponses
+
+import (
+	"shared/models"
+	"shared/mongodb"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestClaims(t *testing.T) {
+	userID := primitive.NewObjectID()
+	form := &models.FormStructure{
+		Attrs:           []models.FormField{{Key: "email", Question: "Email"}, {Key: "team", Question: "Team"}},
+		UniqueFieldKeys: []string{"email"},
+	}
+
+	claims := Claims(form, userID, map[string]interface{}{"email": "Someone@Example.com ", "team": "a"})
+	assert.Len(t, claims, 2)
+	assert.Equal(t, "user:"+userID.Hex(), claims[0])
+	assert.True(t, strings.HasPrefix(claims[1], "field:email:"))
+	assert.NotContains(t, claims[1], "example")
+
+	// Values are compared case-insensitively
+	other := Claims(form, primitive.NewObjectID(), map[string]interface{}{"email": "someone@example.com"})
+	assert.Equal(t, claims[1], other[1])
+
+	// Empty unique fields don't claim anything
+	assert.Equal(t, []string{"user:" + userID.Hex()}, Claims(form, userID, map[string]interface{}{"email": " "}))
+
+	// Anonymous responses only claim their unique fields
+	anonymous := Claims(form, primitive.NilObjectID, map[string]interface{}{"email": "someone@example.com"})
+	assert.Equal(t, []string{claims[1]}, anonymous)
+
+	form.AllowMultipleSubmissions = true
+	assert.Equal(t, []string{}, Claims(form, userID, map[string]interface{}{}))
+}
+
+func TestClaimTakenMessage(t *testing.T) {
+	form := &models.FormStructure{Attrs: []models.FormField{{Key: "email", Question: "Email"}}}
+
+	assert.Equal(t, "You have already submitted this form", ClaimTakenMessage(form, &mongodb.ClaimTakenError{Claim: "user:abc"}))
+	assert.Equal(t, `A response with this value for "Email" has already been submitted`, ClaimTakenMessage(form, &mongodb.ClaimTakenError{Claim: "field:email:0a1b"}))
+	assert.Equal(t, "A response with one of these values has already been submitted", ClaimTakenMessage(form, &mongodb.ClaimTakenError{Claim: "field:removed:0a1b"}))
+}
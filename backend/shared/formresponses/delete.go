@@ -0,0 +1,48 @@
+package formresponses
+
+import (
+	"context"
+	"shared/logger"
+	"shared/models"
+	"shared/mongodb"
+	"shared/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Delete deletes a response and releases everything held by it: its seat, files, claims and reviews. Its history is kept,
+// ending with the change, which only needs who deleted it and how. capacity is that of the response's form,
+// it returns the responses promoted into the freed seat even when a later step fails
+func Delete(c context.Context, mongo mongodb.MongoService, objectStorage storage.ObjectStorage, capacity models.FormCapacity, response models.FormResponse, change models.ResponseChange) ([]models.FormResponse, error) {
+	var promoted []models.FormResponse
+
+	// Give up the response's seat first so the next person on the waitlist is promoted into it
+	if admission := response.Admission; admission != nil && admission.Status.HoldsSeat() {
+		var err error
+		promoted, err = mongo.DeclineAdmission(c, response.ID, capacity)
+		if err != nil && err != mongodb.ErrAdmissionStatusChanged {
+			return nil, err
+		}
+	}
+
+	if err := storage.DeleteFileUploads(c, objectStorage, mongo, bson.M{"formID": response.FormID, "responseID": response.ID}); err != nil {
+		return promoted, err
+	}
+
+	// The answers are kept in the history, so it shows what was deleted
+	change.FormID = response.FormID
+	change.Changes = models.DiffResponseData(response.Data, nil)
+	if _, err := mongo.DeleteResponse(c, response.ID, &change); err != nil {
+		return promoted, err
+	}
+
+	if _, err := mongo.DeleteResponseClaims(c, bson.M{"responseID": response.ID}); err != nil {
+		logger.Error("Failed to release form response claims", err)
+	}
+
+	if _, err := mongo.DeleteReviews(c, bson.M{"responseID": response.ID}); err != nil {
+		logger.Error("Failed to delete form response reviews", err)
+	}
+
+	return promoted, nil
+}
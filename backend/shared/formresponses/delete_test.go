@@ -0,0 +1,58 @@
+package formresponses
+
+import (
+	"context"
+	"shared/models"
+	"shared/mongodb"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// deleteMongo records what deleting a response without files or a seat does
+type deleteMongo struct {
+	mongodb.MongoService
+	deleted []primitive.ObjectID
+	history []models.ResponseChange
+}
+
+func (m *deleteMongo) ListFileUploads(ctx context.Context, filter bson.M) ([]models.FileUpload, error) {
+	return nil, nil
+}
+
+func (m *deleteMongo) DeleteResponse(ctx context.Context, responseID primitive.ObjectID, change *models.ResponseChange) (*mongo.DeleteResult, error) {
+	m.deleted = append(m.deleted, responseID)
+	m.history = append(m.history, *change)
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+func (m *deleteMongo) DeleteResponseClaims(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return &mongo.DeleteResult{}, nil
+}
+
+func (m *deleteMongo) DeleteReviews(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return &mongo.DeleteResult{}, nil
+}
+
+func TestDeleteRecordsHistory(t *testing.T) {
+	m := &deleteMongo{}
+	organizer := primitive.NewObjectID()
+	response := models.FormResponse{ID: primitive.NewObjectID(), FormID: primitive.NewObjectID(), Data: map[string]interface{}{"name": "Ada", "decision": "Accepted"}}
+
+	promoted, err := Delete(context.Background(), m, nil, models.FormCapacity{}, response, models.ResponseChange{ActorID: organizer, Source: models.ResponseChangeOrganizerDeletion})
+	require.NoError(t, err)
+	assert.Empty(t, promoted)
+
+	assert.Equal(t, []primitive.ObjectID{response.ID}, m.deleted)
+	require.Len(t, m.history, 1, "the history isn't deleted, the deletion is added to it")
+	assert.Equal(t, organizer, m.history[0].ActorID)
+	assert.Equal(t, response.FormID, m.history[0].FormID)
+	assert.Equal(t, []models.ResponseFieldChange{
+		{Key: "decision", Old: "Accepted"},
+		{Key: "name", Old: "Ada"},
+	}, m.history[0].Changes, "the deleted answers are kept")
+}
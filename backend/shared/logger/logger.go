@@ -28,6 +28,11 @@ func Error(description string, err error) {
 	}
 }
 
+// Security logs a security event, like an account being locked out, with the fields to look it up by
+func Security(event string, fields map[string]interface{}) {
+	Logger.WithFields(logrus.Fields(fields)).WithField("securityEvent", event).Warn("Security event: " + event)
+}
+
 // Info logs an informational message
 func LogInfo(description string) {
 	Logger.Info(description)
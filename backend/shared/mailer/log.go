@@ -0,0 +1,22 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"shared/logger"
+	"strings"
+)
+
+// LogMailer writes emails to the log instead of sending them, for development without an SMTP server
+type LogMailer struct {
+	From string
+}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	if message.From == "" {
+		message.From = m.From
+	}
+
+	logger.LogInfo(fmt.Sprintf("[mailer] email from %s to %s: %s\n%s", message.From, strings.Join(message.To, ", "), message.Subject, message.Body))
+	return nil
+}
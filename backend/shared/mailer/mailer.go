@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"shared/config"
+)
+
+// Message is an email to send
+type Message struct {
+	From    string // the mailer's default sender is used when empty
+	To      []string
+	CC      []string
+	BCC     []string
+	ReplyTo string // defaults to the sender
+	Subject string
+	Body    string
+	IsHTML  bool
+}
+
+// Mailer is the interface emails are sent through
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewMailer creates the mailer for the platform's own emails selected in the API config.
+// Without an SMTP host emails are written to the log instead, which is enough for local development
+func NewMailer() (Mailer, error) {
+	cfg, err := config.GetAPIConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SMTP_HOST == "" {
+		return &LogMailer{From: cfg.MAIL_FROM}, nil
+	}
+
+	return &SMTPMailer{
+		Host:     cfg.SMTP_HOST,
+		Port:     cfg.SMTP_PORT,
+		Username: cfg.SMTP_USERNAME,
+		Password: cfg.SMTP_PASSWORD,
+		From:     cfg.MAIL_FROM,
+	}, nil
+}
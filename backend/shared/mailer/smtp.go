@@ -0,0 +1,71 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoRecipients is returned when a message has nobody to send it to
+var ErrNoRecipients = errors.New("email has no recipients")
+
+// SMTPMailer sends emails through an SMTP server with plain authentication
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // default sender for messages without one
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	if message.From == "" {
+		message.From = m.From
+	}
+
+	recipients := append(append(append([]string{}, message.To...), message.CC...), message.BCC...)
+	if len(recipients) == 0 {
+		return ErrNoRecipients
+	}
+
+	address := fmt.Sprintf("%s:%d", m.Host, m.Port)
+	auth := smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	return smtp.SendMail(address, auth, message.From, recipients, buildMessage(message, m.Host, time.Now()))
+}
+
+// buildMessage renders the headers and body of the message, BCC recipients are left out of the headers
+func buildMessage(message Message, host string, now time.Time) []byte {
+	replyTo := message.ReplyTo
+	if replyTo == "" {
+		replyTo = message.From
+	}
+
+	contentType := "text/plain"
+	body := message.Body
+	if message.IsHTML {
+		contentType = "text/html"
+		body = "<html><body>" + body + "</body></html>"
+	}
+
+	var b strings.Builder
+	b.WriteString("Subject: " + message.Subject + "\r\n")
+	b.WriteString("From: " + message.From + "\r\n")
+	b.WriteString("To: " + strings.Join(message.To, ", ") + "\r\n")
+	if len(message.CC) > 0 {
+		b.WriteString("Cc: " + strings.Join(message.CC, ", ") + "\r\n")
+	}
+	b.WriteString("Reply-To: " + replyTo + "\r\n")
+	b.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString(fmt.Sprintf("Message-ID: <%s@%s>\r\n", uuid.NewString(), host))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: " + contentType + "; charset=\"UTF-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(body)
+
+	return []byte(b.String())
+}
@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMessage(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("plain text defaults the reply to the sender", func(t *testing.T) {
+		raw := string(buildMessage(Message{
+			From:    "events@example.com",
+			To:      []string{"a@example.com", "b@example.com"},
+			BCC:     []string{"hidden@example.com"},
+			Subject: "Hello",
+			Body:    "Hi there",
+		}, "smtp.example.com", now))
+
+		headers, body, _ := strings.Cut(raw, "\r\n\r\n")
+		assert.Contains(t, headers, "To: a@example.com, b@example.com\r\n")
+		assert.Contains(t, headers, "Reply-To: events@example.com\r\n")
+		assert.Contains(t, headers, "Content-Type: text/plain")
+		assert.Contains(t, headers, "@smtp.example.com>")
+		assert.NotContains(t, headers, "Cc:")
+		assert.NotContains(t, raw, "hidden@example.com")
+		assert.Equal(t, "Hi there", body)
+	})
+
+	t.Run("html is wrapped", func(t *testing.T) {
+		raw := string(buildMessage(Message{
+			From:    "events@example.com",
+			To:      []string{"a@example.com"},
+			CC:      []string{"c@example.com"},
+			ReplyTo: "help@example.com",
+			Body:    "<p>Hi</p>",
+			IsHTML:  true,
+		}, "smtp.example.com", now))
+
+		assert.Contains(t, raw, "Cc: c@example.com\r\n")
+		assert.Contains(t, raw, "Reply-To: help@example.com\r\n")
+		assert.Contains(t, raw, "Content-Type: text/html")
+		assert.True(t, strings.HasSuffix(raw, "<html><body><p>Hi</p></body></html>"))
+	})
+}
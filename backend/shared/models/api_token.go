@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APITokenPrefix starts every API token, so the auth middleware can tell them from JWTs
+const APITokenPrefix = "aat_"
+
+// APIToken lets a user's scripts call the API without signing in. It only allows its permissions on its event, or on one of the event's forms,
+// and never more than the user's role on the event allows
+type APIToken struct {
+	ID                primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID            primitive.ObjectID  `bson:"userID" json:"userID"`
+	Name              string              `bson:"name" json:"name"`
+	TokenHash         string              `bson:"tokenHash" json:"-"` // only the hash of the token is stored
+	Hint              string              `bson:"hint" json:"hint"`   // the last characters of the token, to tell tokens apart
+	EventID           primitive.ObjectID  `bson:"eventID" json:"eventID"`
+	FormID            *primitive.ObjectID `bson:"formID,omitempty" json:"formID,omitempty"` // limits the token to the routes of the form
+	Permissions       []Permission        `bson:"permissions" json:"permissions"`
+	TwoFactorVerified bool                `bson:"twoFactorVerified" json:"twoFactorVerified"` // created from a session signed in with a second factor
+	CreatedAt         time.Time           `bson:"createdAt" json:"createdAt"`
+	LastUsedAt        *time.Time          `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	ExpiresAt         time.Time           `bson:"expiresAt" json:"expiresAt"` // removed by a TTL index once this passes
+}
+
+// Allows checks the token was given the permission on the event, formID is the form the request is for or nil if it isn't for one
+func (t *APIToken) Allows(eventID primitive.ObjectID, formID primitive.ObjectID, permission Permission) bool {
+	if t.EventID != eventID {
+		return false
+	}
+
+	if t.FormID != nil && *t.FormID != formID {
+		return false
+	}
+
+	for _, p := range t.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAPITokenAllows(t *testing.T) {
+	eventID, formID, otherFormID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	eventToken := APIToken{EventID: eventID, Permissions: []Permission{PermissionViewResponses}}
+	assert.True(t, eventToken.Allows(eventID, formID, PermissionViewResponses))
+	assert.True(t, eventToken.Allows(eventID, primitive.NilObjectID, PermissionViewResponses))
+	assert.False(t, eventToken.Allows(eventID, formID, PermissionEditResponses), "a permission it wasn't given")
+	assert.False(t, eventToken.Allows(primitive.NewObjectID(), formID, PermissionViewResponses), "another event")
+
+	formToken := APIToken{EventID: eventID, FormID: &formID, Permissions: []Permission{PermissionEditResponses}}
+	assert.True(t, formToken.Allows(eventID, formID, PermissionEditResponses))
+	assert.False(t, formToken.Allows(eventID, otherFormID, PermissionEditResponses), "another form of the event")
+	assert.False(t, formToken.Allows(eventID, primitive.NilObjectID, PermissionEditResponses), "the event's own routes")
+}
+
+func TestPermissionIsValid(t *testing.T) {
+	assert.True(t, PermissionEditEmailTemplates.IsValid())
+	assert.False(t, Permission("responses:*").IsValid())
+}
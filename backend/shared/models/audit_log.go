@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditTarget is what an audited action was done to, the most specific ID in its path
+type AuditTarget struct {
+	Type string             `bson:"type" json:"type"` // event | form | pipeline | emailTemplate | response | organizer | invite
+	ID   primitive.ObjectID `bson:"id" json:"id"`
+}
+
+// AuditChange is a field of the target before and after an audited action, a nil value means it wasn't set or isn't known
+type AuditChange struct {
+	Field string      `bson:"field" json:"field"`
+	Old   interface{} `bson:"old,omitempty" json:"old"`
+	New   interface{} `bson:"new,omitempty" json:"new"`
+}
+
+// AuditLogEntry is an entry in the append-only audit log of an event, recording who changed it or read its sensitive data
+type AuditLogEntry struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	EventID    primitive.ObjectID  `bson:"eventID" json:"eventID"`
+	ActorID    primitive.ObjectID  `bson:"actorID" json:"actorID"`
+	ActorEmail string              `bson:"actorEmail" json:"actorEmail"`
+	APITokenID *primitive.ObjectID `bson:"apiTokenID,omitempty" json:"apiTokenID,omitempty"` // when the actor used an API token
+	Action     string              `bson:"action" json:"action"`                             // the method and route, eg: DELETE /forms/:form_id
+	Path       string              `bson:"path" json:"path"`                                 // the route with its params filled in
+	Permission Permission          `bson:"permission,omitempty" json:"permission,omitempty"` // the permission the action needed
+	Target     AuditTarget         `bson:"target" json:"target"`
+	IP         string              `bson:"ip" json:"ip"`
+	UserAgent  string              `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	Status     int                 `bson:"status" json:"status"`
+	Changes    []AuditChange       `bson:"changes,omitempty" json:"changes"`
+	CreatedAt  time.Time           `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  *time.Time          `bson:"expiresAt,omitempty" json:"-"` // nil when entries are kept forever
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BulkJobType is the operation a bulk job performs on each of its responses
+type BulkJobType string
+
+const (
+	BulkJobSetField BulkJobType = "setField"
+	BulkJobDelete   BulkJobType = "delete"
+	BulkJobMove     BulkJobType = "move"
+)
+
+// BulkJobStatus is how far along a bulk job is
+type BulkJobStatus string
+
+const (
+	BulkJobPending   BulkJobStatus = "pending"
+	BulkJobRunning   BulkJobStatus = "running"
+	BulkJobCompleted BulkJobStatus = "completed"
+)
+
+// BulkJobItemStatus is the result of a bulk job on one of its responses
+type BulkJobItemStatus string
+
+const (
+	BulkJobItemPending   BulkJobItemStatus = "pending"
+	BulkJobItemSucceeded BulkJobItemStatus = "succeeded"
+	BulkJobItemFailed    BulkJobItemStatus = "failed"
+)
+
+// BulkJobItem is a response a bulk job works on and how it went
+type BulkJobItem struct {
+	ResponseID primitive.ObjectID `bson:"responseID" json:"responseID"`
+	Status     BulkJobItemStatus  `bson:"status" json:"status"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	Triggers   []BulkJobTrigger   `bson:"triggers,omitempty" json:"-"` // pipeline runs the item caused that haven't been sent yet
+}
+
+// BulkJobTrigger is a pipeline run caused by a bulk job, it is sent with the response's data as it is when it's sent
+type BulkJobTrigger struct {
+	PipelineID primitive.ObjectID `bson:"pipelineID"`
+	ResponseID primitive.ObjectID `bson:"responseID"`
+}
+
+// BulkJob is an operation on many responses of a form that runs in the background, tracking the result for each response
+type BulkJob struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	FormID      primitive.ObjectID `bson:"formID" json:"formID"`
+	EventID     primitive.ObjectID `bson:"eventID" json:"eventID"`
+	CreatedByID primitive.ObjectID `bson:"createdByID" json:"createdByID"`
+	Type        BulkJobType        `bson:"type" json:"type"`
+
+	FieldKey string             `bson:"fieldKey,omitempty" json:"fieldKey,omitempty"` // setField
+	Value    interface{}        `bson:"value,omitempty" json:"value,omitempty"`       // setField, clears the field when nil
+	ToFormID primitive.ObjectID `bson:"toFormID,omitempty" json:"toFormID,omitempty"` // move
+
+	Status        BulkJobStatus `bson:"status" json:"status"`
+	Items         []BulkJobItem `bson:"items" json:"items,omitempty"`
+	Total         int           `bson:"total" json:"total"`
+	Succeeded     int           `bson:"succeeded" json:"succeeded"`
+	Failed        int           `bson:"failed" json:"failed"`
+	PipelineError string        `bson:"pipelineError,omitempty" json:"pipelineError,omitempty"` // why some of the job's pipelines weren't triggered
+
+	CreatedAt      time.Time `bson:"createdAt" json:"createdAt"`
+	FinishedAt     time.Time `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+	LeaseExpiresAt time.Time `bson:"leaseExpiresAt" json:"-"` // until when the runner working on the job holds it
+}
@@ -10,10 +10,12 @@ import (
 
 // Event represents an event in the database
 type Event struct {
-	ID           primitive.ObjectID   `bson:"_id,omitempty" mongoPreventOverride:"true"`
-	OrganizerIDs []primitive.ObjectID `bson:"organizerIDs" json:"organizerIDs"`
-	CreatedByID  primitive.ObjectID   `bson:"createdByID" json:"createdByID"`
-	Metadata     EventMetadata        `bson:"metadata" json:"metadata"`
+	ID               primitive.ObjectID   `bson:"_id,omitempty" mongoPreventOverride:"true"`
+	OrganizerIDs     []primitive.ObjectID `bson:"organizerIDs" json:"organizerIDs"`
+	OrganizerRoles   map[string]EventRole `bson:"organizerRoles,omitempty" json:"organizerRoles,omitempty"` // keyed by organizer ID, see OrganizerRole
+	CreatedByID      primitive.ObjectID   `bson:"createdByID" json:"createdByID"`
+	Metadata         EventMetadata        `bson:"metadata" json:"metadata"`
+	RequireTwoFactor bool                 `bson:"requireTwoFactor,omitempty" json:"requireTwoFactor,omitempty"` // organizers have to sign in with a second factor
 }
 
 // EventMetadata represents the user defined metadata for an event
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventRole is what an organizer of an event is allowed to do on it
+type EventRole string
+
+const (
+	EventRoleOwner    EventRole = "owner"
+	EventRoleAdmin    EventRole = "admin"
+	EventRoleReviewer EventRole = "reviewer"
+	EventRoleCheckIn  EventRole = "checkIn"
+	EventRoleViewer   EventRole = "viewer"
+)
+
+// Permission is an action on an event, its forms, responses, pipelines or email templates that roles are granted
+type Permission string
+
+const (
+	PermissionViewEvent          Permission = "event:view" // the event and its forms, pipelines and email templates
+	PermissionEditEvent          Permission = "event:edit"
+	PermissionDeleteEvent        Permission = "event:delete"
+	PermissionManageOrganizers   Permission = "organizers:manage"
+	PermissionManageSecrets      Permission = "secrets:manage"
+	PermissionManageSecurity     Permission = "security:manage" // whether organizers need two-factor authentication
+	PermissionEditForms          Permission = "forms:edit"
+	PermissionViewResponses      Permission = "responses:view"
+	PermissionExportResponses    Permission = "responses:export"
+	PermissionEditResponses      Permission = "responses:edit"    // includes imports and bulk jobs
+	PermissionCheckIn            Permission = "responses:checkIn" // only marks respondents as checked in, without changing their answers
+	PermissionViewReviews        Permission = "reviews:view"
+	PermissionSubmitReviews      Permission = "reviews:submit"
+	PermissionManageReviews      Permission = "reviews:manage" // the rubric and review assignments
+	PermissionViewAdmissions     Permission = "admissions:view"
+	PermissionManageAdmissions   Permission = "admissions:manage"
+	PermissionEditPipelines      Permission = "pipelines:edit"
+	PermissionEditEmailTemplates Permission = "emailTemplates:edit"
+	PermissionViewAuditLog       Permission = "audit:view"
+)
+
+// Permissions are all the permissions, in the order they are listed above
+var Permissions = []Permission{
+	PermissionViewEvent, PermissionEditEvent, PermissionDeleteEvent, PermissionManageOrganizers, PermissionManageSecrets,
+	PermissionManageSecurity, PermissionEditForms, PermissionViewResponses, PermissionExportResponses, PermissionEditResponses,
+	PermissionCheckIn, PermissionViewReviews, PermissionSubmitReviews, PermissionManageReviews, PermissionViewAdmissions,
+	PermissionManageAdmissions, PermissionEditPipelines, PermissionEditEmailTemplates, PermissionViewAuditLog,
+}
+
+// IsValid checks the permission is one of Permissions
+func (p Permission) IsValid() bool {
+	for _, permission := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// rolePermissions lists what each role is allowed, owners are allowed everything
+var rolePermissions = map[EventRole][]Permission{
+	EventRoleAdmin: {
+		PermissionViewEvent, PermissionEditEvent, PermissionManageOrganizers, PermissionEditForms,
+		PermissionViewResponses, PermissionExportResponses, PermissionEditResponses, PermissionCheckIn,
+		PermissionViewReviews, PermissionSubmitReviews, PermissionManageReviews,
+		PermissionViewAdmissions, PermissionManageAdmissions, PermissionEditPipelines, PermissionEditEmailTemplates,
+	},
+	// Reviewers only see the responses assigned to them, without the fields the rubric hides, so reviews stay blind
+	EventRoleReviewer: {PermissionViewEvent, PermissionSubmitReviews},
+	// Check-in staff look respondents up at the door and check them in, they can't change anything else
+	EventRoleCheckIn: {PermissionViewEvent, PermissionViewResponses, PermissionViewAdmissions, PermissionCheckIn},
+	EventRoleViewer: {
+		PermissionViewEvent, PermissionViewResponses, PermissionExportResponses, PermissionViewReviews, PermissionViewAdmissions,
+	},
+}
+
+// EventRoles are the roles in order of decreasing access
+var EventRoles = []EventRole{EventRoleOwner, EventRoleAdmin, EventRoleReviewer, EventRoleCheckIn, EventRoleViewer}
+
+// IsValid checks the role is one of EventRoles
+func (r EventRole) IsValid() bool {
+	for _, role := range EventRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Can checks if the role is granted the permission
+func (r EventRole) Can(permission Permission) bool {
+	if r == EventRoleOwner {
+		return true
+	}
+
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// OrganizerRole returns the role of an organizer of the event, false if the user isn't one.
+// Organizers added before roles existed have none saved, the creator is treated as the owner and everyone else as an admin
+func (e *Event) OrganizerRole(userID primitive.ObjectID) (EventRole, bool) {
+	isOrganizer := false
+	for _, organizerID := range e.OrganizerIDs {
+		if organizerID == userID {
+			isOrganizer = true
+			break
+		}
+	}
+
+	if !isOrganizer {
+		return "", false
+	}
+
+	if role, ok := e.OrganizerRoles[userID.Hex()]; ok {
+		return role, true
+	}
+
+	if userID == e.CreatedByID {
+		return EventRoleOwner, true
+	}
+	return EventRoleAdmin, true
+}
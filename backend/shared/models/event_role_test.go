@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOrganizerRole(t *testing.T) {
+	creator, legacy, reviewer, outsider := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	event := Event{
+		OrganizerIDs:   []primitive.ObjectID{creator, legacy, reviewer},
+		OrganizerRoles: map[string]EventRole{reviewer.Hex(): EventRoleReviewer, outsider.Hex(): EventRoleAdmin},
+		CreatedByID:    creator,
+	}
+
+	role, ok := event.OrganizerRole(creator)
+	assert.True(t, ok)
+	assert.Equal(t, EventRoleOwner, role, "the creator is the owner of events from before roles")
+
+	role, ok = event.OrganizerRole(legacy)
+	assert.True(t, ok)
+	assert.Equal(t, EventRoleAdmin, role, "organizers from before roles are admins")
+
+	role, ok = event.OrganizerRole(reviewer)
+	assert.True(t, ok)
+	assert.Equal(t, EventRoleReviewer, role)
+
+	_, ok = event.OrganizerRole(outsider)
+	assert.False(t, ok, "a role without being an organizer grants nothing")
+}
+
+func TestEventRoleCan(t *testing.T) {
+	assert.True(t, EventRoleOwner.Can(PermissionDeleteEvent))
+	assert.True(t, EventRoleOwner.Can(PermissionManageSecrets))
+	assert.False(t, EventRoleAdmin.Can(PermissionDeleteEvent))
+	assert.False(t, EventRoleAdmin.Can(PermissionManageSecrets))
+	assert.True(t, EventRoleAdmin.Can(PermissionEditResponses))
+	assert.False(t, EventRoleReviewer.Can(PermissionViewResponses), "reviews are blind")
+	assert.True(t, EventRoleReviewer.Can(PermissionSubmitReviews))
+	assert.True(t, EventRoleCheckIn.Can(PermissionViewAdmissions))
+	assert.False(t, EventRoleCheckIn.Can(PermissionExportResponses))
+	assert.True(t, EventRoleCheckIn.Can(PermissionCheckIn))
+	assert.False(t, EventRoleCheckIn.Can(PermissionEditResponses), "check-in staff can't change answers")
+	assert.False(t, EventRoleViewer.Can(PermissionCheckIn))
+	assert.True(t, EventRoleViewer.Can(PermissionExportResponses))
+	assert.False(t, EventRoleViewer.Can(PermissionEditResponses))
+	assert.False(t, EventRole("").Can(PermissionViewEvent))
+
+	assert.True(t, EventRoleCheckIn.IsValid())
+	assert.False(t, EventRole("superuser").IsValid())
+}
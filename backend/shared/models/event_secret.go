@@ -12,6 +12,7 @@ type EventSecrets struct {
 	// Embed each specific secret type
 	// Each secret type should implement the StripableSecret interface
 	// Update the service.go GetEventSecret() method to handle any additional secret types
+	// They are all stored encrypted together, see CreateOrUpdateEventSecrets
 	Email *EmailSecret `bson:"email" json:"email,omitempty"`
 }
 
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FileUpload represents a file uploaded for a "file" form field.
+// Uploads are created before the response is submitted and are attached to it on submission.
+type FileUpload struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty" mongoPreventOverride:"true"`
+	FormID      primitive.ObjectID `bson:"formID" json:"formID" mongoPreventOverride:"true"`
+	FieldKey    string             `bson:"fieldKey" json:"fieldKey"`
+	UserID      primitive.ObjectID `bson:"userID" json:"userID" mongoPreventOverride:"true"`
+	ResponseID  primitive.ObjectID `bson:"responseID,omitempty" json:"responseID,omitempty"` // empty until the response is submitted
+	StorageKey  string             `bson:"storageKey" json:"-"`
+	FileName    string             `bson:"fileName" json:"fileName"`
+	ContentType string             `bson:"contentType" json:"contentType"`
+	Size        int64              `bson:"size" json:"size"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
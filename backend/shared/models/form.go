@@ -18,7 +18,7 @@ type SelectorSource struct {
 	Options     []string           `bson:"options" json:"options"`
 }
 
-// Address represents a physical address
+// Address represents a physical address, it is also the value of an address field
 type Address struct {
 	StreetAddress string `json:"streetAddress,omitempty" bson:"streetAddress"`
 	City          string `json:"city,omitempty" bson:"city"`
@@ -41,6 +41,9 @@ type FieldValidation struct {
 	Max                           int                    `json:"max,omitempty" bson:"max"`
 	DateAndTimestampFromTimeField time.Time              `json:"dateAndTimestampFromTimeField,omitempty" bson:"dateAndTimestampFromTimeField"` // used for min & max age validation around a given timestamp
 	IsEmail                       EmailValidationOptions `json:"isEmail,omitempty" bson:"isEmail"`
+	MaxFileSizeBytes              int64                  `json:"maxFileSizeBytes,omitempty" bson:"maxFileSizeBytes,omitempty"` // file fields, capped by the platform upload limit
+	MaxFiles                      int                    `json:"maxFiles,omitempty" bson:"maxFiles,omitempty"`                 // file fields, defaults to 1
+	AllowedMimeTypes              []string               `json:"allowedMimeTypes,omitempty" bson:"allowedMimeTypes,omitempty"` // file fields, supports wildcards like image/*
 }
 
 // AdditionalOptions for extra field-specific settings
@@ -51,9 +54,58 @@ type AdditionalOptions struct {
 	UseDefaultValuesFrom string `json:"useDefaultValuesFrom,omitempty" bson:"useDefaultValuesFrom"`
 }
 
+// RatingOptions for configuring a rating scale field, the scale defaults to 1-5
+type RatingOptions struct {
+	Min      int    `json:"min,omitempty" bson:"min"`
+	Max      int    `json:"max,omitempty" bson:"max"`
+	MinLabel string `json:"minLabel,omitempty" bson:"minLabel"` // eg: "Strongly disagree"
+	MaxLabel string `json:"maxLabel,omitempty" bson:"maxLabel"` // eg: "Strongly agree"
+}
+
+// MatrixOptions for configuring a matrix field, where each row is answered with one of the columns
+type MatrixOptions struct {
+	Rows           []string `json:"rows,omitempty" bson:"rows"`
+	Columns        []string `json:"columns,omitempty" bson:"columns"`
+	AllowMultiple  bool     `json:"allowMultiple,omitempty" bson:"allowMultiple"`   // answer each row with a list of columns
+	RequireAllRows bool     `json:"requireAllRows,omitempty" bson:"requireAllRows"` // only applies when the field is required
+}
+
+// RankingOptions for configuring a ranking field, the items ranked are the field Options
+type RankingOptions struct {
+	RequireAll bool `json:"requireAll,omitempty" bson:"requireAll"` // every option must be ranked
+	MaxRanked  int  `json:"maxRanked,omitempty" bson:"maxRanked"`   // eg: "pick your top 3"
+}
+
+// AddressOptions for configuring an address field
+type AddressOptions struct {
+	RequiredParts []string `json:"requiredParts,omitempty" bson:"requiredParts"` // json names of the Address parts that must be filled in
+}
+
 // FormFieldType for defining the type of a form field
 type FormFieldType string
 
+const (
+	FormFieldTypeNumber            FormFieldType = "number"
+	FormFieldTypeText              FormFieldType = "text"
+	FormFieldTypeDate              FormFieldType = "date"
+	FormFieldTypeTimestamp         FormFieldType = "timestamp"
+	FormFieldTypeTelephone         FormFieldType = "telephone"
+	FormFieldTypeTextarea          FormFieldType = "textarea"
+	FormFieldTypeSelect            FormFieldType = "select"
+	FormFieldTypeMultiSelect       FormFieldType = "multiselect"
+	FormFieldTypeCustomSelect      FormFieldType = "customselect"
+	FormFieldTypeCustomMultiSelect FormFieldType = "custommultiselect"
+	FormFieldTypeCheckbox          FormFieldType = "checkbox"
+	FormFieldTypeRadio             FormFieldType = "radio"
+	FormFieldTypeAddress           FormFieldType = "address"
+	FormFieldTypeColorPicker       FormFieldType = "colorpicker"
+	FormFieldTypeRichText          FormFieldType = "richtext"
+	FormFieldTypeFile              FormFieldType = "file"
+	FormFieldTypeRating            FormFieldType = "rating"
+	FormFieldTypeMatrix            FormFieldType = "matrix"
+	FormFieldTypeRanking           FormFieldType = "ranking"
+)
+
 // FieldValue represents the value a field can hold
 type FieldValue string // TODO: maybe should union type
 
@@ -71,6 +123,12 @@ type FormField struct {
 	Disabled             bool              `json:"disabled,omitempty" bson:"disabled"`
 	AdditionalOptions    AdditionalOptions `json:"additionalOptions,omitempty" bson:"additionalOptions,omitempty"`
 	IsInternal           bool              `json:"isInternal" bson:"isInternal"`
+
+	// Type specific configuration
+	RatingOptions  RatingOptions  `json:"ratingOptions,omitempty" bson:"ratingOptions,omitempty"`
+	MatrixOptions  MatrixOptions  `json:"matrixOptions,omitempty" bson:"matrixOptions,omitempty"`
+	RankingOptions RankingOptions `json:"rankingOptions,omitempty" bson:"rankingOptions,omitempty"`
+	AddressOptions AddressOptions `json:"addressOptions,omitempty" bson:"addressOptions,omitempty"`
 }
 
 // FormAllowedSubmitter represents a user who is allowed to submit a form with additional options
@@ -79,6 +137,21 @@ type FormAllowedSubmitter struct {
 	ExpiresAt time.Time `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
 }
 
+// FormCapacity limits how many respondents are admitted, the rest are kept on an ordered waitlist.
+// Unlike MaxSubmissions it never refuses a submission, admitted respondents hold a seat until they decline or their RSVP deadline passes
+type FormCapacity struct {
+	Limit           int `json:"limit,omitempty" bson:"limit" validate:"min=0"`                     // number of seats, 0 disables capacity
+	RSVPWindowHours int `json:"rsvpWindowHours,omitempty" bson:"rsvpWindowHours" validate:"min=0"` // how long a seat is held for an unconfirmed respondent, 0 holds it until they respond
+}
+
+// AnonymousSubmissions lets people respond to a form without an account.
+// Anonymous submissions must solve a proof of work challenge and are rate limited per IP
+type AnonymousSubmissions struct {
+	Enabled                  bool   `json:"enabled,omitempty" bson:"enabled"`
+	RequireEmailVerification bool   `json:"requireEmailVerification,omitempty" bson:"requireEmailVerification" validate:"requireExistsIf=true;EmailFieldKey"` // the response only counts once the respondent follows the link emailed to them
+	EmailFieldKey            string `json:"emailFieldKey,omitempty" bson:"emailFieldKey"`                                                                     // the field holding the address the confirmation link is sent to
+}
+
 // FormStructure represents the overall structure of a form
 type FormStructure struct {
 	Attrs                    []FormField            `json:"attrs" bson:"attrs" validate:"dive"`
@@ -96,6 +169,13 @@ type FormStructure struct {
 	SubmissionMessage        string                 `json:"submissionMessage,omitempty" bson:"submissionMessage"`
 	IsRestricted             bool                   `json:"isRestricted,omitempty" bson:"isRestricted"`
 	AllowedSubmitters        []FormAllowedSubmitter `json:"allowedSubmitters,omitempty" bson:"allowedSubmitters" validate:"dive"`
+	Capacity                 FormCapacity           `json:"capacity,omitempty" bson:"capacity"`
+	UniqueFieldKeys          []string               `json:"uniqueFieldKeys,omitempty" bson:"uniqueFieldKeys"`       // fields whose value can only appear in one response, eg: one application per email
+	ResubmitAsEdit           bool                   `json:"resubmitAsEdit,omitempty" bson:"resubmitAsEdit"`         // without multiple submissions, resubmitting edits the user's response instead of being refused
+	AllowResponseEdits       bool                   `json:"allowResponseEdits,omitempty" bson:"allowResponseEdits"` // applicants can edit their own responses
+	EditResponsesUntil       time.Time              `json:"editResponsesUntil,omitempty" bson:"editResponsesUntil"` // applicant edits close at this time, zero keeps them open
+	Anonymous                AnonymousSubmissions   `json:"anonymous,omitempty" bson:"anonymous"`
+	Version                  int                    `json:"version,omitempty" bson:"version" mongoPreventOverride:"true"` // the current FormVersion, only changed when the fields are saved
 
 	LastUpdatedAt time.Time `json:"lastUpdatedAt,omitempty" bson:"lastUpdatedAt"`
 }
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FormVersion is a snapshot of a form's fields, a new version is saved each time the fields change.
+// Responses record the version they were submitted against so their data can be read with the fields it was answered for
+type FormVersion struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	FormID      primitive.ObjectID `bson:"formID" json:"formID"`
+	Version     int                `bson:"version" json:"version"`
+	Attrs       []FormField        `bson:"attrs" json:"attrs"`
+	CreatedByID primitive.ObjectID `bson:"createdByID" json:"createdByID"` // zero for the snapshot of a form saved before versioning
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// FormFieldRename is a field whose question or type changed between versions, the field keeps its key
+type FormFieldRename struct {
+	Key          string        `json:"key"`
+	FromQuestion string        `json:"fromQuestion"`
+	ToQuestion   string        `json:"toQuestion"`
+	FromType     FormFieldType `json:"fromType"`
+	ToType       FormFieldType `json:"toType"`
+}
+
+// FormFieldChanges is how the fields of a form changed from one version to the next
+type FormFieldChanges struct {
+	Added   []FormField       `json:"added"`
+	Removed []FormField       `json:"removed"`
+	Renamed []FormFieldRename `json:"renamed"` // also lists fields whose type changed
+}
+
+// DiffFormFields compares two versions of a form's fields by key, in the order they appear on the form
+func DiffFormFields(previous []FormField, current []FormField) FormFieldChanges {
+	changes := FormFieldChanges{Added: []FormField{}, Removed: []FormField{}, Renamed: []FormFieldRename{}}
+
+	previousByKey := make(map[string]FormField)
+	for _, field := range previous {
+		previousByKey[field.Key] = field
+	}
+
+	currentKeys := make(map[string]bool)
+	for _, field := range current {
+		currentKeys[field.Key] = true
+
+		old, ok := previousByKey[field.Key]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, field)
+		case old.Question != field.Question || old.Type != field.Type:
+			changes.Renamed = append(changes.Renamed, FormFieldRename{
+				Key:          field.Key,
+				FromQuestion: old.Question,
+				ToQuestion:   field.Question,
+				FromType:     old.Type,
+				ToType:       field.Type,
+			})
+		}
+	}
+
+	for _, field := range previous {
+		if !currentKeys[field.Key] {
+			changes.Removed = append(changes.Removed, field)
+		}
+	}
+
+	return changes
+}
+
+// RemovedFormFields returns the fields of earlier versions that the current fields no longer have,
+// each as it was last defined so old responses can still be labelled and typed
+func RemovedFormFields(current []FormField, versions []FormVersion) []FormField {
+	seen := make(map[string]bool)
+	for _, field := range current {
+		seen[field.Key] = true
+	}
+
+	removed := []FormField{}
+	for i := len(versions) - 1; i >= 0; i-- {
+		for _, field := range versions[i].Attrs {
+			if !seen[field.Key] {
+				seen[field.Key] = true
+				removed = append(removed, field)
+			}
+		}
+	}
+
+	return removed
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffFormFields(t *testing.T) {
+	previous := []FormField{
+		{Key: "name", Question: "Name", Type: FormFieldTypeText},
+		{Key: "shirt", Question: "Shirt size", Type: FormFieldTypeSelect},
+		{Key: "age", Question: "Age", Type: FormFieldTypeText},
+	}
+	current := []FormField{
+		{Key: "name", Question: "Full name", Type: FormFieldTypeText},
+		{Key: "age", Question: "Age", Type: FormFieldTypeNumber},
+		{Key: "diet", Question: "Dietary restrictions", Type: FormFieldTypeText},
+	}
+
+	changes := DiffFormFields(previous, current)
+	assert.Equal(t, []FormField{current[2]}, changes.Added)
+	assert.Equal(t, []FormField{previous[1]}, changes.Removed)
+	assert.Equal(t, []FormFieldRename{
+		{Key: "name", FromQuestion: "Name", ToQuestion: "Full name", FromType: FormFieldTypeText, ToType: FormFieldTypeText},
+		{Key: "age", FromQuestion: "Age", ToQuestion: "Age", FromType: FormFieldTypeText, ToType: FormFieldTypeNumber},
+	}, changes.Renamed)
+
+	first := DiffFormFields(nil, previous)
+	assert.Equal(t, previous, first.Added, "the first version adds every field")
+	assert.Empty(t, first.Removed)
+}
+
+func TestRemovedFormFields(t *testing.T) {
+	versions := []FormVersion{
+		{Version: 1, Attrs: []FormField{{Key: "name", Question: "Name"}, {Key: "shirt", Question: "Shirt"}}},
+		{Version: 2, Attrs: []FormField{{Key: "name", Question: "Name"}, {Key: "shirt", Question: "Shirt size"}, {Key: "team", Question: "Team"}}},
+		{Version: 3, Attrs: []FormField{{Key: "name", Question: "Full name"}}},
+	}
+
+	removed := RemovedFormFields([]FormField{{Key: "name", Question: "Full name"}}, versions)
+	assert.Equal(t, []FormField{{Key: "shirt", Question: "Shirt size"}, {Key: "team", Question: "Team"}}, removed, "fields keep their last definition")
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginThrottle counts the failed sign ins of an account or IP, too many lock it out for a while
+type LoginThrottle struct {
+	Key           string     `bson:"_id" json:"key"` // account:<email> or ip:<address>
+	Failures      int        `bson:"failures" json:"failures"`
+	LastFailureAt time.Time  `bson:"lastFailureAt" json:"lastFailureAt"`
+	LockedUntil   *time.Time `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	ExpiresAt     time.Time  `bson:"expiresAt" json:"expiresAt"` // the failures are forgotten by a TTL index once this passes without another
+}
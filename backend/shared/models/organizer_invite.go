@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrganizerInvite is an invitation emailed to someone to become an organizer of an event, they accept it once they have an account
+type OrganizerInvite struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	EventID     primitive.ObjectID `bson:"eventID" json:"eventID"`
+	Email       string             `bson:"email" json:"email"` // lower cased, the account accepting it must have this email
+	Role        EventRole          `bson:"role" json:"role"`
+	InvitedByID primitive.ObjectID `bson:"invitedByID" json:"invitedByID"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	SentAt      time.Time          `bson:"sentAt" json:"sentAt"`
+	ExpiresAt   time.Time          `bson:"expiresAt" json:"expiresAt"` // only the token signed for this expiry is accepted, so resending invalidates older links
+}
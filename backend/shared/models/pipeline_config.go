@@ -24,8 +24,10 @@ type PipelineEvent struct {
 	Name string `bson:"name" json:"name" validate:"required"`
 
 	// Embed each specific event type
-	FormSubmission *FormSubmission `bson:"formSubmission" json:"formSubmission"`
-	FieldChange    *FieldChange    `bson:"fieldChange" json:"fieldChange"`
+	FormSubmission    *FormSubmission    `bson:"formSubmission" json:"formSubmission"`
+	FieldChange       *FieldChange       `bson:"fieldChange" json:"fieldChange"`
+	ReviewCompleted   *ReviewCompleted   `bson:"reviewCompleted" json:"reviewCompleted"`
+	WaitlistPromotion *WaitlistPromotion `bson:"waitlistPromotion" json:"waitlistPromotion"`
 }
 
 // FormSubmission represents a form submission event
@@ -40,6 +42,16 @@ type FieldChange struct {
 	Condition FieldChangeCondition `bson:"condition" json:"condition" validate:"required"`
 }
 
+// ReviewCompleted represents a response receiving all of the reviews its form's rubric asks for
+type ReviewCompleted struct {
+	OnFormID primitive.ObjectID `bson:"onFormID" json:"onFormID" validate:"required"`
+}
+
+// WaitlistPromotion represents a waitlisted respondent being offered a seat on a form with a capacity
+type WaitlistPromotion struct {
+	OnFormID primitive.ObjectID `bson:"onFormID" json:"onFormID" validate:"required"`
+}
+
 // FieldChangeCondition represents the condition for a field change
 type FieldChangeCondition struct {
 	Comparison Comparison `bson:"comparison" json:"comparison" validate:"required,comparison"`
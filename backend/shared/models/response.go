@@ -11,7 +11,75 @@ type FormResponse struct {
 	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty" mongoPreventOverride:"true"`
 	FormID        primitive.ObjectID     `bson:"formID" json:"formID" validate:"required" mongoPreventOverride:"true"`
 	Data          map[string]interface{} `bson:"data" json:"data" validate:"required"`
-	UserID        primitive.ObjectID     `bson:"userID" json:"userID"`
+	UserID        primitive.ObjectID     `bson:"userID" json:"userID"` // zero for anonymous responses
 	CreatedAt     time.Time              `bson:"createdAt" json:"createdAt" validate:"required"`
 	LastUpdatedAt time.Time              `bson:"lastUpdatedAt" json:"lastUpdatedAt"`
+	Admission     *Admission             `bson:"admission,omitempty" json:"admission,omitempty" mongoPreventOverride:"true"` // only set on forms with a capacity
+	FormVersion   int                    `bson:"formVersion,omitempty" json:"formVersion,omitempty"`                         // the FormVersion the data was last answered against, zero when unknown
+	// ReviewCompletedAt is when the response received the reviews its form's rubric requires, set once so ReviewCompleted pipelines only fire once
+	ReviewCompletedAt *time.Time `bson:"reviewCompletedAt,omitempty" json:"reviewCompletedAt,omitempty" mongoPreventOverride:"true"`
+	CheckIn           *CheckIn   `bson:"checkIn,omitempty" json:"checkIn,omitempty" mongoPreventOverride:"true"` // set once the respondent arrived at the event
+}
+
+// CheckIn records when a respondent was checked in at the event and by whom
+type CheckIn struct {
+	At   time.Time          `bson:"at" json:"at"`
+	ByID primitive.ObjectID `bson:"byID" json:"byID"`
+}
+
+// ResponseClaim reserves a unique key on a form for a response, eg: the user who submitted it or the value of a unique field.
+// Claims are backed by a unique index so concurrent submissions can't both take the same key
+type ResponseClaim struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	FormID     primitive.ObjectID `bson:"formID" json:"formID"`
+	Key        string             `bson:"key" json:"key"`
+	ResponseID primitive.ObjectID `bson:"responseID" json:"responseID"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// AdmissionStatus for tracking a respondent's place on a form with a capacity
+type AdmissionStatus string
+
+const (
+	AdmissionStatusOffered    AdmissionStatus = "offered"    // holds a seat, waiting on the respondent to RSVP
+	AdmissionStatusAccepted   AdmissionStatus = "accepted"   // holds a seat
+	AdmissionStatusWaitlisted AdmissionStatus = "waitlisted" // waiting for a seat to free up
+	AdmissionStatusDeclined   AdmissionStatus = "declined"
+	AdmissionStatusExpired    AdmissionStatus = "expired" // did not RSVP before the deadline
+)
+
+// HoldsSeat reports whether a respondent with this status counts against the form capacity
+func (s AdmissionStatus) HoldsSeat() bool {
+	return s == AdmissionStatusOffered || s == AdmissionStatusAccepted
+}
+
+// Admission represents a respondent's place on a form with a capacity
+type Admission struct {
+	Status       AdmissionStatus `bson:"status" json:"status"`
+	WaitlistedAt time.Time       `bson:"waitlistedAt,omitempty" json:"waitlistedAt,omitempty"` // orders the waitlist
+	OfferedAt    time.Time       `bson:"offeredAt,omitempty" json:"offeredAt,omitempty"`
+	RSVPDeadline time.Time       `bson:"rsvpDeadline,omitempty" json:"rsvpDeadline,omitempty"` // zero when the offer does not expire
+	RespondedAt  time.Time       `bson:"respondedAt,omitempty" json:"respondedAt,omitempty"`
+}
+
+// PendingResponse is an anonymous response waiting for its respondent to confirm their email, it only becomes a FormResponse once confirmed
+type PendingResponse struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	FormID    primitive.ObjectID     `bson:"formID" json:"formID"`
+	Data      map[string]interface{} `bson:"data" json:"data"`
+	Email     string                 `bson:"email" json:"email"`
+	TokenHash string                 `bson:"tokenHash" json:"-"` // only the hash of the emailed token is stored
+	CreatedAt time.Time              `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time              `bson:"expiresAt" json:"expiresAt"` // unconfirmed responses are removed by a TTL index once this passes
+}
+
+// FormResponseDraft represents an in-progress response that a user can resume before submitting
+type FormResponseDraft struct {
+	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty" mongoPreventOverride:"true"`
+	FormID        primitive.ObjectID     `bson:"formID" json:"formID" mongoPreventOverride:"true"`
+	UserID        primitive.ObjectID     `bson:"userID" json:"userID" mongoPreventOverride:"true"`
+	Data          map[string]interface{} `bson:"data" json:"data"`
+	CreatedAt     time.Time              `bson:"createdAt" json:"createdAt"`
+	LastUpdatedAt time.Time              `bson:"lastUpdatedAt" json:"lastUpdatedAt"`
+	ExpiresAt     time.Time              `bson:"expiresAt" json:"expiresAt"` // drafts are removed by a TTL index once this passes
 }
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ResponseChangeSource is how a change to a response was made
+type ResponseChangeSource string
+
+const (
+	ResponseChangeOrganizerEdit ResponseChangeSource = "organizerEdit"
+	ResponseChangeApplicantEdit ResponseChangeSource = "applicantEdit"
+	ResponseChangeResubmission  ResponseChangeSource = "resubmission" // on forms that treat resubmitting as an edit
+	ResponseChangeBulkEdit      ResponseChangeSource = "bulkEdit"
+
+	// Deleting a response ends its history with an entry that clears every field, the history itself is kept
+	ResponseChangeOrganizerDeletion ResponseChangeSource = "organizerDeletion"
+	ResponseChangeWithdrawal        ResponseChangeSource = "withdrawal" // the applicant deleted their own response
+	ResponseChangeBulkDeletion      ResponseChangeSource = "bulkDeletion"
+)
+
+// ResponseFieldChange is the value of a field before and after a change, a nil value means the field had no answer
+type ResponseFieldChange struct {
+	Key string      `bson:"key" json:"key"`
+	Old interface{} `bson:"old,omitempty" json:"old"`
+	New interface{} `bson:"new,omitempty" json:"new"`
+}
+
+// ResponseChange is an entry in the append-only history of a response, recording who changed which fields
+type ResponseChange struct {
+	ID         primitive.ObjectID    `bson:"_id,omitempty" json:"id,omitempty"`
+	ResponseID primitive.ObjectID    `bson:"responseID" json:"responseID"`
+	FormID     primitive.ObjectID    `bson:"formID" json:"formID"`
+	ActorID    primitive.ObjectID    `bson:"actorID" json:"actorID"`
+	Source     ResponseChangeSource  `bson:"source" json:"source"`
+	BulkJobID  primitive.ObjectID    `bson:"bulkJobID,omitempty" json:"bulkJobID,omitempty"` // bulk edits and deletions
+	Changes    []ResponseFieldChange `bson:"changes" json:"changes"`
+	CreatedAt  time.Time             `bson:"createdAt" json:"createdAt"`
+}
+
+// DiffResponseData returns the fields whose value differs between the two versions of a response's data, ordered by key
+func DiffResponseData(previous map[string]interface{}, updated map[string]interface{}) []ResponseFieldChange {
+	keys := []string{}
+	for key := range previous {
+		keys = append(keys, key)
+	}
+	for key := range updated {
+		if _, ok := previous[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []ResponseFieldChange{}
+	for _, key := range keys {
+		if !reflect.DeepEqual(previous[key], updated[key]) {
+			changes = append(changes, ResponseFieldChange{Key: key, Old: previous[key], New: updated[key]})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffResponseData(t *testing.T) {
+	previous := map[string]interface{}{
+		"decision": "Accepted",
+		"skills":   []interface{}{"go", "sql"},
+		"notes":    "late",
+		"name":     "Ada",
+	}
+	updated := map[string]interface{}{
+		"decision": "Rejected",
+		"skills":   []interface{}{"go", "sql"},
+		"name":     "Ada",
+		"age":      36.0,
+	}
+
+	assert.Equal(t, []ResponseFieldChange{
+		{Key: "age", Old: nil, New: 36.0},
+		{Key: "decision", Old: "Accepted", New: "Rejected"},
+		{Key: "notes", Old: "late", New: nil},
+	}, DiffResponseData(previous, updated))
+
+	assert.Empty(t, DiffResponseData(previous, previous))
+}
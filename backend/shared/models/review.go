@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReviewCriterion is a single scored criterion of a review rubric
+type ReviewCriterion struct {
+	Key         string  `bson:"key" json:"key" validate:"required,max=50"`
+	Name        string  `bson:"name" json:"name" validate:"required,max=100"`
+	Description string  `bson:"description,omitempty" json:"description,omitempty" validate:"max=1000"`
+	Weight      float64 `bson:"weight" json:"weight" validate:"gt=0"`
+	MinScore    float64 `bson:"minScore" json:"minScore"`
+	MaxScore    float64 `bson:"maxScore" json:"maxScore" validate:"gtfield=MinScore"`
+}
+
+// ReviewRubric defines how the responses to a form are reviewed, there is at most one rubric per form
+type ReviewRubric struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty" mongoPreventOverride:"true"`
+	FormID             primitive.ObjectID `bson:"formID" json:"formID" mongoPreventOverride:"true"`
+	EventID            primitive.ObjectID `bson:"eventID" json:"eventID" mongoPreventOverride:"true"`
+	Criteria           []ReviewCriterion  `bson:"criteria" json:"criteria" validate:"required,min=1,dive"`
+	HiddenFieldKeys    []string           `bson:"hiddenFieldKeys" json:"hiddenFieldKeys"`                               // fields reviewers can't see, for blind review
+	ReviewsPerResponse int                `bson:"reviewsPerResponse" json:"reviewsPerResponse" validate:"gte=0,lte=20"` // reviews needed before a response is complete, defaults to 1
+
+	LastUpdatedAt time.Time `bson:"lastUpdatedAt" json:"lastUpdatedAt"`
+}
+
+// ReviewAssignment is a batch of responses assigned to a reviewer
+type ReviewAssignment struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty" mongoPreventOverride:"true"`
+	FormID      primitive.ObjectID   `bson:"formID" json:"formID" mongoPreventOverride:"true"`
+	ReviewerID  primitive.ObjectID   `bson:"reviewerID" json:"reviewerID"`
+	ResponseIDs []primitive.ObjectID `bson:"responseIDs" json:"responseIDs"`
+	CreatedByID primitive.ObjectID   `bson:"createdByID" json:"createdByID"`
+	CreatedAt   time.Time            `bson:"createdAt" json:"createdAt"`
+}
+
+// Review is a reviewer's scores for a response, kept apart from the response data
+type Review struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty" mongoPreventOverride:"true"`
+	FormID        primitive.ObjectID `bson:"formID" json:"formID" mongoPreventOverride:"true"`
+	ResponseID    primitive.ObjectID `bson:"responseID" json:"responseID" mongoPreventOverride:"true"`
+	ReviewerID    primitive.ObjectID `bson:"reviewerID" json:"reviewerID" mongoPreventOverride:"true"`
+	Scores        map[string]float64 `bson:"scores" json:"scores"` // criterion key -> score
+	Comment       string             `bson:"comment" json:"comment" validate:"max=5000"`
+	WeightedScore float64            `bson:"weightedScore" json:"weightedScore"` // 0-100, calculated from the rubric when the review is saved
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUpdatedAt time.Time          `bson:"lastUpdatedAt" json:"lastUpdatedAt"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a signed in device, the access tokens issued to it are only accepted while it exists so deleting it signs the device out.
+// Its refresh token is replaced every time it is used
+type Session struct {
+	ID                       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID                   primitive.ObjectID `bson:"userID" json:"userID"`
+	RefreshTokenHash         string             `bson:"refreshTokenHash" json:"-"`         // only the hash of the refresh token is stored
+	PreviousRefreshTokenHash string             `bson:"previousRefreshTokenHash" json:"-"` // seeing this again means the refresh token was stolen
+	UserAgent                string             `bson:"userAgent" json:"userAgent"`
+	IP                       string             `bson:"ip" json:"ip"`
+	CreatedAt                time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUsedAt               time.Time          `bson:"lastUsedAt" json:"lastUsedAt"`
+	ExpiresAt                time.Time          `bson:"expiresAt" json:"expiresAt"`                 // removed by a TTL index once this passes, refreshing pushes it back
+	TwoFactorVerified        bool               `bson:"twoFactorVerified" json:"twoFactorVerified"` // the user entered a second factor to sign in, events can require it
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"shared/secrets"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,5 +15,37 @@ type User struct {
 	LastName              string             `bson:"lastName" json:"lastName"`
 	Email                 string             `bson:"email" json:"email"`
 	Birthday              time.Time          `bson:"birthday" json:"birthday"`
-	PasswordHash          string             `bson:"passwordHash" json:"-"` // Don't return the password hash
+	PasswordHash          string             `bson:"passwordHash" json:"-"`                                      // Don't return the password hash
+	EmailVerifiedAt       *time.Time         `bson:"emailVerifiedAt,omitempty" json:"emailVerifiedAt,omitempty"` // nil until the user follows the link emailed to them
+	Identities            []ExternalIdentity `bson:"identities,omitempty" json:"identities,omitempty"`           // the SSO accounts the user signs in with
+	TwoFactor             *TwoFactor         `bson:"twoFactor,omitempty" json:"-"`                               // nil unless the user set up a second factor
+}
+
+// HasTwoFactor checks the user has to enter a code from their authenticator app when they sign in
+func (u *User) HasTwoFactor() bool {
+	return u.TwoFactor != nil && u.TwoFactor.EnabledAt != nil
+}
+
+// ExternalIdentity is the user's account at an SSO provider
+type ExternalIdentity struct {
+	Provider string `bson:"provider" json:"provider"` // ID of the provider in the API config
+	Subject  string `bson:"subject" json:"subject"`   // the provider's ID for the user
+}
+
+// TwoFactor is the user's TOTP authenticator and the recovery codes to sign in without it
+type TwoFactor struct {
+	SealedSecret       *secrets.Envelope `bson:"sealedSecret,omitempty"` // the base32 secret shared with the authenticator app, only opened to verify a code
+	Secret             string            `bson:"secret,omitempty"`       // the plaintext secret of authenticators set up before it was encrypted
+	EnabledAt          *time.Time        `bson:"enabledAt,omitempty"`    // nil while it is set up, until the user enters a code from it
+	LastUsedStep       int64             `bson:"lastUsedStep"`           // the time step of the last code used, so a code can't be used twice
+	RecoveryCodeHashes []string          `bson:"recoveryCodeHashes"`     // each recovery code works once
+}
+
+// PasswordReset is a forgotten password link emailed to a user, each one can only be used once
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userID" json:"userID"`
+	TokenHash string             `bson:"tokenHash" json:"-"` // only the hash of the emailed token is stored
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"` // removed by a TTL index once this passes
 }
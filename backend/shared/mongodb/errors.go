@@ -1,6 +1,9 @@
 package mongodb
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrUserAlreadyExists is returned when the user already exists in the database
@@ -14,4 +17,28 @@ var (
 
 	// ErrUserNotAuthorized is returned when the user does not have admin permission to modify the document
 	ErrUserNotAuthorized = errors.New("user is not authorized to modify the document")
+
+	// ErrAdmissionStatusChanged is returned when an admission is no longer in a status that allows the change
+	ErrAdmissionStatusChanged = errors.New("admission status has changed")
+
+	// ErrMaxSubmissionsReached is returned when a form already has its maximum number of responses
+	ErrMaxSubmissionsReached = errors.New("form has reached its maximum number of submissions")
+
+	// ErrInvalidResponseQuery is returned when a response query uses a field path or value that isn't allowed
+	ErrInvalidResponseQuery = errors.New("invalid response query")
+
+	// ErrChallengeAlreadyUsed is returned when a proof of work challenge has already been spent on a submission
+	ErrChallengeAlreadyUsed = errors.New("proof of work challenge has already been used")
+
+	// ErrRefreshTokenReused is returned when a refresh token that was already exchanged is used again, its session is signed out
+	ErrRefreshTokenReused = errors.New("refresh token has already been used")
 )
+
+// ClaimTakenError is returned when another response on the form already holds a claim
+type ClaimTakenError struct {
+	Claim string
+}
+
+func (e *ClaimTakenError) Error() string {
+	return fmt.Sprintf("response claim %q is already taken", e.Claim)
+}
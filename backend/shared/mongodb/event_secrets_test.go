@@ -0,0 +1,56 @@
+package mongodb
+
+import (
+	"context"
+	"shared/models"
+	"shared/secrets"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEventSecretsAreStoredEncrypted(t *testing.T) {
+	ctx := context.Background()
+	keys, err := secrets.NewKeyring("test", "a", map[string][]byte{"a": make([]byte, 32)})
+	require.NoError(t, err)
+	s := &Service{Keys: keys}
+
+	data := models.EventSecrets{
+		EventID: primitive.NewObjectID(),
+		Email:   &models.EmailSecret{SMTPServer: "smtp.example.com", Port: 587, Username: "events", Password: "hunter2"},
+	}
+
+	stored, err := s.sealEventSecrets(ctx, data)
+	require.NoError(t, err)
+	raw, err := bson.Marshal(stored)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "hunter2")
+	assert.NotContains(t, string(raw), "smtp.example.com")
+
+	opened, err := s.openEventSecrets(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, data, *opened)
+
+	// Sealed secrets can't be moved to another event
+	stored.EventID = primitive.NewObjectID()
+	raw, err = bson.Marshal(stored)
+	require.NoError(t, err)
+	_, err = s.openEventSecrets(ctx, raw)
+	assert.Error(t, err)
+
+	_, err = (&Service{}).sealEventSecrets(ctx, data)
+	assert.ErrorIs(t, err, secrets.ErrNoMasterKey)
+}
+
+func TestLegacyEventSecretsAreStillRead(t *testing.T) {
+	data := models.EventSecrets{EventID: primitive.NewObjectID(), Email: &models.EmailSecret{Password: "hunter2"}}
+	raw, err := bson.Marshal(data)
+	require.NoError(t, err)
+
+	opened, err := (&Service{}).openEventSecrets(context.Background(), raw)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", opened.Email.Password)
+}
@@ -0,0 +1,153 @@
+package mongodb
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+ResponseQuery is a search over the responses of a form, built by the API from the organizer's query.
+
+Fields are named by their document path, data.<fieldKey> for form fields, or createdAt and lastUpdatedAt.
+Paths and values are checked before they are put in the filter, so a query can never smuggle in mongo operators.
+Full text search relies on the responses text index, see CreateIndexes.
+*/
+
+// ResponseFilterOperator compares a response field with a value
+type ResponseFilterOperator string
+
+const (
+	ResponseFilterEquals             ResponseFilterOperator = "="
+	ResponseFilterNotEquals          ResponseFilterOperator = "!="
+	ResponseFilterGreaterThan        ResponseFilterOperator = ">"
+	ResponseFilterGreaterThanOrEqual ResponseFilterOperator = ">="
+	ResponseFilterLessThan           ResponseFilterOperator = "<"
+	ResponseFilterLessThanOrEqual    ResponseFilterOperator = "<="
+	ResponseFilterContains           ResponseFilterOperator = "~" // case insensitive substring match on text
+)
+
+var responseFilterOperators = map[ResponseFilterOperator]string{
+	ResponseFilterEquals:             "$eq",
+	ResponseFilterNotEquals:          "$ne",
+	ResponseFilterGreaterThan:        "$gt",
+	ResponseFilterGreaterThanOrEqual: "$gte",
+	ResponseFilterLessThan:           "$lt",
+	ResponseFilterLessThanOrEqual:    "$lte",
+}
+
+// ResponseFilter is a single condition of a ResponseQuery, all of the query's filters must match
+type ResponseFilter struct {
+	Path     string
+	Operator ResponseFilterOperator
+	Value    interface{} // a string, number, bool or time
+}
+
+// ResponseSort orders the results of a ResponseQuery
+type ResponseSort struct {
+	Path       string
+	Descending bool
+}
+
+// ResponseQuery searches, filters and sorts the responses of a form
+type ResponseQuery struct {
+	Search  string // full text search over the response data
+	Filters []ResponseFilter
+	Sort    []ResponseSort // newest first when empty
+	Skip    int64
+	Limit   int64 // no limit when 0
+}
+
+// ResponseDataPath returns the document path of a form field's value
+func ResponseDataPath(fieldKey string) string {
+	return "data." + fieldKey
+}
+
+// isResponseQueryPath reports whether queries can use the path, only plain field keys are allowed under data
+func isResponseQueryPath(path string) bool {
+	if path == "createdAt" || path == "lastUpdatedAt" {
+		return true
+	}
+
+	key, ok := strings.CutPrefix(path, "data.")
+	return ok && key != "" && !strings.ContainsAny(key, "$.\x00")
+}
+
+func isResponseQueryValue(value interface{}) bool {
+	switch value.(type) {
+	case string, bool, int, int32, int64, float64, time.Time:
+		return true
+	}
+	return false
+}
+
+// filter translates the query into the filter for the form's responses
+func (q ResponseQuery) filter(formID primitive.ObjectID) (bson.M, error) {
+	filter := bson.M{"formID": formID}
+
+	conditions := bson.A{}
+	for _, f := range q.Filters {
+		if !isResponseQueryPath(f.Path) || !isResponseQueryValue(f.Value) {
+			return nil, ErrInvalidResponseQuery
+		}
+
+		if f.Operator == ResponseFilterContains {
+			text, ok := f.Value.(string)
+			if !ok {
+				return nil, ErrInvalidResponseQuery
+			}
+			conditions = append(conditions, bson.M{f.Path: primitive.Regex{Pattern: regexp.QuoteMeta(text), Options: "i"}})
+			continue
+		}
+
+		operator, ok := responseFilterOperators[f.Operator]
+		if !ok {
+			return nil, ErrInvalidResponseQuery
+		}
+		conditions = append(conditions, bson.M{f.Path: bson.M{operator: f.Value}})
+	}
+
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+
+	if search := strings.TrimSpace(q.Search); search != "" {
+		filter["$text"] = bson.M{"$search": search}
+	}
+
+	return filter, nil
+}
+
+// findOptions translates the query's sort and page into find options
+func (q ResponseQuery) findOptions() (*options.FindOptions, error) {
+	sort := bson.D{}
+	for _, s := range q.Sort {
+		if !isResponseQueryPath(s.Path) {
+			return nil, ErrInvalidResponseQuery
+		}
+
+		direction := 1
+		if s.Descending {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: s.Path, Value: direction})
+	}
+
+	if len(sort) == 0 {
+		sort = append(sort, bson.E{Key: "createdAt", Value: -1})
+	}
+
+	// Ties are broken by ID so pages don't overlap
+	sort = append(sort, bson.E{Key: "_id", Value: 1})
+
+	opts := options.Find().SetSort(sort).SetSkip(q.Skip)
+	if q.Limit > 0 {
+		opts.SetLimit(q.Limit)
+	}
+
+	return opts, nil
+}
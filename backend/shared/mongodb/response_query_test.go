@@ -0,0 +1,77 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResponseQueryFilter(t *testing.T) {
+	formID := primitive.NewObjectID()
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query := ResponseQuery{
+		Search: " stanford ",
+		Filters: []ResponseFilter{
+			{Path: ResponseDataPath("decision"), Operator: ResponseFilterEquals, Value: "Pending"},
+			{Path: ResponseDataPath("age"), Operator: ResponseFilterGreaterThanOrEqual, Value: 18.0},
+			{Path: ResponseDataPath("school"), Operator: ResponseFilterContains, Value: "a.b"},
+			{Path: "createdAt", Operator: ResponseFilterLessThan, Value: since},
+		},
+	}
+
+	filter, err := query.filter(formID)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{
+		"formID": formID,
+		"$and": bson.A{
+			bson.M{"data.decision": bson.M{"$eq": "Pending"}},
+			bson.M{"data.age": bson.M{"$gte": 18.0}},
+			bson.M{"data.school": primitive.Regex{Pattern: `a\.b`, Options: "i"}},
+			bson.M{"createdAt": bson.M{"$lt": since}},
+		},
+		"$text": bson.M{"$search": "stanford"},
+	}, filter)
+
+	empty, err := ResponseQuery{}.filter(formID)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"formID": formID}, empty)
+}
+
+func TestResponseQueryRejectsUnsafeInput(t *testing.T) {
+	formID := primitive.NewObjectID()
+
+	unsafe := []ResponseFilter{
+		{Path: "userID", Operator: ResponseFilterEquals, Value: "x"},
+		{Path: "data.$where", Operator: ResponseFilterEquals, Value: "x"},
+		{Path: "data.a.b", Operator: ResponseFilterEquals, Value: "x"},
+		{Path: "data.", Operator: ResponseFilterEquals, Value: "x"},
+		{Path: "data.a", Operator: ResponseFilterEquals, Value: bson.M{"$ne": nil}},
+		{Path: "data.a", Operator: ResponseFilterContains, Value: 1.0},
+		{Path: "data.a", Operator: "$regex", Value: "x"},
+	}
+
+	for _, f := range unsafe {
+		_, err := ResponseQuery{Filters: []ResponseFilter{f}}.filter(formID)
+		assert.ErrorIs(t, err, ErrInvalidResponseQuery, "%+v", f)
+	}
+
+	_, err := ResponseQuery{Sort: []ResponseSort{{Path: "$natural"}}}.findOptions()
+	assert.ErrorIs(t, err, ErrInvalidResponseQuery)
+}
+
+func TestResponseQueryFindOptions(t *testing.T) {
+	opts, err := ResponseQuery{Skip: 20, Limit: 10}.findOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: 1}}, opts.Sort)
+	assert.Equal(t, int64(20), *opts.Skip)
+	assert.Equal(t, int64(10), *opts.Limit)
+
+	opts, err = ResponseQuery{Sort: []ResponseSort{{Path: "data.score", Descending: true}, {Path: "createdAt"}}}.findOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "data.score", Value: -1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}, opts.Sort)
+	assert.Nil(t, opts.Limit)
+}
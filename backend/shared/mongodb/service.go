@@ -3,10 +3,14 @@ package mongodb
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
 	"reflect"
 	"shared/models"
+	"shared/secrets"
 	"shared/utils"
 	"time"
 
@@ -26,23 +30,59 @@ type MongoService interface {
 	InsertUser(ctx context.Context, user models.User) (*mongo.InsertOneResult, error)
 	GetUserDetails(ctx context.Context, userId primitive.ObjectID) (*models.User, error)
 	DeleteUserByEmail(ctx context.Context, email string) (*mongo.DeleteResult, error)
+	FindUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error)
+	AddUserIdentity(ctx context.Context, userID primitive.ObjectID, identity models.ExternalIdentity) (*mongo.UpdateResult, error)
+	ChangeUserPassword(ctx context.Context, userID primitive.ObjectID, passwordHash string, keepSessionID primitive.ObjectID) error
+	MarkUserEmailVerified(ctx context.Context, userID primitive.ObjectID, email string) (*mongo.UpdateResult, error)
+	CreatePasswordReset(ctx context.Context, reset models.PasswordReset) (*mongo.InsertOneResult, error)
+	ConsumePasswordReset(ctx context.Context, tokenHash string) (*models.PasswordReset, error)
+	DeletePasswordResets(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	CreateSession(ctx context.Context, session models.Session) (*mongo.InsertOneResult, error)
+	GetSession(ctx context.Context, sessionID primitive.ObjectID) (*models.Session, error)
+	RotateSession(ctx context.Context, refreshTokenHash string, newRefreshTokenHash string, expiresAt time.Time) (*models.Session, error)
+	DeleteSession(ctx context.Context, userID primitive.ObjectID, sessionID primitive.ObjectID) (*mongo.DeleteResult, error)
+	DeleteUserSessions(ctx context.Context, userID primitive.ObjectID, keepSessionID primitive.ObjectID) (*mongo.DeleteResult, error)
+	SetUpTwoFactor(ctx context.Context, userID primitive.ObjectID, secret string) (*mongo.UpdateResult, error)
+	OpenTwoFactorSecret(ctx context.Context, user *models.User) (string, error)
+	EnableTwoFactor(ctx context.Context, userID primitive.ObjectID, step int64, recoveryCodeHashes []string, sessionID primitive.ObjectID) (bool, error)
+	UseTwoFactorCode(ctx context.Context, userID primitive.ObjectID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, recoveryCodeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID primitive.ObjectID, recoveryCodeHashes []string) (*mongo.UpdateResult, error)
+	DisableTwoFactor(ctx context.Context, userID primitive.ObjectID) error
+	CreateAPIToken(ctx context.Context, token models.APIToken) (*mongo.InsertOneResult, error)
+	ListAPITokens(ctx context.Context, userID primitive.ObjectID) ([]models.APIToken, error)
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	TouchAPIToken(ctx context.Context, tokenID primitive.ObjectID) error
+	DeleteAPIToken(ctx context.Context, userID primitive.ObjectID, tokenID primitive.ObjectID) (*mongo.DeleteResult, error)
 	UpdateUserDetails(ctx context.Context, userId primitive.ObjectID, updatedUserDetails models.User) error
 	UpdateUser(ctx context.Context, userId primitive.ObjectID, user models.User) (*mongo.UpdateResult, error)
 	CreateEvent(ctx context.Context, event models.Event) (*mongo.InsertOneResult, error)
 	DeleteEvent(ctx *gin.Context, eventID primitive.ObjectID) (*mongo.DeleteResult, error)
 	GetEvent(ctx *gin.Context, eventID primitive.ObjectID) (*models.Event, error)
+	FindEvent(ctx context.Context, eventID primitive.ObjectID) (*models.Event, error)
 	UpdateEventMetadata(ctx *gin.Context, eventID primitive.ObjectID, metadata models.EventMetadata) (*mongo.UpdateResult, error)
 	ListEventsMetadata(ctx context.Context, filter bson.M) ([]models.Event, error)
-	AddOrganizerToEvent(ctx context.Context, eventID primitive.ObjectID, organizerID primitive.ObjectID) (*mongo.UpdateResult, error)
+	AddOrganizerToEvent(ctx context.Context, eventID primitive.ObjectID, organizerID primitive.ObjectID, role models.EventRole) (*mongo.UpdateResult, error)
+	SetOrganizerRole(ctx context.Context, eventID primitive.ObjectID, organizerID primitive.ObjectID, role models.EventRole) (*mongo.UpdateResult, error)
 	RemoveOrganizerFromEvent(ctx context.Context, eventID primitive.ObjectID, organizerID primitive.ObjectID) (*mongo.UpdateResult, error)
+	SetEventRequireTwoFactor(ctx context.Context, eventID primitive.ObjectID, required bool) (*mongo.UpdateResult, error)
+	SaveOrganizerInvite(ctx context.Context, invite models.OrganizerInvite) (*models.OrganizerInvite, error)
+	GetOrganizerInvite(ctx context.Context, eventID primitive.ObjectID, inviteID primitive.ObjectID) (*models.OrganizerInvite, error)
+	ListOrganizerInvites(ctx context.Context, eventID primitive.ObjectID) ([]models.OrganizerInvite, error)
+	RenewOrganizerInvite(ctx context.Context, eventID primitive.ObjectID, inviteID primitive.ObjectID, expiresAt time.Time) (*models.OrganizerInvite, error)
+	DeleteOrganizerInvite(ctx context.Context, eventID primitive.ObjectID, inviteID primitive.ObjectID) (*mongo.DeleteResult, error)
+	AcceptOrganizerInvite(ctx context.Context, inviteID primitive.ObjectID, expiresAt time.Time, userID primitive.ObjectID) (*models.OrganizerInvite, error)
 	CreateSource(ctx context.Context, source models.SelectorSource) (*mongo.InsertOneResult, error)
 	UpdateSource(ctx context.Context, source models.SelectorSource, sourceID primitive.ObjectID) (*mongo.UpdateResult, error)
 	GetSourceByName(ctx context.Context, name string) (*models.SelectorSource, error)
 	ListSelectorSources(ctx context.Context) ([]models.SelectorSource, error)
 	GetForm(ctx context.Context, formID primitive.ObjectID, stripSecrets bool) (*models.FormStructure, error)
 	ListForms(ctx context.Context, filter bson.M) ([]models.FormStructure, error)
-	CreateForm(ctx context.Context, form models.FormStructure) (*mongo.InsertOneResult, error)
+	CreateForm(ctx context.Context, form models.FormStructure, createdByID primitive.ObjectID) (*mongo.InsertOneResult, error)
 	UpdateForm(ctx context.Context, form models.FormStructure, formID primitive.ObjectID) (*mongo.UpdateResult, error)
+	UpdateFormWithVersion(ctx context.Context, form models.FormStructure, formID primitive.ObjectID, createdByID primitive.ObjectID) (int, error)
+	ListFormVersions(ctx context.Context, formID primitive.ObjectID) ([]models.FormVersion, error)
+	DeleteFormVersions(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
 	AddAllowedSubmitter(ctx context.Context, formID primitive.ObjectID, submitter models.FormAllowedSubmitter) (*mongo.UpdateResult, error)
 	DeleteForm(ctx context.Context, formID primitive.ObjectID) (*mongo.DeleteResult, error)
 	CreatePipeline(ctx context.Context, pipeline models.PipelineConfiguration) (*mongo.InsertOneResult, error)
@@ -53,7 +93,61 @@ type MongoService interface {
 	ListResponses(ctx context.Context, filter bson.M, options *options.FindOptions) ([]models.FormResponse, error)
 	CreateResponse(ctx context.Context, response models.FormResponse) (*mongo.InsertOneResult, error)
 	UpdateResponse(ctx context.Context, response models.FormResponse, responseID primitive.ObjectID) (*mongo.UpdateResult, error)
-	DeleteResponse(ctx context.Context, responseID primitive.ObjectID) (*mongo.DeleteResult, error)
+	DeleteResponse(ctx context.Context, responseID primitive.ObjectID, change *models.ResponseChange) (*mongo.DeleteResult, error)
+	CountResponses(ctx context.Context, filter bson.M) (int64, error)
+	QueryResponses(ctx context.Context, formID primitive.ObjectID, query ResponseQuery) ([]models.FormResponse, int64, error)
+	StreamResponses(ctx context.Context, formID primitive.ObjectID, query ResponseQuery, fn func(models.FormResponse) error) error
+	ListResponseDataKeys(ctx context.Context, formID primitive.ObjectID) ([]string, error)
+	SubmitResponse(ctx context.Context, response models.FormResponse, submission ResponseSubmission) (*models.FormResponse, error)
+	ImportResponses(ctx context.Context, formID primitive.ObjectID, responses []models.FormResponse, claims [][]string, maxSubmissions int) ([]models.FormResponse, error)
+	UpdateResponseWithClaims(ctx context.Context, response models.FormResponse, responseID primitive.ObjectID, claims []string, change *models.ResponseChange) (*mongo.UpdateResult, error)
+	ListTakenClaims(ctx context.Context, formID primitive.ObjectID, claims []string) ([]string, error)
+	ReplaceFormClaims(ctx context.Context, formID primitive.ObjectID, claimsByResponse map[primitive.ObjectID][]string) error
+	MoveResponse(ctx context.Context, response models.FormResponse, toFormID primitive.ObjectID, claims []string, maxSubmissions int) error
+	DeleteResponseClaims(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	ListResponseHistory(ctx context.Context, formID primitive.ObjectID, responseID primitive.ObjectID) ([]models.ResponseChange, error)
+	DeleteResponseHistory(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	InsertAuditLogEntry(ctx context.Context, entry models.AuditLogEntry) (*mongo.InsertOneResult, error)
+	ListAuditLog(ctx context.Context, filter bson.M, options *options.FindOptions) ([]models.AuditLogEntry, error)
+	GetResponseDraft(ctx context.Context, formID primitive.ObjectID, userID primitive.ObjectID) (*models.FormResponseDraft, error)
+	SaveResponseDraft(ctx context.Context, draft models.FormResponseDraft) (*mongo.UpdateResult, error)
+	DeleteResponseDraft(ctx context.Context, formID primitive.ObjectID, userID primitive.ObjectID) (*mongo.DeleteResult, error)
+	CreatePendingResponse(ctx context.Context, pending models.PendingResponse) (*mongo.InsertOneResult, error)
+	ConsumePendingResponse(ctx context.Context, formID primitive.ObjectID, tokenHash string) (*models.PendingResponse, error)
+	DeletePendingResponses(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	ConsumeProofOfWorkChallenge(ctx context.Context, challenge string, expiresAt time.Time) error
+	IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error)
+	GetLoginThrottles(ctx context.Context, keys []string) ([]models.LoginThrottle, error)
+	RecordLoginFailure(ctx context.Context, key string, expiresAt time.Time) (*models.LoginThrottle, error)
+	LockLogin(ctx context.Context, key string, lockedUntil time.Time, expiresAt time.Time) (*mongo.UpdateResult, error)
+	DeleteLoginThrottles(ctx context.Context, keys []string) (*mongo.DeleteResult, error)
+	CreateBulkJob(ctx context.Context, job models.BulkJob) (*mongo.InsertOneResult, error)
+	GetBulkJob(ctx context.Context, formID primitive.ObjectID, jobID primitive.ObjectID) (*models.BulkJob, error)
+	ListBulkJobs(ctx context.Context, formID primitive.ObjectID, limit int64) ([]models.BulkJob, error)
+	ClaimBulkJob(ctx context.Context, lease time.Duration) (*models.BulkJob, error)
+	RecordBulkJobResults(ctx context.Context, jobID primitive.ObjectID, results map[int]models.BulkJobItem, lease time.Duration) error
+	ClearBulkJobItemTriggers(ctx context.Context, jobID primitive.ObjectID, index int, lease time.Duration) error
+	FinishBulkJob(ctx context.Context, jobID primitive.ObjectID, pipelineError string) error
+	DeleteBulkJobs(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	CreateFileUpload(ctx context.Context, upload models.FileUpload) (*mongo.InsertOneResult, error)
+	ListFileUploads(ctx context.Context, filter bson.M) ([]models.FileUpload, error)
+	AttachFileUploads(ctx context.Context, uploadIDs []primitive.ObjectID, responseID primitive.ObjectID) (*mongo.UpdateResult, error)
+	DeleteFileUploads(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	GetReviewRubric(ctx context.Context, formID primitive.ObjectID) (*models.ReviewRubric, error)
+	SaveReviewRubric(ctx context.Context, rubric models.ReviewRubric) (*mongo.UpdateResult, error)
+	CreateReviewAssignment(ctx context.Context, assignment models.ReviewAssignment) (*mongo.InsertOneResult, error)
+	ListReviewAssignments(ctx context.Context, filter bson.M) ([]models.ReviewAssignment, error)
+	SaveReview(ctx context.Context, review models.Review) (*mongo.UpdateResult, error)
+	ListReviews(ctx context.Context, filter bson.M) ([]models.Review, error)
+	DeleteReviews(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	MarkReviewCompleted(ctx context.Context, formID primitive.ObjectID, responseID primitive.ObjectID) (bool, error)
+	AcceptAdmission(ctx context.Context, responseID primitive.ObjectID) (*mongo.UpdateResult, error)
+	DeclineAdmission(ctx context.Context, responseID primitive.ObjectID, capacity models.FormCapacity) ([]models.FormResponse, error)
+	ExpireAdmissionOffers(ctx context.Context, formID primitive.ObjectID, capacity models.FormCapacity) ([]models.FormResponse, error)
+	SetResponseCheckIn(ctx context.Context, formID primitive.ObjectID, responseID primitive.ObjectID, checkIn *models.CheckIn) (*mongo.UpdateResult, error)
+	ListFormsWithLapsedOffers(ctx context.Context) ([]primitive.ObjectID, error)
+	GetWaitlistPosition(ctx context.Context, response models.FormResponse) (int64, error)
+	DeleteFormCapacity(ctx context.Context, formID primitive.ObjectID) (*mongo.DeleteResult, error)
 	CreatePipelineRun(ctx context.Context, pipelineRun models.PipelineRun) (*mongo.InsertOneResult, error)
 	GetPipelineRun(ctx context.Context, filter bson.M) (*models.PipelineRun, error)
 	UpdatePipelineRun(ctx context.Context, pipelineRun models.PipelineRun, pipelineRunID primitive.ObjectID) (*mongo.UpdateResult, error)
@@ -66,6 +160,11 @@ type MongoService interface {
 	GetEventSecrets(ctx context.Context, filter bson.M, stripSecrets bool) (*models.EventSecrets, error)
 	CreateOrUpdateEventSecrets(ctx context.Context, secret models.EventSecrets) (*mongo.UpdateResult, error)
 	DeleteEventSecrets(ctx context.Context, secretID primitive.ObjectID) (*mongo.DeleteResult, error)
+	RotateEventSecretsKey(ctx context.Context) (int, error)
+	RotateTwoFactorSecretsKey(ctx context.Context) (int, error)
+
+	// Setup
+	CreateIndexes(ctx context.Context) error
 
 	// Billing
 	SeedPlans(ctx context.Context) error
@@ -74,6 +173,7 @@ type MongoService interface {
 	ListSubscriptions(ctx context.Context, filter bson.M) ([]models.Subscription, error)
 	GetSubscription(ctx context.Context, subscriptionID primitive.ObjectID) (*models.Subscription, error)
 	IncrementSubscriptionUtilization(ctx context.Context, subscriptionID primitive.ObjectID, utilizationKey string, limitKey string) (*mongo.UpdateResult, error)
+	AddSubscriptionUtilization(ctx context.Context, subscriptionID primitive.ObjectID, utilizationKey string, limitKey string, amount int) (*mongo.UpdateResult, error)
 	DecrementSubscriptionEventUtilization(ctx context.Context, subscriptionID primitive.ObjectID, eventID primitive.ObjectID) (*mongo.UpdateResult, error)
 }
 
@@ -81,6 +181,7 @@ type MongoService interface {
 type Service struct {
 	Client   *mongo.Client
 	Database *mongo.Database
+	Keys     secrets.KeyProvider // the master keys event secrets are encrypted with, nil if none are configured
 }
 
 // NewService creates a new Service.
@@ -98,8 +199,20 @@ func NewService() (*Service, func(), error) {
 		}
 	}
 
+	// Without a master key everything but event secrets still works
+	keys, err := secrets.NewKeyProvider()
+	if err == secrets.ErrNoMasterKey {
+		log.Println("[WARNING] SECRETS_MASTER_KEYS is not set. Event secrets can't be stored or read until a master key is configured.")
+	} else if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
 	database := client.Database(MongoDBName)
-	return &Service{Client: client, Database: database}, cleanup, nil
+	service := &Service{Client: client, Database: database, Keys: keys}
+	// Optionally authenticated routes check the session of the caller's token here
+	utils.UseSessionStore(service)
+	return service, cleanup, nil
 }
 
 // FindUserByEmail finds a user by their email.
@@ -139,6 +252,23 @@ func (s *Service) DeleteUserByEmail(ctx context.Context, email string) (*mongo.D
 	return s.Database.Collection("users").DeleteOne(ctx, bson.M{"email": email})
 }
 
+// FindUserByIdentity finds the user who signs in with the account at the SSO provider
+func (s *Service) FindUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error) {
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+
+	var user models.User
+	if err := s.Database.Collection("users").FindOne(ctx, filter).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// AddUserIdentity links an account at an SSO provider to the user so they can sign in with it
+func (s *Service) AddUserIdentity(ctx context.Context, userID primitive.ObjectID, identity models.ExternalIdentity) (*mongo.UpdateResult, error) {
+	update := bson.M{"$addToSet": bson.M{"identities": identity}}
+	return s.Database.Collection("users").UpdateByID(ctx, userID, update)
+}
+
 // Create a new event
 func (s *Service) CreateEvent(ctx context.Context, event models.Event) (*mongo.InsertOneResult, error) {
 	// First make sure the name exists
@@ -162,7 +292,7 @@ func (s *Service) UpdateEventMetadata(ctx *gin.Context, eventID primitive.Object
 		return nil, err
 	}
 
-	if !CanUserModifyEvent(ctx, s, authenticatedUser, event.ID, &event) {
+	if !HasEventPermission(ctx, s, authenticatedUser, event.ID, &event, models.PermissionEditEvent) {
 		return nil, ErrUserNotAuthorized
 	}
 
@@ -211,17 +341,32 @@ func (s *Service) ListEventsMetadata(ctx context.Context, filter bson.M) ([]mode
 	return events, nil
 }
 
-func (s *Service) AddOrganizerToEvent(ctx context.Context, eventID primitive.ObjectID, organizerID primitive.ObjectID) (*mongo.UpdateResult, error) {
+// AddOrganizerToEvent adds an organizer to the event with the role, an existing organizer has their role changed
+func (s *Service) AddOrganizerToEvent(ctx context.Context, eventID primitive.ObjectID, organizerID primitive.ObjectID, role models.EventRole) (*mongo.UpdateResult, error) {
 	update := bson.M{
 		"$addToSet": bson.M{"organizerIDs": organizerID},
+		"$set":      bson.M{"organizerRoles." + organizerID.Hex(): role},
 	}
 
 	return s.Database.Collection("events").UpdateByID(ctx, eventID, update)
 }
 
+func (s *Service) SetEventRequireTwoFactor(ctx context.Context, eventID primitive.ObjectID, required bool) (*mongo.UpdateResult, error) {
+	return s.Database.Collection("events").UpdateByID(ctx, eventID, bson.M{"$set": bson.M{"requireTwoFactor": required}})
+}
+
+// SetOrganizerRole changes the role of an organizer of the event, nothing is matched if the user isn't one
+func (s *Service) SetOrganizerRole(ctx context.Context, eventID primitive.ObjectID, organizerID primitive.ObjectID, role models.EventRole) (*mongo.UpdateResult, error) {
+	filter := bson.M{"_id": eventID, "organizerIDs": organizerID}
+	update := bson.M{"$set": bson.M{"organizerRoles." + organizerID.Hex(): role}}
+
+	return s.Database.Collection("events").UpdateOne(ctx, filter, update)
+}
+
 func (s *Service) RemoveOrganizerFromEvent(ctx context.Context, eventID primitive.ObjectID, organizerID primitive.ObjectID) (*mongo.UpdateResult, error) {
 	update := bson.M{
-		"$pull": bson.M{"organizerIDs": organizerID},
+		"$pull":  bson.M{"organizerIDs": organizerID},
+		"$unset": bson.M{"organizerRoles." + organizerID.Hex(): ""},
 	}
 
 	return s.Database.Collection("events").UpdateByID(ctx, eventID, update)
@@ -241,8 +386,7 @@ func (s *Service) DeleteEvent(ctx *gin.Context, eventID primitive.ObjectID) (*mo
 		return nil, err
 	}
 
-	// Ensure the user is the creator of the event
-	if authenticatedUser.ID != event.CreatedByID {
+	if !HasEventPermission(ctx, s, authenticatedUser, event.ID, &event, models.PermissionDeleteEvent) {
 		return nil, ErrUserNotAuthorized
 	}
 
@@ -267,7 +411,7 @@ func (s *Service) GetEvent(ctx *gin.Context, eventID primitive.ObjectID) (*model
 	}
 
 	// If the user is not an organizer then return the metadata
-	if !isAuthenticated || !CanUserModifyEvent(ctx, s, authenticatedUser, event.ID, &event) {
+	if !isAuthenticated || !HasEventPermission(ctx, s, authenticatedUser, event.ID, &event, models.PermissionViewEvent) {
 		return &models.Event{
 			ID:       event.ID,
 			Metadata: event.Metadata,
@@ -277,6 +421,17 @@ func (s *Service) GetEvent(ctx *gin.Context, eventID primitive.ObjectID) (*model
 	return &event, nil
 }
 
+// FindEvent retrieves the full event by its ID without checking who is asking, for work the server does on the event's behalf
+func (s *Service) FindEvent(ctx context.Context, eventID primitive.ObjectID) (*models.Event, error) {
+	var event models.Event
+	err := s.Database.Collection("events").FindOne(ctx, bson.M{"_id": eventID}).Decode(&event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
 // GetEventForms returns a list of all of the events forms
 func (s *Service) ListForms(ctx context.Context, filter bson.M) ([]models.FormStructure, error) {
 	var forms []models.FormStructure
@@ -407,13 +562,32 @@ func (s *Service) GetForm(ctx context.Context, formID primitive.ObjectID, stripS
 	return &form, nil
 }
 
-// CreateForm creates a new form
-func (s *Service) CreateForm(ctx context.Context, form models.FormStructure) (*mongo.InsertOneResult, error) {
+// CreateForm creates a form along with the first version of its fields
+func (s *Service) CreateForm(ctx context.Context, form models.FormStructure, createdByID primitive.ObjectID) (*mongo.InsertOneResult, error) {
 	form.CreatedAt = time.Now()
 	form.LastUpdatedAt = time.Now()
 	form.IsDeleted = false
 	form.Status = "draft"
-	return s.Database.Collection("forms").InsertOne(ctx, form)
+	form.Version = 1
+
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		inserted, err := s.Database.Collection("forms").InsertOne(sessCtx, form)
+		if err != nil {
+			return nil, err
+		}
+
+		version := models.FormVersion{FormID: inserted.InsertedID.(primitive.ObjectID), Version: 1, Attrs: form.Attrs, CreatedByID: createdByID, CreatedAt: form.CreatedAt}
+		if _, err := s.Database.Collection(FORM_VERSION_COLLECTION).InsertOne(sessCtx, version); err != nil {
+			return nil, err
+		}
+
+		return inserted, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*mongo.InsertOneResult), nil
 }
 
 // UpdateForm updates a form by its ID
@@ -429,6 +603,68 @@ func (s *Service) UpdateForm(ctx context.Context, form models.FormStructure, for
 	return s.Database.Collection("forms").UpdateOne(ctx, filter, update)
 }
 
+const (
+	FORM_VERSION_COLLECTION = "form_versions"
+)
+
+// UpdateFormWithVersion updates a form whose fields changed and saves them as its next version in a transaction, it returns the new version.
+// Forms saved before versioning first get a version holding their fields as they were
+func (s *Service) UpdateFormWithVersion(ctx context.Context, form models.FormStructure, formID primitive.ObjectID, createdByID primitive.ObjectID) (int, error) {
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		var current models.FormStructure
+		if err := s.Database.Collection("forms").FindOne(sessCtx, bson.M{"_id": formID}).Decode(&current); err != nil {
+			return nil, err
+		}
+
+		versions := []interface{}{}
+		next := current.Version + 1
+		if current.Version == 0 {
+			versions = append(versions, models.FormVersion{FormID: formID, Version: 1, Attrs: current.Attrs, CreatedAt: current.LastUpdatedAt})
+			next = 2
+		}
+		versions = append(versions, models.FormVersion{FormID: formID, Version: next, Attrs: form.Attrs, CreatedByID: createdByID, CreatedAt: time.Now()})
+
+		if _, err := s.Database.Collection(FORM_VERSION_COLLECTION).InsertMany(sessCtx, versions); err != nil {
+			return nil, err
+		}
+
+		if _, err := s.UpdateForm(sessCtx, form, formID); err != nil {
+			return nil, err
+		}
+
+		if _, err := s.Database.Collection("forms").UpdateOne(sessCtx, bson.M{"_id": formID}, bson.M{"$set": bson.M{"version": next}}); err != nil {
+			return nil, err
+		}
+
+		return next, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.(int), nil
+}
+
+// ListFormVersions lists every version of a form's fields, oldest first
+func (s *Service) ListFormVersions(ctx context.Context, formID primitive.ObjectID) ([]models.FormVersion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	cursor, err := s.Database.Collection(FORM_VERSION_COLLECTION).Find(ctx, bson.M{"formID": formID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []models.FormVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (s *Service) DeleteFormVersions(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(FORM_VERSION_COLLECTION).DeleteMany(ctx, filter)
+}
+
 // AddAllowedSubmitter adds a new allowed submitter to a form
 func (s *Service) AddAllowedSubmitter(ctx context.Context, formID primitive.ObjectID, submitter models.FormAllowedSubmitter) (*mongo.UpdateResult, error) {
 	// Prepare the update
@@ -583,231 +819,2184 @@ func (s *Service) UpdateResponse(ctx context.Context, response models.FormRespon
 	return s.Database.Collection("responses").UpdateOne(ctx, filter, update)
 }
 
-// DeleteResponse
-func (s *Service) DeleteResponse(ctx context.Context, responseID primitive.ObjectID) (*mongo.DeleteResult, error) {
-	filter := bson.M{"_id": responseID}
-	return s.Database.Collection("responses").DeleteOne(ctx, filter)
-}
-
-func (s *Service) CreatePipelineRun(ctx context.Context, pipelineRun models.PipelineRun) (*mongo.InsertOneResult, error) {
-	return s.Database.Collection("pipeline_runs").InsertOne(ctx, pipelineRun)
-}
+// DeleteResponse deletes a response, the change records the deletion in the response's history which is kept
+func (s *Service) DeleteResponse(ctx context.Context, responseID primitive.ObjectID, change *models.ResponseChange) (*mongo.DeleteResult, error) {
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := s.Database.Collection("responses").DeleteOne(sessCtx, bson.M{"_id": responseID})
+		if err != nil {
+			return nil, err
+		}
 
-func (s *Service) GetPipelineRun(ctx context.Context, filter bson.M) (*models.PipelineRun, error) {
-	var pipelineRun models.PipelineRun
-	err := s.Database.Collection("pipeline_runs").FindOne(ctx, filter).Decode(&pipelineRun)
-	if err != nil {
-		return nil, err
-	}
-	return &pipelineRun, nil
-}
+		// Only the request that deleted the response records it
+		if change != nil && result.DeletedCount > 0 {
+			change.ResponseID = responseID
+			change.CreatedAt = time.Now()
+			if _, err := s.Database.Collection(RESPONSE_HISTORY_COLLECTION).InsertOne(sessCtx, change); err != nil {
+				return nil, err
+			}
+		}
 
-func (s *Service) UpdatePipelineRun(ctx context.Context, pipelineRun models.PipelineRun, pipelineRunID primitive.ObjectID) (*mongo.UpdateResult, error) {
-	u, err := utils.StructToBsonM(pipelineRun)
+		return result, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	cleanUpdatePayload := RemoveNonOverridableFields(u, pipelineRun)
-
-	update := bson.M{"$set": cleanUpdatePayload}
-	filter := bson.M{"_id": pipelineRunID}
-	return s.Database.Collection("pipeline_runs").UpdateOne(ctx, filter, update)
+	return result.(*mongo.DeleteResult), nil
 }
 
-func (s *Service) ListPipelineRuns(ctx context.Context, filter bson.M, options *options.FindOptions) ([]models.PipelineRun, error) {
-	var pipelineRuns []models.PipelineRun
+// CountResponses counts the responses matching a filter
+func (s *Service) CountResponses(ctx context.Context, filter bson.M) (int64, error) {
+	return s.Database.Collection("responses").CountDocuments(ctx, filter)
+}
 
-	cursor, err := s.Database.Collection("pipeline_runs").Find(ctx, filter, options)
+// QueryResponses lists the page of a form's responses matching the query, along with how many match in total
+func (s *Service) QueryResponses(ctx context.Context, formID primitive.ObjectID, query ResponseQuery) ([]models.FormResponse, int64, error) {
+	filter, err := query.filter(formID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var pipelineRun models.PipelineRun
-		if err := cursor.Decode(&pipelineRun); err != nil {
-			return nil, err
-		}
-
-		pipelineRuns = append(pipelineRuns, pipelineRun)
+	opts, err := query.findOptions()
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if err := cursor.Err(); err != nil {
-		return nil, err
+	responses, err := s.ListResponses(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// If pipelineRuns is null then return an empty slice instead
-	if pipelineRuns == nil {
-		return []models.PipelineRun{}, nil
+	total, err := s.CountResponses(ctx, filter)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return pipelineRuns, nil
+	return responses, total, nil
 }
 
-// ListEmailTemplates retrieves email templates based on a filter
-func (s *Service) ListEmailTemplates(ctx context.Context, filter bson.M) ([]models.EmailTemplate, error) {
-	var emailTemplates []models.EmailTemplate
+// StreamResponses calls fn with each of the form's responses matching the query in turn, reading them from a cursor
+// so large forms are never held in memory at once. It stops at the first error fn returns
+func (s *Service) StreamResponses(ctx context.Context, formID primitive.ObjectID, query ResponseQuery, fn func(models.FormResponse) error) error {
+	filter, err := query.filter(formID)
+	if err != nil {
+		return err
+	}
 
-	cursor, err := s.Database.Collection("email_templates").Find(ctx, filter)
+	opts, err := query.findOptions()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	cursor, err := s.Database.Collection("responses").Find(ctx, filter, opts.SetBatchSize(500))
+	if err != nil {
+		return err
 	}
 	defer cursor.Close(ctx)
 
 	for cursor.Next(ctx) {
-		var emailTemplate models.EmailTemplate
-		if err := cursor.Decode(&emailTemplate); err != nil {
-			return nil, err
+		var response models.FormResponse
+		if err := cursor.Decode(&response); err != nil {
+			return err
 		}
 
-		emailTemplates = append(emailTemplates, emailTemplate)
+		if err := fn(response); err != nil {
+			return err
+		}
 	}
 
-	if err := cursor.Err(); err != nil {
+	return cursor.Err()
+}
+
+// ListResponseDataKeys returns the keys used in the data of a form's responses, including those of deleted fields
+func (s *Service) ListResponseDataKeys(ctx context.Context, formID primitive.ObjectID) ([]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"formID": formID}}},
+		{{Key: "$project", Value: bson.M{"fields": bson.M{"$objectToArray": "$data"}}}},
+		{{Key: "$unwind", Value: "$fields"}},
+		{{Key: "$group", Value: bson.M{"_id": "$fields.k"}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := s.Database.Collection("responses").Aggregate(ctx, pipeline)
+	if err != nil {
 		return nil, err
 	}
 
-	// If emailTemplates is null then return an empty slice instead
-	if emailTemplates == nil {
-		return []models.EmailTemplate{}, nil
+	var results []struct {
+		Key string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
 	}
 
-	return emailTemplates, nil
-}
+	keys := make([]string, 0, len(results))
+	for _, result := range results {
+		keys = append(keys, result.Key)
+	}
 
-// CreateEmailTemplate creates a new email template
-func (s *Service) CreateEmailTemplate(ctx context.Context, emailTemplate models.EmailTemplate) (*mongo.InsertOneResult, error) {
-	return s.Database.Collection("email_templates").InsertOne(ctx, emailTemplate)
+	return keys, nil
 }
 
-// UpdateEmailTemplate updates an email template by its ID
-func (s *Service) UpdateEmailTemplate(ctx context.Context, emailTemplate models.EmailTemplate, emailTemplateID primitive.ObjectID) (*mongo.UpdateResult, error) {
-	u, err := utils.StructToBsonM(emailTemplate)
-	if err != nil {
-		return nil, err
-	}
+/*
+* ACCOUNT RECOVERY
+*
+ */
 
-	cleanUpdatePayload := RemoveNonOverridableFields(u, emailTemplate)
+const (
+	PASSWORD_RESET_COLLECTION = "password_resets"
+)
 
-	update := bson.M{"$set": cleanUpdatePayload}
-	filter := bson.M{"_id": emailTemplateID}
-	return s.Database.Collection("email_templates").UpdateOne(ctx, filter, update)
+// MarkUserEmailVerified records that the user owns their email, nothing is matched if their email has changed since
+func (s *Service) MarkUserEmailVerified(ctx context.Context, userID primitive.ObjectID, email string) (*mongo.UpdateResult, error) {
+	filter := bson.M{"_id": userID, "email": email}
+	update := bson.M{"$set": bson.M{"emailVerifiedAt": time.Now()}}
+	return s.Database.Collection("users").UpdateOne(ctx, filter, update)
 }
 
-// DeleteEmailTemplate deletes an email template by its ID
-func (s *Service) DeleteEmailTemplate(ctx context.Context, emailTemplateID primitive.ObjectID) (*mongo.DeleteResult, error) {
-	return s.Database.Collection("email_templates").DeleteOne(ctx, bson.M{"_id": emailTemplateID})
+func (s *Service) CreatePasswordReset(ctx context.Context, reset models.PasswordReset) (*mongo.InsertOneResult, error) {
+	reset.CreatedAt = time.Now()
+	return s.Database.Collection(PASSWORD_RESET_COLLECTION).InsertOne(ctx, reset)
 }
 
-// GetEmailTemplate retrieves an email template by its ID
-func (s *Service) GetEmailTemplate(ctx context.Context, emailTemplateID primitive.ObjectID) (*models.EmailTemplate, error) {
-	var emailTemplate models.EmailTemplate
+// ConsumePasswordReset removes and returns the unexpired password reset with the token, so each reset link works once
+func (s *Service) ConsumePasswordReset(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	// The TTL monitor only runs periodically, so expired documents can still be around
+	filter := bson.M{"tokenHash": tokenHash, "expiresAt": bson.M{"$gt": time.Now()}}
 
-	err := s.Database.Collection("email_templates").FindOne(ctx, bson.M{"_id": emailTemplateID}).Decode(&emailTemplate)
-	if err != nil {
+	var reset models.PasswordReset
+	if err := s.Database.Collection(PASSWORD_RESET_COLLECTION).FindOneAndDelete(ctx, filter).Decode(&reset); err != nil {
 		return nil, err
 	}
 
-	return &emailTemplate, nil
+	return &reset, nil
 }
 
-// GetEventSecret retrieves secrets based on a filter
-func (s *Service) GetEventSecrets(ctx context.Context, filter bson.M, stripSecrets bool) (*models.EventSecrets, error) {
-	var data models.EventSecrets
+func (s *Service) DeletePasswordResets(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(PASSWORD_RESET_COLLECTION).DeleteMany(ctx, filter)
+}
 
-	err := s.Database.Collection("event_secrets").FindOne(ctx, filter).Decode(&data)
-	if err != nil {
-		return nil, err
-	}
+/*
+* SESSIONS
+*
+ */
 
-	if stripSecrets {
-		if data.Email != nil {
-			stripped := data.Email.StripSecret()
-			if strippedEmail, ok := stripped.(*models.EmailSecret); ok {
-				data.Email = strippedEmail
-			}
+const (
+	SESSION_COLLECTION = "sessions"
+)
+
+// ChangeUserPassword sets the user's password, signs out all their other sessions and revokes their API tokens.
+// keepSessionID can be nil to sign out all of them
+func (s *Service) ChangeUserPassword(ctx context.Context, userID primitive.ObjectID, passwordHash string, keepSessionID primitive.ObjectID) error {
+	_, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := s.Database.Collection("users").UpdateByID(sessCtx, userID, bson.M{"$set": bson.M{"passwordHash": passwordHash}})
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, mongo.ErrNoDocuments
 		}
-	}
 
-	return &data, nil
+		if _, err := s.DeleteUserSessions(sessCtx, userID, keepSessionID); err != nil {
+			return nil, err
+		}
+
+		// Tokens created by whoever knew the old password would keep working otherwise
+		return s.Database.Collection(API_TOKEN_COLLECTION).DeleteMany(sessCtx, bson.M{"userID": userID})
+	})
+
+	return err
 }
 
-// CreateOrUpdateEventSecrets creates a new event secret
-func (s *Service) CreateOrUpdateEventSecrets(ctx context.Context, newSecret models.EventSecrets) (*mongo.UpdateResult, error) {
-	filter := bson.M{"eventID": newSecret.EventID}
+func (s *Service) CreateSession(ctx context.Context, session models.Session) (*mongo.InsertOneResult, error) {
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastUsedAt = now
+	return s.Database.Collection(SESSION_COLLECTION).InsertOne(ctx, session)
+}
+
+// GetSession returns the session if it hasn't been signed out or expired
+func (s *Service) GetSession(ctx context.Context, sessionID primitive.ObjectID) (*models.Session, error) {
+	// The TTL monitor only runs periodically, so expired documents can still be around
+	filter := bson.M{"_id": sessionID, "expiresAt": bson.M{"$gt": time.Now()}}
 
-	var existingSecret models.EventSecrets
-	err := s.Database.Collection("event_secrets").FindOne(ctx, filter).Decode(&existingSecret)
-	if err != nil && err != mongo.ErrNoDocuments {
+	var session models.Session
+	if err := s.Database.Collection(SESSION_COLLECTION).FindOne(ctx, filter).Decode(&session); err != nil {
 		return nil, err
 	}
 
-	// Prepare the update document based on non-nil and changed fields in newSecret
-	updateDoc := bson.M{}
-	val := reflect.ValueOf(newSecret)
-	typ := val.Type()
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		fieldType := typ.Field(i)
+	return &session, nil
+}
 
-		// Check if the field is a pointer to a struct and not nil
-		if field.Kind() == reflect.Ptr && !field.IsNil() {
-			fieldName := fieldType.Tag.Get("bson")
+// RotateSession exchanges the refresh token of a session for a new one and extends the session until expiresAt.
+// A refresh token that was already exchanged means it was copied, the session is deleted and ErrRefreshTokenReused is returned
+func (s *Service) RotateSession(ctx context.Context, refreshTokenHash string, newRefreshTokenHash string, expiresAt time.Time) (*models.Session, error) {
+	collection := s.Database.Collection(SESSION_COLLECTION)
 
-			// Get the corresponding field in the existing document
-			existingField := reflect.ValueOf(existingSecret).Field(i)
+	filter := bson.M{"refreshTokenHash": refreshTokenHash, "expiresAt": bson.M{"$gt": time.Now()}}
+	update := bson.M{"$set": bson.M{
+		"refreshTokenHash":         newRefreshTokenHash,
+		"previousRefreshTokenHash": refreshTokenHash,
+		"lastUsedAt":               time.Now(),
+		"expiresAt":                expiresAt,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-			// If the fields are not deeply equal, add them to the update document
-			if !reflect.DeepEqual(field.Interface(), existingField.Interface()) {
-				updateDoc[fieldName] = field.Interface()
-			}
+	var session models.Session
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&session)
+	if err != mongo.ErrNoDocuments {
+		if err != nil {
+			return nil, err
 		}
+		return &session, nil
 	}
 
-	if len(updateDoc) == 0 {
-		// No updates necessary
-		return &mongo.UpdateResult{}, nil
-	}
-
-	// Perform the update with upsert true to handle cases where the document doesn't exist
-	opts := options.Update().SetUpsert(true)
-	result, err := s.Database.Collection("event_secrets").UpdateOne(ctx, filter, bson.M{"$set": updateDoc}, opts)
+	deleted, err := collection.DeleteOne(ctx, bson.M{"previousRefreshTokenHash": refreshTokenHash})
 	if err != nil {
 		return nil, err
 	}
+	if deleted.DeletedCount > 0 {
+		return nil, ErrRefreshTokenReused
+	}
 
-	return result, nil
+	return nil, mongo.ErrNoDocuments
 }
 
-// DeleteEventSecrets
-func (s *Service) DeleteEventSecrets(ctx context.Context, eventID primitive.ObjectID) (*mongo.DeleteResult, error) {
-	filter := bson.M{"eventID": eventID}
-	return s.Database.Collection("event_secrets").DeleteOne(ctx, filter)
+func (s *Service) DeleteSession(ctx context.Context, userID primitive.ObjectID, sessionID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(SESSION_COLLECTION).DeleteOne(ctx, bson.M{"_id": sessionID, "userID": userID})
+}
+
+// DeleteUserSessions signs the user out everywhere but keepSessionID, which can be nil to sign out all of them
+func (s *Service) DeleteUserSessions(ctx context.Context, userID primitive.ObjectID, keepSessionID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	filter := bson.M{"userID": userID}
+	if !keepSessionID.IsZero() {
+		filter["_id"] = bson.M{"$ne": keepSessionID}
+	}
+
+	return s.Database.Collection(SESSION_COLLECTION).DeleteMany(ctx, filter)
 }
 
 /*
-* BILLING
+* TWO-FACTOR AUTHENTICATION
 *
  */
 
-const (
-	PLAN_COLLECTION         = "plans"
-	SUBSCRIPTION_COLLECTION = "subscriptions"
-)
+// SetUpTwoFactor starts setting up a new authenticator for the user, replacing one that wasn't finished. Nothing is matched once it is enabled.
+// The secret is stored encrypted, with the user ID as the associated data so it can't be copied to another user
+func (s *Service) SetUpTwoFactor(ctx context.Context, userID primitive.ObjectID, secret string) (*mongo.UpdateResult, error) {
+	sealed, err := secrets.Seal(ctx, s.Keys, []byte(secret), []byte(userID.Hex()))
+	if err != nil {
+		return nil, err
+	}
 
-var (
-	ERR_PLAN_NOT_FOUND_OR_LIMIT_EXCEEDED = errors.New("no active subscription found with ID, or limit exceeded")
-)
+	filter := bson.M{"_id": userID, "twoFactor.enabledAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"twoFactor": models.TwoFactor{SealedSecret: sealed, RecoveryCodeHashes: []string{}}}}
+	return s.Database.Collection("users").UpdateOne(ctx, filter, update)
+}
 
-// SeedPlans seeds the plans collection with the default plans, if they don't already exist
-func (s *Service) SeedPlans(ctx context.Context) error {
-	initialPlans := []models.Plan{
-		{
-			Name: "Free",
-			BillingCycleOptions: models.PlanCycleOptions{
-				Cycle: "monthly",
-				Price: 0.0,
+// OpenTwoFactorSecret decrypts the secret of the user's authenticator, only to verify a code from it.
+// Secrets stored before they were encrypted are returned as they are until RotateTwoFactorSecretsKey encrypts them
+func (s *Service) OpenTwoFactorSecret(ctx context.Context, user *models.User) (string, error) {
+	if user.TwoFactor == nil {
+		return "", errors.New("the user has no authenticator")
+	}
+
+	if user.TwoFactor.SealedSecret == nil {
+		return user.TwoFactor.Secret, nil
+	}
+
+	secret, err := secrets.Open(ctx, s.Keys, user.TwoFactor.SealedSecret, []byte(user.ID.Hex()))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt two-factor secret: %w", err)
+	}
+
+	return string(secret), nil
+}
+
+// EnableTwoFactor finishes setting up the authenticator once the user entered the code of the time step from it,
+// the session they entered it on counts as signed in with it. It returns false if it was already enabled
+func (s *Service) EnableTwoFactor(ctx context.Context, userID primitive.ObjectID, step int64, recoveryCodeHashes []string, sessionID primitive.ObjectID) (bool, error) {
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		filter := bson.M{"_id": userID, "twoFactor": bson.M{"$exists": true}, "twoFactor.enabledAt": bson.M{"$exists": false}}
+		update := bson.M{"$set": bson.M{
+			"twoFactor.enabledAt":          time.Now(),
+			"twoFactor.lastUsedStep":       step,
+			"twoFactor.recoveryCodeHashes": recoveryCodeHashes,
+		}}
+
+		result, err := s.Database.Collection("users").UpdateOne(sessCtx, filter, update)
+		if err != nil || result.ModifiedCount == 0 {
+			return false, err
+		}
+
+		_, err = s.Database.Collection(SESSION_COLLECTION).UpdateOne(sessCtx, bson.M{"_id": sessionID, "userID": userID}, bson.M{"$set": bson.M{"twoFactorVerified": true}})
+		return err == nil, err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.(bool), nil
+}
+
+// UseTwoFactorCode records that the user used the code of the time step, it returns false if a code of it or a later one was already used
+func (s *Service) UseTwoFactorCode(ctx context.Context, userID primitive.ObjectID, step int64) (bool, error) {
+	filter := bson.M{"_id": userID, "twoFactor.enabledAt": bson.M{"$exists": true}, "twoFactor.lastUsedStep": bson.M{"$lt": step}}
+	update := bson.M{"$set": bson.M{"twoFactor.lastUsedStep": step}}
+
+	result, err := s.Database.Collection("users").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// UseRecoveryCode removes the recovery code from the user's, it returns false if they don't have it
+func (s *Service) UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, recoveryCodeHash string) (bool, error) {
+	filter := bson.M{"_id": userID, "twoFactor.enabledAt": bson.M{"$exists": true}, "twoFactor.recoveryCodeHashes": recoveryCodeHash}
+	update := bson.M{"$pull": bson.M{"twoFactor.recoveryCodeHashes": recoveryCodeHash}}
+
+	result, err := s.Database.Collection("users").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (s *Service) ReplaceRecoveryCodes(ctx context.Context, userID primitive.ObjectID, recoveryCodeHashes []string) (*mongo.UpdateResult, error) {
+	filter := bson.M{"_id": userID, "twoFactor.enabledAt": bson.M{"$exists": true}}
+	update := bson.M{"$set": bson.M{"twoFactor.recoveryCodeHashes": recoveryCodeHashes}}
+	return s.Database.Collection("users").UpdateOne(ctx, filter, update)
+}
+
+// DisableTwoFactor removes the user's authenticator, none of their sessions or API tokens count as signed in with a second factor anymore
+func (s *Service) DisableTwoFactor(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if _, err := s.Database.Collection("users").UpdateByID(sessCtx, userID, bson.M{"$unset": bson.M{"twoFactor": ""}}); err != nil {
+			return nil, err
+		}
+
+		unverify := bson.M{"$set": bson.M{"twoFactorVerified": false}}
+		if _, err := s.Database.Collection(SESSION_COLLECTION).UpdateMany(sessCtx, bson.M{"userID": userID}, unverify); err != nil {
+			return nil, err
+		}
+
+		return s.Database.Collection(API_TOKEN_COLLECTION).UpdateMany(sessCtx, bson.M{"userID": userID}, unverify)
+	})
+
+	return err
+}
+
+/*
+* API TOKENS
+*
+ */
+
+const (
+	API_TOKEN_COLLECTION = "api_tokens"
+)
+
+// apiTokenTouchInterval is how stale lastUsedAt can get, so a busy script doesn't write on every request
+const apiTokenTouchInterval = time.Minute
+
+func (s *Service) CreateAPIToken(ctx context.Context, token models.APIToken) (*mongo.InsertOneResult, error) {
+	return s.Database.Collection(API_TOKEN_COLLECTION).InsertOne(ctx, token)
+}
+
+// ListAPITokens returns the user's unexpired API tokens, newest first
+func (s *Service) ListAPITokens(ctx context.Context, userID primitive.ObjectID) ([]models.APIToken, error) {
+	filter := bson.M{"userID": userID, "expiresAt": bson.M{"$gt": time.Now()}}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := s.Database.Collection(API_TOKEN_COLLECTION).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []models.APIToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// GetAPITokenByHash returns the API token if it hasn't been revoked or expired
+func (s *Service) GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	// The TTL monitor only runs periodically, so expired documents can still be around
+	filter := bson.M{"tokenHash": tokenHash, "expiresAt": bson.M{"$gt": time.Now()}}
+
+	var token models.APIToken
+	if err := s.Database.Collection(API_TOKEN_COLLECTION).FindOne(ctx, filter).Decode(&token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// TouchAPIToken records that the API token was just used, at most once every apiTokenTouchInterval
+func (s *Service) TouchAPIToken(ctx context.Context, tokenID primitive.ObjectID) error {
+	now := time.Now()
+	filter := bson.M{"_id": tokenID, "$or": []bson.M{
+		{"lastUsedAt": bson.M{"$exists": false}},
+		{"lastUsedAt": bson.M{"$lt": now.Add(-apiTokenTouchInterval)}},
+	}}
+
+	_, err := s.Database.Collection(API_TOKEN_COLLECTION).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"lastUsedAt": now}})
+	return err
+}
+
+func (s *Service) DeleteAPIToken(ctx context.Context, userID primitive.ObjectID, tokenID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(API_TOKEN_COLLECTION).DeleteOne(ctx, bson.M{"_id": tokenID, "userID": userID})
+}
+
+/*
+* RESPONSE SUBMISSIONS
+*
+ */
+
+const (
+	RESPONSE_CLAIM_COLLECTION       = "response_claims"
+	FORM_SUBMISSION_LOCK_COLLECTION = "form_submission_locks"
+)
+
+// ResponseSubmission describes the rules a new response is created under
+type ResponseSubmission struct {
+	Claims         []string            // unique keys the response takes on its form, eg: one response per user
+	MaxSubmissions int                 // 0 for no limit
+	Capacity       models.FormCapacity // with a limit the response is offered a seat or waitlisted
+}
+
+// insertResponseClaims reserves the claims for a response, it returns a *ClaimTakenError if another response holds one
+func (s *Service) insertResponseClaims(ctx context.Context, formID primitive.ObjectID, responseID primitive.ObjectID, claims []string) error {
+	now := time.Now()
+	for _, claim := range claims {
+		_, err := s.Database.Collection(RESPONSE_CLAIM_COLLECTION).InsertOne(ctx, models.ResponseClaim{
+			FormID:     formID,
+			Key:        claim,
+			ResponseID: responseID,
+			CreatedAt:  now,
+		})
+		if mongo.IsDuplicateKeyError(err) {
+			return &ClaimTakenError{Claim: claim}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSubmissionLimit returns ErrMaxSubmissionsReached if adding responses to the form would go over its limit of maxSubmissions, 0 for no limit.
+// It must be called in the transaction that creates the responses
+func (s *Service) checkSubmissionLimit(sessCtx mongo.SessionContext, formID primitive.ObjectID, maxSubmissions int, adding int) error {
+	if maxSubmissions <= 0 {
+		return nil
+	}
+
+	// Bumping the lock makes concurrent submissions to the form conflict, so they are counted one at a time
+	opts := options.Update().SetUpsert(true)
+	if _, err := s.Database.Collection(FORM_SUBMISSION_LOCK_COLLECTION).UpdateOne(sessCtx, bson.M{"formID": formID}, bson.M{"$inc": bson.M{"version": 1}}, opts); err != nil {
+		return err
+	}
+
+	count, err := s.Database.Collection("responses").CountDocuments(sessCtx, bson.M{"formID": formID})
+	if err != nil {
+		return err
+	}
+
+	if count+int64(adding) > int64(maxSubmissions) {
+		return ErrMaxSubmissionsReached
+	}
+
+	return nil
+}
+
+// SubmitResponse creates a response in a transaction with its claims, the form's submission limit and its capacity.
+// It returns a *ClaimTakenError or ErrMaxSubmissionsReached when the response is refused
+func (s *Service) SubmitResponse(ctx context.Context, response models.FormResponse, submission ResponseSubmission) (*models.FormResponse, error) {
+	now := time.Now()
+	response.ID = primitive.NewObjectID()
+	response.LastUpdatedAt = now
+
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		submitted := response
+
+		if err := s.checkSubmissionLimit(sessCtx, response.FormID, submission.MaxSubmissions, 1); err != nil {
+			return nil, err
+		}
+
+		if err := s.insertResponseClaims(sessCtx, response.FormID, response.ID, submission.Claims); err != nil {
+			return nil, err
+		}
+
+		if submission.Capacity.Limit > 0 {
+			claimed, err := s.claimFormSeat(sessCtx, response.FormID, submission.Capacity.Limit)
+			if err != nil {
+				return nil, err
+			}
+
+			admission := models.Admission{Status: models.AdmissionStatusWaitlisted, WaitlistedAt: now}
+			if claimed {
+				admission = models.Admission{Status: models.AdmissionStatusOffered, OfferedAt: now, RSVPDeadline: rsvpDeadline(submission.Capacity, now)}
+			}
+			submitted.Admission = &admission
+		}
+
+		if _, err := s.Database.Collection("responses").InsertOne(sessCtx, submitted); err != nil {
+			return nil, err
+		}
+
+		return &submitted, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.FormResponse), nil
+}
+
+// ImportResponses creates many responses of a form in a transaction with their claims, claims[i] being the claims of responses[i].
+// Either all of the responses are created or none are, it returns a *ClaimTakenError or ErrMaxSubmissionsReached when the import is refused
+func (s *Service) ImportResponses(ctx context.Context, formID primitive.ObjectID, responses []models.FormResponse, claims [][]string, maxSubmissions int) ([]models.FormResponse, error) {
+	if len(responses) == 0 {
+		return []models.FormResponse{}, nil
+	}
+
+	now := time.Now()
+	imported := make([]models.FormResponse, len(responses))
+	documents := make([]interface{}, len(responses))
+	claimDocuments := []interface{}{}
+	for i, response := range responses {
+		response.ID = primitive.NewObjectID()
+		response.FormID = formID
+		response.LastUpdatedAt = now
+		imported[i] = response
+		documents[i] = response
+
+		if i < len(claims) {
+			for _, claim := range claims[i] {
+				claimDocuments = append(claimDocuments, models.ResponseClaim{FormID: formID, Key: claim, ResponseID: response.ID, CreatedAt: now})
+			}
+		}
+	}
+
+	_, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if err := s.checkSubmissionLimit(sessCtx, formID, maxSubmissions, len(responses)); err != nil {
+			return nil, err
+		}
+
+		if len(claimDocuments) > 0 {
+			if _, err := s.Database.Collection(RESPONSE_CLAIM_COLLECTION).InsertMany(sessCtx, claimDocuments); err != nil {
+				// The insert is ordered, so the first write error is the claim that was already held
+				var bulkErr mongo.BulkWriteException
+				if errors.As(err, &bulkErr) && mongo.IsDuplicateKeyError(err) && len(bulkErr.WriteErrors) > 0 {
+					return nil, &ClaimTakenError{Claim: claimDocuments[bulkErr.WriteErrors[0].Index].(models.ResponseClaim).Key}
+				}
+				return nil, err
+			}
+		}
+
+		return s.Database.Collection("responses").InsertMany(sessCtx, documents)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return imported, nil
+}
+
+// UpdateResponseWithClaims updates a response and swaps its claims for the given ones in a transaction,
+// it returns a *ClaimTakenError if another response holds one of the claims.
+// The change is added to the response's history in the same transaction unless it is nil or changes nothing
+func (s *Service) UpdateResponseWithClaims(ctx context.Context, response models.FormResponse, responseID primitive.ObjectID, claims []string, change *models.ResponseChange) (*mongo.UpdateResult, error) {
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if _, err := s.Database.Collection(RESPONSE_CLAIM_COLLECTION).DeleteMany(sessCtx, bson.M{"responseID": responseID}); err != nil {
+			return nil, err
+		}
+
+		if err := s.insertResponseClaims(sessCtx, response.FormID, responseID, claims); err != nil {
+			return nil, err
+		}
+
+		if change != nil && len(change.Changes) > 0 {
+			change.ResponseID = responseID
+			change.FormID = response.FormID
+			change.CreatedAt = time.Now()
+			if _, err := s.Database.Collection(RESPONSE_HISTORY_COLLECTION).InsertOne(sessCtx, change); err != nil {
+				return nil, err
+			}
+		}
+
+		return s.UpdateResponse(sessCtx, response, responseID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*mongo.UpdateResult), nil
+}
+
+// ReplaceFormClaims replaces the claims of every response of the form, for when the form's rules about what has to be unique change.
+// Nothing is replaced and a ClaimTakenError is returned if two of the responses take the same claim
+func (s *Service) ReplaceFormClaims(ctx context.Context, formID primitive.ObjectID, claimsByResponse map[primitive.ObjectID][]string) error {
+	_, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if _, err := s.Database.Collection(RESPONSE_CLAIM_COLLECTION).DeleteMany(sessCtx, bson.M{"formID": formID}); err != nil {
+			return nil, err
+		}
+
+		for responseID, claims := range claimsByResponse {
+			if err := s.insertResponseClaims(sessCtx, formID, responseID, claims); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
+
+// ListTakenClaims returns which of the claims are already held by responses of the form
+func (s *Service) ListTakenClaims(ctx context.Context, formID primitive.ObjectID, claims []string) ([]string, error) {
+	taken := []string{}
+	if len(claims) == 0 {
+		return taken, nil
+	}
+
+	var held []models.ResponseClaim
+	cursor, err := s.Database.Collection(RESPONSE_CLAIM_COLLECTION).Find(ctx, bson.M{"formID": formID, "key": bson.M{"$in": claims}}, options.Find().SetProjection(bson.M{"key": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &held); err != nil {
+		return nil, err
+	}
+
+	for _, claim := range held {
+		taken = append(taken, claim.Key)
+	}
+
+	return taken, nil
+}
+
+// MoveResponse moves a response to another form in a transaction, swapping its claims for the ones it takes on that form.
+// Its files and history go with it and its reviews are deleted as they were scored against the old form's rubric.
+// It returns a *ClaimTakenError if another response of the form holds one of the claims, ErrMaxSubmissionsReached if the form
+// already has maxSubmissions responses and mongo.ErrNoDocuments if the response is no longer on its form
+func (s *Service) MoveResponse(ctx context.Context, response models.FormResponse, toFormID primitive.ObjectID, claims []string, maxSubmissions int) error {
+	_, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if err := s.checkSubmissionLimit(sessCtx, toFormID, maxSubmissions, 1); err != nil {
+			return nil, err
+		}
+
+		if _, err := s.Database.Collection(RESPONSE_CLAIM_COLLECTION).DeleteMany(sessCtx, bson.M{"responseID": response.ID}); err != nil {
+			return nil, err
+		}
+
+		if err := s.insertResponseClaims(sessCtx, toFormID, response.ID, claims); err != nil {
+			return nil, err
+		}
+
+		// The data was answered against the other form's fields, so it has no version on this one, and its reviews are deleted below
+		update := bson.M{"$set": bson.M{"formID": toFormID, "lastUpdatedAt": time.Now()}, "$unset": bson.M{"admission": "", "formVersion": "", "reviewCompletedAt": ""}}
+		result, err := s.Database.Collection("responses").UpdateOne(sessCtx, bson.M{"_id": response.ID, "formID": response.FormID}, update)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount != 1 {
+			// Deleted or moved since, the claims taken above are rolled back
+			return nil, mongo.ErrNoDocuments
+		}
+
+		if _, err := s.Database.Collection(FILE_UPLOAD_COLLECTION).UpdateMany(sessCtx, bson.M{"responseID": response.ID}, bson.M{"$set": bson.M{"formID": toFormID}}); err != nil {
+			return nil, err
+		}
+
+		if _, err := s.Database.Collection(RESPONSE_HISTORY_COLLECTION).UpdateMany(sessCtx, bson.M{"responseID": response.ID}, bson.M{"$set": bson.M{"formID": toFormID}}); err != nil {
+			return nil, err
+		}
+
+		return s.Database.Collection(REVIEW_COLLECTION).DeleteMany(sessCtx, bson.M{"responseID": response.ID})
+	})
+
+	return err
+}
+
+// DeleteResponseClaims releases claims based on a filter
+func (s *Service) DeleteResponseClaims(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(RESPONSE_CLAIM_COLLECTION).DeleteMany(ctx, filter)
+}
+
+/*
+* RESPONSE HISTORY
+*
+ */
+
+const (
+	RESPONSE_HISTORY_COLLECTION = "response_history"
+)
+
+// ListResponseHistory lists the changes made to a response of the form, newest first, also once the response is deleted.
+// Entries are only added by UpdateResponseWithClaims and DeleteResponse and never changed
+func (s *Service) ListResponseHistory(ctx context.Context, formID primitive.ObjectID, responseID primitive.ObjectID) ([]models.ResponseChange, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := s.Database.Collection(RESPONSE_HISTORY_COLLECTION).Find(ctx, bson.M{"formID": formID, "responseID": responseID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []models.ResponseChange{}
+	if err := cursor.All(ctx, &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// DeleteResponseHistory deletes the history of responses based on a filter, for when their form is deleted
+func (s *Service) DeleteResponseHistory(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(RESPONSE_HISTORY_COLLECTION).DeleteMany(ctx, filter)
+}
+
+/*
+* AUDIT LOG
+*
+ */
+
+const (
+	AUDIT_LOG_COLLECTION = "audit_logs"
+)
+
+// InsertAuditLogEntry appends an entry to the audit log, entries are never changed and only removed by the retention TTL
+func (s *Service) InsertAuditLogEntry(ctx context.Context, entry models.AuditLogEntry) (*mongo.InsertOneResult, error) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	return s.Database.Collection(AUDIT_LOG_COLLECTION).InsertOne(ctx, entry)
+}
+
+// ListAuditLog lists audit log entries based on a filter, the options set the sort and pagination
+func (s *Service) ListAuditLog(ctx context.Context, filter bson.M, options *options.FindOptions) ([]models.AuditLogEntry, error) {
+	cursor, err := s.Database.Collection(AUDIT_LOG_COLLECTION).Find(ctx, filter, options)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []models.AuditLogEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+/*
+* RESPONSE DRAFTS
+*
+ */
+
+const (
+	RESPONSE_DRAFT_COLLECTION = "response_drafts"
+)
+
+// GetResponseDraft retrieves the draft a user has saved for a form, if it hasn't expired
+func (s *Service) GetResponseDraft(ctx context.Context, formID primitive.ObjectID, userID primitive.ObjectID) (*models.FormResponseDraft, error) {
+	// The TTL monitor only runs periodically, so expired documents can still be around
+	filter := bson.M{"formID": formID, "userID": userID, "expiresAt": bson.M{"$gt": time.Now()}}
+
+	var draft models.FormResponseDraft
+	err := s.Database.Collection(RESPONSE_DRAFT_COLLECTION).FindOne(ctx, filter).Decode(&draft)
+	if err != nil {
+		return nil, err
+	}
+
+	return &draft, nil
+}
+
+// SaveResponseDraft creates or overwrites the draft a user has saved for a form
+func (s *Service) SaveResponseDraft(ctx context.Context, draft models.FormResponseDraft) (*mongo.UpdateResult, error) {
+	now := time.Now()
+	filter := bson.M{"formID": draft.FormID, "userID": draft.UserID}
+	update := bson.M{
+		"$set": bson.M{
+			"data":          draft.Data,
+			"lastUpdatedAt": now,
+			"expiresAt":     draft.ExpiresAt,
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	return s.Database.Collection(RESPONSE_DRAFT_COLLECTION).UpdateOne(ctx, filter, update, opts)
+}
+
+// DeleteResponseDraft removes the draft a user has saved for a form
+func (s *Service) DeleteResponseDraft(ctx context.Context, formID primitive.ObjectID, userID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(RESPONSE_DRAFT_COLLECTION).DeleteOne(ctx, bson.M{"formID": formID, "userID": userID})
+}
+
+/*
+* PENDING RESPONSES
+*
+ */
+
+const (
+	PENDING_RESPONSE_COLLECTION = "pending_responses"
+)
+
+// CreatePendingResponse stores an anonymous response until its respondent confirms their email
+func (s *Service) CreatePendingResponse(ctx context.Context, pending models.PendingResponse) (*mongo.InsertOneResult, error) {
+	pending.CreatedAt = time.Now()
+	return s.Database.Collection(PENDING_RESPONSE_COLLECTION).InsertOne(ctx, pending)
+}
+
+// ConsumePendingResponse removes and returns the unexpired pending response with the token, so each confirmation link works once
+func (s *Service) ConsumePendingResponse(ctx context.Context, formID primitive.ObjectID, tokenHash string) (*models.PendingResponse, error) {
+	// The TTL monitor only runs periodically, so expired documents can still be around
+	filter := bson.M{"formID": formID, "tokenHash": tokenHash, "expiresAt": bson.M{"$gt": time.Now()}}
+
+	var pending models.PendingResponse
+	if err := s.Database.Collection(PENDING_RESPONSE_COLLECTION).FindOneAndDelete(ctx, filter).Decode(&pending); err != nil {
+		return nil, err
+	}
+
+	return &pending, nil
+}
+
+func (s *Service) DeletePendingResponses(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(PENDING_RESPONSE_COLLECTION).DeleteMany(ctx, filter)
+}
+
+/*
+* ORGANIZER INVITES
+*
+ */
+
+const (
+	ORGANIZER_INVITE_COLLECTION = "organizer_invites"
+
+	// organizerInviteRetention is how long an expired invite is kept so owners can still see and resend it
+	organizerInviteRetention = 30 * 24 * time.Hour
+)
+
+// SaveOrganizerInvite invites the email to the event, inviting the same email again replaces the earlier invite's role and expiry
+func (s *Service) SaveOrganizerInvite(ctx context.Context, invite models.OrganizerInvite) (*models.OrganizerInvite, error) {
+	now := time.Now()
+	filter := bson.M{"eventID": invite.EventID, "email": invite.Email}
+	update := bson.M{
+		"$set": bson.M{
+			"role":        invite.Role,
+			"invitedByID": invite.InvitedByID,
+			"sentAt":      now,
+			"expiresAt":   invite.ExpiresAt,
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved models.OrganizerInvite
+	if err := s.Database.Collection(ORGANIZER_INVITE_COLLECTION).FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved); err != nil {
+		return nil, err
+	}
+
+	return &saved, nil
+}
+
+func (s *Service) GetOrganizerInvite(ctx context.Context, eventID primitive.ObjectID, inviteID primitive.ObjectID) (*models.OrganizerInvite, error) {
+	var invite models.OrganizerInvite
+	if err := s.Database.Collection(ORGANIZER_INVITE_COLLECTION).FindOne(ctx, bson.M{"_id": inviteID, "eventID": eventID}).Decode(&invite); err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+// ListOrganizerInvites returns the invites of the event that haven't been accepted, including recently expired ones
+func (s *Service) ListOrganizerInvites(ctx context.Context, eventID primitive.ObjectID) ([]models.OrganizerInvite, error) {
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cursor, err := s.Database.Collection(ORGANIZER_INVITE_COLLECTION).Find(ctx, bson.M{"eventID": eventID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invites := []models.OrganizerInvite{}
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+// RenewOrganizerInvite gives the invite a new expiry for it to be sent again, which stops the links sent before from working
+func (s *Service) RenewOrganizerInvite(ctx context.Context, eventID primitive.ObjectID, inviteID primitive.ObjectID, expiresAt time.Time) (*models.OrganizerInvite, error) {
+	filter := bson.M{"_id": inviteID, "eventID": eventID}
+	update := bson.M{"$set": bson.M{"sentAt": time.Now(), "expiresAt": expiresAt}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var invite models.OrganizerInvite
+	if err := s.Database.Collection(ORGANIZER_INVITE_COLLECTION).FindOneAndUpdate(ctx, filter, update, opts).Decode(&invite); err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+func (s *Service) DeleteOrganizerInvite(ctx context.Context, eventID primitive.ObjectID, inviteID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(ORGANIZER_INVITE_COLLECTION).DeleteOne(ctx, bson.M{"_id": inviteID, "eventID": eventID})
+}
+
+// AcceptOrganizerInvite removes the invite and adds the user to its event with the invite's role, as long as the invite
+// still has the expiry its token was signed with and it hasn't passed. It returns mongo.ErrNoDocuments when it doesn't or the event is gone
+func (s *Service) AcceptOrganizerInvite(ctx context.Context, inviteID primitive.ObjectID, expiresAt time.Time, userID primitive.ObjectID) (*models.OrganizerInvite, error) {
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		filter := bson.M{"_id": inviteID, "expiresAt": bson.M{"$eq": expiresAt, "$gt": time.Now()}}
+
+		var invite models.OrganizerInvite
+		if err := s.Database.Collection(ORGANIZER_INVITE_COLLECTION).FindOneAndDelete(sessCtx, filter).Decode(&invite); err != nil {
+			return nil, err
+		}
+
+		updated, err := s.AddOrganizerToEvent(sessCtx, invite.EventID, userID, invite.Role)
+		if err != nil {
+			return nil, err
+		}
+		if updated.MatchedCount == 0 {
+			return nil, mongo.ErrNoDocuments
+		}
+
+		return &invite, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.OrganizerInvite), nil
+}
+
+/*
+* ABUSE PROTECTION
+*
+ */
+
+const (
+	PROOF_OF_WORK_COLLECTION  = "proof_of_work_challenges"
+	RATE_LIMIT_COLLECTION     = "rate_limits"
+	LOGIN_THROTTLE_COLLECTION = "login_throttles"
+)
+
+// ConsumeProofOfWorkChallenge records a solved challenge so it can't be replayed, it is kept until the challenge would have expired anyway
+func (s *Service) ConsumeProofOfWorkChallenge(ctx context.Context, challenge string, expiresAt time.Time) error {
+	hash := sha256.Sum256([]byte(challenge))
+	_, err := s.Database.Collection(PROOF_OF_WORK_COLLECTION).InsertOne(ctx, bson.M{"_id": hex.EncodeToString(hash[:]), "expiresAt": expiresAt})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrChallengeAlreadyUsed
+	}
+
+	return err
+}
+
+// IncrementRateLimit counts a request against the key in the current fixed window and returns how many it has made in that window
+func (s *Service) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
+	windowStart := time.Now().Truncate(window)
+	filter := bson.M{"_id": fmt.Sprintf("%s:%d", key, windowStart.Unix())}
+	update := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$setOnInsert": bson.M{"key": key, "expiresAt": windowStart.Add(window)},
+	}
+
+	var counter struct {
+		Count int64 `bson:"count"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := s.Database.Collection(RATE_LIMIT_COLLECTION).FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter); err != nil {
+		return 0, err
+	}
+
+	return counter.Count, nil
+}
+
+// GetLoginThrottles returns the failures of the keys that haven't been forgotten yet
+func (s *Service) GetLoginThrottles(ctx context.Context, keys []string) ([]models.LoginThrottle, error) {
+	// The TTL monitor only runs periodically, so expired documents can still be around
+	filter := bson.M{"_id": bson.M{"$in": keys}, "expiresAt": bson.M{"$gt": time.Now()}}
+
+	cursor, err := s.Database.Collection(LOGIN_THROTTLE_COLLECTION).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	throttles := []models.LoginThrottle{}
+	if err := cursor.All(ctx, &throttles); err != nil {
+		return nil, err
+	}
+	return throttles, nil
+}
+
+// RecordLoginFailure counts a failed sign in against the key, its failures are remembered until expiresAt. Expired failures start again from one
+func (s *Service) RecordLoginFailure(ctx context.Context, key string, expiresAt time.Time) (*models.LoginThrottle, error) {
+	now := time.Now()
+	collection := s.Database.Collection(LOGIN_THROTTLE_COLLECTION)
+
+	// The TTL monitor may not have removed expired failures yet
+	if _, err := collection.DeleteOne(ctx, bson.M{"_id": key, "expiresAt": bson.M{"$lte": now}}); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"failures": 1},
+		"$set": bson.M{"lastFailureAt": now, "expiresAt": expiresAt},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var throttle models.LoginThrottle
+	if err := collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&throttle); err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// LockLogin locks the key out of signing in until lockedUntil, its failures are remembered until expiresAt
+func (s *Service) LockLogin(ctx context.Context, key string, lockedUntil time.Time, expiresAt time.Time) (*mongo.UpdateResult, error) {
+	update := bson.M{"$set": bson.M{"lockedUntil": lockedUntil, "expiresAt": expiresAt}}
+	return s.Database.Collection(LOGIN_THROTTLE_COLLECTION).UpdateByID(ctx, key, update)
+}
+
+func (s *Service) DeleteLoginThrottles(ctx context.Context, keys []string) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(LOGIN_THROTTLE_COLLECTION).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": keys}})
+}
+
+/*
+* BULK JOBS
+*
+ */
+
+const (
+	BULK_JOB_COLLECTION = "bulk_jobs"
+
+	// bulkJobRetention is how long finished jobs are kept for organizers to look at their results
+	bulkJobRetention = 30 * 24 * time.Hour
+)
+
+// CreateBulkJob stores a new job, it can be claimed by a runner straight away
+func (s *Service) CreateBulkJob(ctx context.Context, job models.BulkJob) (*mongo.InsertOneResult, error) {
+	job.CreatedAt = time.Now()
+	job.Status = models.BulkJobPending
+	job.Total = len(job.Items)
+	return s.Database.Collection(BULK_JOB_COLLECTION).InsertOne(ctx, job)
+}
+
+// GetBulkJob retrieves a job of a form with the results of its items
+func (s *Service) GetBulkJob(ctx context.Context, formID primitive.ObjectID, jobID primitive.ObjectID) (*models.BulkJob, error) {
+	var job models.BulkJob
+	if err := s.Database.Collection(BULK_JOB_COLLECTION).FindOne(ctx, bson.M{"_id": jobID, "formID": formID}).Decode(&job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// ListBulkJobs lists the newest jobs of a form, without their items
+func (s *Service) ListBulkJobs(ctx context.Context, formID primitive.ObjectID, limit int64) ([]models.BulkJob, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit).SetProjection(bson.M{"items": 0})
+	cursor, err := s.Database.Collection(BULK_JOB_COLLECTION).Find(ctx, bson.M{"formID": formID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []models.BulkJob{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// ClaimBulkJob takes the lease of the oldest unfinished job that no runner holds, so only one runner works on a job at a time.
+// It returns mongo.ErrNoDocuments when there is no such job
+func (s *Service) ClaimBulkJob(ctx context.Context, lease time.Duration) (*models.BulkJob, error) {
+	now := time.Now()
+	filter := bson.M{
+		"status":         bson.M{"$in": []models.BulkJobStatus{models.BulkJobPending, models.BulkJobRunning}},
+		"leaseExpiresAt": bson.M{"$lt": now},
+	}
+	update := bson.M{"$set": bson.M{"status": models.BulkJobRunning, "leaseExpiresAt": now.Add(lease)}}
+
+	var job models.BulkJob
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	if err := s.Database.Collection(BULK_JOB_COLLECTION).FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// RecordBulkJobResults saves the results of the job's items by their index and extends the runner's lease
+func (s *Service) RecordBulkJobResults(ctx context.Context, jobID primitive.ObjectID, results map[int]models.BulkJobItem, lease time.Duration) error {
+	set := bson.M{"leaseExpiresAt": time.Now().Add(lease)}
+	succeeded, failed := 0, 0
+	for index, item := range results {
+		set[fmt.Sprintf("items.%d", index)] = item
+		switch item.Status {
+		case models.BulkJobItemSucceeded:
+			succeeded++
+		case models.BulkJobItemFailed:
+			failed++
+		}
+	}
+
+	update := bson.M{"$set": set, "$inc": bson.M{"succeeded": succeeded, "failed": failed}}
+	_, err := s.Database.Collection(BULK_JOB_COLLECTION).UpdateOne(ctx, bson.M{"_id": jobID}, update)
+	return err
+}
+
+// ClearBulkJobItemTriggers removes the pipeline runs of the job's item by its index once they are sent and extends the runner's lease
+func (s *Service) ClearBulkJobItemTriggers(ctx context.Context, jobID primitive.ObjectID, index int, lease time.Duration) error {
+	update := bson.M{
+		"$unset": bson.M{fmt.Sprintf("items.%d.triggers", index): ""},
+		"$set":   bson.M{"leaseExpiresAt": time.Now().Add(lease)},
+	}
+	_, err := s.Database.Collection(BULK_JOB_COLLECTION).UpdateOne(ctx, bson.M{"_id": jobID}, update)
+	return err
+}
+
+// FinishBulkJob marks the job completed, pipelineError explains why some of its pipelines weren't triggered
+func (s *Service) FinishBulkJob(ctx context.Context, jobID primitive.ObjectID, pipelineError string) error {
+	set := bson.M{"status": models.BulkJobCompleted, "finishedAt": time.Now()}
+	if pipelineError != "" {
+		set["pipelineError"] = pipelineError
+	}
+
+	_, err := s.Database.Collection(BULK_JOB_COLLECTION).UpdateOne(ctx, bson.M{"_id": jobID}, bson.M{"$set": set})
+	return err
+}
+
+func (s *Service) DeleteBulkJobs(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(BULK_JOB_COLLECTION).DeleteMany(ctx, filter)
+}
+
+/*
+* FILE UPLOADS
+*
+ */
+
+const (
+	FILE_UPLOAD_COLLECTION = "file_uploads"
+)
+
+// CreateFileUpload records a file that has been written to object storage
+func (s *Service) CreateFileUpload(ctx context.Context, upload models.FileUpload) (*mongo.InsertOneResult, error) {
+	upload.CreatedAt = time.Now()
+	return s.Database.Collection(FILE_UPLOAD_COLLECTION).InsertOne(ctx, upload)
+}
+
+// ListFileUploads retrieves file uploads based on a filter
+func (s *Service) ListFileUploads(ctx context.Context, filter bson.M) ([]models.FileUpload, error) {
+	var uploads []models.FileUpload
+
+	cursor, err := s.Database.Collection(FILE_UPLOAD_COLLECTION).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return nil, err
+	}
+
+	// If uploads is null then return an empty slice instead
+	if uploads == nil {
+		return []models.FileUpload{}, nil
+	}
+
+	return uploads, nil
+}
+
+// AttachFileUploads links uploads to the response they were submitted with
+func (s *Service) AttachFileUploads(ctx context.Context, uploadIDs []primitive.ObjectID, responseID primitive.ObjectID) (*mongo.UpdateResult, error) {
+	filter := bson.M{"_id": bson.M{"$in": uploadIDs}}
+	update := bson.M{"$set": bson.M{"responseID": responseID}}
+	return s.Database.Collection(FILE_UPLOAD_COLLECTION).UpdateMany(ctx, filter, update)
+}
+
+// DeleteFileUploads removes file upload records, the stored objects must be deleted separately
+func (s *Service) DeleteFileUploads(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(FILE_UPLOAD_COLLECTION).DeleteMany(ctx, filter)
+}
+
+/*
+* REVIEWS
+*
+ */
+
+const (
+	REVIEW_RUBRIC_COLLECTION     = "review_rubrics"
+	REVIEW_ASSIGNMENT_COLLECTION = "review_assignments"
+	REVIEW_COLLECTION            = "reviews"
+)
+
+// GetReviewRubric retrieves the review rubric of a form
+func (s *Service) GetReviewRubric(ctx context.Context, formID primitive.ObjectID) (*models.ReviewRubric, error) {
+	var rubric models.ReviewRubric
+	err := s.Database.Collection(REVIEW_RUBRIC_COLLECTION).FindOne(ctx, bson.M{"formID": formID}).Decode(&rubric)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rubric, nil
+}
+
+// SaveReviewRubric creates or replaces the review rubric of a form
+func (s *Service) SaveReviewRubric(ctx context.Context, rubric models.ReviewRubric) (*mongo.UpdateResult, error) {
+	filter := bson.M{"formID": rubric.FormID}
+	update := bson.M{
+		"$set": bson.M{
+			"eventID":            rubric.EventID,
+			"criteria":           rubric.Criteria,
+			"hiddenFieldKeys":    rubric.HiddenFieldKeys,
+			"reviewsPerResponse": rubric.ReviewsPerResponse,
+			"lastUpdatedAt":      rubric.LastUpdatedAt,
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	return s.Database.Collection(REVIEW_RUBRIC_COLLECTION).UpdateOne(ctx, filter, update, opts)
+}
+
+// CreateReviewAssignment assigns a batch of responses to a reviewer
+func (s *Service) CreateReviewAssignment(ctx context.Context, assignment models.ReviewAssignment) (*mongo.InsertOneResult, error) {
+	assignment.CreatedAt = time.Now()
+	return s.Database.Collection(REVIEW_ASSIGNMENT_COLLECTION).InsertOne(ctx, assignment)
+}
+
+// ListReviewAssignments retrieves review assignments based on a filter
+func (s *Service) ListReviewAssignments(ctx context.Context, filter bson.M) ([]models.ReviewAssignment, error) {
+	var assignments []models.ReviewAssignment
+
+	cursor, err := s.Database.Collection(REVIEW_ASSIGNMENT_COLLECTION).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &assignments); err != nil {
+		return nil, err
+	}
+
+	// If assignments is null then return an empty slice instead
+	if assignments == nil {
+		return []models.ReviewAssignment{}, nil
+	}
+
+	return assignments, nil
+}
+
+// SaveReview creates or overwrites a reviewer's review of a response,
+// UpsertedCount on the result tells whether this is the reviewer's first review of the response
+func (s *Service) SaveReview(ctx context.Context, review models.Review) (*mongo.UpdateResult, error) {
+	now := time.Now()
+	filter := bson.M{"responseID": review.ResponseID, "reviewerID": review.ReviewerID}
+	update := bson.M{
+		"$set": bson.M{
+			"scores":        review.Scores,
+			"comment":       review.Comment,
+			"weightedScore": review.WeightedScore,
+			"lastUpdatedAt": now,
+		},
+		"$setOnInsert": bson.M{
+			"formID":    review.FormID,
+			"createdAt": now,
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	return s.Database.Collection(REVIEW_COLLECTION).UpdateOne(ctx, filter, update, opts)
+}
+
+// ListReviews retrieves reviews based on a filter
+func (s *Service) ListReviews(ctx context.Context, filter bson.M) ([]models.Review, error) {
+	var reviews []models.Review
+
+	cursor, err := s.Database.Collection(REVIEW_COLLECTION).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, err
+	}
+
+	// If reviews is null then return an empty slice instead
+	if reviews == nil {
+		return []models.Review{}, nil
+	}
+
+	return reviews, nil
+}
+
+// DeleteReviews removes reviews based on a filter
+func (s *Service) DeleteReviews(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(REVIEW_COLLECTION).DeleteMany(ctx, filter)
+}
+
+// MarkReviewCompleted records that the response has received its required reviews, it returns false if it already had,
+// so only one of the reviews that complete it at the same time fires the ReviewCompleted pipelines
+func (s *Service) MarkReviewCompleted(ctx context.Context, formID primitive.ObjectID, responseID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": responseID, "formID": formID, "reviewCompletedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"reviewCompletedAt": time.Now()}}
+
+	err := s.Database.Collection("responses").FindOneAndUpdate(ctx, filter, update).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+/*
+* ADMISSIONS
+*
+ */
+
+const (
+	FORM_CAPACITY_COLLECTION = "form_capacity"
+)
+
+// withTransaction runs fn in a transaction, retrying it on transient errors such as write conflicts.
+// Note: transactions require MongoDB to run as a replica set
+func (s *Service) withTransaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) (interface{}, error)) (interface{}, error) {
+	session, err := s.Client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	return session.WithTransaction(ctx, fn)
+}
+
+// claimFormSeat takes a seat from the form's capacity counter, it reports false when the form is full
+func (s *Service) claimFormSeat(ctx context.Context, formID primitive.ObjectID, limit int) (bool, error) {
+	collection := s.Database.Collection(FORM_CAPACITY_COLLECTION)
+
+	// Make sure the counter exists so the conditional increment below doesn't have to upsert
+	opts := options.Update().SetUpsert(true)
+	if _, err := collection.UpdateOne(ctx, bson.M{"formID": formID}, bson.M{"$setOnInsert": bson.M{"held": 0}}, opts); err != nil {
+		return false, err
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"formID": formID, "held": bson.M{"$lt": limit}}, bson.M{"$inc": bson.M{"held": 1}})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// releaseFormSeats gives seats back to the form's capacity counter
+func (s *Service) releaseFormSeats(ctx context.Context, formID primitive.ObjectID, seats int64) error {
+	update := bson.A{
+		bson.M{"$set": bson.M{"held": bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{"$held", seats}}}}}},
+	}
+
+	_, err := s.Database.Collection(FORM_CAPACITY_COLLECTION).UpdateOne(ctx, bson.M{"formID": formID}, update)
+	return err
+}
+
+// rsvpDeadline returns when an offer made now lapses, or the zero time when offers don't lapse
+func rsvpDeadline(capacity models.FormCapacity, now time.Time) time.Time {
+	if capacity.RSVPWindowHours <= 0 {
+		return time.Time{}
+	}
+
+	return now.Add(time.Duration(capacity.RSVPWindowHours) * time.Hour)
+}
+
+// fillFormSeats offers the free seats of the form to the waitlist in order, it returns the promoted responses
+func (s *Service) fillFormSeats(ctx context.Context, formID primitive.ObjectID, capacity models.FormCapacity, now time.Time) ([]models.FormResponse, error) {
+	promoted := []models.FormResponse{}
+	for {
+		claimed, err := s.claimFormSeat(ctx, formID, capacity.Limit)
+		if err != nil {
+			return nil, err
+		}
+
+		if !claimed {
+			return promoted, nil
+		}
+
+		offer := bson.M{
+			"admission.status":    models.AdmissionStatusOffered,
+			"admission.offeredAt": now,
+			"lastUpdatedAt":       now,
+		}
+		if deadline := rsvpDeadline(capacity, now); !deadline.IsZero() {
+			offer["admission.rsvpDeadline"] = deadline
+		}
+
+		filter := bson.M{"formID": formID, "admission.status": models.AdmissionStatusWaitlisted}
+		opts := options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "admission.waitlistedAt", Value: 1}, {Key: "_id", Value: 1}}).
+			SetReturnDocument(options.After)
+
+		var response models.FormResponse
+		err = s.Database.Collection("responses").FindOneAndUpdate(ctx, filter, bson.M{"$set": offer}, opts).Decode(&response)
+		if err == mongo.ErrNoDocuments {
+			// Nobody is waiting, give the seat back
+			return promoted, s.releaseFormSeats(ctx, formID, 1)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		promoted = append(promoted, response)
+	}
+}
+
+// AcceptAdmission confirms an offered seat, it returns ErrAdmissionStatusChanged if the offer is no longer open
+func (s *Service) AcceptAdmission(ctx context.Context, responseID primitive.ObjectID) (*mongo.UpdateResult, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":              responseID,
+		"admission.status": models.AdmissionStatusOffered,
+		"$or": bson.A{
+			bson.M{"admission.rsvpDeadline": bson.M{"$exists": false}},
+			bson.M{"admission.rsvpDeadline": bson.M{"$gt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"admission.status":      models.AdmissionStatusAccepted,
+		"admission.respondedAt": now,
+		"lastUpdatedAt":         now,
+	}}
+
+	result, err := s.Database.Collection("responses").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, ErrAdmissionStatusChanged
+	}
+
+	return result, nil
+}
+
+// DeclineAdmission gives up a response's seat or place on the waitlist, a freed seat is offered to the waitlist in the same transaction.
+// It returns the promoted responses, or ErrAdmissionStatusChanged if the response no longer holds a seat or place
+func (s *Service) DeclineAdmission(ctx context.Context, responseID primitive.ObjectID, capacity models.FormCapacity) ([]models.FormResponse, error) {
+	now := time.Now()
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		filter := bson.M{
+			"_id":              responseID,
+			"admission.status": bson.M{"$in": bson.A{models.AdmissionStatusOffered, models.AdmissionStatusAccepted, models.AdmissionStatusWaitlisted}},
+		}
+		update := bson.M{"$set": bson.M{
+			"admission.status":      models.AdmissionStatusDeclined,
+			"admission.respondedAt": now,
+			"lastUpdatedAt":         now,
+		}}
+
+		var previous models.FormResponse
+		err := s.Database.Collection("responses").FindOneAndUpdate(sessCtx, filter, update).Decode(&previous)
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAdmissionStatusChanged
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !previous.Admission.Status.HoldsSeat() {
+			return []models.FormResponse{}, nil
+		}
+
+		if err := s.releaseFormSeats(sessCtx, previous.FormID, 1); err != nil {
+			return nil, err
+		}
+
+		return s.fillFormSeats(sessCtx, previous.FormID, capacity, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]models.FormResponse), nil
+}
+
+// ExpireAdmissionOffers expires the offers of a form whose RSVP deadline has passed and offers any free seats to the waitlist.
+// It returns the promoted responses
+func (s *Service) ExpireAdmissionOffers(ctx context.Context, formID primitive.ObjectID, capacity models.FormCapacity) ([]models.FormResponse, error) {
+	now := time.Now()
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		filter := bson.M{
+			"formID":                 formID,
+			"admission.status":       models.AdmissionStatusOffered,
+			"admission.rsvpDeadline": bson.M{"$lte": now},
+		}
+		update := bson.M{"$set": bson.M{
+			"admission.status": models.AdmissionStatusExpired,
+			"lastUpdatedAt":    now,
+		}}
+
+		expired, err := s.Database.Collection("responses").UpdateMany(sessCtx, filter, update)
+		if err != nil {
+			return nil, err
+		}
+
+		if expired.ModifiedCount > 0 {
+			if err := s.releaseFormSeats(sessCtx, formID, expired.ModifiedCount); err != nil {
+				return nil, err
+			}
+		}
+
+		return s.fillFormSeats(sessCtx, formID, capacity, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]models.FormResponse), nil
+}
+
+// SetResponseCheckIn checks in the respondent of a response of the form, or undoes it when checkIn is nil.
+// A respondent who is already checked in isn't matched, so the same ticket can't be checked in twice
+func (s *Service) SetResponseCheckIn(ctx context.Context, formID primitive.ObjectID, responseID primitive.ObjectID, checkIn *models.CheckIn) (*mongo.UpdateResult, error) {
+	filter := bson.M{"_id": responseID, "formID": formID}
+	if checkIn == nil {
+		return s.Database.Collection("responses").UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"checkIn": ""}})
+	}
+
+	filter["checkIn"] = bson.M{"$exists": false}
+	return s.Database.Collection("responses").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"checkIn": checkIn}})
+}
+
+// ListFormsWithLapsedOffers returns the IDs of the forms with offers whose RSVP deadline has passed, which ExpireAdmissionOffers hasn't expired yet
+func (s *Service) ListFormsWithLapsedOffers(ctx context.Context) ([]primitive.ObjectID, error) {
+	filter := bson.M{"admission.status": models.AdmissionStatusOffered, "admission.rsvpDeadline": bson.M{"$lte": time.Now()}}
+	values, err := s.Database.Collection("responses").Distinct(ctx, "formID", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	formIDs := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if formID, ok := value.(primitive.ObjectID); ok {
+			formIDs = append(formIDs, formID)
+		}
+	}
+
+	return formIDs, nil
+}
+
+// GetWaitlistPosition returns the 1-based position of a waitlisted response on its form's waitlist
+func (s *Service) GetWaitlistPosition(ctx context.Context, response models.FormResponse) (int64, error) {
+	if response.Admission == nil || response.Admission.Status != models.AdmissionStatusWaitlisted {
+		return 0, nil
+	}
+
+	filter := bson.M{
+		"formID":           response.FormID,
+		"admission.status": models.AdmissionStatusWaitlisted,
+		"$or": bson.A{
+			bson.M{"admission.waitlistedAt": bson.M{"$lt": response.Admission.WaitlistedAt}},
+			bson.M{"admission.waitlistedAt": response.Admission.WaitlistedAt, "_id": bson.M{"$lt": response.ID}},
+		},
+	}
+
+	ahead, err := s.Database.Collection("responses").CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return ahead + 1, nil
+}
+
+// DeleteFormCapacity removes the capacity counter of a form
+func (s *Service) DeleteFormCapacity(ctx context.Context, formID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	return s.Database.Collection(FORM_CAPACITY_COLLECTION).DeleteOne(ctx, bson.M{"formID": formID})
+}
+
+func (s *Service) CreatePipelineRun(ctx context.Context, pipelineRun models.PipelineRun) (*mongo.InsertOneResult, error) {
+	return s.Database.Collection("pipeline_runs").InsertOne(ctx, pipelineRun)
+}
+
+func (s *Service) GetPipelineRun(ctx context.Context, filter bson.M) (*models.PipelineRun, error) {
+	var pipelineRun models.PipelineRun
+	err := s.Database.Collection("pipeline_runs").FindOne(ctx, filter).Decode(&pipelineRun)
+	if err != nil {
+		return nil, err
+	}
+	return &pipelineRun, nil
+}
+
+func (s *Service) UpdatePipelineRun(ctx context.Context, pipelineRun models.PipelineRun, pipelineRunID primitive.ObjectID) (*mongo.UpdateResult, error) {
+	u, err := utils.StructToBsonM(pipelineRun)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanUpdatePayload := RemoveNonOverridableFields(u, pipelineRun)
+
+	update := bson.M{"$set": cleanUpdatePayload}
+	filter := bson.M{"_id": pipelineRunID}
+	return s.Database.Collection("pipeline_runs").UpdateOne(ctx, filter, update)
+}
+
+func (s *Service) ListPipelineRuns(ctx context.Context, filter bson.M, options *options.FindOptions) ([]models.PipelineRun, error) {
+	var pipelineRuns []models.PipelineRun
+
+	cursor, err := s.Database.Collection("pipeline_runs").Find(ctx, filter, options)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var pipelineRun models.PipelineRun
+		if err := cursor.Decode(&pipelineRun); err != nil {
+			return nil, err
+		}
+
+		pipelineRuns = append(pipelineRuns, pipelineRun)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	// If pipelineRuns is null then return an empty slice instead
+	if pipelineRuns == nil {
+		return []models.PipelineRun{}, nil
+	}
+
+	return pipelineRuns, nil
+}
+
+// ListEmailTemplates retrieves email templates based on a filter
+func (s *Service) ListEmailTemplates(ctx context.Context, filter bson.M) ([]models.EmailTemplate, error) {
+	var emailTemplates []models.EmailTemplate
+
+	cursor, err := s.Database.Collection("email_templates").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var emailTemplate models.EmailTemplate
+		if err := cursor.Decode(&emailTemplate); err != nil {
+			return nil, err
+		}
+
+		emailTemplates = append(emailTemplates, emailTemplate)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	// If emailTemplates is null then return an empty slice instead
+	if emailTemplates == nil {
+		return []models.EmailTemplate{}, nil
+	}
+
+	return emailTemplates, nil
+}
+
+// CreateEmailTemplate creates a new email template
+func (s *Service) CreateEmailTemplate(ctx context.Context, emailTemplate models.EmailTemplate) (*mongo.InsertOneResult, error) {
+	return s.Database.Collection("email_templates").InsertOne(ctx, emailTemplate)
+}
+
+// UpdateEmailTemplate updates an email template by its ID
+func (s *Service) UpdateEmailTemplate(ctx context.Context, emailTemplate models.EmailTemplate, emailTemplateID primitive.ObjectID) (*mongo.UpdateResult, error) {
+	u, err := utils.StructToBsonM(emailTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanUpdatePayload := RemoveNonOverridableFields(u, emailTemplate)
+
+	update := bson.M{"$set": cleanUpdatePayload}
+	filter := bson.M{"_id": emailTemplateID}
+	return s.Database.Collection("email_templates").UpdateOne(ctx, filter, update)
+}
+
+// DeleteEmailTemplate deletes an email template by its ID
+func (s *Service) DeleteEmailTemplate(ctx context.Context, emailTemplateID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	return s.Database.Collection("email_templates").DeleteOne(ctx, bson.M{"_id": emailTemplateID})
+}
+
+// GetEmailTemplate retrieves an email template by its ID
+func (s *Service) GetEmailTemplate(ctx context.Context, emailTemplateID primitive.ObjectID) (*models.EmailTemplate, error) {
+	var emailTemplate models.EmailTemplate
+
+	err := s.Database.Collection("email_templates").FindOne(ctx, bson.M{"_id": emailTemplateID}).Decode(&emailTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &emailTemplate, nil
+}
+
+// storedEventSecrets is how event secrets are stored, all of an event's secrets are sealed together with a data key of their own.
+// Secrets stored before they were encrypted have no envelope and are read as they are until RotateEventSecretsKey encrypts them
+type storedEventSecrets struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"`
+	EventID primitive.ObjectID `bson:"eventID"`
+	Sealed  *secrets.Envelope  `bson:"sealed,omitempty"`
+}
+
+// openEventSecrets decrypts the stored document
+func (s *Service) openEventSecrets(ctx context.Context, raw bson.Raw) (*models.EventSecrets, error) {
+	var stored storedEventSecrets
+	if err := bson.Unmarshal(raw, &stored); err != nil {
+		return nil, err
+	}
+
+	var data models.EventSecrets
+	if stored.Sealed == nil {
+		if err := bson.Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	}
+
+	plaintext, err := secrets.Open(ctx, s.Keys, stored.Sealed, []byte(stored.EventID.Hex()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt event secrets: %w", err)
+	}
+
+	if err := bson.Unmarshal(plaintext, &data); err != nil {
+		return nil, err
+	}
+	data.EventID = stored.EventID
+	return &data, nil
+}
+
+// sealEventSecrets encrypts the secrets for storing, the event ID is the associated data so they can't be moved to another event
+func (s *Service) sealEventSecrets(ctx context.Context, data models.EventSecrets) (*storedEventSecrets, error) {
+	plaintext, err := bson.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := secrets.Seal(ctx, s.Keys, plaintext, []byte(data.EventID.Hex()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &storedEventSecrets{EventID: data.EventID, Sealed: sealed}, nil
+}
+
+// GetEventSecret retrieves secrets based on a filter
+func (s *Service) GetEventSecrets(ctx context.Context, filter bson.M, stripSecrets bool) (*models.EventSecrets, error) {
+	var raw bson.Raw
+	err := s.Database.Collection("event_secrets").FindOne(ctx, filter).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.openEventSecrets(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if stripSecrets {
+		if data.Email != nil {
+			stripped := data.Email.StripSecret()
+			if strippedEmail, ok := stripped.(*models.EmailSecret); ok {
+				data.Email = strippedEmail
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// CreateOrUpdateEventSecrets creates a new event secret, or replaces the secret types set in it. They are stored encrypted
+func (s *Service) CreateOrUpdateEventSecrets(ctx context.Context, newSecret models.EventSecrets) (*mongo.UpdateResult, error) {
+	filter := bson.M{"eventID": newSecret.EventID}
+
+	result, err := s.withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		existingSecret, err := s.GetEventSecrets(sessCtx, filter, false)
+		if err == mongo.ErrNoDocuments {
+			existingSecret = &models.EventSecrets{EventID: newSecret.EventID}
+		} else if err != nil {
+			return nil, err
+		}
+
+		// Replace the secret types that are set in newSecret and changed
+		merged := reflect.ValueOf(existingSecret).Elem()
+		val := reflect.ValueOf(newSecret)
+		changed := false
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Field(i)
+
+			// Check if the field is a pointer to a struct and not nil
+			if field.Kind() == reflect.Ptr && !field.IsNil() {
+				// If the fields are not deeply equal, replace the existing one
+				if !reflect.DeepEqual(field.Interface(), merged.Field(i).Interface()) {
+					merged.Field(i).Set(field)
+					changed = true
+				}
+			}
+		}
+
+		if !changed {
+			// No updates necessary
+			return &mongo.UpdateResult{}, nil
+		}
+
+		stored, err := s.sealEventSecrets(sessCtx, *existingSecret)
+		if err != nil {
+			return nil, err
+		}
+
+		// Replacing drops the plaintext fields of secrets stored before they were encrypted
+		opts := options.Replace().SetUpsert(true)
+		return s.Database.Collection("event_secrets").ReplaceOne(sessCtx, filter, stored, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*mongo.UpdateResult), nil
+}
+
+// RotateEventSecretsKey re-wraps the data keys of event secrets that aren't wrapped with the active master key, and encrypts secrets stored
+// before they were encrypted. Once it's done the old master keys can be removed. It returns how many event's secrets were rewritten
+func (s *Service) RotateEventSecretsKey(ctx context.Context) (int, error) {
+	if s.Keys == nil {
+		return 0, secrets.ErrNoMasterKey
+	}
+
+	collection := s.Database.Collection("event_secrets")
+	filter := bson.M{"sealed.keyID": bson.M{"$ne": s.Keys.ActiveKeyID()}}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	rotated := 0
+	for cursor.Next(ctx) {
+		var stored storedEventSecrets
+		if err := cursor.Decode(&stored); err != nil {
+			return rotated, err
+		}
+
+		if stored.Sealed == nil {
+			data, err := s.openEventSecrets(ctx, cursor.Current)
+			if err != nil {
+				return rotated, err
+			}
+
+			sealed, err := s.sealEventSecrets(ctx, *data)
+			if err != nil {
+				return rotated, err
+			}
+
+			// Only replaced if it still hasn't been encrypted, an update in the meantime already did
+			replaceFilter := bson.M{"_id": stored.ID, "sealed": bson.M{"$exists": false}}
+			if _, err := collection.ReplaceOne(ctx, replaceFilter, sealed); err != nil {
+				return rotated, err
+			}
+		} else {
+			sealed, _, err := secrets.Rewrap(ctx, s.Keys, stored.Sealed)
+			if err != nil {
+				return rotated, fmt.Errorf("failed to rotate the secrets of event %s: %w", stored.EventID.Hex(), err)
+			}
+
+			// Only the data key changes, an update in the meantime sealed it with a new one under the active key anyway
+			updateFilter := bson.M{"_id": stored.ID, "sealed.wrappedKey": stored.Sealed.WrappedKey}
+			if _, err := collection.UpdateOne(ctx, updateFilter, bson.M{"$set": bson.M{"sealed": sealed}}); err != nil {
+				return rotated, err
+			}
+		}
+
+		rotated++
+	}
+
+	return rotated, cursor.Err()
+}
+
+// RotateTwoFactorSecretsKey re-wraps the data keys of authenticator secrets that aren't wrapped with the active master key, and encrypts
+// secrets stored before they were encrypted. It returns how many users' secrets were rewritten
+func (s *Service) RotateTwoFactorSecretsKey(ctx context.Context) (int, error) {
+	if s.Keys == nil {
+		return 0, secrets.ErrNoMasterKey
+	}
+
+	collection := s.Database.Collection("users")
+	filter := bson.M{"twoFactor": bson.M{"$exists": true}, "twoFactor.sealedSecret.keyID": bson.M{"$ne": s.Keys.ActiveKeyID()}}
+	opts := options.Find().SetProjection(bson.M{"twoFactor": 1})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	rotated := 0
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return rotated, err
+		}
+
+		var updateFilter, update bson.M
+		if user.TwoFactor.SealedSecret == nil {
+			sealed, err := secrets.Seal(ctx, s.Keys, []byte(user.TwoFactor.Secret), []byte(user.ID.Hex()))
+			if err != nil {
+				return rotated, err
+			}
+
+			// Only updated if it still has the same plaintext secret, setting up a new authenticator in the meantime already encrypted it
+			updateFilter = bson.M{"_id": user.ID, "twoFactor.secret": user.TwoFactor.Secret}
+			update = bson.M{"$set": bson.M{"twoFactor.sealedSecret": sealed}, "$unset": bson.M{"twoFactor.secret": ""}}
+		} else {
+			sealed, _, err := secrets.Rewrap(ctx, s.Keys, user.TwoFactor.SealedSecret)
+			if err != nil {
+				return rotated, fmt.Errorf("failed to rotate the two-factor secret of user %s: %w", user.ID.Hex(), err)
+			}
+
+			updateFilter = bson.M{"_id": user.ID, "twoFactor.sealedSecret.wrappedKey": user.TwoFactor.SealedSecret.WrappedKey}
+			update = bson.M{"$set": bson.M{"twoFactor.sealedSecret": sealed}}
+		}
+
+		if _, err := collection.UpdateOne(ctx, updateFilter, update); err != nil {
+			return rotated, err
+		}
+
+		rotated++
+	}
+
+	return rotated, cursor.Err()
+}
+
+// DeleteEventSecrets
+func (s *Service) DeleteEventSecrets(ctx context.Context, eventID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	filter := bson.M{"eventID": eventID}
+	return s.Database.Collection("event_secrets").DeleteOne(ctx, filter)
+}
+
+/*
+* SETUP
+*
+ */
+
+// CreateIndexes creates the indexes the application relies on, it is safe to call on every startup
+func (s *Service) CreateIndexes(ctx context.Context) error {
+	indexes := map[string][]mongo.IndexModel{
+		"users": {
+			{Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}}},
+		},
+		RESPONSE_DRAFT_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "formID", Value: 1}, {Key: "userID", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		PENDING_RESPONSE_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "formID", Value: 1}}},
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		PASSWORD_RESET_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "userID", Value: 1}}},
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		SESSION_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "refreshTokenHash", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "previousRefreshTokenHash", Value: 1}}},
+			{Keys: bson.D{{Key: "userID", Value: 1}}},
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		AUDIT_LOG_COLLECTION: {
+			{Keys: bson.D{{Key: "eventID", Value: 1}, {Key: "createdAt", Value: -1}}},
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		API_TOKEN_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "userID", Value: 1}}},
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		ORGANIZER_INVITE_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "eventID", Value: 1}, {Key: "email", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(int32(organizerInviteRetention.Seconds())),
+			},
+		},
+		PROOF_OF_WORK_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		RATE_LIMIT_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		LOGIN_THROTTLE_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		FILE_UPLOAD_COLLECTION: {
+			{Keys: bson.D{{Key: "formID", Value: 1}, {Key: "responseID", Value: 1}}},
+			// Finds the uploads that were never attached to a response, to delete them
+			{Keys: bson.D{{Key: "responseID", Value: 1}, {Key: "createdAt", Value: 1}}},
+		},
+		FORM_VERSION_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "formID", Value: 1}, {Key: "version", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		RESPONSE_HISTORY_COLLECTION: {
+			{Keys: bson.D{{Key: "formID", Value: 1}, {Key: "responseID", Value: 1}, {Key: "createdAt", Value: -1}}},
+		},
+		BULK_JOB_COLLECTION: {
+			{Keys: bson.D{{Key: "formID", Value: 1}, {Key: "createdAt", Value: -1}}},
+			// Runners claim the oldest job nobody holds
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "leaseExpiresAt", Value: 1}, {Key: "createdAt", Value: 1}}},
+			{
+				// Unfinished jobs have no finishedAt, so they are never removed
+				Keys:    bson.D{{Key: "finishedAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(int32(bulkJobRetention.Seconds())),
+			},
+		},
+		REVIEW_RUBRIC_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "formID", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		REVIEW_ASSIGNMENT_COLLECTION: {
+			{Keys: bson.D{{Key: "formID", Value: 1}, {Key: "reviewerID", Value: 1}}},
+		},
+		REVIEW_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "responseID", Value: 1}, {Key: "reviewerID", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "formID", Value: 1}}},
+		},
+		RESPONSE_CLAIM_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "formID", Value: 1}, {Key: "key", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "responseID", Value: 1}}},
+		},
+		FORM_SUBMISSION_LOCK_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "formID", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		FORM_CAPACITY_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "formID", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		"responses": {
+			{Keys: bson.D{{Key: "formID", Value: 1}, {Key: "admission.status", Value: 1}, {Key: "admission.waitlistedAt", Value: 1}}},
+			{Keys: bson.D{{Key: "admission.status", Value: 1}, {Key: "admission.rsvpDeadline", Value: 1}}},
+			{Keys: bson.D{{Key: "formID", Value: 1}, {Key: "userID", Value: 1}}},
+			// Response queries, sorting and filtering on any form field is served by the wildcard index
+			{Keys: bson.D{{Key: "formID", Value: 1}, {Key: "createdAt", Value: -1}}},
+			{Keys: bson.D{{Key: "data.$**", Value: 1}}},
+			{
+				Keys:    bson.D{{Key: "formID", Value: 1}, {Key: "$**", Value: "text"}},
+				Options: options.Index().SetName("response_text_search"),
+			},
+		},
+	}
+
+	for collection, indexModels := range indexes {
+		if _, err := s.Database.Collection(collection).Indexes().CreateMany(ctx, indexModels); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+* BILLING
+*
+ */
+
+const (
+	PLAN_COLLECTION         = "plans"
+	SUBSCRIPTION_COLLECTION = "subscriptions"
+)
+
+var (
+	ERR_PLAN_NOT_FOUND_OR_LIMIT_EXCEEDED = errors.New("no active subscription found with ID, or limit exceeded")
+)
+
+// SeedPlans seeds the plans collection with the default plans, if they don't already exist
+func (s *Service) SeedPlans(ctx context.Context) error {
+	initialPlans := []models.Plan{
+		{
+			Name: "Free",
+			BillingCycleOptions: models.PlanCycleOptions{
+				Cycle: "monthly",
+				Price: 0.0,
 			},
 			Limits: models.PlanLimits{
 				MaxEvents:              1,
@@ -923,18 +3112,24 @@ func (s *Service) GetSubscription(ctx context.Context, subscriptionID primitive.
 }
 
 func (s *Service) IncrementSubscriptionUtilization(ctx context.Context, subscriptionID primitive.ObjectID, utilizationKey string, limitKey string) (*mongo.UpdateResult, error) {
+	return s.AddSubscriptionUtilization(ctx, subscriptionID, utilizationKey, limitKey, 1)
+}
+
+// AddSubscriptionUtilization counts amount uses against the subscription's limit all at once, none are counted if they don't all fit.
+// A negative amount gives back uses that were counted for something that then failed
+func (s *Service) AddSubscriptionUtilization(ctx context.Context, subscriptionID primitive.ObjectID, utilizationKey string, limitKey string, amount int) (*mongo.UpdateResult, error) {
 	collection := s.Database.Collection(SUBSCRIPTION_COLLECTION)
 
 	utilizationField := "utilization." + utilizationKey
 	limitField := "limits." + limitKey
 
 	update := bson.M{
-		"$inc": bson.M{utilizationField: 1},
+		"$inc": bson.M{utilizationField: amount},
 	}
 
 	condition := bson.M{
 		"$lte": []interface{}{
-			bson.M{"$add": []interface{}{"$" + utilizationField, 1}},
+			bson.M{"$add": []interface{}{"$" + utilizationField, amount}},
 			"$" + limitField,
 		},
 	}
@@ -0,0 +1,49 @@
+package mongodb
+
+import (
+	"context"
+	"shared/models"
+	"shared/secrets"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTwoFactorSecretIsStoredEncrypted(t *testing.T) {
+	ctx := context.Background()
+	keys, err := secrets.NewKeyring("test", "a", map[string][]byte{"a": make([]byte, 32)})
+	require.NoError(t, err)
+	s := &Service{Keys: keys}
+
+	user := models.User{ID: primitive.NewObjectID()}
+	sealed, err := secrets.Seal(ctx, keys, []byte("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"), []byte(user.ID.Hex()))
+	require.NoError(t, err)
+	user.TwoFactor = &models.TwoFactor{SealedSecret: sealed}
+
+	raw, err := bson.Marshal(user)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "GEZDGNBVGY3TQOJQ")
+
+	secret, err := s.OpenTwoFactorSecret(ctx, &user)
+	require.NoError(t, err)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", secret)
+
+	// The sealed secret can't be copied to another user
+	other := models.User{ID: primitive.NewObjectID(), TwoFactor: user.TwoFactor}
+	_, err = s.OpenTwoFactorSecret(ctx, &other)
+	assert.Error(t, err)
+
+	_, err = (&Service{}).OpenTwoFactorSecret(ctx, &user)
+	assert.ErrorIs(t, err, secrets.ErrNoMasterKey)
+}
+
+func TestLegacyTwoFactorSecretIsStillRead(t *testing.T) {
+	user := models.User{ID: primitive.NewObjectID(), TwoFactor: &models.TwoFactor{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}}
+
+	secret, err := (&Service{}).OpenTwoFactorSecret(context.Background(), &user)
+	require.NoError(t, err)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", secret)
+}
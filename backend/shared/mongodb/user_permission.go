@@ -1,7 +1,8 @@
-// This package is for helper functions checking if a user can modify a document.
+// This package is for helper functions checking what a user is allowed to do on a document.
 package mongodb
 
 import (
+	"context"
 	"shared/models"
 	"shared/utils"
 	"time"
@@ -10,8 +11,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// CanUserModifyPipeline checks if the user provided can modify a pipeline.
-func CanUserModifyPipeline(c *gin.Context, m MongoService, u *models.User, pipelineID primitive.ObjectID, pipelineObject *models.PipelineConfiguration) bool {
+// HasPipelinePermission checks if the user provided has a permission on the event of a pipeline.
+func HasPipelinePermission(c context.Context, m MongoService, u *models.User, pipelineID primitive.ObjectID, pipelineObject *models.PipelineConfiguration, permission models.Permission) bool {
 	if u == nil {
 		return false
 	}
@@ -24,11 +25,11 @@ func CanUserModifyPipeline(c *gin.Context, m MongoService, u *models.User, pipel
 		}
 		pipeline = p
 	}
-	return CanUserModifyEvent(c, m, u, pipeline.EventID, nil)
+	return HasEventPermission(c, m, u, pipeline.EventID, nil, permission)
 }
 
-// CanUserModifyEmailTemplate checks if the user provided can modify an email template.
-func CanUserModifyEmailTemplate(c *gin.Context, m MongoService, u *models.User, templateID primitive.ObjectID, template *models.EmailTemplate) bool {
+// HasEmailTemplatePermission checks if the user provided has a permission on the event of an email template.
+func HasEmailTemplatePermission(c context.Context, m MongoService, u *models.User, templateID primitive.ObjectID, template *models.EmailTemplate, permission models.Permission) bool {
 	if u == nil {
 		return false
 	}
@@ -41,11 +42,11 @@ func CanUserModifyEmailTemplate(c *gin.Context, m MongoService, u *models.User,
 		}
 		emailTemplate = t
 	}
-	return CanUserModifyEvent(c, m, u, emailTemplate.EventID, nil)
+	return HasEventPermission(c, m, u, emailTemplate.EventID, nil, permission)
 }
 
-// CanUserModifyForm checks if the user provided can modify a form.
-func CanUserModifyForm(c *gin.Context, m MongoService, u *models.User, formID primitive.ObjectID, formObject *models.FormStructure) bool {
+// HasFormPermission checks if the user provided has a permission on the event of a form.
+func HasFormPermission(c context.Context, m MongoService, u *models.User, formID primitive.ObjectID, formObject *models.FormStructure, permission models.Permission) bool {
 	if u == nil {
 		return false
 	}
@@ -60,19 +61,20 @@ func CanUserModifyForm(c *gin.Context, m MongoService, u *models.User, formID pr
 		form = f
 	}
 
-	return CanUserModifyEvent(c, m, u, form.EventID, nil)
+	return HasEventPermission(c, m, u, form.EventID, nil, permission)
 }
 
-// CanUserModify event checks if the given user and event can manipulate an event
+// HasEventPermission checks if the given user is an organizer of the event whose role grants the permission,
+// and that they signed in with a second factor if the event requires it.
 // If eventObject is nil, we will retrieve a new object from mongo, otherwise we use it.
-func CanUserModifyEvent(c *gin.Context, m MongoService, u *models.User, eventID primitive.ObjectID, eventObject *models.Event) bool {
+func HasEventPermission(c context.Context, m MongoService, u *models.User, eventID primitive.ObjectID, eventObject *models.Event, permission models.Permission) bool {
 	if u == nil {
 		return false
 	}
 
 	event := eventObject
 	if event == nil {
-		e, err := m.GetEvent(c, eventID)
+		e, err := m.FindEvent(c, eventID)
 		if err != nil {
 			return false
 		}
@@ -80,26 +82,40 @@ func CanUserModifyEvent(c *gin.Context, m MongoService, u *models.User, eventID
 		event = e
 	}
 
-	var found = false
-	for _, organizerID := range event.OrganizerIDs {
-		if organizerID == u.ID {
-			found = true
-			break
-		}
+	role, ok := event.OrganizerRole(u.ID)
+	return ok && role.Can(permission) && !MissingTwoFactor(c, event)
+}
+
+// MissingTwoFactor checks if the event requires organizers to sign in with a second factor and the request's session or API token didn't
+func MissingTwoFactor(c context.Context, event *models.Event) bool {
+	if !event.RequireTwoFactor {
+		return false
 	}
 
-	return found
+	ginCtx, ok := c.(*gin.Context)
+	return !ok || !utils.IsTwoFactorVerified(ginCtx)
 }
 
-func IsUserEmailInWhitelist(c *gin.Context, whitelist []models.FormAllowedSubmitter) (bool, string) {
+// IsUserEmailInWhitelist checks the user's verified email is allowed to submit a restricted form, it returns why when it isn't
+func IsUserEmailInWhitelist(c *gin.Context, m MongoService, whitelist []models.FormAllowedSubmitter) (bool, string) {
 	authenticatedUser, ok := utils.GetUserFromContext(c, true)
 	if !ok {
 		return false, "You must be logged in to view this form"
 	}
 
+	// Anyone can register with any email, so it only counts once the user has shown they own it
+	user, err := m.GetUserDetails(c, authenticatedUser.ID)
+	if err != nil {
+		return false, "You are not authorized to view this form"
+	}
+
+	if user.EmailVerifiedAt == nil {
+		return false, "Please verify your email address to access this form"
+	}
+
 	hasExpired := false
 	for _, allowedSubmitter := range whitelist {
-		if allowedSubmitter.Email == authenticatedUser.Email {
+		if allowedSubmitter.Email == user.Email {
 			if allowedSubmitter.ExpiresAt.IsZero() || allowedSubmitter.ExpiresAt.After(time.Now()) {
 				return true, ""
 			} else {
@@ -0,0 +1,74 @@
+package mongodb
+
+import (
+	"context"
+	"shared/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// whitelistMongo holds a single user, only the user lookup of the service is implemented
+type whitelistMongo struct {
+	MongoService
+	user models.User
+}
+
+func (m *whitelistMongo) GetUserDetails(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	user := m.user
+	return &user, nil
+}
+
+func TestIsUserEmailInWhitelist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verifiedAt := time.Now()
+	user := models.User{ID: primitive.NewObjectID(), Email: "applicant@example.com", EmailVerifiedAt: &verifiedAt}
+	whitelist := []models.FormAllowedSubmitter{
+		{Email: "applicant@example.com"},
+		{Email: "expired@example.com", ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+
+	check := func(user models.User) (bool, string) {
+		c, _ := gin.CreateTestContext(nil)
+		c.Set("user", &models.User{ID: user.ID, Email: user.Email})
+		return IsUserEmailInWhitelist(c, &whitelistMongo{user: user}, whitelist)
+	}
+
+	allowed, _ := check(user)
+	assert.True(t, allowed)
+
+	unverified := user
+	unverified.EmailVerifiedAt = nil
+	allowed, message := check(unverified)
+	assert.False(t, allowed)
+	assert.Equal(t, "Please verify your email address to access this form", message)
+
+	expired := user
+	expired.Email = "expired@example.com"
+	allowed, message = check(expired)
+	assert.False(t, allowed)
+	assert.Equal(t, "Your access to this form has expired", message)
+
+	other := user
+	other.Email = "other@example.com"
+	allowed, _ = check(other)
+	assert.False(t, allowed)
+}
+
+func TestHasEventPermissionRequiresTwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	organizer := &models.User{ID: primitive.NewObjectID()}
+	event := &models.Event{ID: primitive.NewObjectID(), CreatedByID: organizer.ID, OrganizerIDs: []primitive.ObjectID{organizer.ID}, RequireTwoFactor: true}
+
+	c, _ := gin.CreateTestContext(nil)
+	assert.False(t, HasEventPermission(c, nil, organizer, event.ID, event, models.PermissionEditForms))
+	assert.False(t, HasEventPermission(context.Background(), nil, organizer, event.ID, event, models.PermissionEditForms), "outside a request nobody signed in with a second factor")
+
+	c.Set("twoFactorVerified", true)
+	assert.True(t, HasEventPermission(c, nil, organizer, event.ID, event, models.PermissionEditForms))
+}
@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Keyring is a KeyProvider holding the master keys itself, from the environment or a file.
+// Data keys are wrapped with AES-GCM, with the master key's ID as associated data
+type Keyring struct {
+	source      string
+	activeKeyID string
+	keys        map[string][]byte
+}
+
+// NewKeyring creates a keyring with the master keys by their IDs, each has to be 32 bytes
+func NewKeyring(source string, activeKeyID string, keys map[string][]byte) (*Keyring, error) {
+	for id, key := range keys {
+		if len(key) != dataKeySize {
+			return nil, fmt.Errorf("master key %q must be %d bytes", id, dataKeySize)
+		}
+	}
+
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("the active master key %q isn't one of the keys", activeKeyID)
+	}
+
+	return &Keyring{source: source, activeKeyID: activeKeyID, keys: keys}, nil
+}
+
+// ParseMasterKeys parses a comma-separated list of id:base64 keys, it also returns the first key's ID
+func ParseMasterKeys(list string) (map[string][]byte, string, error) {
+	keys := map[string][]byte{}
+	firstKeyID := ""
+	for _, entry := range strings.Split(list, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, "", errors.New("master keys must be formatted as id:base64")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", fmt.Errorf("master key %q isn't valid base64: %w", id, err)
+		}
+
+		if _, ok := keys[id]; ok {
+			return nil, "", fmt.Errorf("master key %q is listed twice", id)
+		}
+		keys[id] = key
+
+		if firstKeyID == "" {
+			firstKeyID = id
+		}
+	}
+
+	return keys, firstKeyID, nil
+}
+
+// keyringFile is the JSON a keyring file holds, the keys are base64
+type keyringFile struct {
+	ActiveKeyID string            `json:"activeKeyID"`
+	Keys        map[string]string `json:"keys"`
+}
+
+// LoadKeyringFile reads a keyring from a JSON file, eg: {"activeKeyID": "2024-06", "keys": {"2024-06": "<base64>", "2023-01": "<base64>"}}
+func LoadKeyringFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid keyring file: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q isn't valid base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	return NewKeyring("file", file.ActiveKeyID, keys)
+}
+
+func (k *Keyring) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	nonce, ciphertext, err := encrypt(k.keys[k.activeKeyID], dataKey, []byte(k.activeKeyID))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return k.activeKeyID, append(nonce, ciphertext...), nil
+}
+
+func (k *Keyring) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, errors.New("invalid wrapped key")
+	}
+
+	return gcm.Open(nil, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():], []byte(keyID))
+}
+
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeKeyID
+}
+
+func (k *Keyring) GetType() string {
+	return k.source
+}
@@ -0,0 +1,175 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"shared/config"
+)
+
+/*
+Secrets are stored with envelope encryption. Every record is encrypted with a data key of its own, and only the data key wrapped with a
+master key is stored beside it. The master keys never leave their KeyProvider, so rotating one only means re-wrapping the data keys.
+*/
+
+var (
+	// ErrNoMasterKey is returned when secrets are used without a master key configured
+	ErrNoMasterKey = errors.New("no master key is configured for secrets, set SECRETS_MASTER_KEYS")
+
+	// ErrUnknownKey is returned when a data key was wrapped with a master key the provider doesn't have
+	ErrUnknownKey = errors.New("unknown master key")
+)
+
+const dataKeySize = 32 // AES-256
+
+// KeyProvider holds the master keys, like a KMS would. It wraps data keys with its active master key and unwraps them with any key it has
+type KeyProvider interface {
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+	// ActiveKeyID is the master key WrapKey uses, data keys wrapped with other keys are re-wrapped with it when rotating
+	ActiveKeyID() string
+	GetType() string
+}
+
+// Envelope is a record encrypted with its own data key, stored with the data key wrapped with a master key
+type Envelope struct {
+	KeyID      string `bson:"keyID" json:"keyID"` // the master key the data key is wrapped with
+	WrappedKey []byte `bson:"wrappedKey" json:"wrappedKey"`
+	Nonce      []byte `bson:"nonce" json:"nonce"`
+	Ciphertext []byte `bson:"ciphertext" json:"ciphertext"`
+}
+
+// NewKeyProvider creates the key provider selected in the secrets config, it returns ErrNoMasterKey if there are no keys configured
+func NewKeyProvider() (KeyProvider, error) {
+	cfg, err := config.GetSecretsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.SECRETS_KEY_PROVIDER {
+	case "env":
+		if cfg.SECRETS_MASTER_KEYS == "" {
+			return nil, ErrNoMasterKey
+		}
+
+		keys, firstKeyID, err := ParseMasterKeys(cfg.SECRETS_MASTER_KEYS)
+		if err != nil {
+			return nil, err
+		}
+
+		activeKeyID := cfg.SECRETS_MASTER_KEY_ID
+		if activeKeyID == "" {
+			activeKeyID = firstKeyID
+		}
+		return NewKeyring("env", activeKeyID, keys)
+	case "file":
+		if cfg.SECRETS_KEY_FILE == "" {
+			return nil, errors.New("SECRETS_KEY_FILE is required for the file key provider")
+		}
+		return LoadKeyringFile(cfg.SECRETS_KEY_FILE)
+	default:
+		return nil, errors.New("invalid secrets key provider specified")
+	}
+}
+
+// Seal encrypts the plaintext with a new data key. The associated data isn't stored but has to be the same to open it,
+// it ties the envelope to its record so it can't be copied to another
+func Seal(ctx context.Context, keys KeyProvider, plaintext []byte, associatedData []byte) (*Envelope, error) {
+	if keys == nil {
+		return nil, ErrNoMasterKey
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	nonce, ciphertext, err := encrypt(dataKey, plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, wrapped, err := keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	return &Envelope{KeyID: keyID, WrappedKey: wrapped, Nonce: nonce, Ciphertext: ciphertext}, nil
+}
+
+// Open decrypts the envelope, with the same associated data it was sealed with
+func Open(ctx context.Context, keys KeyProvider, envelope *Envelope, associatedData []byte) ([]byte, error) {
+	if keys == nil {
+		return nil, ErrNoMasterKey
+	}
+
+	dataKey, err := keys.UnwrapKey(ctx, envelope.KeyID, envelope.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	return decrypt(dataKey, envelope.Nonce, envelope.Ciphertext, associatedData)
+}
+
+// Rewrap wraps the envelope's data key with the active master key, the ciphertext stays the same.
+// It returns false if it was already wrapped with the active key
+func Rewrap(ctx context.Context, keys KeyProvider, envelope *Envelope) (*Envelope, bool, error) {
+	if keys == nil {
+		return nil, false, ErrNoMasterKey
+	}
+
+	if envelope.KeyID == keys.ActiveKeyID() {
+		return envelope, false, nil
+	}
+
+	dataKey, err := keys.UnwrapKey(ctx, envelope.KeyID, envelope.WrappedKey)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	keyID, wrapped, err := keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	return &Envelope{KeyID: keyID, WrappedKey: wrapped, Nonce: envelope.Nonce, Ciphertext: envelope.Ciphertext}, true, nil
+}
+
+// encrypt encrypts with AES-GCM under a random nonce
+func encrypt(key []byte, plaintext []byte, associatedData []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, associatedData), nil
+}
+
+func decrypt(key []byte, nonce []byte, ciphertext []byte, associatedData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+
+	return gcm.Open(nil, nonce, ciphertext, associatedData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
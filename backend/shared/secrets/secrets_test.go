@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, dataKeySize)
+}
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+	keys, err := NewKeyring("test", "a", map[string][]byte{"a": testKey(1)})
+	require.NoError(t, err)
+
+	envelope, err := Seal(ctx, keys, []byte("hunter2"), []byte("event-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", envelope.KeyID)
+	assert.NotContains(t, string(envelope.Ciphertext), "hunter2")
+
+	plaintext, err := Open(ctx, keys, envelope, []byte("event-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(plaintext))
+
+	_, err = Open(ctx, keys, envelope, []byte("event-2"))
+	assert.Error(t, err, "an envelope copied to another record")
+
+	again, err := Seal(ctx, keys, []byte("hunter2"), []byte("event-1"))
+	require.NoError(t, err)
+	assert.NotEqual(t, envelope.WrappedKey, again.WrappedKey, "every record has its own data key")
+
+	_, err = Seal(ctx, nil, []byte("hunter2"), nil)
+	assert.ErrorIs(t, err, ErrNoMasterKey)
+}
+
+func TestRewrap(t *testing.T) {
+	ctx := context.Background()
+	old, err := NewKeyring("test", "old", map[string][]byte{"old": testKey(1)})
+	require.NoError(t, err)
+
+	envelope, err := Seal(ctx, old, []byte("hunter2"), nil)
+	require.NoError(t, err)
+
+	// The new key is made active, the old one is kept until everything is rotated away from it
+	rotated, err := NewKeyring("test", "new", map[string][]byte{"old": testKey(1), "new": testKey(2)})
+	require.NoError(t, err)
+
+	rewrapped, changed, err := Rewrap(ctx, rotated, envelope)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "new", rewrapped.KeyID)
+	assert.Equal(t, envelope.Ciphertext, rewrapped.Ciphertext)
+
+	_, changed, err = Rewrap(ctx, rotated, rewrapped)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	retired, err := NewKeyring("test", "new", map[string][]byte{"new": testKey(2)})
+	require.NoError(t, err)
+
+	plaintext, err := Open(ctx, retired, rewrapped, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(plaintext))
+
+	_, err = Open(ctx, retired, envelope, nil)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestParseMasterKeys(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testKey(1))
+
+	keys, first, err := ParseMasterKeys("b:" + encoded + ", a:" + encoded)
+	require.NoError(t, err)
+	assert.Equal(t, "b", first)
+	assert.Len(t, keys, 2)
+
+	_, _, err = ParseMasterKeys(encoded)
+	assert.Error(t, err, "a key without an ID")
+
+	_, _, err = ParseMasterKeys("a:" + encoded + ",a:" + encoded)
+	assert.Error(t, err, "a key listed twice")
+
+	_, err = NewKeyring("test", "a", map[string][]byte{"a": []byte("short")})
+	assert.Error(t, err)
+
+	_, err = NewKeyring("test", "missing", map[string][]byte{"a": testKey(1)})
+	assert.Error(t, err)
+}
+
+func TestLoadKeyringFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	encoded := base64.StdEncoding.EncodeToString(testKey(3))
+	require.NoError(t, os.WriteFile(path, []byte(`{"activeKeyID": "2024", "keys": {"2024": "`+encoded+`"}}`), 0600))
+
+	keys, err := LoadKeyringFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "2024", keys.ActiveKeyID())
+	assert.Equal(t, "file", keys.GetType())
+
+	envelope, err := Seal(context.Background(), keys, []byte("hunter2"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "2024", envelope.KeyID)
+}
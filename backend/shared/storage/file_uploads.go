@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"shared/logger"
+	"shared/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DeleteFileUploads removes the uploads matching the filter from object storage and the database.
+// Objects that fail to delete are logged and skipped so a single bad object doesn't block the cleanup.
+func DeleteFileUploads(c context.Context, objectStorage ObjectStorage, mongo mongodb.MongoService, filter bson.M) error {
+	uploads, err := mongo.ListFileUploads(c, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, upload := range uploads {
+		if err := objectStorage.Delete(c, upload.StorageKey); err != nil {
+			logger.Error("Failed to delete uploaded file from object storage", err)
+		}
+	}
+
+	if len(uploads) == 0 {
+		return nil
+	}
+
+	_, err = mongo.DeleteFileUploads(c, filter)
+	return err
+}
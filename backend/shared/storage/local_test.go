@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStoragePutGetDelete(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir(), "http://localhost/files/local", []byte("secret"))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	err = store.Put(ctx, "forms/a/b/c", strings.NewReader("hello"), 5, "text/plain")
+	assert.NoError(t, err)
+
+	body, err := store.Get(ctx, "forms/a/b/c")
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "hello", string(content))
+
+	assert.NoError(t, store.Delete(ctx, "forms/a/b/c"))
+	_, err = store.Get(ctx, "forms/a/b/c")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	// Deleting a missing object is not an error
+	assert.NoError(t, store.Delete(ctx, "forms/a/b/c"))
+}
+
+func TestLocalStorageRejectsPathTraversal(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir(), "http://localhost/files/local", []byte("secret"))
+	assert.NoError(t, err)
+
+	cases := []string{"", "../escape", "forms/../../escape", "/../escape"}
+	for _, key := range cases {
+		t.Run(key, func(t *testing.T) {
+			err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain")
+			assert.ErrorIs(t, err, ErrInvalidObjectKey)
+		})
+	}
+}
+
+func TestLocalStorageSignedURL(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir(), "http://localhost/files/local", []byte("secret"))
+	assert.NoError(t, err)
+
+	signed, err := store.SignedURL(context.Background(), "forms/a/b/c", "resume.pdf", time.Minute)
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, "/files/local", parsed.Path)
+
+	key, fileName, err := store.VerifySignedURL(parsed.Query())
+	assert.NoError(t, err)
+	assert.Equal(t, "forms/a/b/c", key)
+	assert.Equal(t, "resume.pdf", fileName)
+
+	// Tampering with the key invalidates the signature
+	tampered := parsed.Query()
+	tampered.Set("key", "forms/a/b/d")
+	_, _, err = store.VerifySignedURL(tampered)
+	assert.Error(t, err)
+
+	// A different secret cannot verify the URL
+	other, _ := NewLocalStorage(t.TempDir(), "http://localhost/files/local", []byte("other"))
+	_, _, err = other.VerifySignedURL(parsed.Query())
+	assert.Error(t, err)
+
+	// Expired links are rejected
+	expired, err := store.SignedURL(context.Background(), "forms/a/b/c", "resume.pdf", -time.Minute)
+	assert.NoError(t, err)
+	parsed, _ = url.Parse(expired)
+	_, _, err = store.VerifySignedURL(parsed.Query())
+	assert.Error(t, err)
+}
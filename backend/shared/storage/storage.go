@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"shared/config"
+	"time"
+)
+
+var (
+	// ErrObjectNotFound is returned when the requested object does not exist in the storage backend
+	ErrObjectNotFound = errors.New("object not found")
+
+	// ErrInvalidObjectKey is returned when an object key would escape the storage location
+	ErrInvalidObjectKey = errors.New("invalid object key")
+)
+
+// ObjectStorage is the interface uploaded files are stored through
+type ObjectStorage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL anyone holding it can download the object from until it expires
+	SignedURL(ctx context.Context, key string, fileName string, expiresIn time.Duration) (string, error)
+	GetType() string
+}
+
+// NewObjectStorage creates the object storage backend selected in the API config
+func NewObjectStorage() (ObjectStorage, error) {
+	cfg, err := config.GetAPIConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.STORAGE_TYPE {
+	case "local":
+		secret := []byte(cfg.STORAGE_SIGNING_SECRET)
+		if len(secret) == 0 {
+			log.Println("[WARNING] STORAGE_SIGNING_SECRET is not set. Generating a random secret key, signed download URLs will not work across restarts or on multi-instance deployments.")
+			secret, err = generateRandomSecret(32)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return NewLocalStorage(cfg.STORAGE_LOCAL_PATH, cfg.API_PUBLIC_URL+"/files/local", secret)
+	case "s3":
+		if cfg.STORAGE_S3_BUCKET == "" {
+			return nil, errors.New("STORAGE_S3_BUCKET is required for s3 object storage")
+		}
+
+		if cfg.STORAGE_S3_REGION == "" {
+			return nil, errors.New("STORAGE_S3_REGION is required for s3 object storage")
+		}
+
+		return NewS3Storage(S3Options{
+			Bucket:          cfg.STORAGE_S3_BUCKET,
+			Region:          cfg.STORAGE_S3_REGION,
+			Endpoint:        cfg.STORAGE_S3_ENDPOINT,
+			ForcePathStyle:  cfg.STORAGE_S3_FORCE_PATH_STYLE,
+			AccessKeyID:     cfg.STORAGE_S3_ACCESS_KEY_ID,
+			SecretAccessKey: cfg.STORAGE_S3_SECRET_ACCESS_KEY,
+		})
+	default:
+		return nil, errors.New("invalid object storage type specified")
+	}
+}
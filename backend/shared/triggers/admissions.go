@@ -0,0 +1,49 @@
+package triggers
+
+import (
+	"context"
+	"shared/kafka/producer"
+	"shared/models"
+	"shared/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SweepAdmissions expires the lapsed offers of a form with a capacity and promotes the waitlist into the freed seats
+func SweepAdmissions(c context.Context, producer producer.MessageProducer, mongo mongodb.MongoService, form *models.FormStructure) error {
+	promoted, err := mongo.ExpireAdmissionOffers(c, form.ID, form.Capacity)
+	if err != nil {
+		return err
+	}
+
+	return WaitlistPromotions(c, producer, mongo, form, promoted)
+}
+
+// WaitlistPromotions triggers the WaitlistPromotion pipelines of the form once for each promoted response
+func WaitlistPromotions(c context.Context, producer producer.MessageProducer, mongo mongodb.MongoService, form *models.FormStructure, promoted []models.FormResponse) error {
+	if len(promoted) == 0 {
+		return nil
+	}
+
+	pipelines, err := mongo.ListPipelines(c, bson.M{"eventID": form.EventID, "event.type": "WaitlistPromotion", "event.waitlistPromotion.onFormID": form.ID})
+	if err != nil {
+		return err
+	}
+
+	if len(pipelines) == 0 {
+		return nil
+	}
+
+	sub, err := GetEventSubscription(c, mongo, form.EventID)
+	if err != nil {
+		return err
+	}
+
+	for _, response := range promoted {
+		if err := BilledPipelines(c, producer, mongo, sub, pipelines, response.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
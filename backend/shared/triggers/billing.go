@@ -0,0 +1,64 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+	"shared/kafka/producer"
+	"shared/models"
+	"shared/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrNoActiveSubscription = errors.New("event owner does not have an active subscription")
+	ErrPipelineLimitReached = errors.New("pipeline limit reached")
+)
+
+// GetEventSubscription returns the active subscription of the event creator, which usage of the event is billed to
+func GetEventSubscription(c context.Context, mongo mongodb.MongoService, eventID primitive.ObjectID) (*models.Subscription, error) {
+	// Not GetEvent, the owner is looked up whoever triggered the usage
+	event, err := mongo.FindEvent(c, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := mongo.GetUserDetails(c, event.CreatedByID)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.CurrentSubscriptionID == primitive.NilObjectID {
+		return nil, ErrNoActiveSubscription
+	}
+
+	sub, err := mongo.GetSubscription(c, u.CurrentSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.Status != models.SubscriptionStatusActive {
+		return nil, ErrNoActiveSubscription
+	}
+
+	return sub, nil
+}
+
+// BilledPipelines triggers each pipeline, counting the run against the subscription's pipeline run limit
+func BilledPipelines(c context.Context, producer producer.MessageProducer, mongo mongodb.MongoService, sub *models.Subscription, pipelines []models.PipelineConfiguration, actionData map[string]interface{}) error {
+	for _, pipeline := range pipelines {
+		if !pipeline.Enabled {
+			continue
+		}
+
+		if _, err := mongo.IncrementSubscriptionUtilization(c, sub.ID, "pipelineRuns", "maxMonthlyPipelineRuns"); err != nil {
+			return ErrPipelineLimitReached
+		}
+
+		if err := Pipeline(c, producer, mongo, pipeline, actionData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
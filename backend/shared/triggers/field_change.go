@@ -0,0 +1,49 @@
+package triggers
+
+import (
+	"context"
+	"reflect"
+	"shared/kafka"
+	"shared/kafka/producer"
+	"shared/models"
+	"shared/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ChangedFieldPipelines returns the FieldChange pipelines whose field was changed by the update and now matches their condition
+func ChangedFieldPipelines(pipelines []models.PipelineConfiguration, previous map[string]interface{}, updated map[string]interface{}) []models.PipelineConfiguration {
+	changed := []models.PipelineConfiguration{}
+	for _, pipeline := range pipelines {
+		fieldID := pipeline.Event.FieldChange.OnFieldID
+		if reflect.DeepEqual(previous[fieldID], updated[fieldID]) {
+			continue
+		}
+
+		if kafka.FieldChangeCheck(pipeline.Event.FieldChange, &updated) {
+			changed = append(changed, pipeline)
+		}
+	}
+
+	return changed
+}
+
+// FieldChangePipelines triggers the FieldChange pipelines of the form whose field was changed by the update and now matches their condition
+func FieldChangePipelines(c context.Context, producer producer.MessageProducer, mongo mongodb.MongoService, form *models.FormStructure, previous map[string]interface{}, updated map[string]interface{}) error {
+	pipelines, err := mongo.ListPipelines(c, bson.M{"eventID": form.EventID, "event.type": "FieldChange", "event.fieldChange.onFormID": form.ID})
+	if err != nil {
+		return err
+	}
+
+	changed := ChangedFieldPipelines(pipelines, previous, updated)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	sub, err := GetEventSubscription(c, mongo, form.EventID)
+	if err != nil {
+		return err
+	}
+
+	return BilledPipelines(c, producer, mongo, sub, changed, updated)
+}
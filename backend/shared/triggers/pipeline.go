@@ -1,4 +1,4 @@
-package helpers
+package triggers
 
 import (
 	"context"
@@ -16,7 +16,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func TriggerPipeline(c context.Context, producer producer.MessageProducer, mongo mongodb.MongoService, pipeline models.PipelineConfiguration, actionData map[string]interface{}) error {
+// Pipeline starts a run of the pipeline, producing a message for each of its actions for the event listener
+func Pipeline(c context.Context, producer producer.MessageProducer, mongo mongodb.MongoService, pipeline models.PipelineConfiguration, actionData map[string]interface{}) error {
 	if !pipeline.Enabled {
 		return nil
 	}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"errors"
 	"log"
 	"net/http"
@@ -15,7 +18,21 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-var jwtSecret []byte
+var (
+	jwtSecret      []byte
+	accessTokenTTL time.Duration
+	sessionStore   SessionStore
+)
+
+// SessionStore looks up the sessions of tokens that GetUserFromContext reads from the Authorization header itself
+type SessionStore interface {
+	GetSession(ctx context.Context, sessionID primitive.ObjectID) (*models.Session, error)
+}
+
+// UseSessionStore sets where GetUserFromContext looks up sessions, until it is set only tokens checked by JWTAuthMiddleware are accepted
+func UseSessionStore(store SessionStore) {
+	sessionStore = store
+}
 
 func init() {
 	apiConfig, err := config.GetAPIConfig()
@@ -29,23 +46,34 @@ func init() {
 	} else {
 		jwtSecret = []byte(apiConfig.JWT_SECRET_TOKEN)
 	}
+
+	accessTokenTTL = time.Duration(apiConfig.ACCESS_TOKEN_TTL_MINUTES) * time.Minute
 }
 
-// GenerateJWT generates a JWT token for the given user
-func GenerateJWT(user *models.User) (string, error) {
+// DeriveSecret derives a key for another purpose from the JWT secret, so whatever it signs can never pass as a JWT signature
+func DeriveSecret(purpose string) []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// GenerateJWT generates a short lived access token for the given user's session, it is only accepted while the session exists
+func GenerateJWT(user *models.User, sessionID primitive.ObjectID) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"id":        user.ID.Hex(),
+		"sid":       sessionID.Hex(),
 		"email":     user.Email,
 		"firstName": user.FirstName,
 		"lastName":  user.LastName,
-		"exp":       time.Now().Add(time.Hour * 72).Unix(),
+		"exp":       time.Now().Add(accessTokenTTL).Unix(),
 	})
 
 	return token.SignedString(jwtSecret)
 }
 
-// VerifyJWT validates a JWT token and returns the user information if it's valid
-func VerifyJWT(tokenString string) (*models.User, error) {
+// VerifyJWT validates a JWT token and returns the user information and session ID if it's valid.
+// It doesn't check the session still exists, JWTAuthMiddleware and GetUserFromContext do
+func VerifyJWT(tokenString string) (*models.User, primitive.ObjectID, error) {
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -55,23 +83,36 @@ func VerifyJWT(tokenString string) (*models.User, error) {
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, primitive.NilObjectID, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, err := hexToObjectID(claims["id"].(string))
-		if err != nil {
-			return nil, err
-		}
-		return &models.User{
-			ID:        userID,
-			Email:     claims["email"].(string),
-			FirstName: claims["firstName"].(string),
-			LastName:  claims["lastName"].(string),
-		}, nil
-	} else {
-		return nil, errors.New("invalid token")
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, primitive.NilObjectID, errors.New("invalid token")
 	}
+
+	userID, err := hexToObjectID(stringClaim(claims, "id"))
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+
+	// Tokens issued before sessions existed have no session and can't be revoked, so they aren't accepted anymore
+	sessionID, err := hexToObjectID(stringClaim(claims, "sid"))
+	if err != nil {
+		return nil, primitive.NilObjectID, errors.New("token has no session")
+	}
+
+	return &models.User{
+		ID:        userID,
+		Email:     stringClaim(claims, "email"),
+		FirstName: stringClaim(claims, "firstName"),
+		LastName:  stringClaim(claims, "lastName"),
+	}, sessionID, nil
+}
+
+func stringClaim(claims jwt.MapClaims, key string) string {
+	value, _ := claims[key].(string)
+	return value
 }
 
 // GetUserFromContext retrieves the authenticated user from the Gin context
@@ -89,15 +130,27 @@ func GetUserFromContext(c *gin.Context, writeResponse bool) (*models.User, bool)
 			return nil, false
 		}
 
-		// Verify the JWT token
-		var err error
-		user, err = VerifyJWT(authHeader)
-		if err != nil {
+		// Verify the JWT token and its session, like JWTAuthMiddleware does, so a signed out token isn't accepted
+		tokenUser, sessionID, err := VerifyJWT(authHeader)
+		if err != nil || sessionStore == nil {
+			if writeResponse {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": badTokenString})
+			}
+			return nil, false
+		}
+
+		session, err := sessionStore.GetSession(c, sessionID)
+		if err != nil || session.UserID != tokenUser.ID {
 			if writeResponse {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": badTokenString})
 			}
 			return nil, false
 		}
+
+		user = tokenUser
+		c.Set("user", tokenUser)
+		c.Set("sessionID", sessionID)
+		c.Set("twoFactorVerified", session.TwoFactorVerified)
 	}
 
 	authenticatedUser, ok := user.(*models.User)
@@ -115,9 +168,44 @@ func GetUserFromContext(c *gin.Context, writeResponse bool) (*models.User, bool)
 		return nil, false
 	}
 
+	// API tokens only work on routes that checked the token's scope, never on the user's account or on routes that don't belong to an event
+	if _, usingAPIToken := GetAPITokenFromContext(c); usingAPIToken && !c.GetBool("apiTokenAllowed") {
+		if writeResponse {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API tokens can't be used for this, sign in instead"})
+		}
+		return nil, false
+	}
+
 	return authenticatedUser, true
 }
 
+// GetAPITokenFromContext retrieves the API token the request was authenticated with, set by JWTAuthMiddleware. It returns false for signed in sessions
+func GetAPITokenFromContext(c *gin.Context) (*models.APIToken, bool) {
+	token, ok := c.Get("apiToken")
+	if !ok {
+		return nil, false
+	}
+
+	apiToken, ok := token.(*models.APIToken)
+	return apiToken, ok
+}
+
+// AllowAPIToken lets GetUserFromContext accept the request's API token, once the caller has checked the token's scope allows the request
+func AllowAPIToken(c *gin.Context) {
+	c.Set("apiTokenAllowed", true)
+}
+
+// GetSessionIDFromContext retrieves the session of the authenticated user, set by JWTAuthMiddleware
+func GetSessionIDFromContext(c *gin.Context) (primitive.ObjectID, bool) {
+	sessionID, ok := c.Get("sessionID")
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+
+	id, ok := sessionID.(primitive.ObjectID)
+	return id, ok
+}
+
 // generateRandomSecret generates a random secret key of the given length
 func generateRandomSecret(length int) []byte {
 	b := make([]byte, length)
@@ -140,3 +228,8 @@ func hexToObjectID(hexStr string) (primitive.ObjectID, error) {
 //
 // Document Authentication Helpers
 //
+
+// IsTwoFactorVerified checks the authenticated user's session was signed in with a second factor, set by JWTAuthMiddleware
+func IsTwoFactorVerified(c *gin.Context) bool {
+	return c.GetBool("twoFactorVerified")
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"net/http/httptest"
 	"shared/models"
 	"testing"
 	"time"
@@ -18,7 +21,7 @@ func TestGenerateJWT(t *testing.T) {
 		LastName:  "Doe",
 	}
 
-	token, err := GenerateJWT(&user)
+	token, err := GenerateJWT(&user, primitive.NewObjectID())
 	assert.Nil(t, err)
 	assert.NotEmpty(t, token)
 }
@@ -31,7 +34,11 @@ func TestVerifyJWT(t *testing.T) {
 	}
 
 	// Generate a valid token
-	validToken, _ := GenerateJWT(&user)
+	sessionID := primitive.NewObjectID()
+	validToken, _ := GenerateJWT(&user, sessionID)
+
+	// Generate a token from before sessions existed
+	sessionlessToken := generateSessionlessJWT(user)
 
 	// Generate an expired token
 	expiredToken := generateExpiredJWT(user)
@@ -48,13 +55,15 @@ func TestVerifyJWT(t *testing.T) {
 		{"Expired Token", expiredToken, false},
 		{"Invalid Signature", invalidSigToken, false},
 		{"Malformed Token", "malformed.token.string", false},
+		{"Token Without Session", sessionlessToken, false},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := VerifyJWT(tc.token)
+			_, tokenSessionID, err := VerifyJWT(tc.token)
 			if tc.isValid {
 				assert.Nil(t, err)
+				assert.Equal(t, sessionID, tokenSessionID)
 			} else {
 				assert.NotNil(t, err)
 			}
@@ -74,6 +83,19 @@ func generateExpiredJWT(user models.User) string {
 	return tokenString
 }
 
+func generateSessionlessJWT(user models.User) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":        primitive.NewObjectID().Hex(),
+		"email":     user.Email,
+		"firstName": user.FirstName,
+		"lastName":  user.LastName,
+		"exp":       time.Now().Add(72 * time.Hour).Unix(),
+	})
+
+	tokenString, _ := token.SignedString(jwtSecret)
+	return tokenString
+}
+
 func generateTokenWithInvalidSignature(user models.User) string {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"email":     user.Email,
@@ -112,6 +134,62 @@ func TestGetUserFromContext(t *testing.T) {
 	assert.Equal(t, user.Email, retrievedUser.Email)
 }
 
+func TestGetUserFromContextWithAPIToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	user := models.User{ID: primitive.NewObjectID(), Email: "test@example.com"}
+	c.Set("user", &user)
+	c.Set("apiToken", &models.APIToken{UserID: user.ID})
+
+	_, exists := GetUserFromContext(c, false)
+	assert.False(t, exists, "API tokens are refused until their scope is checked")
+
+	AllowAPIToken(c)
+	retrievedUser, exists := GetUserFromContext(c, false)
+	assert.True(t, exists)
+	assert.Equal(t, user.ID, retrievedUser.ID)
+}
+
+// testSessionStore keeps the sessions that haven't been signed out
+type testSessionStore map[primitive.ObjectID]models.Session
+
+func (s testSessionStore) GetSession(ctx context.Context, sessionID primitive.ObjectID) (*models.Session, error) {
+	session, ok := s[sessionID]
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+	return &session, nil
+}
+
+func TestGetUserFromContextWithHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user := models.User{ID: primitive.NewObjectID(), Email: "test@example.com"}
+	signedIn, signedOut := primitive.NewObjectID(), primitive.NewObjectID()
+	UseSessionStore(testSessionStore{signedIn: {ID: signedIn, UserID: user.ID, TwoFactorVerified: true}})
+	defer UseSessionStore(nil)
+
+	fromHeader := func(sessionID primitive.ObjectID) (*gin.Context, *models.User, bool) {
+		token, err := GenerateJWT(&user, sessionID)
+		assert.NoError(t, err)
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+		retrievedUser, exists := GetUserFromContext(c, false)
+		return c, retrievedUser, exists
+	}
+
+	c, retrievedUser, exists := fromHeader(signedIn)
+	assert.True(t, exists)
+	assert.Equal(t, user.ID, retrievedUser.ID)
+	assert.True(t, IsTwoFactorVerified(c))
+
+	_, _, exists = fromHeader(signedOut)
+	assert.False(t, exists, "tokens of signed out sessions are refused")
+}
+
 func TestGenerateRandomSecret(t *testing.T) {
 	secret := generateRandomSecret(32)
 	assert.Len(t, secret, 32)
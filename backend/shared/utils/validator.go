@@ -33,6 +33,19 @@ func registerCustomValidations(v *validator.Validate) {
 	v.RegisterValidation("pipelineevent", validateEventType)
 	v.RegisterValidation("pipelineactiontype", validateActionType)
 	v.RegisterValidation("uuidv4", validateUUIDv4)
+	v.RegisterStructValidation(ratingOptionsValidation, models.RatingOptions{})
+}
+
+// ratingOptionsValidation checks a configured rating scale goes up from its min to its max, leaving both unset uses the default scale
+func ratingOptionsValidation(sl validator.StructLevel) {
+	options := sl.Current().Interface().(models.RatingOptions)
+	if options.Min == 0 && options.Max == 0 {
+		return
+	}
+
+	if options.Max <= options.Min {
+		sl.ReportError(options.Max, "Max", "max", "ratingscale", "")
+	}
 }
 
 func validateComparison(fl validator.FieldLevel) bool {
@@ -154,7 +167,7 @@ func requireExistsIf(fl validator.FieldLevel) bool {
 func validateEventType(fl validator.FieldLevel) bool {
 	if event, ok := fl.Field().Interface().(models.PipelineEvent); ok {
 		switch event.Type {
-		case "FormSubmission", "FieldChange":
+		case "FormSubmission", "FieldChange", "ReviewCompleted", "WaitlistPromotion":
 			return true
 		default:
 			return false
@@ -223,6 +236,8 @@ func TranslateValidationError(fe validator.FieldError) string {
 		return fmt.Sprintf("%s must be at most %s characters long", fe.Field(), fe.Param())
 	case "comparison":
 		return fmt.Sprintf("%s is not a valid comparison", fe.Field())
+	case "ratingscale":
+		return "A rating scale must have a max greater than its min"
 	default:
 		return fmt.Sprintf("%s is not valid", fe.Field())
 	}
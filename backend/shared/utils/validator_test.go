@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"shared/models"
 	"testing"
 	"time"
 
@@ -75,3 +76,25 @@ func TestValidateStruct(t *testing.T) {
 		})
 	}
 }
+
+func TestRatingOptionsValidation(t *testing.T) {
+	cases := []struct {
+		name     string
+		options  models.RatingOptions
+		expected []string
+	}{
+		{name: "Default scale", options: models.RatingOptions{}, expected: nil},
+		{name: "Custom scale", options: models.RatingOptions{Min: 0, Max: 10}, expected: nil},
+		{name: "Negative scale", options: models.RatingOptions{Min: -2, Max: 2}, expected: nil},
+		{name: "Only min", options: models.RatingOptions{Min: 3}, expected: []string{"A rating scale must have a max greater than its min"}},
+		{name: "Reversed", options: models.RatingOptions{Min: 5, Max: 1}, expected: []string{"A rating scale must have a max greater than its min"}},
+		{name: "Single value", options: models.RatingOptions{Min: 4, Max: 4}, expected: []string{"A rating scale must have a max greater than its min"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			field := models.FormField{Question: "Rate us", Type: models.FormFieldTypeRating, Key: "9b2e3f8a-1c4d-4e5f-8a6b-7c8d9e0f1a2b", RatingOptions: tc.options}
+			assert.Equal(t, tc.expected, ValidateStruct(Validator, models.FormStructure{Attrs: []models.FormField{field}}))
+		})
+	}
+}
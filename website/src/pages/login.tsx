@@ -1,8 +1,8 @@
-import React, { useEffect } from 'react';
+import React, { useEffect, useState } from 'react';
 import Link from 'next/link';
 import { useRouter } from 'next/router';
 
-import AuthService from '@/services/AuthService';
+import AuthService, { SSOProvider } from '@/services/AuthService';
 import { eventEmitter } from '@/events/EventEmitter';
 import FormBuilder from '@/components/Form/FormBuilder';
 import { FormStructure } from '@/types/models/Form';
@@ -12,13 +12,47 @@ import Metadata from '@/components/Metadata';
 
 const LoginPage = () => {
   const router = useRouter();
+  const [ssoProviders, setSSOProviders] = useState<SSOProvider[]>([]);
+  const [twoFactorToken, setTwoFactorToken] = useState<string | null>(null);
 
   useEffect(() => {
+    // Signing in with SSO comes back here with a refresh token, a two-factor token or an error in the fragment
+    const fragment = new URLSearchParams(window.location.hash.slice(1));
+    const refreshToken = fragment.get('refreshToken');
+    const ssoTwoFactorToken = fragment.get('twoFactorToken');
+    const error = fragment.get('error');
+    if (refreshToken || ssoTwoFactorToken || error) {
+      window.history.replaceState(null, '', window.location.pathname);
+    }
+
+    if (error) {
+      eventEmitter.emit('apiError', error);
+    } else if (ssoTwoFactorToken) {
+      setTwoFactorToken(ssoTwoFactorToken);
+      return;
+    } else if (refreshToken) {
+      AuthService.completeSSOLogin(refreshToken)
+        .then(() => {
+          eventEmitter.emit('success', 'Successfully logged in!');
+          router.push('/user/dashboard');
+        })
+        .catch(() => {
+          eventEmitter.emit('apiError', 'Sign in failed, please try again');
+        });
+      return;
+    }
+
     if (AuthService.isAuth()) {
       router.push('/user/dashboard');
     }
   }, [router]);
 
+  useEffect(() => {
+    AuthService.listSSOProviders()
+      .then(setSSOProviders)
+      .catch(() => setSSOProviders([]));
+  }, []);
+
   const loginFormStructure: FormStructure = {
     attrs: [
       {
@@ -48,6 +82,35 @@ const LoginPage = () => {
   const handleSubmit = (formData: Record<string, any>) => {
     const { email, password } = formData;
     AuthService.login({ email, password } as User)
+      .then((result) => {
+        if (result.twoFactorToken) {
+          setTwoFactorToken(result.twoFactorToken);
+          return;
+        }
+        eventEmitter.emit('success', 'Successfully logged in!');
+        router.push('/user/dashboard');
+      })
+      .catch((err) => {
+        if (err.response) {
+          eventEmitter.emit('apiError', err.response.data.error);
+        }
+      });
+  };
+
+  const twoFactorFormStructure: FormStructure = {
+    attrs: [
+      {
+        question: 'Code from your authenticator app, or a recovery code',
+        type: 'text',
+        key: 'code',
+        required: true,
+      },
+    ],
+  };
+
+  // eslint-disable-next-line @typescript-eslint/no-explicit-any -- this is a generic form submission handler
+  const handleTwoFactorSubmit = (formData: Record<string, any>) => {
+    AuthService.verifyTwoFactor(twoFactorToken!, formData.code)
       .then(() => {
         eventEmitter.emit('success', 'Successfully logged in!');
         router.push('/user/dashboard');
@@ -55,10 +118,32 @@ const LoginPage = () => {
       .catch((err) => {
         if (err.response) {
           eventEmitter.emit('apiError', err.response.data.error);
+          // The two-factor token expires, then the password has to be entered again
+          if (err.response.status === 401) {
+            setTwoFactorToken(null);
+          }
         }
       });
   };
 
+  if (twoFactorToken) {
+    return (
+      <>
+        <Metadata title="ApplicantAtlas | Login" />
+        <Header />
+        <div className="flex items-center justify-center h-screen bg-gray-100">
+          <div className="w-full max-w-xs">
+            <FormBuilder
+              formStructure={twoFactorFormStructure}
+              submissionFunction={handleTwoFactorSubmit}
+              buttonText="Verify"
+            />
+          </div>
+        </div>
+      </>
+    );
+  }
+
   return (
     <>
       <Metadata title="ApplicantAtlas | Login" />
@@ -70,6 +155,15 @@ const LoginPage = () => {
             submissionFunction={handleSubmit}
             buttonText="Login"
           />
+          {ssoProviders.map((provider) => (
+            <a
+              key={provider.id}
+              href={AuthService.ssoLoginURL(provider.id)}
+              className="block w-full mb-2 py-2 px-4 text-center border border-gray-300 rounded bg-white text-gray-700 hover:bg-gray-50"
+            >
+              Sign in with {provider.name}
+            </a>
+          ))}
           <Link href="/register">
             <div className="inline-block align-baseline font-bold text-sm text-blue-500 hover:text-blue-800 cursor-pointer">
               Sign Up
@@ -1,9 +1,10 @@
 // AuthService.ts
-import { AxiosResponse } from 'axios';
+import axios, { AxiosResponse } from 'axios';
 import { jwtDecode } from 'jwt-decode';
 import posthog from 'posthog-js';
 
 import { User } from '@/types/models/User';
+import { API_URL } from '@/config/constants';
 
 import api from './AxiosInterceptor';
 import { SendEvent } from './AnalyticsService';
@@ -11,9 +12,13 @@ import { SendEvent } from './AnalyticsService';
 const register = async (u: User): Promise<User> => {
   return new Promise(async (resolve, reject) => {
     try {
-      const response = await api.post<{ token: string }>(`/auth/register`, u);
+      const response = await api.post<{ token: string; refreshToken: string }>(
+        `/auth/register`,
+        u,
+      );
       const tok = response.data.token;
       localStorage.setItem('token', tok);
+      localStorage.setItem('refreshToken', response.data.refreshToken);
 
       const decoded: User = jwtDecode<User>(tok);
       localStorage.setItem('user', JSON.stringify(decoded));
@@ -34,33 +39,211 @@ const register = async (u: User): Promise<User> => {
   });
 };
 
-const login = (u: User): Promise<User> => {
-  return new Promise(async (resolve, reject) => {
-    try {
-      const response = await api.post<{ token: string }>(`/auth/login`, u);
-      const tok = response.data.token;
-      localStorage.setItem('token', tok);
+// storeSession keeps the tokens of a new session and the user they are for
+const storeSession = (token: string, refreshToken: string): User => {
+  localStorage.setItem('token', token);
+  localStorage.setItem('refreshToken', refreshToken);
 
-      const decoded: User = jwtDecode<User>(tok);
-      localStorage.setItem('user', JSON.stringify(decoded));
+  const decoded: User = jwtDecode<User>(token);
+  localStorage.setItem('user', JSON.stringify(decoded));
 
-      posthog.identify(decoded.id, {
-        email: decoded.email,
-        name: `${decoded.firstName} ${decoded.lastName}`,
-      });
-      resolve(decoded);
-    } catch (error) {
-      reject(error);
-    }
+  posthog.identify(decoded.id, {
+    email: decoded.email,
+    name: `${decoded.firstName} ${decoded.lastName}`,
   });
+  return decoded;
+};
+
+// Users with two-factor authentication get a two-factor token to finish signing in with verifyTwoFactor instead of a session
+export interface LoginResult {
+  user?: User;
+  twoFactorToken?: string;
+}
+
+const login = async (u: User): Promise<LoginResult> => {
+  const response = await api.post<{
+    token?: string;
+    refreshToken?: string;
+    twoFactorRequired?: boolean;
+    twoFactorToken?: string;
+  }>(`/auth/login`, u);
+
+  if (response.data.twoFactorRequired) {
+    return { twoFactorToken: response.data.twoFactorToken };
+  }
+  return {
+    user: storeSession(response.data.token!, response.data.refreshToken!),
+  };
+};
+
+// Finish signing in with a code from the authenticator app or a recovery code
+const verifyTwoFactor = async (
+  twoFactorToken: string,
+  code: string,
+): Promise<User> => {
+  const response = await api.post<{ token: string; refreshToken: string }>(
+    `/auth/2fa/verify`,
+    { twoFactorToken, code },
+  );
+  return storeSession(response.data.token, response.data.refreshToken);
+};
+
+export interface TwoFactorStatus {
+  enabled: boolean;
+  enabledAt?: string;
+  recoveryCodesRemaining?: number;
+  sessionVerified?: boolean;
+}
+
+const getTwoFactorStatus = async (): Promise<TwoFactorStatus> => {
+  const response = await api.get<TwoFactorStatus>(`/auth/2fa`);
+  return response.data;
+};
+
+// Start adding an authenticator app, the otpauth URL is shown as a QR code
+const setUpTwoFactor = async (): Promise<{
+  secret: string;
+  otpauthURL: string;
+}> => {
+  const response = await api.post(`/auth/2fa/setup`);
+  return response.data;
+};
+
+// Enable the authenticator app with a code from it, the recovery codes are only returned this once
+const enableTwoFactor = async (code: string): Promise<string[]> => {
+  const response = await api.post<{ recoveryCodes: string[] }>(
+    `/auth/2fa/enable`,
+    { code },
+  );
+  return response.data.recoveryCodes;
+};
+
+const regenerateRecoveryCodes = async (code: string): Promise<string[]> => {
+  const response = await api.post<{ recoveryCodes: string[] }>(
+    `/auth/2fa/recovery-codes`,
+    { code },
+  );
+  return response.data.recoveryCodes;
+};
+
+const disableTwoFactor = async (code: string): Promise<AxiosResponse> => {
+  return api.post(`/auth/2fa/disable`, { code });
+};
+
+export interface APIToken {
+  id: string;
+  name: string;
+  hint: string;
+  eventID: string;
+  formID?: string;
+  permissions: string[];
+  createdAt: string;
+  lastUsedAt?: string;
+  expiresAt: string;
+}
+
+export interface NewAPIToken {
+  name: string;
+  eventID: string;
+  formID?: string;
+  permissions: string[];
+  expiresInDays?: number;
+}
+
+const listAPITokens = async (): Promise<APIToken[]> => {
+  const response = await api.get<{ apiTokens: APIToken[] }>(
+    `/auth/api-tokens`,
+  );
+  return response.data.apiTokens;
+};
+
+// Create an API token for scripts, the token itself is only returned this once
+const createAPIToken = async (
+  t: NewAPIToken,
+): Promise<{ token: string; apiToken: APIToken }> => {
+  const response = await api.post(`/auth/api-tokens`, t);
+  return response.data;
+};
+
+const revokeAPIToken = async (tokenID: string): Promise<AxiosResponse> => {
+  return api.delete(`/auth/api-tokens/${tokenID}`);
+};
+
+export interface SSOProvider {
+  id: string;
+  name: string;
+}
+
+// The SSO providers configured on the API
+const listSSOProviders = async (): Promise<SSOProvider[]> => {
+  const response = await api.get<SSOProvider[]>(`/auth/sso/providers`);
+  return response.data;
+};
+
+// Where to send the browser to sign in with an SSO provider, it comes back to the login page with a refresh token
+const ssoLoginURL = (providerID: string): string => {
+  return `${API_URL}/auth/sso/${encodeURIComponent(providerID)}/login`;
+};
+
+// Finish signing in with SSO by exchanging the refresh token the API sent back for an access token
+const completeSSOLogin = async (refreshToken: string): Promise<User> => {
+  const response = await axios.post<{ token: string; refreshToken: string }>(
+    `${API_URL}/auth/refresh`,
+    { refreshToken },
+  );
+  return storeSession(response.data.token, response.data.refreshToken);
 };
 
 const logout = (): void => {
+  // Sign out the session on the server too, without the interceptor as a signed out token would redirect back here
+  const tok = localStorage.getItem('token');
+  if (tok) {
+    axios
+      .post(`${API_URL}/auth/logout`, null, {
+        headers: { Authorization: `Bearer ${tok}` },
+      })
+      .catch(() => {});
+  }
+
   localStorage.removeItem('user');
   localStorage.removeItem('token');
+  localStorage.removeItem('refreshToken');
   posthog.reset();
 };
 
+// Sign out every device, including this one
+const logoutEverywhere = async (): Promise<AxiosResponse> => {
+  const response = await api.post(`/auth/logout-all`);
+  logout();
+  return response;
+};
+
+// Change the password, every other device is signed out
+const changePassword = async (
+  currentPassword: string,
+  newPassword: string,
+): Promise<AxiosResponse> => {
+  return api.put(`/auth/password`, { currentPassword, newPassword });
+};
+
+// Email a link to reset a forgotten password
+const forgotPassword = async (email: string): Promise<AxiosResponse> => {
+  return api.post(`/auth/forgot-password`, { email });
+};
+
+// Set a new password with the token from a password reset link
+const resetPassword = async (
+  token: string,
+  newPassword: string,
+): Promise<AxiosResponse> => {
+  return api.post(`/auth/reset-password`, { token, newPassword });
+};
+
+// Send a new link to verify the user's email
+const resendVerificationEmail = async (): Promise<AxiosResponse> => {
+  return api.post(`/auth/verify-email/send`);
+};
+
 // Delete self
 const deleteUser = async (): Promise<AxiosResponse> => {
   return api.delete(`/auth/delete`);
@@ -73,7 +256,24 @@ const isAuth = (): boolean => {
 const authActions = {
   register,
   login,
+  verifyTwoFactor,
+  getTwoFactorStatus,
+  setUpTwoFactor,
+  enableTwoFactor,
+  regenerateRecoveryCodes,
+  disableTwoFactor,
+  listAPITokens,
+  createAPIToken,
+  revokeAPIToken,
+  listSSOProviders,
+  ssoLoginURL,
+  completeSSOLogin,
   logout,
+  logoutEverywhere,
+  changePassword,
+  forgotPassword,
+  resetPassword,
+  resendVerificationEmail,
   deleteUser,
   isAuth,
 };
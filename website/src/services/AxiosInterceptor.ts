@@ -1,4 +1,5 @@
 import axios from 'axios';
+import { jwtDecode } from 'jwt-decode';
 
 import { eventEmitter } from '../events/EventEmitter';
 import { API_URL } from '../config/constants';
@@ -24,10 +25,60 @@ api.interceptors.request.use(
   },
 );
 
+// Access tokens are short lived, a refresh in flight is shared so the refresh token is only used once
+let refreshing: Promise<string> | null = null;
+
+const refreshToken = (): Promise<string> => {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('refreshToken');
+    refreshing = (
+      refreshToken
+        ? axios
+            .post<{ token: string; refreshToken: string }>(
+              `${API_URL}/auth/refresh`,
+              { refreshToken },
+            )
+            .then((response) => {
+              localStorage.setItem('token', response.data.token);
+              localStorage.setItem('refreshToken', response.data.refreshToken);
+              localStorage.setItem(
+                'user',
+                JSON.stringify(jwtDecode(response.data.token)),
+              );
+              return response.data.token;
+            })
+        : Promise.reject(new Error('No refresh token'))
+    ).finally(() => {
+      refreshing = null;
+    });
+  }
+  return refreshing;
+};
+
 // Response interceptor for API calls
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    // Get a new access token and try again once when the current one has expired
+    const request = error.config;
+    if (
+      error.response &&
+      error.response.status == 401 &&
+      error.response.data &&
+      error.response.data.error == 'Invalid or expired token' &&
+      request &&
+      !request._retried
+    ) {
+      request._retried = true;
+      try {
+        const token = await refreshToken();
+        request.headers['Authorization'] = `Bearer ${token}`;
+        return api(request);
+      } catch {
+        // Fall through to logging the user out
+      }
+    }
+
     if (!error.response) {
       eventEmitter.emit(
         'apiError',
@@ -3,6 +3,7 @@ import { AxiosResponse } from 'axios';
 import { EventMetadata, EventModel } from '@/types/models/Event';
 import { FormStructure } from '@/types/models/Form';
 import { EventSecrets } from '@/types/models/EventSecret';
+import { AuditLogEntry, AuditLogFilter } from '@/types/models/AuditLog';
 
 import api from './AxiosInterceptor';
 
@@ -118,3 +119,14 @@ export const removeEventAdmin = async (
 ): Promise<AxiosResponse> => {
   return api.delete(`/events/${eventId}/organizers/${userId}`);
 };
+
+// List the event's audit log, newest first, only owners can read it
+export const listAuditLog = async (
+  eventId: string,
+  filter: AuditLogFilter = {},
+): Promise<{ entries: AuditLogEntry[]; page: number; pageSize: number }> => {
+  const response = await api.get(`/events/${eventId}/audit-log`, {
+    params: filter,
+  });
+  return response.data;
+};
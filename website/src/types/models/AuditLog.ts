@@ -0,0 +1,38 @@
+export type AuditTarget = {
+  type: string;
+  id: string;
+};
+
+export type AuditChange = {
+  field: string;
+  old?: unknown;
+  new?: unknown;
+};
+
+export type AuditLogEntry = {
+  id: string;
+  eventID: string;
+  actorID: string;
+  actorEmail: string;
+  apiTokenID?: string;
+  action: string;
+  path: string;
+  permission?: string;
+  target: AuditTarget;
+  ip: string;
+  userAgent?: string;
+  status: number;
+  changes: AuditChange[] | null;
+  createdAt: Date;
+};
+
+export type AuditLogFilter = {
+  actor?: string;
+  action?: string;
+  targetType?: string;
+  targetID?: string;
+  since?: string;
+  until?: string;
+  page?: number;
+  pageSize?: number;
+};
@@ -5,6 +5,7 @@ type ObjectID = string;
 export type EventModel = {
   ID: ObjectID;
   organizerIDs?: ObjectID[];
+  requireTwoFactor?: boolean; // organizers have to sign in with a second factor
   metadata: EventMetadata;
 };
 
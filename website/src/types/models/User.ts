@@ -6,4 +6,5 @@ export type User = {
   birthday: string;
   password?: string;
   alternativeEmails?: string[];
+  emailVerifiedAt?: string;
 };